@@ -0,0 +1,106 @@
+package mongo
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrAccessDenied is returned, wrapped with the operation and collection,
+// for operations an access policy doesn't permit.
+var ErrAccessDenied = errors.New("Operation not permitted by the access policy")
+
+// Access is a set of permitted kinds of operation.
+type Access int
+
+const (
+	// AllowFind permits finds, counts, aggregations and backups.
+	AllowFind Access = 1 << iota
+	// AllowInsert permits inserts, including upserts and restores.
+	AllowInsert
+	// AllowUpdate permits updates, including upserts.
+	AllowUpdate
+	// AllowDelete permits deletes, including dropping a collection for a
+	// restore.
+	AllowDelete
+
+	AllowAll = AllowFind | AllowInsert | AllowUpdate | AllowDelete
+)
+
+// opAccess maps operation names to what they need. Operations that aren't
+// listed, like ensureIndex, are always permitted. Bulk writes check each of
+// their operations instead.
+var opAccess = map[string]Access{
+	"find":            AllowFind,
+	"count":           AllowFind,
+	"aggregate":       AllowFind,
+	"backup":          AllowFind,
+	"sync":            AllowFind,
+	"listCollections": AllowFind,
+	"listIndexes":     AllowFind,
+	"insert":          AllowInsert,
+	"update":          AllowUpdate,
+	"delete":          AllowDelete,
+	"restore":         AllowInsert | AllowDelete,
+}
+
+var (
+	policyMu sync.RWMutex
+	policy   = map[string]Access{}
+)
+
+// Permit only the given kinds of operation on a collection. Collections
+// without a policy permit everything. It's a blunt guard against bugs, e.g.
+// an append-only events collection:
+//
+//	mongo.SetAccessPolicy("events", mongo.AllowInsert|mongo.AllowFind)
+func SetAccessPolicy(coll string, access Access) {
+	policyMu.Lock()
+	policy[coll] = access
+	policyMu.Unlock()
+}
+
+// Remove the access policy of a collection so it permits everything again.
+func ClearAccessPolicy(coll string) {
+	policyMu.Lock()
+	delete(policy, coll)
+	policyMu.Unlock()
+}
+
+// allowed returns an error if the access policy of the operation's
+// collection doesn't permit it, or if it writes while read only mode is on.
+func (o *operation) allowed() error {
+	need := opAccess[o.op]
+	if need&^AllowFind != 0 && o.coll != SettingsCollection && atomic.LoadInt32(&readOnly) == 1 {
+		return fmt.Errorf("%w: %v on %v", ErrReadOnly, o.op, o.coll)
+	}
+
+	policyMu.RLock()
+	access, ok := policy[o.coll]
+	policyMu.RUnlock()
+
+	if !ok || access&need == need {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %v on %v, which permits %v", ErrAccessDenied, o.op, o.coll, access)
+}
+
+func (a Access) String() string {
+	var names []string
+	for _, n := range []struct {
+		a    Access
+		name string
+	}{{AllowFind, "find"}, {AllowInsert, "insert"}, {AllowUpdate, "update"}, {AllowDelete, "delete"}} {
+		if a&n.a != 0 {
+			names = append(names, n.name)
+		}
+	}
+
+	if len(names) == 0 {
+		return "nothing"
+	}
+	return strings.Join(names, "+")
+}
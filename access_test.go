@@ -0,0 +1,54 @@
+package mongo
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAccessPolicy(t *testing.T) {
+	SetAccessPolicy("events", AllowInsert|AllowFind)
+	defer ClearAccessPolicy("events")
+
+	for op, allowed := range map[string]bool{
+		"find": true, "count": true, "insert": true, "ensureIndex": true, "listIndexes": true,
+		"update": false, "delete": false, "restore": false,
+	} {
+		o := startOp(op, "events", nil)
+		err := o.allowed()
+		o.done(nil)
+
+		if allowed && err != nil {
+			t.Errorf("Expected %v to be permitted, got: %v", op, err)
+		}
+		if !allowed && !errors.Is(err, ErrAccessDenied) {
+			t.Errorf("Expected %v to be denied, got: %v", op, err)
+		}
+	}
+
+	if err := startOp("delete", "other", nil).allowed(); err != nil {
+		t.Fatal("Expected collections without a policy to permit everything:", err)
+	}
+}
+
+func TestAccessDeniedBeforeConnecting(t *testing.T) {
+	SetAccessPolicy("MongoTest", AllowFind)
+	defer ClearAccessPolicy("MongoTest")
+
+	err := Delete(&MongoTest{})
+	if !errors.Is(err, ErrAccessDenied) {
+		t.Fatal("Expected ErrAccessDenied, got:", err)
+	}
+
+	if (AllowInsert | AllowFind).String() != "find+insert" {
+		t.Fatal("Unexpected String:", (AllowInsert | AllowFind).String())
+	}
+}
+
+func TestCollectionIndexesAccessDenied(t *testing.T) {
+	SetAccessPolicy("MongoTest", AllowInsert)
+	defer ClearAccessPolicy("MongoTest")
+
+	if _, err := CollectionIndexes("MongoTest"); !errors.Is(err, ErrAccessDenied) {
+		t.Fatal("Expected ErrAccessDenied, got:", err)
+	}
+}
@@ -0,0 +1,272 @@
+/*
+The admin package serves JSON endpoints for browsing the database, a
+small alternative to mongo-express built into the application. It
+doesn't do any authentication; wrap the handler with the application's
+own:
+
+	mux.Handle("/admin/db/", requireAdmin(http.StripPrefix("/admin/db", admin.Handler())))
+
+Routes:
+
+	GET /collections                        names and document counts
+	GET /collections/{name}/sample?n=20     random documents
+	GET /collections/{name}/indexes         index definitions
+	GET /collections/{name}/documents       filtered browsing
+	GET /options                            runtime options and their values
+	PUT /options/{name}?publish=true        set a runtime option
+
+The documents route takes a filter parameter holding a query in mongo
+extended JSON, e.g. {"age": {"$gt": 30}}, along with page, perPage and
+sort (comma separated, "-" for descending).
+
+The body of a PUT to an option is its JSON value, e.g. true or "250ms",
+passed to mongo.SetOption. With publish=true it's also stored with
+mongo.PublishOption for the other processes running mongo.WatchOptions.
+*/
+package admin
+
+import (
+	"github.com/globalsign/mgo/bson"
+	"github.com/sfreiberg/mongo"
+
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// DefaultSampleSize is the number of documents returned by the sample route
+// when n isn't given. MaxSampleSize caps it.
+var (
+	DefaultSampleSize = 20
+	MaxSampleSize     = 1000
+)
+
+// MaxPerPage caps the perPage parameter of the documents route.
+var MaxPerPage = 500
+
+// CollectionInfo is an entry of the collections route.
+type CollectionInfo struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// OptionsInfo is the response of the options route. Values only holds the
+// options that were set; the rest have their defaults.
+type OptionsInfo struct {
+	Names  []string               `json:"names"`
+	Values map[string]interface{} `json:"values"`
+}
+
+type documentsResponse struct {
+	Documents []bson.M `json:"documents"`
+	*mongo.Page
+}
+
+// Returns the admin handler. Requests must have any mount prefix stripped.
+func Handler() http.Handler {
+	return http.HandlerFunc(serve)
+}
+
+func serve(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	if r.Method == "PUT" && len(parts) == 2 && parts[0] == "options" {
+		setOption(w, r, parts[1])
+		return
+	}
+
+	if r.Method != "GET" {
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	switch {
+	case len(parts) == 1 && parts[0] == "options":
+		writeJSON(w, http.StatusOK, OptionsInfo{Names: mongo.OptionNames(), Values: mongo.Options()})
+	case len(parts) == 1 && parts[0] == "collections":
+		collections(w)
+	case len(parts) == 3 && parts[0] == "collections" && parts[1] != "":
+		switch parts[2] {
+		case "sample":
+			sample(w, r, parts[1])
+		case "indexes":
+			indexes(w, parts[1])
+		case "documents":
+			documents(w, r, parts[1])
+		default:
+			writeError(w, http.StatusNotFound, "Not found")
+		}
+	default:
+		writeError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+func collections(w http.ResponseWriter) {
+	names, err := mongo.CollectionNames()
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+
+	infos := make([]CollectionInfo, 0, len(names))
+	for _, name := range names {
+		n, err := mongo.CountCollection(name)
+		if err != nil {
+			writeErr(w, err)
+			return
+		}
+		infos = append(infos, CollectionInfo{Name: name, Count: n})
+	}
+
+	writeJSON(w, http.StatusOK, infos)
+}
+
+func sample(w http.ResponseWriter, r *http.Request, coll string) {
+	n, err := intParam(r, "n", DefaultSampleSize)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if n > MaxSampleSize {
+		n = MaxSampleSize
+	}
+
+	docs, err := mongo.SampleDocs(coll, n)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, docs)
+}
+
+func indexes(w http.ResponseWriter, coll string) {
+	idx, err := mongo.CollectionIndexes(coll)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, idx)
+}
+
+func documents(w http.ResponseWriter, r *http.Request, coll string) {
+	q, err := filter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	page, err := intParam(r, "page", 1)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	perPage, err := intParam(r, "perPage", mongo.DefaultPerPage)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if perPage > MaxPerPage {
+		perPage = MaxPerPage
+	}
+
+	var sort []string
+	if s := r.URL.Query().Get("sort"); s != "" {
+		sort = strings.Split(s, ",")
+	}
+
+	docs, p, err := mongo.BrowseDocs(coll, q, page, perPage, sort...)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, documentsResponse{Documents: docs, Page: p})
+}
+
+func setOption(w http.ResponseWriter, r *http.Request, name string) {
+	var value interface{}
+	if err := json.NewDecoder(r.Body).Decode(&value); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid value: "+err.Error())
+		return
+	}
+
+	err := mongo.SetOption(name, value)
+	if err == nil && r.URL.Query().Get("publish") == "true" {
+		err = mongo.PublishOption(name, value)
+	}
+
+	switch {
+	case errors.Is(err, mongo.ErrUnknownOption):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, mongo.ErrInvalidOption):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case err != nil:
+		writeErr(w, err)
+	default:
+		writeJSON(w, http.StatusOK, map[string]interface{}{name: value})
+	}
+}
+
+// filter parses the filter parameter. An empty filter matches everything.
+func filter(r *http.Request) (bson.M, error) {
+	q := bson.M{}
+
+	f := r.URL.Query().Get("filter")
+	if f == "" {
+		return q, nil
+	}
+
+	if err := bson.UnmarshalJSON([]byte(f), &q); err != nil {
+		return nil, &paramError{"filter", err.Error()}
+	}
+	return q, nil
+}
+
+func intParam(r *http.Request, name string, def int) (int, error) {
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return def, nil
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		return 0, &paramError{name, "must be a positive number"}
+	}
+	return n, nil
+}
+
+type paramError struct {
+	param, msg string
+}
+
+func (e *paramError) Error() string {
+	return "Invalid " + e.param + ": " + e.msg
+}
+
+func writeErr(w http.ResponseWriter, err error) {
+	log.Println("admin:", err)
+	writeError(w, http.StatusInternalServerError, "Internal error")
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	data, err := bson.MarshalJSON(v)
+	if err != nil {
+		log.Println("admin:", err)
+		status = http.StatusInternalServerError
+		data, _ = json.Marshal(map[string]string{"error": "Internal error"})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(data)
+}
@@ -0,0 +1,45 @@
+package admin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIntParam(t *testing.T) {
+	r := httptest.NewRequest("GET", "/collections/users/sample?n=5&page=x", nil)
+
+	if n, err := intParam(r, "n", 20); err != nil || n != 5 {
+		t.Fatal("Expected n to be 5, got:", n, err)
+	}
+	if n, err := intParam(r, "perPage", 20); err != nil || n != 20 {
+		t.Fatal("Expected the default, got:", n, err)
+	}
+	if _, err := intParam(r, "page", 1); err == nil {
+		t.Fatal("Expected an error for a non numeric page")
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest("DELETE", "/collections", nil))
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatal("Expected 405, got:", w.Code)
+	}
+}
+
+func TestSetOption(t *testing.T) {
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest("PUT", "/options/nosuchoption", strings.NewReader("true")))
+	if w.Code != http.StatusNotFound {
+		t.Fatal("Expected 404 for an unknown option, got:", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest("PUT", "/options/readonly", strings.NewReader(`"yes"`)))
+	if w.Code != http.StatusBadRequest {
+		t.Fatal("Expected 400 for an invalid value, got:", w.Code)
+	}
+}
@@ -0,0 +1,175 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Pipeline is an aggregation pipeline. Stages are added with its methods,
+// each of which returns a new Pipeline so a base pipeline can be shared:
+//
+//	p := mongo.Pipeline{}.Match(bson.M{"active": true}).Sort("-createdat").Limit(10)
+type Pipeline []bson.M
+
+// Add a stage to the pipeline, e.g. Stage("$unwind", "$tags").
+func (p Pipeline) Stage(name string, spec interface{}) Pipeline {
+	out := make(Pipeline, len(p), len(p)+1)
+	copy(out, p)
+	return append(out, bson.M{name: spec})
+}
+
+// Add a $match stage.
+func (p Pipeline) Match(q bson.M) Pipeline {
+	return p.Stage("$match", q)
+}
+
+// Add a $sort stage. Prefix a field with - to sort in descending order.
+func (p Pipeline) Sort(fields ...string) Pipeline {
+	return p.Stage("$sort", sortSpec(fields))
+}
+
+// Add a $skip stage.
+func (p Pipeline) Skip(n int) Pipeline {
+	return p.Stage("$skip", n)
+}
+
+// Add a $limit stage.
+func (p Pipeline) Limit(n int) Pipeline {
+	return p.Stage("$limit", n)
+}
+
+// Add a $project stage.
+func (p Pipeline) Project(spec bson.M) Pipeline {
+	return p.Stage("$project", spec)
+}
+
+// Add a $group stage. The spec must contain an _id.
+func (p Pipeline) Group(spec bson.M) Pipeline {
+	return p.Stage("$group", spec)
+}
+
+// AggregateOptions tune how a pipeline is run.
+type AggregateOptions struct {
+	// AllowDiskUse lets stages write temporary files when they exceed the
+	// server's memory limit.
+	AllowDiskUse bool
+	// BatchSize is the number of documents fetched per round trip.
+	BatchSize int
+	// MaxTime aborts the aggregation on the server after the duration.
+	MaxTime time.Duration
+}
+
+// Run an aggregation pipeline on the collection for i. If result is a
+// pointer to a slice every document is returned, otherwise only the first.
+// Types sharing a collection are scoped to their own documents.
+func Aggregate(i interface{}, p Pipeline, result interface{}) error {
+	return std.Aggregate(i, p, result)
+}
+
+// Run an aggregation pipeline using the client. See Aggregate.
+func (c *Client) Aggregate(i interface{}, p Pipeline, result interface{}) error {
+	return c.AggregateWith(i, p, AggregateOptions{}, result)
+}
+
+// Same as Aggregate but with options. Use AggregateIter for results that are
+// too large to hold in memory.
+func AggregateWith(i interface{}, p Pipeline, opts AggregateOptions, result interface{}) error {
+	return std.AggregateWith(i, p, opts, result)
+}
+
+// Run an aggregation pipeline with options using the client. See
+// AggregateWith.
+func (c *Client) AggregateWith(i interface{}, p Pipeline, opts AggregateOptions, result interface{}) error {
+	if !isPtr(result) {
+		return NoPtr
+	}
+
+	op := c.startOp("aggregate", collName(i), nil)
+
+	if err := op.allowed(); err != nil {
+		return op.done(err)
+	}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return op.done(err)
+	}
+	defer s.Close()
+
+	pipe := c.pipeFor(s, i, p, opts)
+
+	// Results are shaped by the pipeline rather than stored records, so
+	// migrations never write them back.
+	if isSlice(reflect.TypeOf(result)) {
+		var raws []bson.Raw
+		if err = pipe.All(&raws); err == nil {
+			err = unmarshalAll(nil, nil, raws, result)
+		}
+	} else {
+		var raw bson.Raw
+		if err = pipe.One(&raw); err == nil {
+			err = unmarshalRecord(nil, nil, raw, result)
+		}
+	}
+	return op.done(err)
+}
+
+// Run an aggregation pipeline and return a cursor over the results so they
+// can be decoded one at a time. The cursor must be closed.
+func AggregateIter(i interface{}, p Pipeline, opts AggregateOptions) *Cursor {
+	return std.AggregateIter(i, p, opts)
+}
+
+// Iterate over the results of a pipeline using the client. See AggregateIter.
+func (c *Client) AggregateIter(i interface{}, p Pipeline, opts AggregateOptions) *Cursor {
+	op := c.startOp("aggregate", collName(i), nil)
+
+	if err := op.allowed(); err != nil {
+		return &Cursor{op: op, err: op.done(err)}
+	}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return &Cursor{op: op, err: op.done(err)}
+	}
+
+	return &Cursor{op: op, session: s, iter: c.pipeFor(s, i, p, opts).Iter()}
+}
+
+func (c *Client) pipeFor(s *mgo.Session, i interface{}, p Pipeline, opts AggregateOptions) *mgo.Pipe {
+	if q := scopeQuery(i, nil); q != nil {
+		p = append(Pipeline{{"$match": q}}, p...)
+	}
+
+	pipe := c.GetColl(s, collName(i)).Pipe(p)
+	if opts.AllowDiskUse {
+		pipe = pipe.AllowDiskUse()
+	}
+	if opts.BatchSize > 0 {
+		pipe = pipe.Batch(opts.BatchSize)
+	}
+	if opts.MaxTime > 0 {
+		pipe = pipe.SetMaxTime(opts.MaxTime)
+	}
+	return pipe
+}
+
+// sortSpec converts sort fields in the mgo style ("-name") into an ordered
+// sort document.
+func sortSpec(fields []string) bson.D {
+	spec := make(bson.D, 0, len(fields))
+	for _, f := range fields {
+		order := 1
+		if strings.HasPrefix(f, "-") {
+			order, f = -1, f[1:]
+		} else {
+			f = strings.TrimPrefix(f, "+")
+		}
+		spec = append(spec, bson.DocElem{Name: f, Value: order})
+	}
+	return spec
+}
@@ -0,0 +1,23 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"reflect"
+	"testing"
+)
+
+func TestPipelineBuilder(t *testing.T) {
+	base := Pipeline{}.Match(bson.M{"active": true})
+	a := base.Limit(1)
+	b := base.Sort("-createdat", "name")
+
+	if len(base) != 1 || len(a) != 2 || len(b) != 2 {
+		t.Fatal("Stages leaked between pipelines:", base, a, b)
+	}
+
+	want := bson.D{{Name: "createdat", Value: -1}, {Name: "name", Value: 1}}
+	if !reflect.DeepEqual(b[1]["$sort"], want) {
+		t.Fatal("Unexpected sort stage:", b[1])
+	}
+}
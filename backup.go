@@ -0,0 +1,407 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ManifestName is the name of the object describing a backup. It's written
+// last so an interrupted backup can't be restored by mistake.
+const ManifestName = "manifest.json"
+
+// How many documents are written between progress callbacks and inserted
+// per batch during a restore.
+const backupBatch = 1000
+
+// maxDocumentSize is the largest BSON document the server accepts, 16MB.
+const maxDocumentSize = 16 * 1024 * 1024
+
+// BackupStore is where backups are written to and read from. Each
+// collection is stored as a separate object of concatenated BSON documents,
+// the same layout mongodump uses, plus a JSON manifest.
+type BackupStore interface {
+	Create(name string) (io.WriteCloser, error)
+	Open(name string) (io.ReadCloser, error)
+}
+
+// DirStore is a BackupStore that keeps backups in a local directory.
+type DirStore string
+
+func (d DirStore) Create(name string) (io.WriteCloser, error) {
+	if err := os.MkdirAll(string(d), 0755); err != nil {
+		return nil, err
+	}
+	return os.Create(filepath.Join(string(d), name))
+}
+
+func (d DirStore) Open(name string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(string(d), name))
+}
+
+// UploadStore adapts streaming upload and download functions, such as
+// those of an S3-compatible client, to a BackupStore. Upload is called in a
+// goroutine with a reader that yields the object's contents as they're
+// produced, so nothing is buffered in memory.
+func UploadStore(upload func(name string, r io.Reader) error, download func(name string) (io.ReadCloser, error)) BackupStore {
+	return &uploadStore{upload: upload, download: download}
+}
+
+type uploadStore struct {
+	upload   func(name string, r io.Reader) error
+	download func(name string) (io.ReadCloser, error)
+}
+
+func (u *uploadStore) Create(name string) (io.WriteCloser, error) {
+	pr, pw := io.Pipe()
+	w := &uploadWriter{pw: pw, done: make(chan error, 1)}
+
+	go func() {
+		err := u.upload(name, pr)
+		pr.CloseWithError(err)
+		w.done <- err
+	}()
+
+	return w, nil
+}
+
+func (u *uploadStore) Open(name string) (io.ReadCloser, error) {
+	return u.download(name)
+}
+
+type uploadWriter struct {
+	pw   *io.PipeWriter
+	done chan error
+}
+
+func (w *uploadWriter) Write(p []byte) (int, error) {
+	return w.pw.Write(p)
+}
+
+// Close finishes the object and waits for the upload to complete.
+func (w *uploadWriter) Close() error {
+	w.pw.Close()
+	return <-w.done
+}
+
+// BackupManifest describes a backup.
+type BackupManifest struct {
+	CreatedAt   time.Time          `json:"createdAt"`
+	Database    string             `json:"database"`
+	Collections []BackupCollection `json:"collections"`
+}
+
+// BackupCollection describes a single collection in a backup.
+type BackupCollection struct {
+	Name      string `json:"name"`
+	File      string `json:"file"`
+	Documents int    `json:"documents"`
+	SHA256    string `json:"sha256"`
+}
+
+// BackupOptions tune BackupTo and RestoreFrom.
+type BackupOptions struct {
+	// Progress, if set, is called periodically with the number of documents
+	// processed so far for the collection, and once when it's finished.
+	Progress func(collection string, docs int)
+	// Drop removes the existing documents of each collection before it's
+	// restored. Without it restoring over existing documents fails on
+	// duplicate ids.
+	Drop bool
+}
+
+// Stream a logical backup of the collections for models into store. Each
+// collection is read from the primary in _id order, walking the _id index so
+// no document is returned twice, but the collections aren't captured at the
+// same instant.
+func BackupTo(store BackupStore, opts BackupOptions, models ...interface{}) (*BackupManifest, error) {
+	return std.BackupTo(store, opts, models...)
+}
+
+// Back up the collections of models using the client. See BackupTo.
+func (c *Client) BackupTo(store BackupStore, opts BackupOptions, models ...interface{}) (*BackupManifest, error) {
+	s, err := c.GetSession()
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+	s.SetMode(mgo.Strong, false)
+
+	manifest := &BackupManifest{CreatedAt: now(), Database: c.Database()}
+
+	for _, name := range uniqueCollections(models) {
+		bc, err := c.backupCollection(s, store, name, opts)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Collections = append(manifest.Collections, *bc)
+	}
+
+	w, err := store.Create(ManifestName)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.NewEncoder(w).Encode(manifest); err != nil {
+		w.Close()
+		return nil, err
+	}
+
+	return manifest, w.Close()
+}
+
+func (c *Client) backupCollection(s *mgo.Session, store BackupStore, name string, opts BackupOptions) (*BackupCollection, error) {
+	op := c.startOp("backup", name, nil)
+
+	if err := op.allowed(); err != nil {
+		return nil, op.done(err)
+	}
+
+	bc := &BackupCollection{Name: name, File: name + ".bson"}
+
+	w, err := store.Create(bc.File)
+	if err != nil {
+		return nil, op.done(err)
+	}
+
+	sum := sha256.New()
+	out := io.MultiWriter(w, sum)
+
+	iter := c.GetColl(s, name).Find(nil).Sort("_id").Iter()
+
+	var raw bson.Raw
+	for iter.Next(&raw) {
+		if _, err := out.Write(raw.Data); err != nil {
+			iter.Close()
+			w.Close()
+			return nil, op.done(err)
+		}
+
+		bc.Documents++
+		if opts.Progress != nil && bc.Documents%backupBatch == 0 {
+			opts.Progress(name, bc.Documents)
+		}
+	}
+
+	if err := iter.Close(); err != nil {
+		w.Close()
+		return nil, op.done(err)
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, op.done(err)
+	}
+
+	if opts.Progress != nil {
+		opts.Progress(name, bc.Documents)
+	}
+
+	bc.SHA256 = hex.EncodeToString(sum.Sum(nil))
+	return bc, op.done(nil)
+}
+
+// Restore a backup written by BackupTo. Only the collections for models are
+// restored, or every collection in the backup if no models are passed.
+// Each collection's checksum and document count are verified before
+// anything is dropped or inserted, so a corrupt or truncated backup leaves
+// the collection as it was.
+func RestoreFrom(store BackupStore, opts BackupOptions, models ...interface{}) (*BackupManifest, error) {
+	return std.RestoreFrom(store, opts, models...)
+}
+
+// Restore the collections of models from a backup using the client. See
+// RestoreFrom.
+func (c *Client) RestoreFrom(store BackupStore, opts BackupOptions, models ...interface{}) (*BackupManifest, error) {
+	r, err := store.Open(ManifestName)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &BackupManifest{}
+	err = json.NewDecoder(r).Decode(manifest)
+	r.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := map[string]bool{}
+	for _, name := range uniqueCollections(models) {
+		wanted[name] = true
+	}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	for _, bc := range manifest.Collections {
+		if len(wanted) > 0 && !wanted[bc.Name] {
+			continue
+		}
+
+		if err := c.restoreCollection(s, store, bc, opts); err != nil {
+			return nil, err
+		}
+	}
+
+	return manifest, nil
+}
+
+func (c *Client) restoreCollection(s *mgo.Session, store BackupStore, bc BackupCollection, opts BackupOptions) error {
+	op := c.startOp("restore", bc.Name, nil)
+
+	if err := op.allowed(); err != nil {
+		return op.done(err)
+	}
+
+	if err := verifyCollection(store, bc); err != nil {
+		return op.done(err)
+	}
+
+	coll := c.GetColl(s, bc.Name)
+	defer c.afterCollectionWrite(s, bc.Name, nil)
+
+	if opts.Drop {
+		if _, err := coll.RemoveAll(nil); err != nil {
+			return op.done(err)
+		}
+	}
+
+	r, err := store.Open(bc.File)
+	if err != nil {
+		return op.done(err)
+	}
+	defer r.Close()
+
+	restored := 0
+	batch := make([]interface{}, 0, backupBatch)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := coll.Insert(batch...); err != nil {
+			return err
+		}
+
+		restored += len(batch)
+		batch = batch[:0]
+		if opts.Progress != nil {
+			opts.Progress(bc.Name, restored)
+		}
+		return nil
+	}
+
+	for {
+		doc, err := readDocument(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return op.done(err)
+		}
+
+		batch = append(batch, bson.Raw{Kind: 3, Data: doc})
+		if len(batch) == backupBatch {
+			if err := flush(); err != nil {
+				return op.done(err)
+			}
+		}
+	}
+
+	return op.done(flush())
+}
+
+// verifyCollection reads the stored collection through once, checking that
+// every document is well formed and that the checksum and document count
+// match the manifest.
+func verifyCollection(store BackupStore, bc BackupCollection) error {
+	r, err := store.Open(bc.File)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	sum := sha256.New()
+	in := io.TeeReader(r, sum)
+
+	docs := 0
+	for {
+		_, err := readDocument(in)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		docs++
+	}
+
+	if err := verifySum(sum, bc.SHA256); err != nil {
+		return err
+	}
+
+	if docs != bc.Documents {
+		return fmt.Errorf("Backup of %v has %d documents, the manifest lists %d", bc.Name, docs, bc.Documents)
+	}
+	return nil
+}
+
+// readDocument reads a single BSON document. It returns io.EOF if the
+// reader is exhausted between documents.
+func readDocument(r io.Reader) ([]byte, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return nil, err
+	}
+
+	n := int(binary.LittleEndian.Uint32(size[:]))
+	if n < 5 || n > maxDocumentSize {
+		return nil, errors.New("Invalid BSON document length")
+	}
+
+	doc := make([]byte, n)
+	copy(doc, size[:])
+	if _, err := io.ReadFull(r, doc[4:]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+func verifySum(sum hash.Hash, want string) error {
+	if got := hex.EncodeToString(sum.Sum(nil)); got != want {
+		return fmt.Errorf("Checksum mismatch: got %v, expected %v", got, want)
+	}
+	return nil
+}
+
+// uniqueCollections returns the collection names for models without
+// duplicates, keeping their order.
+func uniqueCollections(models []interface{}) []string {
+	seen := map[string]bool{}
+	var names []string
+
+	for _, m := range models {
+		name := collName(m)
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
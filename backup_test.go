@@ -0,0 +1,104 @@
+package mongo
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestReadDocument(t *testing.T) {
+	docs := [][]byte{
+		{5, 0, 0, 0, 0},
+		{12, 0, 0, 0, 16, 'a', 0, 1, 0, 0, 0, 0},
+	}
+
+	r := bytes.NewReader(append(append([]byte{}, docs[0]...), docs[1]...))
+	for _, want := range docs {
+		got, err := readDocument(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("Expected %v, got %v", want, got)
+		}
+	}
+
+	if _, err := readDocument(r); err != io.EOF {
+		t.Fatal("Expected io.EOF at the end. Got:", err)
+	}
+
+	if _, err := readDocument(bytes.NewReader([]byte{12, 0, 0, 0, 16})); err != io.ErrUnexpectedEOF {
+		t.Fatal("Expected io.ErrUnexpectedEOF for a truncated document. Got:", err)
+	}
+
+	for _, size := range [][]byte{{4, 0, 0, 0}, {1, 0, 0, 1}, {255, 255, 255, 255}} {
+		if _, err := readDocument(bytes.NewReader(size)); err == nil || err == io.ErrUnexpectedEOF {
+			t.Fatal("Expected an invalid length error for", size, "got:", err)
+		}
+	}
+}
+
+func TestVerifyCollection(t *testing.T) {
+	dir, err := ioutil.TempDir("", "backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store := DirStore(dir)
+
+	data := []byte{5, 0, 0, 0, 0, 5, 0, 0, 0, 0}
+	w, err := store.Create("items.bson")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(data)
+	w.Close()
+
+	sum := sha256.Sum256(data)
+	bc := BackupCollection{Name: "items", File: "items.bson", Documents: 2, SHA256: hex.EncodeToString(sum[:])}
+	if err := verifyCollection(store, bc); err != nil {
+		t.Fatal("Couldn't verify a valid backup:", err)
+	}
+
+	short := bc
+	short.Documents = 3
+	if err := verifyCollection(store, short); err == nil {
+		t.Fatal("Expected an error for a document count mismatch")
+	}
+
+	corrupt := bc
+	corrupt.SHA256 = hex.EncodeToString(make([]byte, sha256.Size))
+	if err := verifyCollection(store, corrupt); err == nil {
+		t.Fatal("Expected an error for a checksum mismatch")
+	}
+}
+
+func TestUploadStore(t *testing.T) {
+	objects := map[string][]byte{}
+
+	store := UploadStore(func(name string, r io.Reader) error {
+		data, err := ioutil.ReadAll(r)
+		objects[name] = data
+		return err
+	}, func(name string) (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(objects[name])), nil
+	})
+
+	w, err := store.Create("test.bson")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("hello "))
+	w.Write([]byte("world"))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if string(objects["test.bson"]) != "hello world" {
+		t.Fatal("Upload didn't receive the data:", string(objects["test.bson"]))
+	}
+}
@@ -0,0 +1,417 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DriftSampleSize is the number of documents Bootstrap samples from each
+// collection to look for type conflicts.
+var DriftSampleSize = 100
+
+// Kinds of drift reported by Bootstrap.
+const (
+	DriftMissingIndex        = "missing_index"
+	DriftIndexOptions        = "index_options"
+	DriftUnexpectedIndex     = "unexpected_index"
+	DriftMissingValidator    = "missing_validator"
+	DriftValidatorMismatch   = "validator_mismatch"
+	DriftUnexpectedValidator = "unexpected_validator"
+	DriftTypeConflict        = "type_conflict"
+)
+
+// Indexer is implemented by models that declare the indexes of their
+// collection. The keys use the mgo format, e.g. "-createdat".
+type Indexer interface {
+	Indexes() []mgo.Index
+}
+
+// SchemaValidator is implemented by models that declare a validator for
+// their collection, e.g. bson.M{"$jsonSchema": ...}.
+type SchemaValidator interface {
+	SchemaValidator() bson.M
+}
+
+// Drift is a single difference between a model and the live database.
+type Drift struct {
+	Collection string `json:"collection"`
+	Kind       string `json:"kind"`
+	Field      string `json:"field,omitempty"`
+	Detail     string `json:"detail"`
+}
+
+// DriftReport lists every difference Bootstrap found. It's meant to be
+// marshaled to JSON and checked by deployment tooling.
+type DriftReport struct {
+	CheckedAt time.Time `json:"checkedAt"`
+	Drift     []Drift   `json:"drift"`
+}
+
+// HasDrift returns true if any drift was found.
+func (r *DriftReport) HasDrift() bool {
+	return len(r.Drift) > 0
+}
+
+func (r *DriftReport) add(coll, kind, field, detail string, args ...interface{}) {
+	r.Drift = append(r.Drift, Drift{Collection: coll, Kind: kind, Field: field, Detail: fmt.Sprintf(detail, args...)})
+}
+
+var (
+	modelsMu sync.RWMutex
+	models   []interface{}
+)
+
+// Models returns every model registered with Bootstrap.
+func Models() []interface{} {
+	modelsMu.RLock()
+	defer modelsMu.RUnlock()
+
+	return append([]interface{}{}, models...)
+}
+
+// Register the models used by the application and compare them with the
+// live database: declared indexes (see Indexer) and validators (see
+// SchemaValidator) against what exists, and struct field types against a
+// sample of stored documents. Nothing is changed; use EnsureIndexes to create
+// missing indexes.
+func Bootstrap(ms ...interface{}) (*DriftReport, error) {
+	return std.Bootstrap(ms...)
+}
+
+// Create the collections and indexes of models using the client. See
+// Bootstrap.
+func (c *Client) Bootstrap(ms ...interface{}) (*DriftReport, error) {
+	modelsMu.Lock()
+	for _, m := range ms {
+		if !isRegistered(m) {
+			models = append(models, m)
+		}
+	}
+	modelsMu.Unlock()
+
+	s, err := c.GetSession()
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	report := &DriftReport{CheckedAt: now(), Drift: []Drift{}}
+	for _, m := range ms {
+		coll := c.GetColl(s, collName(m))
+
+		if err := checkIndexes(coll, m, report); err != nil {
+			return nil, err
+		}
+
+		if err := checkValidator(coll, m, report); err != nil {
+			return nil, err
+		}
+
+		if err := checkTypes(coll, m, report); err != nil {
+			return nil, err
+		}
+	}
+
+	return report, nil
+}
+
+// Create the indexes declared by models that implement Indexer, and the
+// unique index on the key of those that implement Keyed.
+func EnsureIndexes(ms ...interface{}) error {
+	return std.EnsureIndexes(ms...)
+}
+
+// Create the indexes of models using the client. See EnsureIndexes.
+func (c *Client) EnsureIndexes(ms ...interface{}) error {
+	s, err := c.GetSession()
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	for _, m := range ms {
+		indexes := declaredIndexes(m)
+		if len(indexes) == 0 {
+			continue
+		}
+
+		op := c.startOp("ensureIndex", collName(m), nil)
+
+		if err := op.allowed(); err != nil {
+			return op.done(err)
+		}
+
+		for _, idx := range indexes {
+			if err := c.GetColl(s, op.coll).EnsureIndex(idx); err != nil {
+				return op.done(err)
+			}
+		}
+		op.done(nil)
+	}
+
+	return nil
+}
+
+// declaredIndexes returns the indexes m declares through Indexer and Keyed.
+func declaredIndexes(m interface{}) []mgo.Index {
+	var indexes []mgo.Index
+	if indexer, ok := m.(Indexer); ok {
+		indexes = indexer.Indexes()
+	}
+
+	if idx, ok := keyIndex(m); ok {
+		key := strings.Join(idx.Key, ",")
+		for _, other := range indexes {
+			if strings.Join(other.Key, ",") == key {
+				return indexes
+			}
+		}
+		indexes = append(indexes, idx)
+	}
+	return indexes
+}
+
+// isRegistered must be called with modelsMu held.
+func isRegistered(m interface{}) bool {
+	t := structType(m)
+	for _, r := range models {
+		if structType(r) == t {
+			return true
+		}
+	}
+	return false
+}
+
+func checkIndexes(coll *mgo.Collection, m interface{}, report *DriftReport) error {
+	indexes := declaredIndexes(m)
+	if len(indexes) == 0 {
+		return nil
+	}
+
+	live, err := coll.Indexes()
+	if err != nil && !isNamespaceMissing(err) {
+		return err
+	}
+
+	byKey := map[string]mgo.Index{}
+	for _, idx := range live {
+		byKey[strings.Join(idx.Key, ",")] = idx
+	}
+
+	declared := map[string]bool{"_id": true}
+	for _, want := range indexes {
+		key := strings.Join(want.Key, ",")
+		declared[key] = true
+
+		got, ok := byKey[key]
+		if !ok {
+			report.add(coll.Name, DriftMissingIndex, key, "Index on %v doesn't exist", key)
+			continue
+		}
+
+		if got.Unique != want.Unique || got.Sparse != want.Sparse || got.ExpireAfter != want.ExpireAfter {
+			report.add(coll.Name, DriftIndexOptions, key,
+				"Index on %v has unique=%v sparse=%v expireAfter=%v, declared unique=%v sparse=%v expireAfter=%v",
+				key, got.Unique, got.Sparse, got.ExpireAfter, want.Unique, want.Sparse, want.ExpireAfter)
+		}
+	}
+
+	for key, idx := range byKey {
+		if !declared[key] {
+			report.add(coll.Name, DriftUnexpectedIndex, key, "Index %v isn't declared by the model", idx.Name)
+		}
+	}
+
+	return nil
+}
+
+func checkValidator(coll *mgo.Collection, m interface{}, report *DriftReport) error {
+	var res struct {
+		Cursor struct {
+			FirstBatch []struct {
+				Options struct {
+					Validator bson.M
+				}
+			} `bson:"firstBatch"`
+		}
+	}
+
+	cmd := bson.D{{Name: "listCollections", Value: 1}, {Name: "filter", Value: bson.M{"name": coll.Name}}}
+	if err := coll.Database.Run(cmd, &res); err != nil {
+		return err
+	}
+
+	var live bson.M
+	if len(res.Cursor.FirstBatch) > 0 {
+		live = res.Cursor.FirstBatch[0].Options.Validator
+	}
+
+	sv, ok := m.(SchemaValidator)
+	switch {
+	case !ok && len(live) > 0:
+		report.add(coll.Name, DriftUnexpectedValidator, "", "Collection has a validator the model doesn't declare")
+	case ok && len(live) == 0:
+		report.add(coll.Name, DriftMissingValidator, "", "Declared validator isn't set on the collection")
+	case ok:
+		want, err := normalizeDoc(sv.SchemaValidator())
+		if err != nil {
+			return err
+		}
+		if !reflect.DeepEqual(want, live) {
+			report.add(coll.Name, DriftValidatorMismatch, "", "Collection validator differs from the declared one")
+		}
+	}
+
+	return nil
+}
+
+// normalizeDoc round trips a document through BSON so it can be compared
+// with one read from the database.
+func normalizeDoc(doc bson.M) (bson.M, error) {
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	out := bson.M{}
+	return out, bson.Unmarshal(data, &out)
+}
+
+func checkTypes(coll *mgo.Collection, m interface{}, report *DriftReport) error {
+	t := structType(m)
+	if t == nil {
+		return nil
+	}
+
+	expected := map[string]reflect.Type{}
+	for n := 0; n < t.NumField(); n++ {
+		f := t.Field(n)
+		if f.PkgPath != "" || f.Tag.Get("bson") == "-" || strings.Contains(f.Tag.Get("bson"), "inline") {
+			continue
+		}
+		expected[bsonKey(f)] = f.Type
+	}
+
+	p := Pipeline{}
+	if q := scopeQuery(m, nil); q != nil {
+		p = p.Match(q)
+	}
+	p = p.Stage("$sample", bson.M{"size": DriftSampleSize})
+
+	var docs []bson.RawD
+	if err := coll.Pipe(p).All(&docs); err != nil {
+		if isNamespaceMissing(err) {
+			return nil
+		}
+		return err
+	}
+
+	conflicts := map[string]map[byte]int{}
+	for _, doc := range docs {
+		for _, el := range doc {
+			ft, ok := expected[el.Name]
+			if !ok || kindMatches(ft, el.Value.Kind) {
+				continue
+			}
+
+			if conflicts[el.Name] == nil {
+				conflicts[el.Name] = map[byte]int{}
+			}
+			conflicts[el.Name][el.Value.Kind]++
+		}
+	}
+
+	fields := make([]string, 0, len(conflicts))
+	for f := range conflicts {
+		fields = append(fields, f)
+	}
+	sort.Strings(fields)
+
+	for _, f := range fields {
+		for kind, n := range conflicts[f] {
+			report.add(coll.Name, DriftTypeConflict, f, "%d of %d sampled documents store %v as %v, the model expects %v",
+				n, len(docs), f, bsonKindName(kind), expected[f])
+		}
+	}
+
+	return nil
+}
+
+var (
+	getterType = reflect.TypeOf((*bson.Getter)(nil)).Elem()
+	setterType = reflect.TypeOf((*bson.Setter)(nil)).Elem()
+	timeType   = reflect.TypeOf(time.Time{})
+	objIdType  = reflect.TypeOf(bson.ObjectId(""))
+)
+
+// kindMatches returns true if a stored value of the bson kind can be decoded
+// into a field of type t.
+func kindMatches(t reflect.Type, kind byte) bool {
+	if kind == 0x0A {
+		return true
+	}
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == objIdType || t == reflect.TypeOf(Id("")):
+		return kind == 0x07
+	case t == timeType:
+		return kind == 0x09
+	case t.Implements(getterType) || reflect.PtrTo(t).Implements(setterType):
+		return true
+	}
+
+	numeric := kind == 0x01 || kind == 0x10 || kind == 0x12 || kind == 0x13
+
+	switch t.Kind() {
+	case reflect.String:
+		return kind == 0x02 || kind == 0x0E
+	case reflect.Bool:
+		return kind == 0x08
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return numeric || kind == 0x11
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return kind == 0x05
+		}
+		return kind == 0x04
+	case reflect.Struct, reflect.Map:
+		return kind == 0x03
+	}
+
+	return true
+}
+
+func bsonKindName(kind byte) string {
+	names := map[byte]string{
+		0x01: "double", 0x02: "string", 0x03: "document", 0x04: "array", 0x05: "binary",
+		0x07: "objectId", 0x08: "bool", 0x09: "date", 0x0A: "null", 0x0B: "regex",
+		0x0D: "javascript", 0x0E: "symbol", 0x10: "int", 0x11: "timestamp", 0x12: "long", 0x13: "decimal",
+	}
+
+	if name, ok := names[kind]; ok {
+		return name
+	}
+	return fmt.Sprintf("kind 0x%02x", kind)
+}
+
+// isNamespaceMissing returns true for the error returned when a collection
+// doesn't exist yet.
+func isNamespaceMissing(err error) bool {
+	if qe, ok := err.(*mgo.QueryError); ok && qe.Code == 26 {
+		return true
+	}
+	return err != nil && strings.Contains(err.Error(), "ns not found")
+}
@@ -0,0 +1,37 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestKindMatches(t *testing.T) {
+	cases := []struct {
+		t    reflect.Type
+		kind byte
+		ok   bool
+	}{
+		{reflect.TypeOf(""), 0x02, true},
+		{reflect.TypeOf(""), 0x10, false},
+		{reflect.TypeOf(0), 0x01, true},
+		{reflect.TypeOf(0), 0x02, false},
+		{reflect.TypeOf(time.Time{}), 0x09, true},
+		{reflect.TypeOf(time.Time{}), 0x02, false},
+		{reflect.TypeOf(bson.NewObjectId()), 0x07, true},
+		{reflect.TypeOf(bson.NewObjectId()), 0x02, false},
+		{reflect.TypeOf(Id("")), 0x07, true},
+		{reflect.TypeOf([]string{}), 0x04, true},
+		{reflect.TypeOf([]byte{}), 0x05, true},
+		{reflect.TypeOf(&MongoTest{}), 0x03, true},
+		{reflect.TypeOf(&MongoTest{}), 0x0A, true},
+	}
+
+	for _, c := range cases {
+		if got := kindMatches(c.t, c.kind); got != c.ok {
+			t.Errorf("kindMatches(%v, %v) = %v, expected %v", c.t, bsonKindName(c.kind), got, c.ok)
+		}
+	}
+}
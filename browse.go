@@ -0,0 +1,354 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+
+	"sort"
+)
+
+// The functions in this file work with collections by name rather than
+// through a model, for tools that browse whatever is in the database. Since
+// they can't tell the model of the documents they write, counters of the
+// collection are marked dirty rather than adjusted, see CountsCache.
+
+// Returns the names of the collections in the database, sorted. With
+// SetCollectionAffixes only this environment's collections are returned,
+// without the affixes.
+func CollectionNames() ([]string, error) {
+	return std.CollectionNames()
+}
+
+// Returns the names of the collections using the client. See CollectionNames.
+func (c *Client) CollectionNames() ([]string, error) {
+	op := c.startOp("listCollections", "", nil)
+
+	if err := op.allowed(); err != nil {
+		return nil, op.done(err)
+	}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return nil, op.done(err)
+	}
+	defer s.Close()
+
+	all, err := s.DB(c.Database()).CollectionNames()
+	if err != nil {
+		return nil, op.done(err)
+	}
+
+	var names []string
+	for _, name := range all {
+		if name, ok := logicalCollection(name); ok {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	return names, op.done(nil)
+}
+
+// Returns the number of documents in the named collection.
+func CountCollection(coll string) (int, error) {
+	return std.CountCollection(coll)
+}
+
+// Returns the number of documents in a collection using the client. See
+// CountCollection.
+func (c *Client) CountCollection(coll string) (int, error) {
+	op := c.startOp("count", coll, nil)
+
+	if err := op.allowed(); err != nil {
+		return 0, op.done(err)
+	}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return 0, op.done(err)
+	}
+	defer s.Close()
+
+	n, err := c.GetColl(s, coll).Count()
+	return n, op.done(err)
+}
+
+// Returns the indexes of the named collection.
+func CollectionIndexes(coll string) ([]mgo.Index, error) {
+	return std.CollectionIndexes(coll)
+}
+
+// Returns the indexes of a collection using the client. See
+// CollectionIndexes.
+func (c *Client) CollectionIndexes(coll string) ([]mgo.Index, error) {
+	op := c.startOp("listIndexes", coll, nil)
+
+	if err := op.allowed(); err != nil {
+		return nil, op.done(err)
+	}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return nil, op.done(err)
+	}
+	defer s.Close()
+
+	indexes, err := c.GetColl(s, coll).Indexes()
+	return indexes, op.done(err)
+}
+
+// Returns up to n documents picked at random from the named collection.
+func SampleDocs(coll string, n int) ([]bson.M, error) {
+	return std.SampleDocs(coll, n)
+}
+
+// Returns random documents of a collection using the client. See SampleDocs.
+func (c *Client) SampleDocs(coll string, n int) ([]bson.M, error) {
+	op := c.startOp("aggregate", coll, nil)
+
+	if err := op.allowed(); err != nil {
+		return nil, op.done(err)
+	}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return nil, op.done(err)
+	}
+	defer s.Close()
+
+	docs := []bson.M{}
+	err = c.GetColl(s, coll).Pipe([]bson.M{{"$sample": bson.M{"size": n}}}).All(&docs)
+	return docs, op.done(err)
+}
+
+// Find a page of documents in the named collection. See Paginate.
+func BrowseDocs(coll string, q bson.M, page, perPage int, sortFields ...string) ([]bson.M, *Page, error) {
+	return std.BrowseDocs(coll, q, page, perPage, sortFields...)
+}
+
+// Find a page of documents in a collection using the client. See BrowseDocs.
+func (c *Client) BrowseDocs(coll string, q bson.M, page, perPage int, sortFields ...string) ([]bson.M, *Page, error) {
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 {
+		perPage = DefaultPerPage
+	}
+
+	op := c.startOp("find", coll, q)
+
+	if err := op.allowed(); err != nil {
+		return nil, nil, op.done(err)
+	}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return nil, nil, op.done(err)
+	}
+	defer s.Close()
+
+	if q, err = op.check(c, s, q, true); err != nil {
+		return nil, nil, op.done(err)
+	}
+
+	query := c.GetColl(s, coll).Find(q)
+
+	total, err := query.Count()
+	if err != nil {
+		return nil, nil, op.done(err)
+	}
+
+	docs := []bson.M{}
+	err = query.Sort(sortFields...).Skip((page - 1) * perPage).Limit(perPage).All(&docs)
+	if err != nil {
+		return nil, nil, op.done(err)
+	}
+
+	pages := pageCount(total, perPage)
+	return docs, &Page{Page: page, PerPage: perPage, Total: total, Pages: pages, HasNext: page < pages}, op.done(nil)
+}
+
+// Find documents in the named collection. A limit of zero returns them all.
+func FindDocs(coll string, q bson.M, limit int, sortFields ...string) ([]bson.M, error) {
+	return std.FindDocs(coll, q, limit, sortFields...)
+}
+
+// Find documents in a collection using the client. See FindDocs.
+func (c *Client) FindDocs(coll string, q bson.M, limit int, sortFields ...string) ([]bson.M, error) {
+	op := c.startOp("find", coll, q)
+
+	if err := op.allowed(); err != nil {
+		return nil, op.done(err)
+	}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return nil, op.done(err)
+	}
+	defer s.Close()
+
+	if q, err = op.check(c, s, q, limit > 0); err != nil {
+		return nil, op.done(err)
+	}
+
+	docs := []bson.M{}
+	err = c.GetColl(s, coll).Find(q).Sort(sortFields...).Limit(limit).All(&docs)
+	return docs, op.done(err)
+}
+
+// Iterate over the documents in the named collection matching q without
+// loading them all, e.g. to export a large collection. The cursor must be
+// closed.
+func IterDocs(coll string, q bson.M, sortFields ...string) *Cursor {
+	return std.IterDocs(coll, q, sortFields...)
+}
+
+// Iterate over the documents in a collection using the client. See IterDocs.
+func (c *Client) IterDocs(coll string, q bson.M, sortFields ...string) *Cursor {
+	op := c.startOp("find", coll, q)
+
+	if err := op.allowed(); err != nil {
+		return &Cursor{op: op, err: op.done(err)}
+	}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return &Cursor{op: op, err: op.done(err)}
+	}
+
+	if q, err = op.check(c, s, q, false); err != nil {
+		s.Close()
+		return &Cursor{op: op, err: op.done(err)}
+	}
+
+	iter := c.GetColl(s, coll).Find(q).Sort(sortFields...).Iter()
+	return &Cursor{op: op, session: s, iter: iter}
+}
+
+// Returns the number of documents in the named collection matching q.
+func CountDocs(coll string, q bson.M) (int, error) {
+	return std.CountDocs(coll, q)
+}
+
+// Count documents in a collection using the client. See CountDocs.
+func (c *Client) CountDocs(coll string, q bson.M) (int, error) {
+	op := c.startOp("count", coll, q)
+
+	if err := op.allowed(); err != nil {
+		return 0, op.done(err)
+	}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return 0, op.done(err)
+	}
+	defer s.Close()
+
+	if q, err = op.check(c, s, q, true); err != nil {
+		return 0, op.done(err)
+	}
+
+	n, err := c.GetColl(s, coll).Find(q).Count()
+	return n, op.done(err)
+}
+
+// Insert documents into the named collection. Documents without an _id get
+// a new ObjectId.
+func InsertDocs(coll string, docs ...bson.M) error {
+	return std.InsertDocs(coll, docs...)
+}
+
+// Insert documents into a collection using the client. See InsertDocs.
+func (c *Client) InsertDocs(coll string, docs ...bson.M) error {
+	op := c.startOp("insert", coll, nil)
+
+	if err := op.allowed(); err != nil {
+		return op.done(err)
+	}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return op.done(err)
+	}
+	defer s.Close()
+
+	recs := make([]interface{}, len(docs))
+	for n, doc := range docs {
+		if _, ok := doc["_id"]; !ok {
+			doc["_id"] = newObjectId()
+		}
+		recs[n] = doc
+	}
+
+	if err := c.GetColl(s, coll).Insert(recs...); err != nil {
+		return op.done(err)
+	}
+	return op.done(c.afterCollectionWrite(s, coll, nil))
+}
+
+// Apply update to every document in the named collection matching q.
+// Returns the number of documents updated.
+func UpdateDocs(coll string, q, update bson.M) (int, error) {
+	return std.UpdateDocs(coll, q, update)
+}
+
+// Update documents in a collection using the client. See UpdateDocs.
+func (c *Client) UpdateDocs(coll string, q, update bson.M) (int, error) {
+	op := c.startOp("update", coll, q)
+
+	if err := op.allowed(); err != nil {
+		return 0, op.done(err)
+	}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return 0, op.done(err)
+	}
+	defer s.Close()
+
+	if q, err = op.check(c, s, q, false); err != nil {
+		return 0, op.done(err)
+	}
+
+	info, err := c.GetColl(s, coll).UpdateAll(q, update)
+	if err != nil {
+		return 0, op.done(err)
+	}
+	return info.Updated, op.done(c.afterCollectionWrite(s, coll, nil))
+}
+
+// Delete every document in the named collection matching q. Returns the
+// number of documents deleted.
+func DeleteDocs(coll string, q bson.M) (int, error) {
+	return std.DeleteDocs(coll, q)
+}
+
+// Delete documents in a collection using the client. See DeleteDocs.
+func (c *Client) DeleteDocs(coll string, q bson.M) (int, error) {
+	op := c.startOp("delete", coll, q)
+
+	if err := op.allowed(); err != nil {
+		return 0, op.done(err)
+	}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return 0, op.done(err)
+	}
+	defer s.Close()
+
+	if q, err = op.check(c, s, q, false); err != nil {
+		return 0, op.done(err)
+	}
+
+	ids, err := c.trackedIds(s, coll, q)
+	if err != nil {
+		return 0, op.done(err)
+	}
+
+	info, err := c.GetColl(s, coll).RemoveAll(q)
+	if err != nil {
+		return 0, op.done(err)
+	}
+	return info.Removed, op.done(c.afterCollectionWrite(s, coll, ids))
+}
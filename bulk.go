@@ -0,0 +1,283 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type bulkKind int
+
+const (
+	bulkInsert bulkKind = iota
+	bulkUpdate
+	bulkReplace
+	bulkDelete
+)
+
+// BulkOp is a single write in a BulkWrite. Create them with InsertOne,
+// UpdateOne, ReplaceOne and DeleteOne.
+type BulkOp struct {
+	kind   bulkKind
+	rec    interface{}
+	q      bson.M
+	update bson.M
+}
+
+// Insert a record, like Insert. rec must be a pointer to a struct.
+func InsertOne(rec interface{}) BulkOp {
+	return BulkOp{kind: bulkInsert, rec: rec}
+}
+
+// Apply update, e.g. bson.M{"$inc": bson.M{"n": 1}}, to the first record
+// matching q.
+func UpdateOne(q, update bson.M) BulkOp {
+	return BulkOp{kind: bulkUpdate, q: q, update: update}
+}
+
+// Replace a record identified by its Id, like Update. rec must be a pointer
+// to a struct.
+func ReplaceOne(rec interface{}) BulkOp {
+	return BulkOp{kind: bulkReplace, rec: rec}
+}
+
+// Delete the first record matching q.
+func DeleteOne(q bson.M) BulkOp {
+	return BulkOp{kind: bulkDelete, q: q}
+}
+
+// BulkResult summarizes a BulkWrite.
+type BulkResult struct {
+	// Matched and Modified count the records matched and changed by
+	// updates and replacements.
+	Matched  int
+	Modified int
+	// Errors lists the operations that failed.
+	Errors []BulkOpError
+}
+
+// BulkOpError is the failure of a single operation of a BulkWrite. Index is
+// its position in the operations passed in.
+type BulkOpError struct {
+	Index int
+	Err   error
+}
+
+func (e BulkOpError) Error() string {
+	return e.Err.Error()
+}
+
+// BulkOptions configures BulkWriteWith.
+type BulkOptions struct {
+	// Unordered runs every operation even if some fail, in any order, for
+	// best effort imports. By default operations run in order and stop at
+	// the first failure.
+	Unordered bool
+}
+
+// BulkError is returned when operations of a bulk write fail. When the
+// write was ordered, the operations after the first failure weren't run.
+type BulkError struct {
+	Failed  []BulkOpError
+	Ordered bool
+}
+
+func (e *BulkError) Error() string {
+	indexes := make([]string, len(e.Failed))
+	for n, f := range e.Failed {
+		indexes[n] = strconv.Itoa(f.Index)
+	}
+
+	msg := fmt.Sprintf("%v bulk operations failed (%v): %v", len(e.Failed), strings.Join(indexes, ", "), e.Failed[0].Err)
+	if e.Ordered {
+		msg += "; later operations weren't run"
+	}
+	return msg
+}
+
+// Indexes returns the positions of the operations that failed.
+func (e *BulkError) Indexes() []int {
+	indexes := make([]int, len(e.Failed))
+	for n, f := range e.Failed {
+		indexes[n] = f.Index
+	}
+	return indexes
+}
+
+// Run mixed writes against the collection of i in a single ordered batch.
+// See BulkWriteWith.
+func BulkWrite(i interface{}, ops ...BulkOp) (*BulkResult, error) {
+	return std.BulkWrite(i, ops...)
+}
+
+// Run a bulk write using the client. See BulkWrite.
+func (c *Client) BulkWrite(i interface{}, ops ...BulkOp) (*BulkResult, error) {
+	return c.BulkWriteWith(i, BulkOptions{}, ops...)
+}
+
+// Run mixed writes against the collection of i in a single batch, e.g. to
+// apply a client's offline changes:
+//
+//	res, err := mongo.BulkWriteWith(&Note{}, mongo.BulkOptions{Unordered: true},
+//		mongo.InsertOne(note),
+//		mongo.ReplaceOne(edited),
+//		mongo.DeleteOne(bson.M{"_id": removedId}),
+//	)
+//
+// Records are validated and get their Id and timestamps like Insert and
+// Update. If any operation fails a *BulkError listing the failures is
+// returned, and the result's Errors holds the same list. When ordered, a
+// record that can't be prepared fails the write before anything is sent;
+// when unordered it's skipped and reported with the rest.
+func BulkWriteWith(i interface{}, opts BulkOptions, ops ...BulkOp) (*BulkResult, error) {
+	return std.BulkWriteWith(i, opts, ops...)
+}
+
+// Run a bulk write with options using the client. See BulkWriteWith.
+func (c *Client) BulkWriteWith(i interface{}, opts BulkOptions, ops ...BulkOp) (*BulkResult, error) {
+	op := c.startOp("bulk", collName(i), nil)
+
+	for _, o := range ops {
+		if err := bulkAllowed(op.coll, o.kind); err != nil {
+			return nil, op.done(err)
+		}
+	}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return nil, op.done(err)
+	}
+	defer s.Close()
+
+	res := &BulkResult{}
+
+	// The records deletes match are read first if they need tombstones.
+	var deleted []interface{}
+	for _, o := range ops {
+		if o.kind != bulkDelete {
+			continue
+		}
+		ids, err := c.trackedIds(s, op.coll, scopeQuery(i, o.q))
+		if err != nil {
+			return nil, op.done(err)
+		}
+		deleted = append(deleted, ids...)
+	}
+
+	coll := c.GetColl(s, op.coll)
+	b := coll.Bulk()
+	if opts.Unordered {
+		b.Unordered()
+	}
+
+	// added maps the position of each operation in the batch to its
+	// position in ops, since skipped operations aren't added.
+	var added []int
+	for n, o := range ops {
+		if err := c.addBulkOp(b, coll, i, o); err != nil {
+			res.Errors = append(res.Errors, BulkOpError{Index: n, Err: err})
+			if !opts.Unordered {
+				return res, op.done(&BulkError{Failed: res.Errors, Ordered: true})
+			}
+			continue
+		}
+		added = append(added, n)
+	}
+
+	if len(added) > 0 {
+		r, err := b.Run()
+		if herr := c.afterCollectionWrite(s, op.coll, deleted); herr != nil && err == nil {
+			err = herr
+		}
+		if r != nil {
+			res.Matched, res.Modified = r.Matched, r.Modified
+		}
+
+		var be *mgo.BulkError
+		if errors.As(err, &be) {
+			for _, bc := range be.Cases() {
+				idx := bc.Index
+				if idx >= 0 && idx < len(added) {
+					idx = added[idx]
+				}
+				res.Errors = append(res.Errors, BulkOpError{Index: idx, Err: bc.Err})
+			}
+		} else if err != nil {
+			return res, op.done(err)
+		}
+	}
+
+	if len(res.Errors) > 0 {
+		sort.Slice(res.Errors, func(a, b int) bool { return res.Errors[a].Index < res.Errors[b].Index })
+		return res, op.done(&BulkError{Failed: res.Errors, Ordered: !opts.Unordered})
+	}
+
+	return res, op.done(nil)
+}
+
+// bulkAllowed applies the access policy of coll to each kind of operation,
+// since a bulk write may mix them.
+func bulkAllowed(coll string, kind bulkKind) error {
+	name := map[bulkKind]string{bulkInsert: "insert", bulkUpdate: "update", bulkReplace: "update", bulkDelete: "delete"}[kind]
+	return (&operation{op: name, coll: coll}).allowed()
+}
+
+func (c *Client) addBulkOp(b *mgo.Bulk, coll *mgo.Collection, i interface{}, o BulkOp) error {
+	switch o.kind {
+	case bulkInsert:
+		if !isPtr(o.rec) {
+			return NoPtr
+		}
+		if err := normalize(o.rec); err != nil {
+			return err
+		}
+		if err := validate(o.rec); err != nil {
+			return err
+		}
+		if err := c.addNewFields(o.rec); err != nil {
+			return err
+		}
+		doc, err := marshalRecord(o.rec)
+		if err != nil {
+			return err
+		}
+		b.Insert(doc)
+	case bulkReplace:
+		if !isPtr(o.rec) {
+			return NoPtr
+		}
+		if err := normalize(o.rec); err != nil {
+			return err
+		}
+		if err := validate(o.rec); err != nil {
+			return err
+		}
+		if err := addCurrentDateTime(o.rec, "UpdatedAt"); err != nil {
+			return err
+		}
+		id, err := getObjIdFromStruct(o.rec)
+		if err != nil {
+			return err
+		}
+		doc, err := replacementDoc(coll, o.rec, id)
+		if err != nil {
+			return err
+		}
+		b.Update(bson.M{"_id": id}, doc)
+	case bulkUpdate:
+		update, err := protectUpdate(i, o.update)
+		if err != nil {
+			return err
+		}
+		b.Update(scopeQuery(i, o.q), update)
+	case bulkDelete:
+		b.Remove(scopeQuery(i, o.q))
+	}
+
+	return nil
+}
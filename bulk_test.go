@@ -0,0 +1,33 @@
+package mongo
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBulkWritePolicy(t *testing.T) {
+	SetAccessPolicy("MongoTest", AllowInsert|AllowFind)
+	defer ClearAccessPolicy("MongoTest")
+
+	_, err := BulkWrite(&MongoTest{}, InsertOne(&MongoTest{}), DeleteOne(nil))
+	if !errors.Is(err, ErrAccessDenied) {
+		t.Fatal("Expected the delete to be denied, got:", err)
+	}
+}
+
+func TestBulkError(t *testing.T) {
+	err := &BulkError{Failed: []BulkOpError{{Index: 1, Err: errors.New("dup")}, {Index: 4, Err: errors.New("bad")}}}
+
+	if err.Error() != "2 bulk operations failed (1, 4): dup" {
+		t.Fatal("Unexpected message:", err.Error())
+	}
+
+	if idx := err.Indexes(); len(idx) != 2 || idx[0] != 1 || idx[1] != 4 {
+		t.Fatal("Unexpected indexes:", idx)
+	}
+
+	err.Ordered = true
+	if err.Error() != "2 bulk operations failed (1, 4): dup; later operations weren't run" {
+		t.Fatal("Unexpected message:", err.Error())
+	}
+}
@@ -0,0 +1,190 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+
+	"errors"
+	"reflect"
+	"sync"
+	"time"
+)
+
+var (
+	// TombstoneCollection holds a Tombstone for each deleted record of the
+	// models passed to TrackDeletes, or of every model with SetTombstones.
+	TombstoneCollection = "tombstones"
+	// TombstoneRetention is how long tombstones are kept by the index
+	// created with EnsureChangeIndexes.
+	TombstoneRetention = 90 * 24 * time.Hour
+)
+
+// ErrResyncRequired is returned by ChangesSince when the deletions since the
+// time asked for may have been forgotten. The client should fetch everything
+// again.
+var ErrResyncRequired = errors.New("Changes since then are no longer available")
+
+// Tombstone records that a record was deleted.
+type Tombstone struct {
+	Id         bson.ObjectId `bson:"_id"`
+	Collection string        `bson:"collection"`
+	DocId      interface{}   `bson:"docid"`
+	DeletedAt  time.Time     `bson:"deletedat"`
+}
+
+// Changes describes the changes returned by ChangesSince.
+type Changes struct {
+	// Deleted holds the ids of the records deleted.
+	Deleted []interface{}
+	// Until is the time to pass to the next call.
+	Until time.Time
+}
+
+var (
+	tombstoneMu    sync.RWMutex
+	tombstoneTypes = map[reflect.Type]bool{}
+	tombstoneAll   bool
+)
+
+// Make Delete, and the other deletes except Truncate, write a Tombstone for
+// each deleted record of models, so ChangesSince can report deletions.
+func TrackDeletes(models ...interface{}) {
+	tombstoneMu.Lock()
+	defer tombstoneMu.Unlock()
+
+	for _, m := range models {
+		tombstoneTypes[structType(m)] = true
+	}
+}
+
+// Set whether deletes write a Tombstone to TombstoneCollection for every
+// deleted record, whatever its model, so consumers downstream can learn
+// about deletions. Off by default.
+func SetTombstones(on bool) {
+	tombstoneMu.Lock()
+	defer tombstoneMu.Unlock()
+
+	tombstoneAll = on
+}
+
+// Create the indexes ChangesSince needs: UpdatedAt on each model and, on
+// TombstoneCollection, one to find deletions and one expiring tombstones
+// after TombstoneRetention.
+func EnsureChangeIndexes(models ...interface{}) error {
+	return std.EnsureChangeIndexes(models...)
+}
+
+// Create the indexes ChangesSince needs using the client. See
+// EnsureChangeIndexes.
+func (c *Client) EnsureChangeIndexes(models ...interface{}) error {
+	s, err := c.GetSession()
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	for _, m := range models {
+		if err := c.GetColl(s, collName(m)).EnsureIndexKey(fieldKey(m, "UpdatedAt")); err != nil {
+			return err
+		}
+	}
+
+	tombstones := c.GetColl(s, TombstoneCollection)
+	if err := tombstones.EnsureIndexKey("collection", "deletedat"); err != nil {
+		return err
+	}
+	return tombstones.EnsureIndex(mgo.Index{Key: []string{"deletedat"}, ExpireAfter: TombstoneRetention})
+}
+
+// Find the records like those in the slice i points to that changed after
+// since, for clients that sync incrementally, and return the ids of those
+// deleted. Pass the returned Until as since next time; a zero since returns
+// every record. The model must have an UpdatedAt field and be passed to
+// TrackDeletes. Records changed while it runs may be returned again next
+// time. Times come from the clocks of the writing processes, so keep them
+// in sync.
+func ChangesSince(i interface{}, since time.Time) (*Changes, error) {
+	return std.ChangesSince(i, since)
+}
+
+// Find the records changed since a time using the client. See ChangesSince.
+func (c *Client) ChangesSince(i interface{}, since time.Time) (*Changes, error) {
+	if !isPtr(i) || !isSlice(reflect.TypeOf(i)) {
+		return nil, NoPtr
+	}
+	if !hasStructField(reflect.New(structType(i)).Interface(), "UpdatedAt") {
+		return nil, errors.New("Record must have an UpdatedAt field")
+	}
+
+	changes := &Changes{Deleted: []interface{}{}, Until: now()}
+	if !since.IsZero() && changes.Until.Sub(since) > TombstoneRetention {
+		return nil, ErrResyncRequired
+	}
+
+	var q bson.M
+	if !since.IsZero() {
+		q = bson.M{fieldKey(i, "UpdatedAt"): bson.M{"$gt": since}}
+	}
+	q = scopeQuery(i, q)
+	op := c.startOp("find", collName(i), q)
+
+	if err := op.allowed(); err != nil {
+		return nil, op.done(err)
+	}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return nil, op.done(err)
+	}
+	defer s.Close()
+
+	if err := findInto(c, s, c.GetColl(s, op.coll).Find(q).Sort(fieldKey(i, "UpdatedAt")), i); err != nil {
+		return nil, op.done(err)
+	}
+
+	if since.IsZero() {
+		return changes, op.done(nil)
+	}
+
+	var tombstones []Tombstone
+	err = c.GetColl(s, TombstoneCollection).
+		Find(bson.M{"collection": op.coll, "deletedat": bson.M{"$gt": since}}).
+		Sort("deletedat").All(&tombstones)
+	if err != nil {
+		return nil, op.done(err)
+	}
+
+	for _, t := range tombstones {
+		changes.Deleted = append(changes.Deleted, t.DocId)
+	}
+	return changes, op.done(nil)
+}
+
+// deletesTracked returns whether deletes of records like i leave a tombstone.
+func deletesTracked(i interface{}) bool {
+	tombstoneMu.RLock()
+	defer tombstoneMu.RUnlock()
+
+	return tombstoneAll || tombstoneTypes[structType(i)]
+}
+
+// collectionDeletesTracked returns whether deletes of the records in coll,
+// written by collection name, leave a tombstone.
+func collectionDeletesTracked(coll string) bool {
+	tombstoneMu.RLock()
+	defer tombstoneMu.RUnlock()
+
+	if tombstoneAll {
+		return true
+	}
+	for t := range tombstoneTypes {
+		if collName(reflect.New(t).Interface()) == coll {
+			return true
+		}
+	}
+	return false
+}
+
+func writeTombstone(tombstones *mgo.Collection, coll string, id interface{}) error {
+	return tombstones.Insert(Tombstone{Id: newObjectId(), Collection: coll, DocId: id, DeletedAt: now()})
+}
@@ -0,0 +1,72 @@
+package mongo
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestChangesSinceArgs(t *testing.T) {
+	if _, err := ChangesSince(&MongoTest{}, time.Time{}); err != NoPtr {
+		t.Fatal("Expected NoPtr got:", err)
+	}
+
+	if _, err := ChangesSince(&[]fieldsetModel{}, time.Time{}); err == nil {
+		t.Fatal("Expected an error for a model without UpdatedAt")
+	}
+}
+
+func TestChangesSinceResync(t *testing.T) {
+	since := now().Add(-TombstoneRetention - time.Hour)
+
+	if _, err := ChangesSince(&[]MongoTest{}, since); !errors.Is(err, ErrResyncRequired) {
+		t.Fatal("Expected ErrResyncRequired got:", err)
+	}
+}
+
+func TestTrackDeletes(t *testing.T) {
+	if deletesTracked(&existsModel{}) {
+		t.Fatal("Expected deletes not to be tracked")
+	}
+
+	TrackDeletes(&existsModel{})
+	defer func() {
+		tombstoneMu.Lock()
+		delete(tombstoneTypes, structType(&existsModel{}))
+		tombstoneMu.Unlock()
+	}()
+
+	if !deletesTracked(&[]existsModel{}) {
+		t.Fatal("Expected deletes to be tracked")
+	}
+}
+
+func TestSetTombstones(t *testing.T) {
+	SetTombstones(true)
+	if !deletesTracked(&MongoTest{}) {
+		t.Fatal("Expected deletes of every model to leave tombstones")
+	}
+
+	SetTombstones(false)
+	if deletesTracked(&MongoTest{}) {
+		t.Fatal("Expected deletes not to leave tombstones")
+	}
+}
+
+func TestCollectionDeletesTracked(t *testing.T) {
+	coll := collName(&existsModel{})
+	if collectionDeletesTracked(coll) {
+		t.Fatal("Expected deletes not to be tracked")
+	}
+
+	TrackDeletes(&existsModel{})
+	defer func() {
+		tombstoneMu.Lock()
+		delete(tombstoneTypes, structType(&existsModel{}))
+		tombstoneMu.Unlock()
+	}()
+
+	if !collectionDeletesTracked(coll) {
+		t.Fatal("Expected deletes to be tracked")
+	}
+}
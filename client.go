@@ -0,0 +1,76 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo"
+)
+
+// Client is a connection to a database on a set of servers. The package
+// level functions use the connection configured with SetServers; create a
+// Client to work with another cluster or database at the same time. Every
+// package level function that reads or writes records, and every type that
+// does, such as CountsCache and WriteQueue, has a Client method of the same
+// name. Settings like SetSafetyMode and the options apply to all clients.
+type Client struct {
+	session  *mgo.Session
+	database string
+
+	// lazy marks std, which uses the package's connection, and views of it.
+	lazy bool
+	// view marks a client sharing another client's session. See UseDatabase.
+	view bool
+
+	read readPref
+}
+
+// std is the Client behind the package level functions.
+var std = &Client{lazy: true}
+
+// Connect to the mongo servers and use the database. See Dial for more
+// options.
+func NewClient(servers, db string) (*Client, error) {
+	return Dial(Config{Servers: servers, Database: db})
+}
+
+// Returns a Mongo session. You must call Session.Close() when you're done.
+func (c *Client) GetSession() (*mgo.Session, error) {
+	if c.lazy {
+		return GetSession()
+	}
+
+	s := c.session.Clone()
+	c.read.apply(s)
+	return s, nil
+}
+
+// Returns the named collection of the client's database using the session.
+func (c *Client) GetColl(session *mgo.Session, coll string) *mgo.Collection {
+	return session.DB(c.Database()).C(PhysicalCollection(coll))
+}
+
+// Database returns the name of the client's database.
+func (c *Client) Database() string {
+	if c.lazy && c.database == "" {
+		return currentDatabase()
+	}
+	return c.database
+}
+
+// Close the client's connection.
+func (c *Client) Close() {
+	if c.lazy || c.view {
+		return
+	}
+	c.session.Close()
+}
+
+// Returns a client that shares the connection but uses the named database.
+// Closing it does nothing; close the original instead.
+func (c *Client) UseDatabase(name string) *Client {
+	v := &Client{session: c.session, database: name, lazy: c.lazy, view: true}
+
+	c.read.mu.Lock()
+	v.read.set, v.read.mode, v.read.maxStaleness = c.read.set, c.read.mode, c.read.maxStaleness
+	c.read.mu.Unlock()
+
+	return v
+}
@@ -0,0 +1,44 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo"
+
+	"testing"
+)
+
+func TestClientDatabase(t *testing.T) {
+	c := &Client{database: "app"}
+
+	if c.Database() != "app" {
+		t.Fatal("Expected app got:", c.Database())
+	}
+
+	if db := std.Database(); db != currentDatabase() {
+		t.Fatal("Expected the default client to use the package database got:", db)
+	}
+
+	named := std.UseDatabase("reports")
+	if named.Database() != "reports" || !named.lazy || !named.view {
+		t.Fatal("Expected a lazy view of the reports database got:", named)
+	}
+}
+
+func TestUseDatabaseKeepsReadPref(t *testing.T) {
+	c := &Client{database: "app"}
+	c.read.set, c.read.mode = true, mgo.SecondaryPreferred
+
+	v := c.UseDatabase("reports")
+	if !v.read.set || v.read.mode != mgo.SecondaryPreferred {
+		t.Fatal("Expected the read preference to be copied got:", v.read.mode)
+	}
+	if v.session != c.session {
+		t.Fatal("Expected the view to share the session")
+	}
+}
+
+func TestCloseView(t *testing.T) {
+	// Closing the default client or a view must not touch a session; both
+	// have none here, so closing would panic.
+	std.Close()
+	(&Client{database: "reports", view: true}).Close()
+}
@@ -0,0 +1,55 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"sync"
+	"time"
+)
+
+var (
+	clockMu     sync.RWMutex
+	idGenerator = bson.NewObjectId
+	clock       = time.Now
+)
+
+// Set the function used to generate ids for new records. Tests can use it to
+// get deterministic ids. Pass nil to restore bson.NewObjectId.
+func SetIdGenerator(fn func() bson.ObjectId) {
+	if fn == nil {
+		fn = bson.NewObjectId
+	}
+
+	clockMu.Lock()
+	defer clockMu.Unlock()
+	idGenerator = fn
+}
+
+// Set the function used to get the current time for CreatedAt, UpdatedAt and
+// any other time the package stores. Tests can use it to get deterministic
+// timestamps. Pass nil to restore time.Now.
+func SetClock(fn func() time.Time) {
+	if fn == nil {
+		fn = time.Now
+	}
+
+	clockMu.Lock()
+	defer clockMu.Unlock()
+	clock = fn
+}
+
+func newObjectId() bson.ObjectId {
+	clockMu.RLock()
+	gen := idGenerator
+	clockMu.RUnlock()
+
+	return gen()
+}
+
+func now() time.Time {
+	clockMu.RLock()
+	c := clock
+	clockMu.RUnlock()
+
+	return c()
+}
@@ -0,0 +1,49 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"testing"
+	"time"
+)
+
+func TestDeterministicIdsAndTimes(t *testing.T) {
+	id := bson.NewObjectId()
+	at := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	SetIdGenerator(func() bson.ObjectId { return id })
+	SetClock(func() time.Time { return at })
+	defer SetIdGenerator(nil)
+	defer SetClock(nil)
+
+	m := &MongoTest{Name: "deterministic"}
+	if err := std.addNewFields(m); err != nil {
+		t.Fatal(err)
+	}
+
+	if m.Id != id {
+		t.Fatal("Expected the injected id. Got:", m.Id.Hex())
+	}
+
+	if !m.CreatedAt.Equal(at) || !m.UpdatedAt.Equal(at) {
+		t.Fatal("Expected the injected time. Got:", m.CreatedAt, m.UpdatedAt)
+	}
+}
+
+// Run with -race to check the settings are safe to change while in use.
+func TestConcurrentSettings(t *testing.T) {
+	done := make(chan bool)
+	go func() {
+		for i := 0; i < 100; i++ {
+			SetClock(time.Now)
+			SetRedaction(RedactHash)
+		}
+		done <- true
+	}()
+
+	for i := 0; i < 100; i++ {
+		now()
+		SanitizeQuery(bson.M{"name": "George"})
+	}
+	<-done
+}
@@ -0,0 +1,90 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"reflect"
+)
+
+// Copy the stored record i identifies by its Id into a new record with a
+// fresh Id and timestamps. Returns the Id of the copy. Must pass in a pointer
+// to a struct.
+func Clone(i interface{}) (string, error) {
+	return std.Clone(i)
+}
+
+// Insert a copy of a record using the client. See Clone.
+func (c *Client) Clone(i interface{}) (string, error) {
+	return c.CloneWith(i, nil)
+}
+
+// Copy a record like Clone, passing the copy to transform before it's
+// inserted, e.g. to change fields that must be unique:
+//
+//	id, err := mongo.CloneWith(page, func(clone interface{}) error {
+//		p := clone.(*Page)
+//		p.Title += " (copy)"
+//		p.Slug = ""
+//		return nil
+//	})
+//
+// The copy is a pointer to a new struct of the same type as i. An error
+// from transform stops the copy and is returned.
+func CloneWith(i interface{}, transform func(clone interface{}) error) (string, error) {
+	return std.CloneWith(i, transform)
+}
+
+// Insert a changed copy of a record using the client. See CloneWith.
+func (c *Client) CloneWith(i interface{}, transform func(clone interface{}) error) (string, error) {
+	t := structType(i)
+	if !isPtr(i) || t == nil {
+		return "", NoPtr
+	}
+
+	op := c.startOp("find", collName(i), nil)
+
+	if err := op.allowed(); err != nil {
+		return "", op.done(err)
+	}
+
+	id, err := getObjIdFromStruct(i)
+	if err != nil {
+		return "", op.done(err)
+	}
+	op.query = bson.M{"_id": id}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return "", op.done(err)
+	}
+	defer s.Close()
+
+	// Lazy fields are copied too, so the record is read whole.
+	var raw bson.Raw
+	if err := c.GetColl(s, op.coll).FindId(id).One(&raw); err != nil {
+		return "", op.done(err)
+	}
+
+	clone := reflect.New(t).Interface()
+	if err := unmarshalRecord(c, s, raw, clone); err != nil {
+		return "", op.done(err)
+	}
+	op.done(nil)
+
+	if transform != nil {
+		if err := transform(clone); err != nil {
+			return "", err
+		}
+	}
+
+	// Insert gives the copy its own Id and timestamps.
+	if err := c.Insert(clone); err != nil {
+		return "", err
+	}
+
+	newId, err := getObjIdFromStruct(clone)
+	if err != nil {
+		return "", err
+	}
+	return newId.Hex(), nil
+}
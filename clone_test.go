@@ -0,0 +1,21 @@
+package mongo
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCloneNoPtr(t *testing.T) {
+	if _, err := Clone(validatedModel{}); err != NoPtr {
+		t.Fatal("Expected NoPtr got:", err)
+	}
+}
+
+func TestCloneAccessPolicy(t *testing.T) {
+	SetAccessPolicy("MongoTest", AllowInsert)
+	defer ClearAccessPolicy("MongoTest")
+
+	if _, err := Clone(testObj); !errors.Is(err, ErrAccessDenied) {
+		t.Fatal("Expected ErrAccessDenied got:", err)
+	}
+}
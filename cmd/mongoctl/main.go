@@ -0,0 +1,17 @@
+// Command mongoctl runs common operations from the shell. See the ctl
+// package for the commands.
+package main
+
+import (
+	"github.com/sfreiberg/mongo/ctl"
+
+	"fmt"
+	"os"
+)
+
+func main() {
+	if err := ctl.Run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "mongoctl:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,182 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// SortOptions configures FindSorted.
+type SortOptions struct {
+	// Locale is the ICU locale to sort by, e.g. "de" or "sv".
+	Locale string
+	// Strength is the ICU comparison level from 1 to 5. 1 ignores case and
+	// accents, 2 ignores case. Zero uses the server's default of 3.
+	Strength int
+	// NumericOrdering sorts digits by their numeric value, so "10" comes
+	// after "9".
+	NumericOrdering bool
+}
+
+// Find records like Find, sorted the way people using the locale expect
+// rather than by byte value. Servers older than 3.4 don't support collations;
+// on those each sort field is replaced by its sort key field if the model
+// declares one with a sortkey tag naming the field it's for:
+//
+//	type User struct {
+//		Name     string `bson:"name"`
+//		NameSort string `bson:"name_sort" sortkey:"name"`
+//	}
+//
+// Sort key fields are filled in on every write with SortKey, which ignores
+// case and accents but isn't specific to a locale.
+func FindSorted(i interface{}, q bson.M, opts SortOptions, sortFields ...string) error {
+	return std.FindSorted(i, q, opts, sortFields...)
+}
+
+// Find records sorted by a collation using the client. See FindSorted.
+func (c *Client) FindSorted(i interface{}, q bson.M, opts SortOptions, sortFields ...string) error {
+	if !isPtr(i) {
+		return NoPtr
+	}
+
+	q = scopeQuery(i, q)
+	op := c.startOp("find", collName(i), q)
+
+	if err := op.allowed(); err != nil {
+		return op.done(err)
+	}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return op.done(err)
+	}
+	defer s.Close()
+
+	if q, err = op.check(c, s, q, !isSlice(reflect.TypeOf(i))); err != nil {
+		return op.done(err)
+	}
+
+	supported, err := collationSupported(s)
+	if err != nil {
+		return op.done(err)
+	}
+
+	query := withoutLazy(c.GetColl(s, op.coll).Find(q), i)
+	if supported {
+		query = query.Sort(sortFields...).Collation(&mgo.Collation{
+			Locale:          opts.Locale,
+			Strength:        opts.Strength,
+			NumericOrdering: opts.NumericOrdering,
+		})
+	} else {
+		query = query.Sort(shadowSort(i, sortFields)...)
+	}
+
+	return op.done(findInto(c, s, query, i))
+}
+
+var (
+	collationMu    sync.Mutex
+	collationKnown bool
+	collationOK    bool
+)
+
+// collationSupported returns whether the server supports collations. It's
+// only asked once.
+func collationSupported(s *mgo.Session) (bool, error) {
+	collationMu.Lock()
+	defer collationMu.Unlock()
+
+	if !collationKnown {
+		info, err := s.BuildInfo()
+		if err != nil {
+			return false, err
+		}
+		collationOK, collationKnown = info.VersionAtLeast(3, 4), true
+	}
+	return collationOK, nil
+}
+
+// shadowSort replaces each sort field with the sort key field the model
+// declares for it, if any.
+func shadowSort(i interface{}, sortFields []string) []string {
+	keys := sortKeyFields(structType(i))
+
+	out := make([]string, len(sortFields))
+	for n, f := range sortFields {
+		dir, name := "", f
+		if strings.HasPrefix(f, "-") || strings.HasPrefix(f, "+") {
+			dir, name = f[:1], f[1:]
+		}
+
+		if shadow, ok := keys[name]; ok {
+			name = shadow
+		}
+		out[n] = dir + name
+	}
+	return out
+}
+
+// sortKeyFields maps the document keys with a sort key field to that field's
+// key.
+func sortKeyFields(t reflect.Type) map[string]string {
+	keys := map[string]string{}
+	if t == nil {
+		return keys
+	}
+
+	for n := 0; n < t.NumField(); n++ {
+		if f := t.Field(n); f.Tag.Get("sortkey") != "" {
+			keys[f.Tag.Get("sortkey")] = bsonKey(f)
+		}
+	}
+	return keys
+}
+
+var foldAccents = strings.NewReplacer(
+	"à", "a", "á", "a", "â", "a", "ã", "a", "ä", "a", "å", "a", "æ", "ae",
+	"ç", "c", "è", "e", "é", "e", "ê", "e", "ë", "e",
+	"ì", "i", "í", "i", "î", "i", "ï", "i", "ñ", "n",
+	"ò", "o", "ó", "o", "ô", "o", "õ", "o", "ö", "o", "ø", "o", "œ", "oe",
+	"ù", "u", "ú", "u", "û", "u", "ü", "u", "ý", "y", "ÿ", "y", "ß", "ss",
+)
+
+// SortKey returns the value stored in sort key fields for s: lowercased with
+// the accents of latin letters removed.
+func SortKey(s string) string {
+	return foldAccents.Replace(strings.ToLower(s))
+}
+
+// fillSortKeys sets the sort key fields of the record v points to from the
+// fields they're for.
+func fillSortKeys(v reflect.Value) {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return
+	}
+
+	t := v.Type()
+	for n := 0; n < t.NumField(); n++ {
+		f := t.Field(n)
+		from := f.Tag.Get("sortkey")
+		if from == "" || f.Type.Kind() != reflect.String {
+			continue
+		}
+
+		for m := 0; m < t.NumField(); m++ {
+			src := t.Field(m)
+			if bsonKey(src) == from && src.Type.Kind() == reflect.String {
+				v.Field(n).SetString(SortKey(v.Field(m).String()))
+			}
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package mongo
+
+import (
+	"reflect"
+	"testing"
+)
+
+type sortedModel struct {
+	Name     string `bson:"name"`
+	NameSort string `bson:"name_sort" sortkey:"name"`
+	City     string `bson:"city"`
+}
+
+func TestSortKey(t *testing.T) {
+	cases := map[string]string{
+		"Émile":  "emile",
+		"Ærø":    "aero",
+		"Straße": "strasse",
+		"Zoë":    "zoe",
+	}
+
+	for in, expected := range cases {
+		if key := SortKey(in); key != expected {
+			t.Errorf("SortKey(%q): expected %q got: %q", in, expected, key)
+		}
+	}
+}
+
+func TestShadowSort(t *testing.T) {
+	fields := shadowSort(&[]sortedModel{}, []string{"-name", "city", "+name"})
+
+	expected := []string{"-name_sort", "city", "+name_sort"}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Fatal("Expected", expected, "got:", fields)
+	}
+}
+
+func TestFillSortKeys(t *testing.T) {
+	rec := &sortedModel{Name: "Ángel"}
+
+	if err := normalize(rec); err != nil {
+		t.Fatal("Couldn't normalize:", err)
+	}
+	if rec.NameSort != "angel" {
+		t.Fatal("Expected angel got:", rec.NameSort)
+	}
+}
@@ -0,0 +1,150 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrMixedIds is returned by CompareCollections for collections whose ids
+// aren't all ObjectIds, all strings or all numbers.
+var ErrMixedIds = errors.New("Can't compare records with ids of different types")
+
+// DocDiff lists the fields that differ between two records with the same
+// id. Before holds the value in the first collection and After the one in
+// the second.
+type DocDiff struct {
+	Id      interface{}
+	Changes []FieldChange
+}
+
+// CollectionDiff is the result of CompareCollections.
+type CollectionDiff struct {
+	// Same is the number of records identical in both.
+	Same    int
+	OnlyInA []interface{}
+	OnlyInB []interface{}
+	Changed []DocDiff
+}
+
+// Returns whether the collections hold the same records.
+func (d *CollectionDiff) Equal() bool {
+	return len(d.OnlyInA) == 0 && len(d.OnlyInB) == 0 && len(d.Changed) == 0
+}
+
+// Compare the records like i matching q through two clients, e.g. a
+// staging and a production deployment, or a collection before and after a
+// migration. Returns the ids of records only one of them has and the field
+// differences of records both have. The records are streamed in _id order,
+// so collections of any size can be compared.
+func CompareCollections(a, b *Client, i interface{}, q bson.M) (*CollectionDiff, error) {
+	q = scopeQuery(i, q)
+	op := a.startOp("find", collName(i), q)
+
+	if err := op.allowed(); err != nil {
+		return nil, op.done(err)
+	}
+
+	sa, err := a.GetSession()
+	if err != nil {
+		return nil, op.done(err)
+	}
+	defer sa.Close()
+
+	sb, err := b.GetSession()
+	if err != nil {
+		return nil, op.done(err)
+	}
+	defer sb.Close()
+
+	ia := a.GetColl(sa, op.coll).Find(q).Sort("_id").Iter()
+	ib := b.GetColl(sb, op.coll).Find(q).Sort("_id").Iter()
+
+	diff := &CollectionDiff{}
+	err = compareIters(ia, ib, diff)
+
+	for _, iter := range []*mgo.Iter{ia, ib} {
+		if cerr := iter.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		return nil, op.done(err)
+	}
+	return diff, op.done(nil)
+}
+
+// compareIters merges two iterators sorted by _id into diff.
+func compareIters(ia, ib *mgo.Iter, diff *CollectionDiff) error {
+	next := func(iter *mgo.Iter) bson.M {
+		doc := bson.M{}
+		if !iter.Next(&doc) {
+			return nil
+		}
+		return doc
+	}
+
+	da, db := next(ia), next(ib)
+	for da != nil || db != nil {
+		var c int
+		switch {
+		case da == nil:
+			c = 1
+		case db == nil:
+			c = -1
+		default:
+			var err error
+			if c, err = compareIds(da["_id"], db["_id"]); err != nil {
+				return err
+			}
+		}
+
+		switch {
+		case c < 0:
+			diff.OnlyInA = append(diff.OnlyInA, da["_id"])
+			da = next(ia)
+		case c > 0:
+			diff.OnlyInB = append(diff.OnlyInB, db["_id"])
+			db = next(ib)
+		default:
+			if changes := diffDocs(da, db, ""); len(changes) > 0 {
+				diff.Changed = append(diff.Changed, DocDiff{Id: da["_id"], Changes: changes})
+			} else {
+				diff.Same++
+			}
+			da, db = next(ia), next(ib)
+		}
+	}
+	return nil
+}
+
+// compareIds orders ids the way the server sorts them, which is only
+// simple for ids of the same kind.
+func compareIds(a, b interface{}) (int, error) {
+	switch av := a.(type) {
+	case bson.ObjectId:
+		if bv, ok := b.(bson.ObjectId); ok {
+			return strings.Compare(string(av), string(bv)), nil
+		}
+	case string:
+		if bv, ok := b.(string); ok {
+			return strings.Compare(av, bv), nil
+		}
+	default:
+		af, aok := numeric(a)
+		bf, bok := numeric(b)
+		if aok && bok {
+			switch {
+			case af < bf:
+				return -1, nil
+			case af > bf:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %T and %T", ErrMixedIds, a, b)
+}
@@ -0,0 +1,32 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"errors"
+	"testing"
+)
+
+func TestCompareIds(t *testing.T) {
+	first, second := bson.ObjectIdHex("5f0000000000000000000001"), bson.ObjectIdHex("5f0000000000000000000002")
+
+	cases := []struct {
+		a, b     interface{}
+		expected int
+	}{
+		{first, second, -1},
+		{second, first, 1},
+		{"b", "a", 1},
+		{1, 1.0, 0},
+		{int64(2), 10, -1},
+	}
+	for _, c := range cases {
+		if n, err := compareIds(c.a, c.b); err != nil || n != c.expected {
+			t.Fatalf("compareIds(%v, %v) = %v, %v, expected %v", c.a, c.b, n, err, c.expected)
+		}
+	}
+
+	if _, err := compareIds(first, "a"); !errors.Is(err, ErrMixedIds) {
+		t.Fatal("Expected ErrMixedIds got:", err)
+	}
+}
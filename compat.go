@@ -0,0 +1,60 @@
+package mongo
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+)
+
+// The original package level functions, Insert, Find, FindById, Update,
+// Delete and Count, are kept for compatibility and call the same method of
+// the default client. Code is best migrated to Client a call at a time:
+//
+//	c := mongo.Default()
+//	err := c.Find(&users, q)
+//
+// SetDeprecationWarnings helps find the calls that are left. The other
+// package level functions that read or write records have Client methods
+// too but aren't deprecated.
+
+var (
+	deprecationWarnings int32
+
+	warnedMu sync.Mutex
+	warned   = map[string]bool{}
+)
+
+// Returns the client the package level functions use. It's configured with
+// SetServers or SetConfig and dials on first use; closing it does nothing.
+func Default() *Client {
+	return std
+}
+
+// Log a warning the first time each of the deprecated package level
+// functions is called, e.g. "mongo: Find is deprecated, use Client.Find".
+// Off by default; the functions work the same either way. It's also the
+// "deprecationwarnings" option of SetOption.
+func SetDeprecationWarnings(on bool) {
+	var v int32
+	if on {
+		v = 1
+	}
+	atomic.StoreInt32(&deprecationWarnings, v)
+}
+
+// deprecated warns that the package level function name was called, once,
+// if warnings are on.
+func deprecated(name string) {
+	if atomic.LoadInt32(&deprecationWarnings) == 0 {
+		return
+	}
+
+	warnedMu.Lock()
+	seen := warned[name]
+	warned[name] = true
+	warnedMu.Unlock()
+
+	if !seen {
+		log.Printf("mongo: %v is deprecated, use Client.%v; mongo.Default returns the client it calls", name, name)
+	}
+}
@@ -0,0 +1,58 @@
+package mongo
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDeprecationWarnings(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	warnedMu.Lock()
+	warned = map[string]bool{}
+	warnedMu.Unlock()
+
+	// Off by default.
+	Insert(MongoTest{})
+	if buf.Len() != 0 {
+		t.Fatal("Expected no warnings got:", buf.String())
+	}
+
+	SetDeprecationWarnings(true)
+	defer SetDeprecationWarnings(false)
+
+	Insert(MongoTest{})
+	Insert(MongoTest{})
+	Find(MongoTest{}, nil)
+
+	out := buf.String()
+	if n := strings.Count(out, "Insert is deprecated"); n != 1 {
+		t.Fatal("Expected one warning for Insert got:", out)
+	}
+	if !strings.Contains(out, "use Client.Find") {
+		t.Fatal("Expected a warning for Find got:", out)
+	}
+
+	// Calls through the default client aren't deprecated.
+	buf.Reset()
+	Default().Insert(MongoTest{})
+	Default().Update(MongoTest{})
+	if buf.Len() != 0 {
+		t.Fatal("Expected no warnings for Client calls got:", buf.String())
+	}
+
+	// Nor are the other package level functions, including the ones added
+	// alongside Client.
+	Upsert(MongoTest{}, nil)
+	Clone(MongoTest{})
+	DeleteByIds(&MongoTest{}, "nope")
+	FindByPublicId(&MongoTest{}, "")
+	if buf.Len() != 0 {
+		t.Fatal("Expected no warnings for functions that aren't deprecated got:", buf.String())
+	}
+}
@@ -0,0 +1,183 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrNoKey is returned by UpsertBy, FindByKey and DeleteByKey for models
+// that don't implement Keyed.
+var ErrNoKey = errors.New("Model doesn't declare a key")
+
+// ErrKeyValues is returned, wrapped with details, when the values given
+// don't match the fields of the key.
+var ErrKeyValues = errors.New("Key values don't match the key")
+
+// Keyed is implemented by models whose natural identity spans several
+// fields, e.g. a tenant and a SKU. KeyFields returns their document keys,
+// and a unique index on them is created the first time the key is used, as
+// well as by EnsureIndexes.
+//
+//	func (p *Product) KeyFields() []string { return []string{"tenant", "sku"} }
+type Keyed interface {
+	KeyFields() []string
+}
+
+// keyIndexes remembers the collections whose key index exists, by
+// connection and namespace since clients may use other servers or databases.
+var keyIndexes sync.Map
+
+type keyIndexKey struct {
+	session *mgo.Session
+	ns      string
+}
+
+// Returns the unique index on the key of m, if it declares one.
+func keyIndex(m interface{}) (mgo.Index, bool) {
+	k, ok := m.(Keyed)
+	if !ok || len(k.KeyFields()) == 0 {
+		return mgo.Index{}, false
+	}
+	return mgo.Index{Key: k.KeyFields(), Unique: true}, true
+}
+
+// Insert the record or update the one with the same key, atomically. i must
+// be a pointer to a struct implementing Keyed and ends up holding the
+// stored record. See UpsertWith.
+func UpsertBy(i interface{}) (created bool, err error) {
+	return std.UpsertBy(i)
+}
+
+// Upsert a record by its composite key using the client. See UpsertBy.
+func (c *Client) UpsertBy(i interface{}) (created bool, err error) {
+	return c.UpsertByWith(i, UpsertOptions{})
+}
+
+// Insert the record or update the one with the same key, with options. See
+// UpsertBy.
+func UpsertByWith(i interface{}, opts UpsertOptions) (created bool, err error) {
+	return std.UpsertByWith(i, opts)
+}
+
+// Upsert a record by its composite key with options using the client. See
+// UpsertByWith.
+func (c *Client) UpsertByWith(i interface{}, opts UpsertOptions) (created bool, err error) {
+	if !isPtr(i) {
+		return false, NoPtr
+	}
+
+	k, ok := i.(Keyed)
+	if !ok {
+		return false, fmt.Errorf("%w: %v", ErrNoKey, collName(i))
+	}
+
+	doc, err := recordDoc(i)
+	if err != nil {
+		return false, err
+	}
+
+	q := bson.M{}
+	for _, f := range k.KeyFields() {
+		v, ok := getDocPath(doc, f)
+		if !ok {
+			return false, fmt.Errorf("%w: %v is missing", ErrKeyValues, f)
+		}
+		q[f] = v
+	}
+
+	if err := c.ensureKeyIndex(i); err != nil {
+		return false, err
+	}
+
+	return c.UpsertWith(i, q, opts)
+}
+
+// Find the record with the key values given, one for each of its KeyFields
+// in order. i must be a pointer to a struct implementing Keyed.
+//
+//	err := mongo.FindByKey(product, tenantId, "SKU-1234")
+func FindByKey(i interface{}, values ...interface{}) error {
+	return std.FindByKey(i, values...)
+}
+
+// Find a record by its composite key using the client. See FindByKey.
+func (c *Client) FindByKey(i interface{}, values ...interface{}) error {
+	q, err := keyQuery(i, values)
+	if err != nil {
+		return err
+	}
+
+	if err := c.ensureKeyIndex(i); err != nil {
+		return err
+	}
+
+	return c.Find(i, q)
+}
+
+// Delete the record with the key values given, like Delete. i must be a
+// pointer to a struct implementing Keyed, and ends up holding the deleted
+// record.
+func DeleteByKey(i interface{}, values ...interface{}) error {
+	return std.DeleteByKey(i, values...)
+}
+
+// Delete a record by its composite key using the client. See DeleteByKey.
+func (c *Client) DeleteByKey(i interface{}, values ...interface{}) error {
+	if err := c.FindByKey(i, values...); err != nil {
+		return err
+	}
+	return c.Delete(i)
+}
+
+// keyQuery matches the record with the key values given.
+func keyQuery(i interface{}, values []interface{}) (bson.M, error) {
+	k, ok := i.(Keyed)
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", ErrNoKey, collName(i))
+	}
+
+	fields := k.KeyFields()
+	if len(values) != len(fields) {
+		return nil, fmt.Errorf("%w: %v values for %v", ErrKeyValues, len(values), fields)
+	}
+
+	q := bson.M{}
+	for n, f := range fields {
+		q[f] = values[n]
+	}
+	return q, nil
+}
+
+// ensureKeyIndex creates the unique index on the key of i once per
+// collection.
+func (c *Client) ensureKeyIndex(i interface{}) error {
+	idx, ok := keyIndex(i)
+	coll := collName(i)
+	key := keyIndexKey{c.session, c.Database() + "." + PhysicalCollection(coll)}
+	if _, done := keyIndexes.Load(key); !ok || done {
+		return nil
+	}
+
+	op := c.startOp("ensureIndex", coll, nil)
+
+	if err := op.allowed(); err != nil {
+		return op.done(err)
+	}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return op.done(err)
+	}
+	defer s.Close()
+
+	if err := c.GetColl(s, coll).EnsureIndex(idx); err != nil {
+		return op.done(err)
+	}
+
+	keyIndexes.Store(key, true)
+	return op.done(nil)
+}
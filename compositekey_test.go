@@ -0,0 +1,57 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+
+	"errors"
+	"testing"
+)
+
+type keyedProduct struct {
+	Id     bson.ObjectId `bson:"_id"`
+	Tenant string        `bson:"tenant"`
+	Sku    string        `bson:"sku"`
+}
+
+func (p *keyedProduct) KeyFields() []string { return []string{"tenant", "sku"} }
+
+type indexedProduct struct {
+	keyedProduct `bson:",inline"`
+}
+
+func (p *indexedProduct) Indexes() []mgo.Index {
+	return []mgo.Index{{Key: []string{"tenant", "sku"}, Unique: true, Sparse: true}}
+}
+
+func TestKeyQuery(t *testing.T) {
+	q, err := keyQuery(&keyedProduct{}, []interface{}{"acme", "SKU-1"})
+	if err != nil || q["tenant"] != "acme" || q["sku"] != "SKU-1" {
+		t.Fatal("Expected a query on the key got:", q, err)
+	}
+
+	if _, err := keyQuery(&keyedProduct{}, []interface{}{"acme"}); !errors.Is(err, ErrKeyValues) {
+		t.Fatal("Expected ErrKeyValues for a missing value got:", err)
+	}
+
+	if _, err := keyQuery(&MongoTest{}, []interface{}{"acme"}); !errors.Is(err, ErrNoKey) {
+		t.Fatal("Expected ErrNoKey got:", err)
+	}
+}
+
+func TestDeclaredIndexes(t *testing.T) {
+	indexes := declaredIndexes(&keyedProduct{})
+	if len(indexes) != 1 || !indexes[0].Unique || len(indexes[0].Key) != 2 {
+		t.Fatal("Expected the unique key index got:", indexes)
+	}
+
+	// A model declaring an index on its key keeps its own options.
+	indexes = declaredIndexes(&indexedProduct{})
+	if len(indexes) != 1 || !indexes[0].Sparse {
+		t.Fatal("Expected only the declared index got:", indexes)
+	}
+
+	if indexes := declaredIndexes(&MongoTest{}); len(indexes) != 0 {
+		t.Fatal("Expected no indexes got:", indexes)
+	}
+}
@@ -0,0 +1,146 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrPreconditionFailed is returned by the conditional updates when the
+// stored record has changed. An HTTP handler answers it with 412
+// Precondition Failed.
+var ErrPreconditionFailed = errors.New("Record has been modified")
+
+// Updates a record like Update, but only if the stored record still has the
+// hash given, e.g. the ETag a client sent in If-Match. See Hash and
+// FindWithHash. Surrounding quotes are ignored. The check and the update are
+// atomic: the update only matches the record exactly as it was hashed.
+func UpdateIfMatch(i interface{}, expectedHash string) error {
+	return std.UpdateIfMatch(i, expectedHash)
+}
+
+// Update a record if its hash matches using the client. See UpdateIfMatch.
+func (c *Client) UpdateIfMatch(i interface{}, expectedHash string) error {
+	if !isPtr(i) {
+		return NoPtr
+	}
+
+	op := c.startOp("update", collName(i), nil)
+
+	if err := op.allowed(); err != nil {
+		return op.done(err)
+	}
+
+	id, err := getObjIdFromStruct(i)
+	if err != nil {
+		return op.done(err)
+	}
+	op.query = bson.M{"_id": id}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return op.done(err)
+	}
+	defer s.Close()
+
+	coll := c.GetColl(s, op.coll)
+
+	var raw bson.Raw
+	if err := coll.FindId(id).One(&raw); err != nil {
+		return op.done(err)
+	}
+
+	h, err := hashDoc(raw.Data)
+	if err != nil {
+		return op.done(err)
+	}
+	if h != strings.Trim(expectedHash, `"`) {
+		return op.done(ErrPreconditionFailed)
+	}
+
+	var stored bson.D
+	if err := raw.Unmarshal(&stored); err != nil {
+		return op.done(err)
+	}
+
+	return op.done(c.conditionalUpdate(s, coll, i, stored))
+}
+
+// Updates a record like Update, but only if the stored UpdatedAt is still
+// the one given, e.g. from If-Unmodified-Since. The record must have an
+// UpdatedAt field. Times are compared to the millisecond, the precision
+// they're stored with.
+func UpdateIfUnmodified(i interface{}, updatedAt time.Time) error {
+	return std.UpdateIfUnmodified(i, updatedAt)
+}
+
+// Update a record if it's unmodified using the client. See
+// UpdateIfUnmodified.
+func (c *Client) UpdateIfUnmodified(i interface{}, updatedAt time.Time) error {
+	if !isPtr(i) {
+		return NoPtr
+	}
+
+	op := c.startOp("update", collName(i), nil)
+
+	if err := op.allowed(); err != nil {
+		return op.done(err)
+	}
+
+	if !hasStructField(i, "UpdatedAt") {
+		return op.done(errors.New("Record must have an UpdatedAt field"))
+	}
+
+	id, err := getObjIdFromStruct(i)
+	if err != nil {
+		return op.done(err)
+	}
+	op.query = bson.M{"_id": id, fieldKey(i, "UpdatedAt"): updatedAt.Truncate(time.Millisecond)}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return op.done(err)
+	}
+	defer s.Close()
+
+	return op.done(c.conditionalUpdate(s, c.GetColl(s, op.coll), i, op.query))
+}
+
+// conditionalUpdate replaces the record matching selector with i. No match
+// means the record changed since the selector was built.
+func (c *Client) conditionalUpdate(s *mgo.Session, coll *mgo.Collection, i interface{}, selector interface{}) error {
+	if err := normalize(i); err != nil {
+		return err
+	}
+
+	if err := validate(i); err != nil {
+		return err
+	}
+
+	if err := addCurrentDateTime(i, "UpdatedAt"); err != nil {
+		return err
+	}
+
+	id, err := getObjIdFromStruct(i)
+	if err != nil {
+		return err
+	}
+
+	doc, err := replacementDoc(coll, i, id)
+	if err != nil {
+		return err
+	}
+
+	before := countedDoc(coll, i, id)
+	err = coll.Update(selector, doc)
+	if err == mgo.ErrNotFound {
+		return ErrPreconditionFailed
+	}
+	if err != nil {
+		return err
+	}
+	return c.afterWrite(s, i, written{id: id, before: before, after: doc})
+}
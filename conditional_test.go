@@ -0,0 +1,17 @@
+package mongo
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestConditionalNoPtr(t *testing.T) {
+	if err := UpdateIfMatch(MongoTest{}, "abc"); err != NoPtr {
+		t.Fatal("Expected NoPtr, got:", err)
+	}
+
+	if err := UpdateIfUnmodified(&validatedModel{Name: "x"}, time.Now()); err == nil || errors.Is(err, ErrPreconditionFailed) {
+		t.Fatal("Expected records without UpdatedAt to be rejected, got:", err)
+	}
+}
@@ -0,0 +1,141 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo"
+
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultDialTimeout is how long dialing waits for the servers when Config
+// doesn't set a Timeout. It's the same as mgo.Dial's.
+const DefaultDialTimeout = 10 * time.Second
+
+// Config describes a connection.
+type Config struct {
+	// Servers is a mongodb:// URL or a comma separated list of hosts.
+	Servers string
+	// Database to use. Defaults to the database in the Servers URL.
+	Database string
+	// Timeout bounds how long dialing waits for the servers to respond.
+	Timeout time.Duration
+	// FailFast gives up as soon as the servers can't be reached instead of
+	// retrying until Timeout, both when dialing and for later operations.
+	// Use it to surface connectivity problems quickly at startup.
+	FailFast bool
+	// TLS connects to the servers over TLS, verifying their certificates
+	// against the system roots. Set TLSConfig to customize it.
+	TLS       bool
+	TLSConfig *tls.Config
+}
+
+// Set the configuration of the connection used by the package level
+// functions and dial it. If the servers can't be reached the error is
+// returned and dialing is retried on first use.
+func SetConfig(cfg Config) error {
+	connMu.Lock()
+	old := conn
+	conn, config, dialing = nil, cfg, nil
+	connGen++
+	gen := connGen
+	connMu.Unlock()
+
+	if old != nil {
+		old.Close()
+	}
+
+	c, err := Dial(cfg)
+	if err != nil {
+		return err
+	}
+
+	// Keep the connection unless the config changed again while dialing, or
+	// first use dialed it already.
+	connMu.Lock()
+	keep := gen == connGen && conn == nil
+	if keep {
+		conn = c
+	}
+	connMu.Unlock()
+
+	if !keep {
+		c.Close()
+	}
+	return nil
+}
+
+// Dial a new client with the configuration.
+func Dial(cfg Config) (*Client, error) {
+	info, err := mgo.ParseURL(cfg.Servers)
+	if err != nil {
+		return nil, err
+	}
+
+	info.Timeout = cfg.Timeout
+	if info.Timeout <= 0 {
+		info.Timeout = DefaultDialTimeout
+	}
+	info.FailFast = cfg.FailFast
+
+	if cfg.TLS || cfg.TLSConfig != nil {
+		tlsConfig := cfg.TLSConfig
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{}
+		}
+		dialer := &net.Dialer{Timeout: info.Timeout}
+		info.DialServer = func(addr *mgo.ServerAddr) (net.Conn, error) {
+			return tls.DialWithDialer(dialer, "tcp", addr.String(), tlsConfig)
+		}
+	}
+
+	db := cfg.Database
+	if db == "" {
+		db = info.Database
+	}
+
+	s, err := mgo.DialWithInfo(info)
+	if err != nil {
+		return nil, fmt.Errorf("Couldn't connect to %v within %v: %v", redactServers(cfg.Servers), info.Timeout, err)
+	}
+
+	if cfg.FailFast {
+		if err := s.Ping(); err != nil {
+			s.Close()
+			return nil, fmt.Errorf("Couldn't ping %v: %v", redactServers(cfg.Servers), err)
+		}
+	}
+
+	return &Client{session: s, database: db}, nil
+}
+
+// redactServers removes the username and password from servers so it can be
+// shown in errors and logs.
+func redactServers(servers string) string {
+	scheme := strings.Contains(servers, "://")
+	raw := servers
+	if !scheme {
+		raw = "mongodb://" + raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		// Keep only what follows the credentials.
+		if at := strings.LastIndex(servers, "@"); at >= 0 {
+			return servers[at+1:]
+		}
+		return servers
+	}
+	if u.User == nil {
+		return servers
+	}
+
+	u.User = nil
+	if !scheme {
+		return strings.TrimPrefix(u.String(), "mongodb://")
+	}
+	return u.String()
+}
@@ -0,0 +1,59 @@
+package mongo
+
+import (
+	"sync"
+)
+
+var (
+	connsMu sync.RWMutex
+	conns   = map[string]*Client{}
+)
+
+// Dial a connection and register it under name, closing any connection
+// previously registered under it. Use Conn to get it back:
+//
+//	err := mongo.AddConnection("reporting", mongo.Config{Servers: "reports.example.com", Database: "reports"})
+//	...
+//	reporting, ok := mongo.Conn("reporting")
+//	if !ok {
+//		return errors.New("no reporting connection")
+//	}
+//	err = reporting.Find(&rows, bson.M{"day": day})
+func AddConnection(name string, cfg Config) error {
+	c, err := Dial(cfg)
+	if err != nil {
+		return err
+	}
+
+	connsMu.Lock()
+	old := conns[name]
+	conns[name] = c
+	connsMu.Unlock()
+
+	if old != nil {
+		old.Close()
+	}
+	return nil
+}
+
+// Returns the connection registered under name by AddConnection. ok is
+// false if there's none.
+func Conn(name string) (c *Client, ok bool) {
+	connsMu.RLock()
+	defer connsMu.RUnlock()
+
+	c, ok = conns[name]
+	return c, ok
+}
+
+// Close the connection registered under name and forget it.
+func RemoveConnection(name string) {
+	connsMu.Lock()
+	c := conns[name]
+	delete(conns, name)
+	connsMu.Unlock()
+
+	if c != nil {
+		c.Close()
+	}
+}
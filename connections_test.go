@@ -0,0 +1,28 @@
+package mongo
+
+import (
+	"testing"
+)
+
+func TestConnUnknown(t *testing.T) {
+	if c, ok := Conn("nope"); ok || c != nil {
+		t.Fatal("Expected no connection, got:", c)
+	}
+
+	// Removing an unknown connection is a no-op.
+	RemoveConnection("nope")
+}
+
+func TestClientNoPtr(t *testing.T) {
+	c := &Client{database: "other"}
+
+	if err := c.Insert(MongoTest{}); err != NoPtr {
+		t.Fatal("Expected NoPtr, got:", err)
+	}
+	if err := c.Find(MongoTest{}, nil); err != NoPtr {
+		t.Fatal("Expected NoPtr, got:", err)
+	}
+	if c.Database() != "other" {
+		t.Fatal("Expected the client's own database, got:", c.Database())
+	}
+}
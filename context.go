@@ -0,0 +1,90 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"context"
+)
+
+type databaseKey struct{}
+
+// Returns a copy of ctx carrying a database name. The functions taking a
+// context use that database instead of the configured one, which routes each
+// tenant to its own database without passing clients around:
+//
+//	ctx = mongo.WithDatabase(ctx, "tenant_42")
+//	...
+//	err := mongo.FindContext(ctx, &users, bson.M{"active": true})
+func WithDatabase(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, databaseKey{}, name)
+}
+
+// Returns the database name carried by ctx, if any.
+func DatabaseFrom(ctx context.Context) (string, bool) {
+	name, ok := ctx.Value(databaseKey{}).(string)
+	return name, ok && name != ""
+}
+
+// Returns the client for ctx: the package level connection using the
+// database carried by ctx or the configured database if there's none.
+func ClientFor(ctx context.Context) *Client {
+	return std.WithContext(ctx)
+}
+
+// Returns the client using the database carried by ctx or c itself if there's
+// none.
+func (c *Client) WithContext(ctx context.Context) *Client {
+	if name, ok := DatabaseFrom(ctx); ok {
+		return c.UseDatabase(name)
+	}
+	return c
+}
+
+// Insert using the database carried by ctx. See Insert. mgo doesn't support
+// cancellation so ctx is only checked before starting.
+func InsertContext(ctx context.Context, records ...interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return ClientFor(ctx).Insert(records...)
+}
+
+// Find using the database carried by ctx. See Find.
+func FindContext(ctx context.Context, i interface{}, q bson.M, sortFields ...string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return ClientFor(ctx).Find(i, q, sortFields...)
+}
+
+// Find by id using the database carried by ctx. See FindById.
+func FindByIdContext(ctx context.Context, i interface{}, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return ClientFor(ctx).FindById(i, id)
+}
+
+// Update using the database carried by ctx. See Update.
+func UpdateContext(ctx context.Context, i interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return ClientFor(ctx).Update(i)
+}
+
+// Delete using the database carried by ctx. See Delete.
+func DeleteContext(ctx context.Context, i interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return ClientFor(ctx).Delete(i)
+}
+
+// Count using the database carried by ctx. See Count.
+func CountContext(ctx context.Context, i interface{}) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return ClientFor(ctx).Count(i)
+}
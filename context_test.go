@@ -0,0 +1,46 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+)
+
+func TestWithDatabase(t *testing.T) {
+	ctx := context.Background()
+
+	if _, ok := DatabaseFrom(ctx); ok {
+		t.Fatal("Expected no database in a plain context")
+	}
+	if c := ClientFor(ctx); c != std {
+		t.Fatal("Expected the default client without a database in the context")
+	}
+
+	ctx = WithDatabase(ctx, "tenant_42")
+
+	if name, ok := DatabaseFrom(ctx); !ok || name != "tenant_42" {
+		t.Fatal("Expected tenant_42 got:", name)
+	}
+	if db := ClientFor(ctx).Database(); db != "tenant_42" {
+		t.Fatal("Expected tenant_42 got:", db)
+	}
+
+	c := &Client{database: "other"}
+	if db := c.WithContext(ctx).Database(); db != "tenant_42" {
+		t.Fatal("Expected tenant_42 got:", db)
+	}
+	if c.Database() != "other" {
+		t.Fatal("Expected the original client to be unchanged, got:", c.Database())
+	}
+}
+
+func TestContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(WithDatabase(context.Background(), "tenant_42"))
+	cancel()
+
+	if err := FindContext(ctx, &[]MongoTest{}, nil); err != context.Canceled {
+		t.Fatal("Expected context.Canceled got:", err)
+	}
+	if _, err := CountContext(ctx, MongoTest{}); err != context.Canceled {
+		t.Fatal("Expected context.Canceled got:", err)
+	}
+}
@@ -0,0 +1,280 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+
+	"log"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// CountsCollection holds the counters maintained by CountsCache.
+var CountsCollection = "counts"
+
+var (
+	countsMu     sync.RWMutex
+	countsCaches = map[reflect.Type][]*CountsCache{}
+)
+
+// CountsCache keeps the number of records for each combination of values of
+// some fields in CountsCollection, so dashboards can show counts of huge
+// collections without counting:
+//
+//	byStatus := mongo.NewCountsCache(&Order{}, "status")
+//	go byStatus.Run(time.Hour, stop, logErr)
+//	...
+//	n, err := byStatus.Count("shipped")
+//
+// Writes of a single record, like Insert, Update and Delete, adjust the
+// counters as they write. Writes that can't tell which records they
+// changed, like UpdateDocs or BulkWrite, mark the counters dirty instead and
+// Run rebuilds them with Reconcile straight away rather than at the next
+// interval. Writes made by another program aren't seen until the next
+// Reconcile.
+type CountsCache struct {
+	client *Client
+	model  interface{}
+	coll   string
+	fields []string
+	dirty  chan struct{}
+}
+
+// Create a cache counting records like model by the values of fields, which
+// are document keys. Counts are only kept once the cache is created.
+func NewCountsCache(model interface{}, fields ...string) *CountsCache {
+	return std.NewCountsCache(model, fields...)
+}
+
+// Create a counts cache using the client. See NewCountsCache.
+func (c *Client) NewCountsCache(model interface{}, fields ...string) *CountsCache {
+	cc := &CountsCache{client: c, model: model, coll: collName(model), fields: fields, dirty: make(chan struct{}, 1)}
+
+	countsMu.Lock()
+	defer countsMu.Unlock()
+
+	t := structType(model)
+	countsCaches[t] = append(countsCaches[t], cc)
+	return cc
+}
+
+// Stop maintaining the counters on writes. They're left in CountsCollection.
+func (c *CountsCache) Close() {
+	countsMu.Lock()
+	defer countsMu.Unlock()
+
+	t := structType(c.model)
+	caches := countsCaches[t][:0]
+	for _, other := range countsCaches[t] {
+		if other != c {
+			caches = append(caches, other)
+		}
+	}
+	countsCaches[t] = caches
+}
+
+// Returns the number of records with the values given, one for each field
+// in order. It's zero until the first Reconcile if there were records before
+// the cache was created.
+func (c *CountsCache) Count(values ...interface{}) (int, error) {
+	s, err := c.client.GetSession()
+	if err != nil {
+		return 0, err
+	}
+	defer s.Close()
+
+	var counter struct {
+		N int `bson:"n"`
+	}
+	err = c.client.GetColl(s, CountsCollection).FindId(c.key(values)).One(&counter)
+	if err == mgo.ErrNotFound {
+		return 0, nil
+	}
+	return counter.N, err
+}
+
+// Rebuild the counters by counting the records. Writes made while it runs
+// may be miscounted until the next time.
+func (c *CountsCache) Reconcile() error {
+	group := bson.D{}
+	for n, f := range c.fields {
+		group = append(group, bson.DocElem{Name: groupKey(n), Value: "$" + f})
+	}
+
+	var rows []struct {
+		Id bson.M `bson:"_id"`
+		N  int    `bson:"n"`
+	}
+	if err := c.client.Aggregate(c.model, Pipeline{}.Group(bson.M{"_id": group, "n": bson.M{"$sum": 1}}), &rows); err != nil {
+		return err
+	}
+
+	s, err := c.client.GetSession()
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	counts := c.client.GetColl(s, CountsCollection)
+	if _, err := counts.RemoveAll(bson.M{"_id.c": c.coll, "_id.f": c.fields}); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		values := make([]interface{}, len(c.fields))
+		for n := range values {
+			values[n] = row.Id[groupKey(n)]
+		}
+
+		if _, err := counts.UpsertId(c.key(values), bson.M{"$set": bson.M{"n": row.N}}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Reconcile the counters now, every interval and whenever they're marked
+// dirty, until stop is closed. Errors are passed to onError, if it isn't
+// nil, and don't stop it.
+func (c *CountsCache) Run(interval time.Duration, stop <-chan struct{}, onError func(error)) {
+	reconcileLoop(c.Reconcile, c.dirty, interval, stop, onError)
+}
+
+// reconcileLoop calls reconcile now, every interval and whenever dirty
+// receives, until stop is closed.
+func reconcileLoop(reconcile func() error, dirty <-chan struct{}, interval time.Duration, stop <-chan struct{}, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := reconcile(); err != nil && onError != nil {
+			onError(err)
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		case <-dirty:
+		}
+	}
+}
+
+// markDirty makes Run reconcile the counters. Marks made while it's busy
+// are merged into one.
+func markDirty(dirty chan struct{}) {
+	select {
+	case dirty <- struct{}{}:
+	default:
+	}
+}
+
+// markCountsDirty marks the counters of every cache counting records of
+// coll dirty.
+func markCountsDirty(coll string) {
+	countsMu.RLock()
+	defer countsMu.RUnlock()
+
+	for _, caches := range countsCaches {
+		for _, c := range caches {
+			if c.coll == coll {
+				markDirty(c.dirty)
+			}
+		}
+	}
+}
+
+// key returns the _id of the counter for values.
+func (c *CountsCache) key(values []interface{}) bson.D {
+	return bson.D{{Name: "c", Value: c.coll}, {Name: "f", Value: c.fields}, {Name: "v", Value: values}}
+}
+
+func (c *CountsCache) values(doc bson.M) []interface{} {
+	values := make([]interface{}, len(c.fields))
+	for n, f := range c.fields {
+		values[n], _ = getDocPath(doc, f)
+	}
+	return values
+}
+
+func groupKey(n int) string {
+	return "k" + strconv.Itoa(n)
+}
+
+func cachesFor(i interface{}) []*CountsCache {
+	countsMu.RLock()
+	defer countsMu.RUnlock()
+
+	return countsCaches[structType(i)]
+}
+
+// counted returns whether writes of records like i adjust a CountsCache or
+// a RefCount.
+func counted(i interface{}) bool {
+	return len(cachesFor(i)) > 0 || len(refCountsFor(i)) > 0
+}
+
+// countedDoc returns the stored record with id if records like i are
+// counted, so countWrite and countRefs can tell which counters it was in.
+func countedDoc(coll *mgo.Collection, i interface{}, id interface{}) bson.M {
+	if !counted(i) {
+		return nil
+	}
+
+	doc := bson.M{}
+	if err := coll.FindId(id).One(&doc); err != nil {
+		return nil
+	}
+	return doc
+}
+
+// countWrite moves a record written through i from the counters matching
+// before to those matching after, the document written. Before is nil for
+// inserts and after for deletes. Counting errors are logged rather than
+// failing the write, which has already happened.
+func countWrite(counts *mgo.Collection, i interface{}, before bson.M, after interface{}) {
+	caches := cachesFor(i)
+	if len(caches) == 0 {
+		return
+	}
+
+	var written bson.M
+	if after != nil {
+		data, err := bson.Marshal(after)
+		if err == nil {
+			err = bson.Unmarshal(data, &written)
+		}
+		if err != nil {
+			log.Println("mongo: counts:", err)
+			return
+		}
+	}
+
+	for _, c := range caches {
+		var from, to []interface{}
+		if before != nil {
+			from = c.values(before)
+		}
+		if written != nil {
+			to = c.values(written)
+		}
+
+		if from != nil && to != nil && reflect.DeepEqual(from, to) {
+			continue
+		}
+
+		if from != nil {
+			if _, err := counts.UpsertId(c.key(from), bson.M{"$inc": bson.M{"n": -1}}); err != nil {
+				log.Println("mongo: counts:", err)
+			}
+		}
+		if to != nil {
+			if _, err := counts.UpsertId(c.key(to), bson.M{"$inc": bson.M{"n": 1}}); err != nil {
+				log.Println("mongo: counts:", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCountsCacheRegistry(t *testing.T) {
+	if caches := cachesFor(&MongoTest{}); len(caches) != 0 {
+		t.Fatal("Expected no caches got:", caches)
+	}
+
+	c := NewCountsCache(&MongoTest{}, "name", "address.city")
+
+	if caches := cachesFor(&[]MongoTest{}); len(caches) != 1 || caches[0] != c {
+		t.Fatal("Expected the cache to be registered for the model got:", caches)
+	}
+
+	c.Close()
+	if caches := cachesFor(&MongoTest{}); len(caches) != 0 {
+		t.Fatal("Expected the cache to be removed got:", caches)
+	}
+}
+
+func TestCountsCacheValues(t *testing.T) {
+	c := &CountsCache{coll: "MongoTest", fields: []string{"name", "address.city"}}
+
+	values := c.values(bson.M{"name": "George", "address": bson.M{"city": "Orbit City"}})
+	if !reflect.DeepEqual(values, []interface{}{"George", "Orbit City"}) {
+		t.Fatal("Unexpected values:", values)
+	}
+
+	// Missing fields are counted as null, like $group does.
+	values = c.values(bson.M{"name": "Jane"})
+	if !reflect.DeepEqual(values, []interface{}{"Jane", nil}) {
+		t.Fatal("Unexpected values:", values)
+	}
+}
+
+func TestCountsCacheDirty(t *testing.T) {
+	c := NewCountsCache(&MongoTest{}, "name")
+	defer c.Close()
+
+	markCountsDirty("other")
+	if len(c.dirty) != 0 {
+		t.Fatal("Expected writes to other collections not to mark the cache")
+	}
+
+	// Marks are merged while the cache is busy.
+	markCountsDirty(c.coll)
+	markCountsDirty(c.coll)
+	if len(c.dirty) != 1 {
+		t.Fatal("Expected the cache to be marked dirty once got:", len(c.dirty))
+	}
+}
+
+func TestReconcileLoop(t *testing.T) {
+	calls := make(chan struct{}, 10)
+	dirty := make(chan struct{}, 1)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		reconcileLoop(func() error { calls <- struct{}{}; return nil }, dirty, time.Hour, stop, nil)
+		close(done)
+	}()
+
+	for n := 0; n < 2; n++ {
+		select {
+		case <-calls:
+		case <-time.After(time.Second):
+			t.Fatal("Expected a reconcile", n)
+		}
+		markDirty(dirty)
+	}
+
+	close(stop)
+	<-done
+}
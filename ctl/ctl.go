@@ -0,0 +1,337 @@
+/*
+The ctl package implements mongoctl, a command line tool for common
+operations that goes through the same code paths as the application.
+
+	mongoctl [-url servers] [-db database] command [arguments]
+
+Commands:
+
+	count <collection> [filter]
+	find [-limit n] [-sort fields] <collection> [filter]
+	insert <collection> [document]
+	update <collection> <filter> <update>
+	delete <collection> <filter>
+	export <collection> [filter]
+	import <collection>
+	ensure-indexes
+
+Filters, documents and updates are mongo extended JSON. find and export
+write one document per line; insert without a document and import read
+one document per line from stdin. The url and db default to the
+MONGO_URL and MONGO_DB environment variables.
+
+ensure-indexes needs the application's models, so applications build
+their own binary:
+
+	func main() {
+		if err := ctl.Run(os.Args[1:], os.Stdin, os.Stdout, &User{}, &Post{}); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+*/
+package ctl
+
+import (
+	"github.com/globalsign/mgo/bson"
+	"github.com/sfreiberg/mongo"
+
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ErrUsage is returned for unknown commands and wrong arguments.
+var ErrUsage = errors.New("usage: mongoctl [-url servers] [-db database] command [arguments]")
+
+// importBatch is the number of documents import inserts at a time.
+const importBatch = 1000
+
+type command struct {
+	usage   string
+	minArgs int
+	maxArgs int
+	run     func(e *env, args []string) error
+}
+
+// env is what a command runs with.
+type env struct {
+	stdin  io.Reader
+	stdout io.Writer
+	models []interface{}
+	limit  int
+	sort   []string
+}
+
+var commands = map[string]command{
+	"count":          {"count <collection> [filter]", 1, 2, count},
+	"find":           {"find [-limit n] [-sort fields] <collection> [filter]", 1, 2, find},
+	"insert":         {"insert <collection> [document]", 1, 2, insert},
+	"update":         {"update <collection> <filter> <update>", 3, 3, update},
+	"delete":         {"delete <collection> <filter>", 2, 2, remove},
+	"export":         {"export <collection> [filter]", 1, 2, export},
+	"import":         {"import <collection>", 1, 1, importDocs},
+	"ensure-indexes": {"ensure-indexes", 0, 0, ensureIndexes},
+}
+
+// Run a mongoctl command. args doesn't include the program name. models are
+// used by ensure-indexes; without them the models registered with
+// mongo.Bootstrap are used.
+func Run(args []string, stdin io.Reader, stdout io.Writer, models ...interface{}) error {
+	fs := flag.NewFlagSet("mongoctl", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	url := fs.String("url", envOr("MONGO_URL", "localhost"), "mongo servers")
+	db := fs.String("db", os.Getenv("MONGO_DB"), "database")
+	if err := fs.Parse(args); err != nil || fs.NArg() == 0 {
+		return ErrUsage
+	}
+
+	name := fs.Arg(0)
+	cmd, ok := commands[name]
+	if !ok {
+		return fmt.Errorf("Unknown command: %v", name)
+	}
+
+	e := &env{stdin: stdin, stdout: stdout, models: models}
+
+	cfs := flag.NewFlagSet(name, flag.ContinueOnError)
+	cfs.SetOutput(io.Discard)
+	sort := ""
+	if name == "find" {
+		cfs.IntVar(&e.limit, "limit", 0, "maximum number of documents")
+		cfs.StringVar(&sort, "sort", "", "comma separated sort fields")
+	}
+	if err := cfs.Parse(fs.Args()[1:]); err != nil {
+		return fmt.Errorf("usage: mongoctl %v", cmd.usage)
+	}
+	if sort != "" {
+		e.sort = strings.Split(sort, ",")
+	}
+
+	rest := cfs.Args()
+	if len(rest) < cmd.minArgs || len(rest) > cmd.maxArgs {
+		return fmt.Errorf("usage: mongoctl %v", cmd.usage)
+	}
+
+	if err := mongo.SetConfig(mongo.Config{Servers: *url, Database: *db, FailFast: true}); err != nil {
+		return err
+	}
+
+	return cmd.run(e, rest)
+}
+
+func count(e *env, args []string) error {
+	q, err := optionalDoc(args, 1)
+	if err != nil {
+		return err
+	}
+
+	n, err := mongo.CountDocs(args[0], q)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(e.stdout, n)
+	return err
+}
+
+func find(e *env, args []string) error {
+	q, err := optionalDoc(args, 1)
+	if err != nil {
+		return err
+	}
+
+	docs, err := mongo.FindDocs(args[0], q, e.limit, e.sort...)
+	if err != nil {
+		return err
+	}
+
+	return writeDocs(e.stdout, docs)
+}
+
+func insert(e *env, args []string) error {
+	if len(args) == 1 {
+		return importDocs(e, args)
+	}
+
+	doc, err := parseDoc(args[1])
+	if err != nil {
+		return err
+	}
+
+	if err := mongo.InsertDocs(args[0], doc); err != nil {
+		return err
+	}
+
+	return writeDocs(e.stdout, []bson.M{doc})
+}
+
+func update(e *env, args []string) error {
+	q, err := parseDoc(args[1])
+	if err != nil {
+		return err
+	}
+
+	u, err := parseDoc(args[2])
+	if err != nil {
+		return err
+	}
+
+	n, err := mongo.UpdateDocs(args[0], q, u)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(e.stdout, "%v updated\n", n)
+	return err
+}
+
+func remove(e *env, args []string) error {
+	q, err := parseDoc(args[1])
+	if err != nil {
+		return err
+	}
+
+	n, err := mongo.DeleteDocs(args[0], q)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(e.stdout, "%v deleted\n", n)
+	return err
+}
+
+// export streams the documents, so collections of any size can be
+// exported.
+func export(e *env, args []string) error {
+	q, err := optionalDoc(args, 1)
+	if err != nil {
+		return err
+	}
+
+	c := mongo.IterDocs(args[0], q)
+
+	doc := bson.M{}
+	for c.Next(&doc) {
+		if err := writeDoc(e.stdout, doc); err != nil {
+			c.Close()
+			return err
+		}
+		doc = bson.M{}
+	}
+
+	return c.Close()
+}
+
+func importDocs(e *env, args []string) error {
+	scanner := bufio.NewScanner(e.stdin)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	var batch []bson.M
+	total := 0
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := mongo.InsertDocs(args[0], batch...); err != nil {
+			return err
+		}
+		total += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		doc, err := parseDoc(text)
+		if err != nil {
+			return fmt.Errorf("line %v: %v", line, err)
+		}
+
+		batch = append(batch, doc)
+		if len(batch) == importBatch {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if err := flush(); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(e.stdout, "%v inserted\n", total)
+	return err
+}
+
+func ensureIndexes(e *env, args []string) error {
+	models := e.models
+	if len(models) == 0 {
+		models = mongo.Models()
+	}
+
+	if len(models) == 0 {
+		return errors.New("No models registered. Build mongoctl with your models, see the ctl package docs.")
+	}
+
+	if err := mongo.EnsureIndexes(models...); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(e.stdout, "Indexes ensured for %v models\n", len(models))
+	return err
+}
+
+func parseDoc(s string) (bson.M, error) {
+	doc := bson.M{}
+	if err := bson.UnmarshalJSON([]byte(s), &doc); err != nil {
+		return nil, fmt.Errorf("Invalid JSON %q: %v", s, err)
+	}
+	return doc, nil
+}
+
+// optionalDoc parses args[n] if it's there. A missing document matches
+// everything.
+func optionalDoc(args []string, n int) (bson.M, error) {
+	if len(args) <= n {
+		return bson.M{}, nil
+	}
+	return parseDoc(args[n])
+}
+
+func writeDocs(w io.Writer, docs []bson.M) error {
+	for _, doc := range docs {
+		if err := writeDoc(w, doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeDoc(w io.Writer, doc bson.M) error {
+	data, err := bson.MarshalJSON(doc)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
+
+func envOr(name, def string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return def
+}
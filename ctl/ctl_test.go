@@ -0,0 +1,27 @@
+package ctl
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRunUsage(t *testing.T) {
+	cases := map[string]string{
+		"":                      "usage: mongoctl [-url",
+		"frobnicate":            "Unknown command",
+		"count":                 "usage: mongoctl count",
+		"delete users":          "usage: mongoctl delete",
+		"update users {} {} {}": "usage: mongoctl update",
+		"find -limit x users":   "usage: mongoctl find",
+		"ensure-indexes extra":  "usage: mongoctl ensure-indexes",
+		"-nope find users":      "usage: mongoctl [-url",
+	}
+
+	for args, expected := range cases {
+		err := Run(strings.Fields(args), nil, &bytes.Buffer{})
+		if err == nil || !strings.HasPrefix(err.Error(), expected) {
+			t.Errorf("%q: expected an error starting with %q, got: %v", args, expected, err)
+		}
+	}
+}
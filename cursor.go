@@ -0,0 +1,79 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+)
+
+// Cursor iterates over results one document at a time. It holds its own
+// session so Close must always be called:
+//
+//	c := mongo.AggregateIter(&Order{}, p, mongo.AggregateOptions{AllowDiskUse: true})
+//	defer c.Close()
+//	var row Summary
+//	for c.Next(&row) {
+//		...
+//	}
+//	if err := c.Err(); err != nil {
+//		...
+//	}
+type Cursor struct {
+	op *operation
+	// client is set when the documents are stored records, which migrations
+	// may write back.
+	client  *Client
+	session *mgo.Session
+	iter    *mgo.Iter
+	err     error
+}
+
+// Decode the next document into result, which must be a pointer. Returns
+// false when there are no more documents or an error occurred.
+func (c *Cursor) Next(result interface{}) bool {
+	if c.err != nil || c.iter == nil {
+		return false
+	}
+
+	var raw bson.Raw
+	if !c.iter.Next(&raw) {
+		return false
+	}
+
+	if err := unmarshalRecord(c.client, c.session, raw, result); err != nil {
+		c.err = c.op.done(err)
+		return false
+	}
+	return true
+}
+
+// Err returns the first error encountered by the cursor.
+func (c *Cursor) Err() error {
+	if c.err != nil {
+		return c.err
+	}
+
+	if c.iter != nil {
+		return c.op.done(c.iter.Err())
+	}
+	return nil
+}
+
+// Close the cursor and its session. It returns the same error as Err.
+func (c *Cursor) Close() error {
+	if c.iter != nil {
+		if err := c.iter.Close(); err != nil && c.err == nil {
+			c.err = c.op.done(err)
+		}
+		c.iter = nil
+	}
+
+	if c.session != nil {
+		c.session.Close()
+		c.session = nil
+	}
+
+	if c.err == nil {
+		c.op.done(nil)
+	}
+	return c.err
+}
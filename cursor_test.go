@@ -0,0 +1,51 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"errors"
+	"testing"
+)
+
+func TestCursorError(t *testing.T) {
+	SetAccessPolicy("MongoTest", AllowInsert)
+	defer ClearAccessPolicy("MongoTest")
+
+	m := &recordingMonitor{}
+	SetCommandMonitor(m)
+	defer SetCommandMonitor(nil)
+
+	c := AggregateIter(&MongoTest{}, Pipeline{}.Match(bson.M{"name": "x"}), AggregateOptions{})
+
+	var row MongoTest
+	if c.Next(&row) {
+		t.Fatal("Expected no results from a failed cursor")
+	}
+	if err := c.Err(); !errors.Is(err, ErrAccessDenied) {
+		t.Fatal("Expected ErrAccessDenied, got:", err)
+	}
+	if err := c.Close(); !errors.Is(err, ErrAccessDenied) {
+		t.Fatal("Expected Close to return the same error, got:", err)
+	}
+	if err := c.Close(); !errors.Is(err, ErrAccessDenied) {
+		t.Fatal("Expected closing twice to return the same error, got:", err)
+	}
+
+	if len(m.started) != 1 || len(m.failed) != 1 || len(m.succeeded) != 0 {
+		t.Fatalf("Expected a single failed operation got: %+v", m)
+	}
+}
+
+func TestIterDocsError(t *testing.T) {
+	SetAccessPolicy("events", AllowInsert)
+	defer ClearAccessPolicy("events")
+
+	c := IterDocs("events", nil)
+	doc := bson.M{}
+	if c.Next(&doc) {
+		t.Fatal("Expected no results from a failed cursor")
+	}
+	if err := c.Close(); !errors.Is(err, ErrAccessDenied) {
+		t.Fatal("Expected ErrAccessDenied, got:", err)
+	}
+}
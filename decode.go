@@ -0,0 +1,155 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DecodeMode controls what happens when a stored document has fields the
+// struct it's decoded into doesn't. Only top level fields are checked.
+type DecodeMode int
+
+const (
+	// DecodeLenient ignores unknown fields. This is the default.
+	DecodeLenient DecodeMode = iota
+	// DecodeReport decodes as usual and passes unknown fields to the
+	// handler set with SetUnknownFieldsHandler, which logs them by default.
+	DecodeReport
+	// DecodeStrict fails decoding with an *UnknownFieldsError. Use it in
+	// development to catch schema drift and mistyped bson tags.
+	DecodeStrict
+)
+
+var decodeMode int32 = int32(DecodeLenient)
+
+var (
+	unknownMu      sync.RWMutex
+	unknownHandler = func(err *UnknownFieldsError) { log.Println("mongo:", err) }
+)
+
+// UnknownFieldsError lists the fields of a stored document that the struct
+// it was decoded into doesn't have.
+type UnknownFieldsError struct {
+	Type   string
+	Fields []string
+}
+
+func (e *UnknownFieldsError) Error() string {
+	return fmt.Sprintf("%v has no fields for %v", e.Type, strings.Join(e.Fields, ", "))
+}
+
+// Set how unknown fields are handled when records are decoded.
+func SetDecodeMode(m DecodeMode) {
+	atomic.StoreInt32(&decodeMode, int32(m))
+}
+
+// Set the function DecodeReport passes unknown fields to. nil restores the
+// default, which logs them.
+func SetUnknownFieldsHandler(fn func(err *UnknownFieldsError)) {
+	if fn == nil {
+		fn = func(err *UnknownFieldsError) { log.Println("mongo:", err) }
+	}
+
+	unknownMu.Lock()
+	unknownHandler = fn
+	unknownMu.Unlock()
+}
+
+// checkUnknownFields applies the decode mode to raw being decoded into i.
+// Records that decode themselves are skipped.
+func checkUnknownFields(raw bson.Raw, i interface{}) error {
+	mode := DecodeMode(atomic.LoadInt32(&decodeMode))
+	if mode == DecodeLenient {
+		return nil
+	}
+
+	switch i.(type) {
+	case Unmarshaler, bson.Setter:
+		return nil
+	}
+
+	t := structType(i)
+	if t == nil {
+		return nil
+	}
+
+	known, all := knownKeys(t)
+	if all {
+		return nil
+	}
+
+	var doc bson.RawD
+	if err := raw.Unmarshal(&doc); err != nil {
+		return err
+	}
+
+	var unknown []string
+	for _, e := range doc {
+		if e.Name != TypeKey && !known[e.Name] {
+			unknown = append(unknown, e.Name)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+
+	err := &UnknownFieldsError{Type: t.Name(), Fields: unknown}
+	if mode == DecodeStrict {
+		return err
+	}
+
+	unknownMu.RLock()
+	fn := unknownHandler
+	unknownMu.RUnlock()
+
+	fn(err)
+	return nil
+}
+
+// knownKeys returns the document keys of struct type t, following inlined
+// structs. all is true if t inlines a map, which accepts every key.
+func knownKeys(t reflect.Type) (keys map[string]bool, all bool) {
+	keys = map[string]bool{}
+
+	for n := 0; n < t.NumField(); n++ {
+		f := t.Field(n)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+
+		tag := f.Tag.Get("bson")
+		if tag == "-" {
+			continue
+		}
+
+		if strings.Contains(tag, ",inline") {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			switch ft.Kind() {
+			case reflect.Map:
+				return keys, true
+			case reflect.Struct:
+				inner, innerAll := knownKeys(ft)
+				if innerAll {
+					return keys, true
+				}
+				for k := range inner {
+					keys[k] = true
+				}
+			}
+			continue
+		}
+
+		keys[bsonKey(f)] = true
+	}
+
+	return keys, false
+}
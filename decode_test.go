@@ -0,0 +1,42 @@
+package mongo
+
+import (
+	"testing"
+)
+
+type inlineBase struct {
+	Owner string
+}
+
+type decodeModel struct {
+	Id      string     `bson:"_id"`
+	Name    string     `bson:"name,omitempty"`
+	Skipped string     `bson:"-"`
+	Base    inlineBase `bson:",inline"`
+	private string
+}
+
+type decodeExtra struct {
+	Name  string
+	Extra map[string]interface{} `bson:",inline"`
+}
+
+func TestKnownKeys(t *testing.T) {
+	keys, all := knownKeys(structType(&decodeModel{}))
+	if all {
+		t.Fatal("Didn't expect every key to be accepted")
+	}
+
+	for _, k := range []string{"_id", "name", "owner"} {
+		if !keys[k] {
+			t.Fatal("Expected key to be known:", k, keys)
+		}
+	}
+	if keys["skipped"] || keys["private"] || len(keys) != 3 {
+		t.Fatal("Unexpected known keys:", keys)
+	}
+
+	if _, all := knownKeys(structType(&decodeExtra{})); !all {
+		t.Fatal("Expected an inline map to accept every key")
+	}
+}
@@ -0,0 +1,130 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+
+	"errors"
+	"math"
+)
+
+// Count the distinct values of a field across the documents matching q.
+// Field is the document key, e.g. "email" or "address.city".
+func CountDistinct(i interface{}, field string, q bson.M) (int, error) {
+	return std.CountDistinct(i, field, q)
+}
+
+// Count the distinct values of a field using the client. See CountDistinct.
+func (c *Client) CountDistinct(i interface{}, field string, q bson.M) (int, error) {
+	p := distinctPipeline(field, q)
+
+	var res struct {
+		N int `bson:"n"`
+	}
+	if err := c.Aggregate(i, p, &res); err != nil {
+		if errors.Is(err, mgo.ErrNotFound) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	return res.N, nil
+}
+
+// Estimate the number of distinct values of a field from a random sample of
+// sampleSize documents, for dashboards where an exact count over a huge
+// collection is too slow. The estimate uses the Guaranteed-Error Estimator
+// (Charikar et al.), which scales up the values seen only once in the sample.
+// If no more than sampleSize documents match the count is exact.
+func CountDistinctApprox(i interface{}, field string, q bson.M, sampleSize int) (int, error) {
+	return std.CountDistinctApprox(i, field, q, sampleSize)
+}
+
+// Estimate the distinct values of a field using the client. See
+// CountDistinctApprox.
+func (c *Client) CountDistinctApprox(i interface{}, field string, q bson.M, sampleSize int) (int, error) {
+	total, err := c.countWhere(i, q)
+	if err != nil {
+		return 0, err
+	}
+
+	if total <= sampleSize {
+		return c.CountDistinct(i, field, q)
+	}
+
+	var groups []struct {
+		N int `bson:"n"`
+	}
+	if err := c.Aggregate(i, sampleDistinctPipeline(field, q, sampleSize), &groups); err != nil {
+		return 0, err
+	}
+
+	counts := make([]int, len(groups))
+	for n, g := range groups {
+		counts[n] = g.N
+	}
+	return estimateDistinct(total, sampleSize, counts), nil
+}
+
+// distinctPipeline counts the distinct values of field in one document
+// {n: count}, or none if nothing matches.
+func distinctPipeline(field string, q bson.M) Pipeline {
+	p := Pipeline{}
+	if len(q) > 0 {
+		p = p.Match(q)
+	}
+	return p.Group(bson.M{"_id": "$" + field}).Stage("$count", "n")
+}
+
+// sampleDistinctPipeline counts how often each value of field occurs in a
+// random sample of size documents.
+func sampleDistinctPipeline(field string, q bson.M, size int) Pipeline {
+	p := Pipeline{}
+	if len(q) > 0 {
+		p = p.Match(q)
+	}
+	return p.Stage("$sample", bson.M{"size": size}).
+		Group(bson.M{"_id": "$" + field, "n": bson.M{"$sum": 1}})
+}
+
+// estimateDistinct applies the Guaranteed-Error Estimator to the number of
+// times each distinct value occurred in a sample of sampleSize out of total
+// documents.
+func estimateDistinct(total, sampleSize int, counts []int) int {
+	singles := 0
+	for _, n := range counts {
+		if n == 1 {
+			singles++
+		}
+	}
+
+	est := math.Sqrt(float64(total)/float64(sampleSize))*float64(singles) + float64(len(counts)-singles)
+	if est > float64(total) {
+		est = float64(total)
+	}
+
+	return int(est + 0.5)
+}
+
+// countWhere counts the documents for i matching q.
+func (c *Client) countWhere(i interface{}, q bson.M) (int, error) {
+	q = scopeQuery(i, q)
+	op := c.startOp("count", collName(i), q)
+
+	if err := op.allowed(); err != nil {
+		return 0, op.done(err)
+	}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return 0, op.done(err)
+	}
+	defer s.Close()
+
+	if q, err = op.check(c, s, q, true); err != nil {
+		return 0, op.done(err)
+	}
+
+	n, err := c.GetColl(s, op.coll).Find(q).Count()
+	return n, op.done(err)
+}
@@ -0,0 +1,56 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"reflect"
+	"testing"
+)
+
+func TestDistinctPipeline(t *testing.T) {
+	p := distinctPipeline("address.city", bson.M{"active": true})
+
+	want := Pipeline{
+		{"$match": bson.M{"active": true}},
+		{"$group": bson.M{"_id": "$address.city"}},
+		{"$count": "n"},
+	}
+	if !reflect.DeepEqual(p, want) {
+		t.Fatal("Unexpected pipeline:", p)
+	}
+
+	if p := distinctPipeline("email", nil); len(p) != 2 {
+		t.Fatal("Expected no $match stage without a query got:", p)
+	}
+}
+
+func TestSampleDistinctPipeline(t *testing.T) {
+	p := sampleDistinctPipeline("email", nil, 500)
+
+	if len(p) != 2 || !reflect.DeepEqual(p[0]["$sample"], bson.M{"size": 500}) {
+		t.Fatal("Expected a $sample stage first got:", p)
+	}
+	if !reflect.DeepEqual(p[1]["$group"], bson.M{"_id": "$email", "n": bson.M{"$sum": 1}}) {
+		t.Fatal("Unexpected $group stage:", p[1])
+	}
+}
+
+func TestEstimateDistinct(t *testing.T) {
+	// Values repeated in the sample are counted once.
+	if n := estimateDistinct(1000, 100, []int{50, 50}); n != 2 {
+		t.Fatal("Expected 2 got:", n)
+	}
+
+	// Values seen once are scaled by sqrt(total/sample).
+	if n := estimateDistinct(400, 100, []int{1, 1, 1, 5}); n != 7 {
+		t.Fatal("Expected 7 got:", n)
+	}
+
+	singles := make([]int, 100)
+	for n := range singles {
+		singles[n] = 1
+	}
+	if n := estimateDistinct(10000, 100, singles); n != 1000 {
+		t.Fatal("Expected 1000 got:", n)
+	}
+}
@@ -0,0 +1,222 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"reflect"
+)
+
+// DefaultEachBatch is the number of changed records UpdateEach writes at a
+// time.
+const DefaultEachBatch = 500
+
+// UpdateEachOptions configures UpdateEachWith.
+type UpdateEachOptions struct {
+	// BatchSize is the number of changed records written at a time.
+	// Defaults to DefaultEachBatch.
+	BatchSize int
+	// Progress, if set, is called after each batch is written and at the
+	// end with the number of records seen and changed so far.
+	Progress func(seen, changed int)
+}
+
+// Apply fn to every record matching q and write back the ones it changed.
+// See UpdateEachWith.
+func UpdateEach(i interface{}, q bson.M, fn func(rec interface{}) error) (changed int, err error) {
+	return std.UpdateEach(i, q, fn)
+}
+
+// Apply fn to records using the client. See UpdateEach.
+func (c *Client) UpdateEach(i interface{}, q bson.M, fn func(rec interface{}) error) (changed int, err error) {
+	return c.UpdateEachWith(i, q, fn, UpdateEachOptions{})
+}
+
+// Apply fn to every record matching q and write back the ones it changed,
+// in bulk. It's a "map over the collection" for data fixes:
+//
+//	n, err := mongo.UpdateEachWith(&User{}, bson.M{"country": "UK"}, func(rec interface{}) error {
+//		u := rec.(*User)
+//		u.Country = "GB"
+//		return nil
+//	}, mongo.UpdateEachOptions{Progress: func(seen, changed int) { log.Println(seen, changed) }})
+//
+// i is only used for its type; fn gets a pointer to a new record of that
+// type for each document. Records are validated and get a new UpdatedAt like
+// Update. Records fn leaves unchanged aren't written. If fn returns an error
+// iteration stops and the error is returned; batches already written stay
+// written.
+func UpdateEachWith(i interface{}, q bson.M, fn func(rec interface{}) error, opts UpdateEachOptions) (changed int, err error) {
+	return std.UpdateEachWith(i, q, fn, opts)
+}
+
+// Apply fn to records with options using the client. See UpdateEachWith.
+func (c *Client) UpdateEachWith(i interface{}, q bson.M, fn func(rec interface{}) error, opts UpdateEachOptions) (changed int, err error) {
+	t := structType(i)
+	if t == nil {
+		return 0, NoPtr
+	}
+
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultEachBatch
+	}
+
+	q = scopeQuery(i, q)
+	op := c.startOp("update", collName(i), q)
+
+	if err := op.allowed(); err != nil {
+		return 0, op.done(err)
+	}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return 0, op.done(err)
+	}
+	defer s.Close()
+
+	if q, err = op.check(c, s, q, false); err != nil {
+		return changed, op.done(err)
+	}
+
+	coll := c.GetColl(s, op.coll)
+	// Records are visited in _id order so writing one back can't move it
+	// ahead of the cursor and have it returned again.
+	iter := coll.Find(q).Sort("_id").Iter()
+
+	var pairs []interface{}
+	var writes []written
+	seen, reported := 0, -1
+	model := reflect.New(t).Interface()
+
+	flush := func() error {
+		if len(pairs) == 0 {
+			return nil
+		}
+
+		b := coll.Bulk()
+		b.Unordered()
+		b.Update(pairs...)
+		if err := runBulk(b); err != nil {
+			// Some of the batch may have been written.
+			c.afterCollectionWrite(s, op.coll, nil)
+			return err
+		}
+
+		for _, w := range writes {
+			if err := c.afterWrite(s, model, w); err != nil {
+				return err
+			}
+		}
+
+		changed += len(pairs) / 2
+		pairs, writes = pairs[:0], writes[:0]
+
+		if opts.Progress != nil {
+			opts.Progress(seen, changed)
+			reported = seen
+		}
+		return nil
+	}
+
+	var raw bson.Raw
+	for iter.Next(&raw) {
+		seen++
+
+		rec := reflect.New(t).Interface()
+		if err := unmarshalRecord(c, s, raw, rec); err != nil {
+			iter.Close()
+			return changed, op.done(err)
+		}
+
+		before, err := Hash(rec)
+		if err != nil {
+			iter.Close()
+			return changed, op.done(err)
+		}
+
+		if err := fn(rec); err != nil {
+			iter.Close()
+			return changed, op.done(err)
+		}
+
+		after, err := Hash(rec)
+		if err != nil {
+			iter.Close()
+			return changed, op.done(err)
+		}
+
+		if before == after {
+			continue
+		}
+
+		doc, err := eachDoc(rec, raw)
+		if err != nil {
+			iter.Close()
+			return changed, op.done(err)
+		}
+
+		id, err := getObjIdFromStruct(rec)
+		if err != nil {
+			iter.Close()
+			return changed, op.done(err)
+		}
+
+		var stored bson.M
+		if counted(model) {
+			if err := raw.Unmarshal(&stored); err != nil {
+				iter.Close()
+				return changed, op.done(err)
+			}
+		}
+
+		pairs = append(pairs, bson.M{"_id": id}, doc)
+		writes = append(writes, written{id: id, before: stored, after: doc})
+		if len(pairs)/2 >= batchSize {
+			if err := flush(); err != nil {
+				iter.Close()
+				return changed, op.done(err)
+			}
+		}
+	}
+
+	if err := iter.Close(); err != nil {
+		return changed, op.done(err)
+	}
+
+	if err := flush(); err != nil {
+		return changed, op.done(err)
+	}
+
+	if opts.Progress != nil && reported != seen {
+		opts.Progress(seen, changed)
+	}
+
+	return changed, op.done(nil)
+}
+
+// eachDoc prepares a changed record for writing the way Update does. raw is
+// the record as it was read.
+func eachDoc(rec interface{}, raw bson.Raw) (interface{}, error) {
+	if err := normalize(rec); err != nil {
+		return nil, err
+	}
+
+	if err := validate(rec); err != nil {
+		return nil, err
+	}
+
+	if err := addCurrentDateTime(rec, "UpdatedAt"); err != nil {
+		return nil, err
+	}
+
+	readonly, immutable := protectedKeys(rec)
+	if _, unloaded := lazyKeys(rec); len(readonly) == 0 && len(immutable) == 0 && len(unloaded) == 0 {
+		return marshalRecord(rec)
+	}
+
+	stored := bson.M{}
+	if err := raw.Unmarshal(&stored); err != nil {
+		return nil, err
+	}
+	return protectedDoc(rec, stored)
+}
@@ -0,0 +1,12 @@
+package mongo
+
+import (
+	"testing"
+)
+
+func TestUpdateEachNeedsStruct(t *testing.T) {
+	_, err := UpdateEach("users", nil, func(rec interface{}) error { return nil })
+	if err != NoPtr {
+		t.Fatal("Expected NoPtr, got:", err)
+	}
+}
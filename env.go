@@ -0,0 +1,90 @@
+package mongo
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Environment variables read by ConfigFromEnv.
+const (
+	EnvURL      = "MONGO_URL"       // required, see Config.Servers
+	EnvDatabase = "MONGO_DB"        // required
+	EnvTLS      = "MONGO_TLS"       // optional bool
+	EnvTimeout  = "MONGO_TIMEOUT"   // optional duration, e.g. 5s
+	EnvFailFast = "MONGO_FAIL_FAST" // optional bool
+)
+
+// EnvError lists every environment variable ConfigFromEnv couldn't use.
+type EnvError struct {
+	Missing []string
+	Invalid []string
+}
+
+func (e *EnvError) Error() string {
+	var parts []string
+	if len(e.Missing) > 0 {
+		parts = append(parts, "missing environment variables: "+strings.Join(e.Missing, ", "))
+	}
+	if len(e.Invalid) > 0 {
+		parts = append(parts, "invalid environment variables: "+strings.Join(e.Invalid, ", "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Returns the Config described by the MONGO_* environment variables. All
+// problems are reported at once in an *EnvError:
+//
+//	cfg, err := mongo.ConfigFromEnv()
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	err = mongo.SetConfig(cfg)
+func ConfigFromEnv() (Config, error) {
+	return configFromLookup(os.LookupEnv)
+}
+
+func configFromLookup(lookup func(string) (string, bool)) (Config, error) {
+	var cfg Config
+	e := &EnvError{}
+
+	get := func(name string, required bool) string {
+		v, ok := lookup(name)
+		v = strings.TrimSpace(v)
+		if required && (!ok || v == "") {
+			e.Missing = append(e.Missing, name)
+		}
+		return v
+	}
+
+	getBool := func(name string) bool {
+		v := get(name, false)
+		if v == "" {
+			return false
+		}
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			e.Invalid = append(e.Invalid, name+" (expected true or false)")
+		}
+		return b
+	}
+
+	cfg.Servers = get(EnvURL, true)
+	cfg.Database = get(EnvDatabase, true)
+	cfg.TLS = getBool(EnvTLS)
+	cfg.FailFast = getBool(EnvFailFast)
+
+	if v := get(EnvTimeout, false); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			e.Invalid = append(e.Invalid, EnvTimeout+" (expected a positive duration such as 5s)")
+		}
+		cfg.Timeout = d
+	}
+
+	if len(e.Missing) > 0 || len(e.Invalid) > 0 {
+		return Config{}, e
+	}
+	return cfg, nil
+}
@@ -0,0 +1,47 @@
+package mongo
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func lookupMap(env map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	cfg, err := configFromLookup(lookupMap(map[string]string{
+		EnvURL:      "mongodb://db1,db2/?replicaSet=rs0",
+		EnvDatabase: "app",
+		EnvTLS:      "true",
+		EnvTimeout:  "5s",
+	}))
+	if err != nil {
+		t.Fatal("Couldn't read config:", err)
+	}
+
+	if cfg.Servers != "mongodb://db1,db2/?replicaSet=rs0" || cfg.Database != "app" || !cfg.TLS || cfg.Timeout != 5*time.Second || cfg.FailFast {
+		t.Fatalf("Unexpected config: %+v", cfg)
+	}
+}
+
+func TestConfigFromEnvErrors(t *testing.T) {
+	_, err := configFromLookup(lookupMap(map[string]string{
+		EnvDatabase: " ",
+		EnvTLS:      "yes please",
+		EnvTimeout:  "soon",
+	}))
+
+	var envErr *EnvError
+	if !errors.As(err, &envErr) {
+		t.Fatal("Expected an EnvError, got:", err)
+	}
+
+	if len(envErr.Missing) != 2 || len(envErr.Invalid) != 2 {
+		t.Fatalf("Expected every problem to be reported: %+v", envErr)
+	}
+}
@@ -0,0 +1,109 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"fmt"
+	"time"
+)
+
+// OpError is returned by operations that fail once they've started talking to
+// the database. It records the operation, the collection and a sanitized copy
+// of the query so logs show what failed and not just "not found". Use
+// errors.Is or errors.As to get at the underlying mgo error.
+type OpError struct {
+	Op         string
+	Collection string
+	Query      string
+	Duration   time.Duration
+	Err        error
+}
+
+func (e *OpError) Error() string {
+	msg := "mongo: " + e.Op + " " + e.Collection
+	if e.Query != "" {
+		msg += " " + e.Query
+	}
+	return fmt.Sprintf("%s (%v): %v", msg, e.Duration, e.Err)
+}
+
+func (e *OpError) Unwrap() error {
+	return e.Err
+}
+
+// operation tracks a single call against a collection so that any error it
+// returns can be wrapped with context. Every operation must finish by
+// calling done, with a nil error on success.
+type operation struct {
+	// client ran the operation; nil for the package's own bookkeeping,
+	// which uses the default client.
+	client   *Client
+	id       int64
+	op       string
+	coll     string
+	query    bson.M
+	start    time.Time
+	finished bool
+}
+
+func startOp(op, coll string, q bson.M) *operation {
+	o := &operation{id: nextRequestId(), op: op, coll: coll, query: q, start: time.Now()}
+
+	if m := currentMonitor(); m != nil {
+		m.Started(CommandStartedEvent{
+			RequestId:  o.id,
+			Op:         op,
+			Collection: coll,
+			Query:      SanitizeQuery(q),
+			Time:       o.start,
+		})
+	}
+
+	return o
+}
+
+// startOp starts an operation run by the client.
+func (c *Client) startOp(op, coll string, q bson.M) *operation {
+	o := startOp(op, coll, q)
+	o.client = c
+	return o
+}
+
+// done wraps err in an *OpError. A nil err is returned as is. Only the first
+// call reports the outcome to the CommandMonitor.
+func (o *operation) done(err error) error {
+	d := time.Since(o.start)
+
+	if !o.finished {
+		o.finished = true
+		recordShape(o.op, o.coll, o.query)
+		o.report(d, err)
+		o.sample(d, err)
+		o.logSlow(d)
+	}
+
+	if err == nil {
+		return nil
+	}
+
+	return &OpError{
+		Op:         o.op,
+		Collection: o.coll,
+		Query:      SanitizeQuery(o.query),
+		Duration:   d,
+		Err:        err,
+	}
+}
+
+func (o *operation) report(d time.Duration, err error) {
+	m := currentMonitor()
+	if m == nil {
+		return
+	}
+
+	if err != nil {
+		m.Failed(CommandFailedEvent{RequestId: o.id, Op: o.op, Collection: o.coll, Duration: d, Err: err})
+	} else {
+		m.Succeeded(CommandSucceededEvent{RequestId: o.id, Op: o.op, Collection: o.coll, Duration: d})
+	}
+}
@@ -0,0 +1,38 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestOpErrorUnwrap(t *testing.T) {
+	op := startOp("find", "MongoTest", bson.M{"Name": "secret"})
+	err := op.done(mgo.ErrNotFound)
+
+	if !errors.Is(err, mgo.ErrNotFound) {
+		t.Fatal("Expected the OpError to unwrap to mgo.ErrNotFound. Got:", err)
+	}
+
+	var opErr *OpError
+	if !errors.As(err, &opErr) {
+		t.Fatal("Expected an *OpError. Got:", err)
+	}
+
+	if opErr.Collection != "MongoTest" || opErr.Op != "find" {
+		t.Fatal("OpError is missing its context:", opErr)
+	}
+
+	if strings.Contains(err.Error(), "secret") {
+		t.Fatal("Query values leaked into the error message:", err)
+	}
+}
+
+func TestOpErrorNil(t *testing.T) {
+	if err := startOp("find", "MongoTest", nil).done(nil); err != nil {
+		t.Fatal("Expected a nil error. Got:", err)
+	}
+}
@@ -0,0 +1,275 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+
+	"errors"
+	"time"
+)
+
+// EventAppendRetries is how many times Append retries when another writer
+// took the next sequence number of the stream first.
+var EventAppendRetries = 10
+
+// SnapshotEvery is how many events Rebuild applies past the last snapshot of
+// a stream before it stores a new one.
+var SnapshotEvery = 100
+
+// ErrSequenceConflict is returned by EventLog.Append when it kept losing the
+// race for the next sequence number.
+var ErrSequenceConflict = errors.New("Couldn't assign an event sequence number")
+
+// StreamEvent is an entry of an EventLog. Type is the Go type name of the
+// event that was appended and Data its stored form; decode it with Decode.
+type StreamEvent struct {
+	Id        bson.ObjectId `bson:"_id"`
+	Stream    string        `bson:"stream"`
+	Seq       int64         `bson:"seq"`
+	Type      string        `bson:"type"`
+	Data      bson.Raw      `bson:"data"`
+	CreatedAt time.Time     `bson:"createdat"`
+}
+
+// Decode the event's data into i, which must be a pointer.
+func (e *StreamEvent) Decode(i interface{}) error {
+	if !isPtr(i) {
+		return NoPtr
+	}
+	return e.Data.Unmarshal(i)
+}
+
+// EventLog is an append-only log of events kept in a collection and split
+// into streams, e.g. one per aggregate. The events of a stream are numbered
+// from one without gaps:
+//
+//	events := mongo.NewEventLog("events")
+//	seq, err := events.Append("order-42", OrderPlaced{Total: 1999})
+//
+//	log, err := events.Read("order-42", 1)
+//	for _, e := range log {
+//		switch e.Type {
+//		case "OrderPlaced":
+//			var placed OrderPlaced
+//			err = e.Decode(&placed)
+//			...
+//		}
+//	}
+type EventLog struct {
+	client     *Client
+	collection string
+	snapshots  string
+}
+
+// Create an event log kept in the named collection. Snapshots made by
+// Rebuild are kept in the collection with "_snapshots" appended. Call
+// EnsureIndexes before appending, the unique index is what keeps sequence
+// numbers unique.
+func NewEventLog(collection string) *EventLog {
+	return std.NewEventLog(collection)
+}
+
+// Create an event log using the client. See NewEventLog.
+func (c *Client) NewEventLog(collection string) *EventLog {
+	return &EventLog{client: c, collection: collection, snapshots: collection + "_snapshots"}
+}
+
+// Create the unique index on stream and sequence number.
+func (l *EventLog) EnsureIndexes() error {
+	s, err := l.client.GetSession()
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	return l.client.GetColl(s, l.collection).EnsureIndex(mgo.Index{Key: []string{"stream", "seq"}, Unique: true})
+}
+
+// Append event, a struct or a pointer to one, to stream and return its
+// sequence number, one more than the last event of the stream. Concurrent
+// appends to the same stream are retried until each gets its own number.
+func (l *EventLog) Append(stream string, event interface{}) (int64, error) {
+	op := l.client.startOp("insert", l.collection, bson.M{"stream": stream})
+
+	if err := op.allowed(); err != nil {
+		return 0, op.done(err)
+	}
+
+	data, err := bson.Marshal(event)
+	if err != nil {
+		return 0, op.done(err)
+	}
+
+	s, err := l.client.GetSession()
+	if err != nil {
+		return 0, op.done(err)
+	}
+	defer s.Close()
+
+	coll := l.client.GetColl(s, l.collection)
+
+	for try := 0; try <= EventAppendRetries; try++ {
+		last, err := lastSeq(coll, stream)
+		if err != nil {
+			return 0, op.done(err)
+		}
+
+		e := StreamEvent{
+			Id:        newObjectId(),
+			Stream:    stream,
+			Seq:       last + 1,
+			Type:      typeName(event),
+			Data:      bson.Raw{Kind: 0x03, Data: data},
+			CreatedAt: now(),
+		}
+
+		err = coll.Insert(e)
+		if err == nil {
+			return e.Seq, op.done(nil)
+		}
+		if !mgo.IsDup(err) {
+			return 0, op.done(err)
+		}
+	}
+
+	return 0, op.done(ErrSequenceConflict)
+}
+
+// Returns the events of stream from sequence number fromSeq on, in order.
+func (l *EventLog) Read(stream string, fromSeq int64) ([]StreamEvent, error) {
+	q := bson.M{"stream": stream, "seq": bson.M{"$gte": fromSeq}}
+	op := l.client.startOp("find", l.collection, q)
+
+	if err := op.allowed(); err != nil {
+		return nil, op.done(err)
+	}
+
+	s, err := l.client.GetSession()
+	if err != nil {
+		return nil, op.done(err)
+	}
+	defer s.Close()
+
+	var events []StreamEvent
+	if err := l.client.GetColl(s, l.collection).Find(q).Sort("seq").All(&events); err != nil {
+		return nil, op.done(err)
+	}
+	return events, op.done(nil)
+}
+
+// Snapshot is the state of a stream after the event numbered Seq, stored by
+// Rebuild.
+type Snapshot struct {
+	Stream    string    `bson:"_id"`
+	Seq       int64     `bson:"seq"`
+	State     bson.Raw  `bson:"state"`
+	CreatedAt time.Time `bson:"createdat"`
+}
+
+// Rebuild the state of stream, e.g. an aggregate, by passing each of its
+// events in order to apply. state must be a pointer, usually to a zero
+// struct. Rather than replaying the whole stream it starts from the last
+// snapshot, and stores a new one once SnapshotEvery events were applied past
+// it. Returns the sequence number of the last event applied, which is zero
+// for an empty stream:
+//
+//	var order Order
+//	version, err := events.Rebuild("order-42", &order, func(state interface{}, e *mongo.StreamEvent) error {
+//		return state.(*Order).Apply(e)
+//	})
+//
+// Since snapshots are decoded into state, changing the fields of the state
+// type may need the snapshots to be deleted.
+func (l *EventLog) Rebuild(stream string, state interface{}, apply func(state interface{}, e *StreamEvent) error) (int64, error) {
+	if !isPtr(state) {
+		return 0, NoPtr
+	}
+
+	op := l.client.startOp("find", l.snapshots, bson.M{"_id": stream})
+
+	if err := op.allowed(); err != nil {
+		return 0, op.done(err)
+	}
+
+	s, err := l.client.GetSession()
+	if err != nil {
+		return 0, op.done(err)
+	}
+	defer s.Close()
+
+	var snap Snapshot
+	err = l.client.GetColl(s, l.snapshots).FindId(stream).One(&snap)
+	switch {
+	case err == mgo.ErrNotFound:
+	case err != nil:
+		return 0, op.done(err)
+	default:
+		if err := snap.State.Unmarshal(state); err != nil {
+			return 0, op.done(err)
+		}
+	}
+	op.done(nil)
+
+	events, err := l.Read(stream, snap.Seq+1)
+	if err != nil {
+		return 0, err
+	}
+
+	seq := snap.Seq
+	for n := range events {
+		if err := apply(state, &events[n]); err != nil {
+			return seq, err
+		}
+		seq = events[n].Seq
+	}
+
+	if seq-snap.Seq >= int64(SnapshotEvery) {
+		if err := l.saveSnapshot(stream, seq, state); err != nil {
+			return seq, err
+		}
+	}
+
+	return seq, nil
+}
+
+// saveSnapshot stores state as the snapshot of stream after the event
+// numbered seq, unless a later snapshot was stored meanwhile.
+func (l *EventLog) saveSnapshot(stream string, seq int64, state interface{}) error {
+	op := l.client.startOp("update", l.snapshots, bson.M{"_id": stream})
+
+	if err := op.allowed(); err != nil {
+		return op.done(err)
+	}
+
+	data, err := bson.Marshal(state)
+	if err != nil {
+		return op.done(err)
+	}
+
+	s, err := l.client.GetSession()
+	if err != nil {
+		return op.done(err)
+	}
+	defer s.Close()
+
+	snap := Snapshot{Stream: stream, Seq: seq, State: bson.Raw{Kind: 0x03, Data: data}, CreatedAt: now()}
+
+	// A concurrent Rebuild may have stored a later snapshot, which the
+	// upsert mustn't replace; it then fails on the duplicate id instead.
+	_, err = l.client.GetColl(s, l.snapshots).Upsert(bson.M{"_id": stream, "seq": bson.M{"$lt": seq}}, snap)
+	if mgo.IsDup(err) {
+		err = nil
+	}
+	return op.done(err)
+}
+
+// lastSeq returns the sequence number of the last event of stream, or zero
+// if it has none.
+func lastSeq(coll *mgo.Collection, stream string) (int64, error) {
+	var last StreamEvent
+	err := coll.Find(bson.M{"stream": stream}).Sort("-seq").Select(bson.M{"seq": 1}).One(&last)
+	if err == mgo.ErrNotFound {
+		return 0, nil
+	}
+	return last.Seq, err
+}
@@ -0,0 +1,39 @@
+package mongo
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestEventLogAccessPolicy(t *testing.T) {
+	SetAccessPolicy("events", AllowFind)
+	defer ClearAccessPolicy("events")
+
+	l := NewEventLog("events")
+
+	if _, err := l.Append("order-42", struct{ Total int }{1999}); !errors.Is(err, ErrAccessDenied) {
+		t.Fatal("Expected ErrAccessDenied got:", err)
+	}
+}
+
+func TestStreamEventDecodeNoPtr(t *testing.T) {
+	var e StreamEvent
+	if err := e.Decode(struct{}{}); err != NoPtr {
+		t.Fatal("Expected NoPtr got:", err)
+	}
+}
+
+func TestRebuildAccessPolicy(t *testing.T) {
+	SetAccessPolicy("events_snapshots", AllowInsert)
+	defer ClearAccessPolicy("events_snapshots")
+
+	var state struct{ Total int }
+	apply := func(state interface{}, e *StreamEvent) error { return nil }
+
+	if _, err := NewEventLog("events").Rebuild("order-42", &state, apply); !errors.Is(err, ErrAccessDenied) {
+		t.Fatal("Expected ErrAccessDenied got:", err)
+	}
+	if _, err := NewEventLog("events").Rebuild("order-42", state, apply); err != NoPtr {
+		t.Fatal("Expected NoPtr got:", err)
+	}
+}
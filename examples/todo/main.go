@@ -0,0 +1,154 @@
+/*
+Command todo is an example todo list API built on the mongo package. It
+shows how the pieces fit together: a model with normalize tags, an
+immutable field, validation and declared indexes, the rest package serving
+it with pagination, the admin package for browsing and runtime options, and
+counters kept up to date on every write.
+
+	MONGO_URL=localhost MONGO_DB=todo go run ./examples/todo
+
+Then, for example:
+
+	curl -d '{"list": "Groceries", "title": "Milk"}' localhost:8080/api/Todo
+	curl 'localhost:8080/api/Todo?list=groceries&sort=-createdat&perPage=10'
+	curl localhost:8080/lists/groceries
+	curl -X PUT -d true localhost:8080/admin/db/options/readonly
+
+The scenario tests next to it double as documentation of how each of those
+behaves.
+*/
+package main
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/sfreiberg/mongo"
+	"github.com/sfreiberg/mongo/admin"
+	"github.com/sfreiberg/mongo/rest"
+
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// MaxTitle is the longest title a todo may have.
+const MaxTitle = 200
+
+// Todo is an item on a list. Lists are identified by their lowercased
+// name and a todo can't move between them.
+type Todo struct {
+	Id        bson.ObjectId `bson:"_id" json:"id"`
+	List      string        `bson:"list" json:"list" normalize:"trim,lower" mongo:"immutable"`
+	Title     string        `bson:"title" json:"title" normalize:"trim,squash"`
+	Done      bool          `bson:"done" json:"done"`
+	CreatedAt time.Time     `bson:"createdat" json:"createdAt"`
+	UpdatedAt time.Time     `bson:"updatedat" json:"updatedAt"`
+}
+
+// Validate runs after the normalize tags, so whitespace only titles are
+// already empty.
+func (t *Todo) Validate() error {
+	switch {
+	case t.List == "":
+		return errors.New("list is required")
+	case t.Title == "":
+		return errors.New("title is required")
+	case len(t.Title) > MaxTitle:
+		return errors.New("title is too long")
+	}
+	return nil
+}
+
+// Indexes serves the list route, which filters by list and sorts by
+// creation.
+func (t *Todo) Indexes() []mgo.Index {
+	return []mgo.Index{{Key: []string{"list", "-createdat"}}}
+}
+
+// ListSummary is the body of the lists route.
+type ListSummary struct {
+	List string `json:"list"`
+	Open int    `json:"open"`
+	Done int    `json:"done"`
+}
+
+// server holds what the handlers share.
+type server struct {
+	counts *mongo.CountsCache
+}
+
+// newHandler returns the API. It doesn't need a database until a request
+// does.
+func newHandler(counts *mongo.CountsCache) http.Handler {
+	srv := &server{counts: counts}
+
+	mux := http.NewServeMux()
+	rest.Mount(mux, "/api", &Todo{})
+	mux.Handle("/admin/db/", http.StripPrefix("/admin/db", admin.Handler()))
+	mux.HandleFunc("/lists/", srv.summary)
+	return mux
+}
+
+// summary answers how many todos of a list are open and done, from the
+// counters rather than by counting.
+func (srv *server) summary(w http.ResponseWriter, r *http.Request) {
+	list := strings.ToLower(strings.Trim(strings.TrimPrefix(r.URL.Path, "/lists"), "/"))
+	if list == "" || strings.Contains(list, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	sum := ListSummary{List: list}
+	var err error
+	if sum.Open, err = srv.counts.Count(list, false); err == nil {
+		sum.Done, err = srv.counts.Count(list, true)
+	}
+	if err != nil {
+		log.Println("todo:", err)
+		http.Error(w, "Internal error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sum)
+}
+
+func main() {
+	cfg, err := mongo.ConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := mongo.SetConfig(cfg); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := mongo.EnsureIndexes(&Todo{}); err != nil {
+		log.Fatal(err)
+	}
+
+	report, err := mongo.Bootstrap(&Todo{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, d := range report.Drift {
+		log.Printf("todo: drift in %v: %v", d.Collection, d.Detail)
+	}
+
+	stop := make(chan struct{})
+	logErr := func(err error) { log.Println("todo:", err) }
+
+	counts := mongo.NewCountsCache(&Todo{}, "list", "done")
+	go counts.Run(time.Hour, stop, logErr)
+	go mongo.WatchOptions(10*time.Second, stop, logErr)
+
+	addr := os.Getenv("ADDR")
+	if addr == "" {
+		addr = ":8080"
+	}
+	log.Println("todo: listening on", addr)
+	log.Fatal(http.ListenAndServe(addr, newHandler(counts)))
+}
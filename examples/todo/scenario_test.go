@@ -0,0 +1,154 @@
+//go:build integration
+// +build integration
+
+package main
+
+import (
+	"github.com/globalsign/mgo/bson"
+	"github.com/sfreiberg/mongo"
+
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// The scenarios run against the server in MONGO_TEST_URI, like the
+// integration tests of the mongo package, in a database dropped afterwards.
+var testURI = os.Getenv("MONGO_TEST_URI")
+
+func TestMain(m *testing.M) {
+	db := "todo_test_" + strconv.FormatInt(time.Now().UnixNano(), 36)
+	if testURI != "" {
+		if err := mongo.SetConfig(mongo.Config{Servers: testURI, Database: db, FailFast: true}); err != nil {
+			fmt.Fprintln(os.Stderr, "Couldn't connect to", testURI+":", err)
+			os.Exit(1)
+		}
+	}
+
+	code := m.Run()
+
+	if testURI != "" {
+		if s, err := mongo.GetSession(); err == nil {
+			s.DB(db).DropDatabase()
+			s.Close()
+		}
+	}
+	os.Exit(code)
+}
+
+// newScenario returns the API over an empty collection with its indexes
+// and counters in place.
+func newScenario(t *testing.T) http.Handler {
+	t.Helper()
+
+	if testURI == "" {
+		t.Skip("Set MONGO_TEST_URI to run the scenarios")
+	}
+
+	if _, err := mongo.DeleteDocs(mongo.CollectionFor(&Todo{}), bson.M{}); err != nil {
+		t.Fatal("Couldn't empty the collection:", err)
+	}
+	if err := mongo.EnsureIndexes(&Todo{}); err != nil {
+		t.Fatal("Couldn't create the indexes:", err)
+	}
+
+	counts := mongo.NewCountsCache(&Todo{}, "list", "done")
+	t.Cleanup(counts.Close)
+	if err := counts.Reconcile(); err != nil {
+		t.Fatal("Couldn't reconcile the counters:", err)
+	}
+
+	return newHandler(counts)
+}
+
+// decode reads the JSON body of w into v.
+func decode(t *testing.T, w interface{ Result() *http.Response }, v interface{}) {
+	t.Helper()
+
+	if err := json.NewDecoder(w.Result().Body).Decode(v); err != nil {
+		t.Fatal("Couldn't decode the response:", err)
+	}
+}
+
+// A shopping list is filled in, paged through, ticked off and pruned. The
+// list name is normalized, so " Groceries" and "groceries" are the same
+// list, and the summary comes from counters kept on every write.
+func TestShoppingList(t *testing.T) {
+	h := newScenario(t)
+
+	var last Todo
+	for n := 0; n < 12; n++ {
+		w := do(h, "POST", "/api/Todo", fmt.Sprintf(`{"list": " Groceries", "title": "Item   %d"}`, n))
+		if w.Code != http.StatusCreated {
+			t.Fatal("Couldn't create a todo:", w.Code, w.Body)
+		}
+		decode(t, w, &last)
+	}
+	if last.List != "groceries" || last.Title != "Item 11" {
+		t.Fatal("Expected the todo to be normalized, got:", last)
+	}
+
+	var page struct {
+		Items []Todo
+		mongo.Page
+	}
+	w := do(h, "GET", "/api/Todo?list=groceries&sort=-createdat&perPage=5&page=3", "")
+	decode(t, w, &page)
+	if len(page.Items) != 2 || page.Total != 12 || page.Pages != 3 || page.HasNext {
+		t.Fatal("Expected the last 2 of 12 todos, got:", page.Items, page.Page)
+	}
+
+	if w := do(h, "PUT", "/api/Todo/"+last.Id.Hex(), `{"done": true}`); w.Code != http.StatusOK {
+		t.Fatal("Couldn't tick off the todo:", w.Code, w.Body)
+	}
+
+	// The list is immutable, so a todo can't be moved.
+	if w := do(h, "PUT", "/api/Todo/"+last.Id.Hex(), `{"list": "work"}`); w.Code != http.StatusUnprocessableEntity {
+		t.Fatal("Expected moving a todo to be rejected, got:", w.Code)
+	}
+
+	var sum ListSummary
+	decode(t, do(h, "GET", "/lists/Groceries", ""), &sum)
+	if sum.Open != 11 || sum.Done != 1 {
+		t.Fatal("Expected 11 open and 1 done, got:", sum)
+	}
+
+	if w := do(h, "DELETE", "/api/Todo/"+last.Id.Hex(), ""); w.Code != http.StatusNoContent {
+		t.Fatal("Couldn't delete the todo:", w.Code)
+	}
+	if w := do(h, "GET", "/api/Todo/"+last.Id.Hex(), ""); w.Code != http.StatusNotFound {
+		t.Fatal("Expected the deleted todo to be gone, got:", w.Code)
+	}
+
+	decode(t, do(h, "GET", "/lists/groceries", ""), &sum)
+	if sum.Open != 11 || sum.Done != 0 {
+		t.Fatal("Expected 11 open and none done, got:", sum)
+	}
+}
+
+// During maintenance the API is switched to read only from the admin
+// routes: lists can still be read but nothing can be added.
+func TestMaintenance(t *testing.T) {
+	h := newScenario(t)
+
+	if w := do(h, "POST", "/api/Todo", `{"list": "work", "title": "Ship it"}`); w.Code != http.StatusCreated {
+		t.Fatal("Couldn't create a todo:", w.Code, w.Body)
+	}
+
+	do(h, "PUT", "/admin/db/options/readonly", "true")
+	defer mongo.SetReadOnly(false)
+
+	if w := do(h, "POST", "/api/Todo", `{"list": "work", "title": "Hotfix"}`); w.Code != http.StatusServiceUnavailable {
+		t.Fatal("Expected 503 while read only, got:", w.Code)
+	}
+
+	var page struct{ Items []Todo }
+	decode(t, do(h, "GET", "/api/Todo?list=work", ""), &page)
+	if len(page.Items) != 1 {
+		t.Fatal("Expected the list to still be readable, got:", page.Items)
+	}
+}
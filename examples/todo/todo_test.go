@@ -0,0 +1,77 @@
+package main
+
+import (
+	"github.com/sfreiberg/mongo"
+
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// do sends a request to h and returns the recorded response.
+func do(h http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return w
+}
+
+// Todos are normalized before they're validated, so a title of spaces is
+// rejected as missing. Nothing reaches the database.
+func TestInvalidTodo(t *testing.T) {
+	h := newHandler(nil)
+
+	w := do(h, "POST", "/api/Todo", `{"list": "Groceries", "title": "   "}`)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatal("Expected 422, got:", w.Code)
+	}
+
+	var body struct{ Error string }
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil || body.Error != "title is required" {
+		t.Fatal("Expected the validation error, got:", body, err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		todo Todo
+		ok   bool
+	}{
+		{Todo{List: "groceries", Title: "Milk"}, true},
+		{Todo{Title: "Milk"}, false},
+		{Todo{List: "groceries"}, false},
+		{Todo{List: "groceries", Title: strings.Repeat("x", MaxTitle+1)}, false},
+	}
+
+	for _, c := range cases {
+		if err := c.todo.Validate(); (err == nil) != c.ok {
+			t.Fatalf("Expected ok=%v for %+v got: %v", c.ok, c.todo, err)
+		}
+	}
+}
+
+// Read only mode, toggled through the admin routes, turns writes away
+// before they reach the database while reads keep working.
+func TestReadOnlyMode(t *testing.T) {
+	h := newHandler(nil)
+	defer mongo.SetReadOnly(false)
+
+	if w := do(h, "PUT", "/admin/db/options/readonly", "true"); w.Code != http.StatusOK {
+		t.Fatal("Couldn't turn on read only mode:", w.Code, w.Body)
+	}
+
+	if w := do(h, "POST", "/api/Todo", `{"list": "Groceries", "title": "Milk"}`); w.Code != http.StatusServiceUnavailable {
+		t.Fatal("Expected 503, got:", w.Code)
+	}
+}
+
+func TestSummaryNotFound(t *testing.T) {
+	h := newHandler(nil)
+
+	for _, path := range []string{"/lists/", "/lists/a/b"} {
+		if w := do(h, "GET", path, ""); w.Code != http.StatusNotFound {
+			t.Fatal("Expected 404 for", path, "got:", w.Code)
+		}
+	}
+}
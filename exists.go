@@ -0,0 +1,77 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// ErrNoUniqueIndex is returned by ExistsBy when the model doesn't declare a
+// unique index on the field.
+var ErrNoUniqueIndex = errors.New("Field has no declared unique index")
+
+// Returns whether a record like i has value in field, e.g. whether an email
+// is taken. It's meant for hot paths: the model must declare a unique index
+// on the field alone (see Indexer) and the query is covered by it, so only
+// the index is read. Field is the document key.
+func ExistsBy(i interface{}, field string, value interface{}) (bool, error) {
+	return std.ExistsBy(i, field, value)
+}
+
+// Check whether a record has a field value using the client. See ExistsBy.
+func (c *Client) ExistsBy(i interface{}, field string, value interface{}) (bool, error) {
+	if !hasUniqueIndex(i, field) {
+		return false, fmt.Errorf("%w: %v", ErrNoUniqueIndex, field)
+	}
+
+	q := scopeQuery(i, bson.M{field: value})
+	op := c.startOp("find", collName(i), q)
+
+	if err := op.allowed(); err != nil {
+		return false, op.done(err)
+	}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return false, op.done(err)
+	}
+	defer s.Close()
+
+	if q, err = op.check(c, s, q, true); err != nil {
+		return false, op.done(err)
+	}
+
+	// Leaving out _id lets the index cover the query.
+	var doc bson.M
+	err = c.GetColl(s, op.coll).Find(q).Select(bson.M{"_id": 0, field: 1}).One(&doc)
+	if err == mgo.ErrNotFound {
+		return false, op.done(nil)
+	}
+	if err != nil {
+		return false, op.done(err)
+	}
+	return true, op.done(nil)
+}
+
+// hasUniqueIndex returns whether i declares a unique index on field alone.
+func hasUniqueIndex(i interface{}, field string) bool {
+	t := structType(i)
+	if t == nil {
+		return false
+	}
+
+	indexer, ok := reflect.New(t).Interface().(Indexer)
+	if !ok {
+		return false
+	}
+
+	for _, idx := range indexer.Indexes() {
+		if idx.Unique && len(idx.Key) == 1 && (idx.Key[0] == field || idx.Key[0] == "-"+field || idx.Key[0] == "+"+field) {
+			return true
+		}
+	}
+	return false
+}
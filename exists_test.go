@@ -0,0 +1,44 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+
+	"errors"
+	"testing"
+)
+
+type existsModel struct {
+	Id    bson.ObjectId `bson:"_id"`
+	Email string        `bson:"email"`
+	Name  string        `bson:"name"`
+}
+
+func (existsModel) Indexes() []mgo.Index {
+	return []mgo.Index{
+		{Key: []string{"email"}, Unique: true},
+		{Key: []string{"name"}},
+		{Key: []string{"name", "-email"}, Unique: true},
+	}
+}
+
+func TestHasUniqueIndex(t *testing.T) {
+	if !hasUniqueIndex(&existsModel{}, "email") {
+		t.Fatal("Expected a unique index on email")
+	}
+
+	if hasUniqueIndex(&existsModel{}, "name") {
+		t.Fatal("Expected no unique index on name alone")
+	}
+
+	if hasUniqueIndex(&MongoTest{}, "name") {
+		t.Fatal("Expected no unique index on a model without indexes")
+	}
+}
+
+func TestExistsByNoIndex(t *testing.T) {
+	_, err := ExistsBy(&existsModel{}, "name", "George")
+	if !errors.Is(err, ErrNoUniqueIndex) {
+		t.Fatal("Expected ErrNoUniqueIndex got:", err)
+	}
+}
@@ -0,0 +1,126 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+)
+
+// ExportTokenEvery is how many documents an ExportCursor reads between
+// checkpoints.
+var ExportTokenEvery = 1000
+
+// ExportCursor iterates over every record matching a query in _id order so
+// an interrupted export can carry on where it stopped. Write the token
+// returned by Token to the client at each checkpoint:
+//
+//	c := mongo.Export(&Order{}, q, r.URL.Query().Get("resume"))
+//	defer c.Close()
+//	var order Order
+//	for c.Next(&order) {
+//		enc.Encode(order)
+//		if c.Checkpoint() {
+//			enc.Encode(map[string]string{"resume": c.Token()})
+//		}
+//	}
+//
+// A client that gets cut off passes the last token it received to resume.
+// Records written or changed behind the export's position while it runs
+// aren't included.
+type ExportCursor struct {
+	Cursor
+	last interface{}
+	read int
+}
+
+// Export the records like i matching q, resuming after token if it isn't
+// empty. The cursor must be closed.
+func Export(i interface{}, q bson.M, token string) *ExportCursor {
+	return std.Export(i, q, token)
+}
+
+// Export records using the client. See Export.
+func (c *Client) Export(i interface{}, q bson.M, token string) *ExportCursor {
+	q = scopeQuery(i, q)
+
+	if token != "" {
+		_, id, err := DecodeCursor(token)
+		if err != nil {
+			op := c.startOp("find", collName(i), q)
+			return &ExportCursor{Cursor: Cursor{op: op, err: op.done(err)}}
+		}
+
+		after := bson.M{"_id": bson.M{"$gt": id}}
+		if len(q) > 0 {
+			after = bson.M{"$and": []bson.M{q, after}}
+		}
+		q = after
+	}
+
+	op := c.startOp("find", collName(i), q)
+
+	if err := op.allowed(); err != nil {
+		return &ExportCursor{Cursor: Cursor{op: op, err: op.done(err)}}
+	}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return &ExportCursor{Cursor: Cursor{op: op, err: op.done(err)}}
+	}
+
+	if q, err = op.check(c, s, q, false); err != nil {
+		s.Close()
+		return &ExportCursor{Cursor: Cursor{op: op, err: op.done(err)}}
+	}
+
+	iter := c.GetColl(s, op.coll).Find(q).Sort("_id").Iter()
+	return &ExportCursor{Cursor: Cursor{op: op, client: c, session: s, iter: iter}}
+}
+
+// Decode the next record into result, which must be a pointer. Returns
+// false when there are no more records or an error occurred.
+func (c *ExportCursor) Next(result interface{}) bool {
+	if c.err != nil || c.iter == nil {
+		return false
+	}
+
+	var raw bson.Raw
+	if !c.iter.Next(&raw) {
+		return false
+	}
+
+	var doc struct {
+		Id interface{} `bson:"_id"`
+	}
+	if err := raw.Unmarshal(&doc); err != nil {
+		c.err = c.op.done(err)
+		return false
+	}
+
+	if err := unmarshalRecord(c.client, c.session, raw, result); err != nil {
+		c.err = c.op.done(err)
+		return false
+	}
+
+	c.last = doc.Id
+	c.read++
+	return true
+}
+
+// Checkpoint reports whether the record just read completes a batch of
+// ExportTokenEvery records, when a token should be handed out.
+func (c *ExportCursor) Checkpoint() bool {
+	return c.read > 0 && ExportTokenEvery > 0 && c.read%ExportTokenEvery == 0
+}
+
+// Token returns the token resuming the export after the record just read.
+// It's empty before the first record.
+func (c *ExportCursor) Token() string {
+	if c.last == nil {
+		return ""
+	}
+
+	token, err := EncodeCursor(nil, c.last)
+	if err != nil {
+		return ""
+	}
+	return token
+}
@@ -0,0 +1,38 @@
+package mongo
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestExportInvalidToken(t *testing.T) {
+	c := Export(&MongoTest{}, nil, "not a token!")
+	defer c.Close()
+
+	if c.Next(&MongoTest{}) {
+		t.Fatal("Expected no records for an invalid token")
+	}
+	if err := c.Err(); !errors.Is(err, ErrInvalidCursor) {
+		t.Fatal("Expected ErrInvalidCursor got:", err)
+	}
+	if c.Token() != "" {
+		t.Fatal("Expected no token before the first record got:", c.Token())
+	}
+}
+
+func TestExportCheckpoint(t *testing.T) {
+	defer func(n int) { ExportTokenEvery = n }(ExportTokenEvery)
+	ExportTokenEvery = 2
+
+	c := &ExportCursor{}
+	var checkpoints []int
+	for c.read = 1; c.read <= 5; c.read++ {
+		if c.Checkpoint() {
+			checkpoints = append(checkpoints, c.read)
+		}
+	}
+
+	if len(checkpoints) != 2 || checkpoints[0] != 2 || checkpoints[1] != 4 {
+		t.Fatal("Expected checkpoints at 2 and 4 got:", checkpoints)
+	}
+}
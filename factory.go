@@ -0,0 +1,263 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var (
+	fakeFirstNames = []string{"George", "Judy", "Jane", "Elroy", "Rosie", "Cosmo", "Astro", "Henry", "Stella", "Orbitty"}
+	fakeLastNames  = []string{"Jetson", "Spacely", "Cogswell", "Flintstone", "Rubble", "Slate", "Gravel", "Quarry"}
+	fakeCities     = []string{"Orbit City", "Bedrock", "Springfield", "Portland", "Austin", "Denver", "Boston", "Seattle"}
+	fakeCountries  = []string{"United States", "Canada", "Mexico", "Germany", "France", "Japan", "Brazil", "Australia"}
+	fakeStreets    = []string{"Main St", "Oak Ave", "Maple Dr", "Cedar Ln", "Elm St", "Pine Rd", "Skyway Blvd"}
+	fakeWords      = []string{"sprocket", "cog", "rocket", "orbit", "robot", "gadget", "widget", "comet", "nebula", "quartz", "meteor", "saucer"}
+	fakeDomains    = []string{"example.com", "example.org", "example.net"}
+)
+
+// Factory builds records filled with plausible fake data for load tests and
+// demos:
+//
+//	users, err := mongo.NewFactory(&User{}).With("Role", "admin").CreateN(100)
+//
+// Fields are filled based on a `fake` tag when present, then on the field's
+// name (Email, Firstname, City, Phone...) and finally on its type. Valid tag
+// values are name, firstname, lastname, email, phone, city, country, street,
+// url, word, sentence and "-" to leave the field alone. Fields the package
+// maintains itself, such as Id and the timestamps, are left for Insert.
+type Factory struct {
+	client *Client
+	model  reflect.Type
+	with   map[string]interface{}
+	rand   *rand.Rand
+}
+
+// Create a factory for records like model, which must be a pointer to a
+// struct.
+func NewFactory(model interface{}) *Factory {
+	return std.NewFactory(model)
+}
+
+// Create a factory using the client. See NewFactory.
+func (c *Client) NewFactory(model interface{}) *Factory {
+	return &Factory{
+		client: c,
+		model:  structType(model),
+		with:   map[string]interface{}{},
+		rand:   rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Set a field to a fixed value or, if value is a func() interface{}, to the
+// result of calling it for each record.
+func (f *Factory) With(field string, value interface{}) *Factory {
+	f.with[field] = value
+	return f
+}
+
+// Seed the random source so the same records are built every time.
+func (f *Factory) Seed(seed int64) *Factory {
+	f.rand = rand.New(rand.NewSource(seed))
+	return f
+}
+
+// Build a single record without inserting it. The result is a pointer to a
+// new struct of the model's type.
+func (f *Factory) Build() (interface{}, error) {
+	if f.model == nil {
+		return nil, fmt.Errorf("Factory model must be a struct")
+	}
+
+	v := reflect.New(f.model)
+	f.fill(v.Elem(), map[reflect.Type]bool{})
+
+	for name, value := range f.with {
+		field := v.Elem().FieldByName(name)
+		if !field.IsValid() || !field.CanSet() {
+			return nil, fmt.Errorf("%v has no settable field %v", f.model.Name(), name)
+		}
+
+		if gen, ok := value.(func() interface{}); ok {
+			value = gen()
+		}
+
+		val := reflect.ValueOf(value)
+		if !val.IsValid() {
+			field.Set(reflect.Zero(field.Type()))
+			continue
+		}
+		if !val.Type().AssignableTo(field.Type()) {
+			if !val.Type().ConvertibleTo(field.Type()) {
+				return nil, fmt.Errorf("Can't use a %v for field %v of type %v", val.Type(), name, field.Type())
+			}
+			val = val.Convert(field.Type())
+		}
+		field.Set(val)
+	}
+
+	return v.Interface(), nil
+}
+
+// Build n records without inserting them.
+func (f *Factory) BuildN(n int) ([]interface{}, error) {
+	records := make([]interface{}, n)
+	for i := range records {
+		rec, err := f.Build()
+		if err != nil {
+			return nil, err
+		}
+		records[i] = rec
+	}
+	return records, nil
+}
+
+// Build and insert n records.
+func (f *Factory) CreateN(n int) ([]interface{}, error) {
+	records, err := f.BuildN(n)
+	if err != nil {
+		return nil, err
+	}
+
+	return records, f.client.Insert(records...)
+}
+
+// managedFields are maintained by the package and never faked.
+var managedFields = map[string]bool{
+	"Id": true, "CreatedAt": true, "UpdatedAt": true, "SchemaVersion": true, "ParentId": true, "Path": true,
+}
+
+// fill fakes the fields of the struct v. visiting holds the struct types
+// being filled, so pointers back to one, like a Parent *Category, are left
+// nil instead of recursing forever.
+func (f *Factory) fill(v reflect.Value, visiting map[reflect.Type]bool) {
+	t := v.Type()
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	for n := 0; n < t.NumField(); n++ {
+		sf := t.Field(n)
+		field := v.Field(n)
+		tag := sf.Tag.Get("fake")
+
+		if !field.CanSet() || tag == "-" || (managedFields[sf.Name] && tag == "") {
+			continue
+		}
+
+		if tag == "" {
+			tag = fakeKindFor(sf.Name)
+		}
+		f.fillValue(field, tag, visiting)
+	}
+}
+
+func (f *Factory) fillValue(v reflect.Value, kind string, visiting map[reflect.Type]bool) {
+	switch v.Kind() {
+	case reflect.String:
+		// References to other records get a new id rather than text.
+		switch v.Type() {
+		case reflect.TypeOf(bson.ObjectId("")):
+			v.Set(reflect.ValueOf(bson.NewObjectId()))
+		case reflect.TypeOf(Id("")):
+			v.Set(reflect.ValueOf(Id(bson.NewObjectId().Hex())))
+		default:
+			v.SetString(f.fakeString(kind))
+		}
+	case reflect.Bool:
+		v.SetBool(f.rand.Intn(2) == 1)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(int64(f.rand.Intn(100)))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(uint64(f.rand.Intn(100)))
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(float64(f.rand.Intn(100000)) / 100)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.String {
+			n := 1 + f.rand.Intn(3)
+			s := reflect.MakeSlice(v.Type(), n, n)
+			for i := 0; i < n; i++ {
+				s.Index(i).SetString(f.fakeString("word"))
+			}
+			v.Set(s)
+		}
+	case reflect.Ptr:
+		if elem := v.Type().Elem(); elem.Kind() == reflect.Struct && !visiting[elem] {
+			p := reflect.New(elem)
+			f.fillValue(p.Elem(), kind, visiting)
+			v.Set(p)
+		}
+	case reflect.Struct:
+		if v.Type() == reflect.TypeOf(time.Time{}) {
+			ago := time.Duration(f.rand.Int63n(int64(365 * 24 * time.Hour)))
+			v.Set(reflect.ValueOf(now().Add(-ago).Truncate(time.Millisecond)))
+		} else {
+			f.fill(v, visiting)
+		}
+	}
+}
+
+// fakeKindFor guesses the kind of data a field holds from its name.
+func fakeKindFor(name string) string {
+	n := strings.ToLower(name)
+
+	switch {
+	case strings.Contains(n, "email"):
+		return "email"
+	case strings.Contains(n, "first"):
+		return "firstname"
+	case strings.Contains(n, "last") || strings.Contains(n, "surname"):
+		return "lastname"
+	case strings.Contains(n, "name"):
+		return "name"
+	case strings.Contains(n, "phone"):
+		return "phone"
+	case strings.Contains(n, "city"):
+		return "city"
+	case strings.Contains(n, "country"):
+		return "country"
+	case strings.Contains(n, "street") || strings.Contains(n, "address"):
+		return "street"
+	case strings.Contains(n, "url") || strings.Contains(n, "website"):
+		return "url"
+	case strings.Contains(n, "description") || strings.Contains(n, "body") || strings.Contains(n, "text"):
+		return "sentence"
+	}
+	return "word"
+}
+
+func (f *Factory) fakeString(kind string) string {
+	pick := func(list []string) string { return list[f.rand.Intn(len(list))] }
+
+	switch kind {
+	case "firstname":
+		return pick(fakeFirstNames)
+	case "lastname":
+		return pick(fakeLastNames)
+	case "name":
+		return pick(fakeFirstNames) + " " + pick(fakeLastNames)
+	case "email":
+		return strings.ToLower(pick(fakeFirstNames)+"."+pick(fakeLastNames)) + fmt.Sprintf("%d@", f.rand.Intn(1000)) + pick(fakeDomains)
+	case "phone":
+		return fmt.Sprintf("555-%03d-%04d", f.rand.Intn(1000), f.rand.Intn(10000))
+	case "city":
+		return pick(fakeCities)
+	case "country":
+		return pick(fakeCountries)
+	case "street":
+		return fmt.Sprintf("%d %s", 1+f.rand.Intn(9999), pick(fakeStreets))
+	case "url":
+		return "https://" + pick(fakeDomains) + "/" + pick(fakeWords)
+	case "sentence":
+		words := make([]string, 6+f.rand.Intn(6))
+		for i := range words {
+			words[i] = pick(fakeWords)
+		}
+		s := strings.Join(words, " ")
+		return strings.ToUpper(s[:1]) + s[1:] + "."
+	}
+	return pick(fakeWords)
+}
@@ -0,0 +1,103 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"strings"
+	"testing"
+	"time"
+)
+
+type FakeCustomer struct {
+	Id        Id `bson:"_id"`
+	Firstname string
+	Email     string
+	Notes     string `fake:"-"`
+	Role      string
+	Visits    int
+	Joined    time.Time
+	CreatedAt time.Time
+}
+
+func TestFactoryBuild(t *testing.T) {
+	n := 0
+	recs, err := NewFactory(&FakeCustomer{}).Seed(1).
+		With("Role", "admin").
+		With("Visits", func() interface{} { n++; return n }).
+		BuildN(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, rec := range recs {
+		c := rec.(*FakeCustomer)
+
+		if c.Firstname == "" || !strings.Contains(c.Email, "@") || c.Joined.IsZero() {
+			t.Fatalf("Fields weren't faked: %+v", c)
+		}
+
+		if c.Notes != "" || c.Id != "" || !c.CreatedAt.IsZero() {
+			t.Fatalf("Skipped and managed fields should be left alone: %+v", c)
+		}
+
+		if c.Role != "admin" || c.Visits != i+1 {
+			t.Fatalf("With values weren't applied: %+v", c)
+		}
+	}
+}
+
+func TestFactoryUnknownField(t *testing.T) {
+	if _, err := NewFactory(&FakeCustomer{}).With("Missing", 1).Build(); err == nil {
+		t.Fatal("Expected an error for an unknown field")
+	}
+}
+
+type fakeCategory struct {
+	Id       bson.ObjectId `bson:"_id"`
+	Name     string
+	OwnerId  Id
+	AuthorId bson.ObjectId
+	Parent   *fakeCategory
+	Featured *fakeFeature
+}
+
+type fakeFeature struct {
+	Title    string
+	Category *fakeCategory
+}
+
+func TestFactoryRecursivePointer(t *testing.T) {
+	rec, err := NewFactory(&fakeCategory{}).Seed(1).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := rec.(*fakeCategory)
+	if c.Parent != nil {
+		t.Fatal("Expected a recursive pointer to be left nil got:", c.Parent)
+	}
+	if c.Featured == nil || c.Featured.Title == "" {
+		t.Fatal("Expected a pointer to another type to be filled got:", c.Featured)
+	}
+	if c.Featured.Category != nil {
+		t.Fatal("Expected an indirect recursive pointer to be left nil got:", c.Featured.Category)
+	}
+}
+
+func TestFactoryReferenceIds(t *testing.T) {
+	rec, err := NewFactory(&fakeCategory{}).Seed(1).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := rec.(*fakeCategory)
+	if !bson.IsObjectIdHex(string(c.OwnerId)) {
+		t.Fatal("Expected OwnerId to be an ObjectId got:", c.OwnerId)
+	}
+	if !c.AuthorId.Valid() {
+		t.Fatal("Expected AuthorId to be an ObjectId got:", c.AuthorId)
+	}
+	if c.Id != "" {
+		t.Fatal("Expected the managed Id to be left alone got:", c.Id)
+	}
+}
@@ -0,0 +1,132 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Replica set member states reported by replSetGetStatus.
+const (
+	memberPrimary   = 1
+	memberSecondary = 2
+)
+
+// ErrNoSecondary is returned by ReplicationLag when the replica set has no
+// secondary to measure.
+var ErrNoSecondary = errors.New("No secondary available")
+
+var (
+	// FallbackTimeout is how long FindWithFallback waits for the primary
+	// before retrying against a secondary.
+	FallbackTimeout = 2 * time.Second
+
+	// FallbackMaxStaleness is how far behind the most recent member of the
+	// replica set a secondary may be for FindWithFallback to read from it.
+	FallbackMaxStaleness = 30 * time.Second
+)
+
+// Find records like Find, but if the primary doesn't answer within
+// FallbackTimeout the read is retried once against a secondary. stale is
+// true when the results came from the secondary and may be up to
+// FallbackMaxStaleness old. If the secondaries are further behind than
+// that, the primary's error is returned. Meant for read heavy dashboards
+// that prefer slightly old data to none.
+func FindWithFallback(i interface{}, q bson.M, sortFields ...string) (stale bool, err error) {
+	return std.FindWithFallback(i, q, sortFields...)
+}
+
+// Find records with a fallback using the client. See FindWithFallback.
+func (c *Client) FindWithFallback(i interface{}, q bson.M, sortFields ...string) (stale bool, err error) {
+	if !isPtr(i) {
+		return false, NoPtr
+	}
+
+	q = scopeQuery(i, q)
+	op := c.startOp("find", collName(i), q)
+
+	if err := op.allowed(); err != nil {
+		return false, op.done(err)
+	}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return false, op.done(err)
+	}
+	defer s.Close()
+
+	if q, err = op.check(c, s, q, !isSlice(reflect.TypeOf(i))); err != nil {
+		return false, op.done(err)
+	}
+
+	s.SetSyncTimeout(FallbackTimeout)
+	s.SetSocketTimeout(FallbackTimeout)
+
+	err = findInto(c, s, withoutLazy(c.GetColl(s, op.coll).Find(q).Sort(sortFields...), i), i)
+	if err == nil || !isTimeout(err) {
+		return false, op.done(err)
+	}
+
+	sec := s.Copy()
+	defer sec.Close()
+	sec.SetMode(mgo.Secondary, true)
+
+	lag, lagErr := secondaryLag(sec)
+	if lagErr != nil || lag > FallbackMaxStaleness {
+		return false, op.done(err)
+	}
+
+	if err := findInto(c, sec, withoutLazy(c.GetColl(sec, op.coll).Find(q).Sort(sortFields...), i), i); err != nil {
+		return false, op.done(err)
+	}
+	return true, op.done(nil)
+}
+
+// isTimeout returns true for errors caused by servers that didn't answer in
+// time rather than by the query itself.
+func isTimeout(err error) bool {
+	if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		return true
+	}
+
+	msg := err.Error()
+	return strings.Contains(msg, "i/o timeout") || strings.Contains(msg, "no reachable servers")
+}
+
+// secondaryLag returns how far the slowest secondary is behind the most
+// recent member of the replica set. The most recent member is used rather
+// than the primary because the primary may be the one that's unavailable.
+func secondaryLag(s *mgo.Session) (time.Duration, error) {
+	var status struct {
+		Members []struct {
+			State      int       `bson:"state"`
+			OptimeDate time.Time `bson:"optimeDate"`
+		} `bson:"members"`
+	}
+	if err := s.Run(bson.D{{Name: "replSetGetStatus", Value: 1}}, &status); err != nil {
+		return 0, err
+	}
+
+	var newest, oldest time.Time
+	for _, m := range status.Members {
+		if m.State != memberPrimary && m.State != memberSecondary {
+			continue
+		}
+		if m.OptimeDate.After(newest) {
+			newest = m.OptimeDate
+		}
+		if m.State == memberSecondary && (oldest.IsZero() || m.OptimeDate.Before(oldest)) {
+			oldest = m.OptimeDate
+		}
+	}
+
+	if oldest.IsZero() {
+		return 0, ErrNoSecondary
+	}
+	return newest.Sub(oldest), nil
+}
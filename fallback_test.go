@@ -0,0 +1,30 @@
+package mongo
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestIsTimeout(t *testing.T) {
+	timeout := &net.OpError{Op: "read", Err: &timeoutErr{}}
+
+	cases := map[error]bool{
+		timeout:                             true,
+		errors.New("no reachable servers"):  true,
+		errors.New("read tcp: i/o timeout"): true,
+		errors.New("not found"):             false,
+	}
+
+	for err, expected := range cases {
+		if isTimeout(err) != expected {
+			t.Errorf("isTimeout(%v) should be %v", err, expected)
+		}
+	}
+}
+
+type timeoutErr struct{}
+
+func (timeoutErr) Error() string   { return "timeout" }
+func (timeoutErr) Timeout() bool   { return true }
+func (timeoutErr) Temporary() bool { return true }
@@ -0,0 +1,47 @@
+package mongo
+
+import (
+	"reflect"
+	"strings"
+)
+
+// structType returns the struct type behind i, dereferencing pointers and
+// slices. It returns nil if there's no struct underneath.
+func structType(i interface{}) reflect.Type {
+	t := reflect.TypeOf(i)
+	for t != nil && (t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice) {
+		t = t.Elem()
+	}
+
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	return t
+}
+
+// fieldKey returns the document key the bson package uses for the named
+// struct field of i. If i has no such field the lowercased name is returned,
+// matching the bson default.
+func fieldKey(i interface{}, name string) string {
+	if t := structType(i); t != nil {
+		if f, ok := t.FieldByName(name); ok {
+			return bsonKey(f)
+		}
+	}
+
+	return strings.ToLower(name)
+}
+
+// bsonKey returns the document key for a struct field, honoring the bson tag.
+func bsonKey(f reflect.StructField) string {
+	tag := f.Tag.Get("bson")
+	if tag == "" && !strings.Contains(string(f.Tag), ":") {
+		tag = string(f.Tag)
+	}
+
+	if name := strings.Split(tag, ",")[0]; name != "" {
+		return name
+	}
+
+	return strings.ToLower(f.Name)
+}
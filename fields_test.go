@@ -0,0 +1,117 @@
+package mongo
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode"
+)
+
+func TestBsonKey(t *testing.T) {
+	type tagged struct {
+		Plain   string
+		Named   string `bson:"name"`
+		Options string `bson:",omitempty"`
+	}
+
+	want := map[string]string{"Plain": "plain", "Named": "name", "Options": "options", "Missing": "missing"}
+	for name, key := range want {
+		if got := fieldKey(&tagged{}, name); got != key {
+			t.Fatalf("Expected %q for %v got: %q", key, name, got)
+		}
+	}
+}
+
+// fuzzFieldTypes are the types FuzzStructTags picks from for its field.
+var fuzzFieldTypes = []reflect.Type{
+	reflect.TypeOf(""),
+	reflect.TypeOf(0),
+	reflect.TypeOf([]float64{}),
+	reflect.TypeOf(map[string]interface{}{}),
+	reflect.TypeOf(&Point{}),
+	reflect.TypeOf(Polygon{}),
+}
+
+// FuzzStructTags builds a struct with a field named and tagged by the input,
+// next to an inlined struct, and checks that inspecting it agrees with the
+// tags and doesn't panic.
+func FuzzStructTags(f *testing.F) {
+	f.Add("Name", "name,omitempty", "readonly", uint8(0))
+	f.Add("Owner", "", "immutable,readonly", uint8(1))
+	f.Add("Location", "loc", "geo", uint8(4))
+	f.Add("Area", ",inline", "geo", uint8(5))
+	f.Add("Skipped", "-", "", uint8(2))
+
+	f.Fuzz(func(t *testing.T, name, bsonTag, mongoTag string, kind uint8) {
+		if !exportedIdent(name) || name == "Inner" {
+			return
+		}
+
+		inner := reflect.StructOf([]reflect.StructField{
+			{Name: "Note", Type: reflect.TypeOf(""), Tag: `bson:"note" mongo:"readonly"`},
+		})
+		typ := reflect.StructOf([]reflect.StructField{
+			{Name: name, Type: fuzzFieldTypes[int(kind)%len(fuzzFieldTypes)], Tag: reflect.StructTag(fmt.Sprintf("bson:%q mongo:%q", bsonTag, mongoTag))},
+			{Name: "Inner", Type: inner, Tag: `bson:",inline"`},
+		})
+		rec := reflect.New(typ).Interface()
+		field := typ.Field(0)
+
+		key := bsonKey(field)
+		if key == "" {
+			t.Fatal("Expected a key for", name, bsonTag)
+		}
+		if got := fieldKey(rec, name); got != key {
+			t.Fatalf("Expected fieldKey %q got: %q", key, got)
+		}
+
+		keys, all := knownKeys(typ)
+		if !keys["note"] && !all {
+			t.Fatal("Expected the inlined key to be known got:", keys)
+		}
+		if bsonTag != "-" && !strings.Contains(bsonTag, ",inline") && !keys[key] {
+			t.Fatalf("Expected %q to be known got: %v", key, keys)
+		}
+
+		readonly, immutable := protectedKeys(rec)
+		if !containsKey(readonly, "note") {
+			t.Fatal("Expected the inlined readonly key got:", readonly)
+		}
+		if strings.Contains(bsonTag, ",inline") {
+			return
+		}
+		if hasMongoTag(field, "readonly") != containsKey(readonly, key) && key != "note" {
+			t.Fatalf("Expected readonly to follow the tag %q got: %v", mongoTag, readonly)
+		}
+		if hasMongoTag(field, "immutable") != containsKey(immutable, key) {
+			t.Fatalf("Expected immutable to follow the tag %q got: %v", mongoTag, immutable)
+		}
+
+		// These only have to cope with whatever the tags say.
+		validateGeo(rec)
+		zeroFields(reflect.ValueOf(rec))
+		lazyKeys(rec)
+	})
+}
+
+func exportedIdent(name string) bool {
+	for n, r := range name {
+		if n == 0 && !unicode.IsUpper(r) {
+			return false
+		}
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+			return false
+		}
+	}
+	return name != ""
+}
+
+func containsKey(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
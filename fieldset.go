@@ -0,0 +1,135 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ErrUnknownField is returned, wrapped with the field's name, by Fieldset
+// for fields the model doesn't have.
+var ErrUnknownField = errors.New("Unknown field")
+
+// Returns the projection for a comma separated list of document keys, e.g.
+// the fields parameter of a JSON:API style request:
+//
+//	proj, err := mongo.Fieldset(&User{}, r.URL.Query().Get("fields"))
+//	if err != nil {
+//		http.Error(w, err.Error(), http.StatusBadRequest)
+//		return
+//	}
+//	err = mongo.FindWithFields(&users, q, proj)
+//
+// Each key must be a bson key of the model, or start with one followed by a
+// dot. The _id and type discriminator are always included. An empty list
+// returns a nil projection, which selects every field.
+func Fieldset(i interface{}, fields string) (bson.M, error) {
+	t := structType(i)
+	if t == nil {
+		return nil, NoPtr
+	}
+	known, all := knownKeys(t)
+
+	proj := bson.M{}
+	for _, name := range strings.Split(fields, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		if root := strings.Split(name, ".")[0]; !all && !known[root] {
+			return nil, fmt.Errorf("%w: %v", ErrUnknownField, name)
+		}
+		proj[name] = 1
+	}
+
+	if len(proj) == 0 {
+		return nil, nil
+	}
+
+	proj["_id"] = 1
+	if _, ok := lookupPoly(i); ok {
+		proj[TypeKey] = 1
+	}
+	return proj, nil
+}
+
+// Find like Find, but only load the fields in the projection, e.g. one
+// returned by Fieldset. Fields left out keep their zero values. A nil
+// projection loads every field.
+func FindWithFields(i interface{}, q bson.M, fields bson.M, sortFields ...string) error {
+	return std.FindWithFields(i, q, fields, sortFields...)
+}
+
+// Find records with only some fields using the client. See FindWithFields.
+func (c *Client) FindWithFields(i interface{}, q bson.M, fields bson.M, sortFields ...string) error {
+	if !isPtr(i) {
+		return NoPtr
+	}
+
+	q = scopeQuery(i, q)
+	op := c.startOp("find", collName(i), q)
+
+	if err := op.allowed(); err != nil {
+		return op.done(err)
+	}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return op.done(err)
+	}
+	defer s.Close()
+
+	if q, err = op.check(c, s, q, !isSlice(reflect.TypeOf(i))); err != nil {
+		return op.done(err)
+	}
+
+	query := c.GetColl(s, op.coll).Find(q).Sort(sortFields...)
+	if fields != nil {
+		query = query.Select(fields)
+	} else {
+		query = withoutLazy(query, i)
+	}
+
+	return op.done(findInto(c, s, query, i))
+}
+
+// Find like Find, but only load limit elements of the array in field, a
+// bson key, starting at skip, so large embedded arrays can be paged through:
+//
+//	err := mongo.FindWithArraySlice(post, bson.M{"_id": id}, "comments", (page-1)*20, 20)
+//
+// A negative skip counts from the end of the array, e.g. -20 for the last 20
+// elements. A limit less than one uses DefaultPerPage. Every other field is
+// loaded as usual, except lazy ones.
+func FindWithArraySlice(i interface{}, q bson.M, field string, skip, limit int) error {
+	return std.FindWithArraySlice(i, q, field, skip, limit)
+}
+
+// Find records with a slice of an array using the client. See
+// FindWithArraySlice.
+func (c *Client) FindWithArraySlice(i interface{}, q bson.M, field string, skip, limit int) error {
+	t := structType(i)
+	if t == nil || !isPtr(i) {
+		return NoPtr
+	}
+
+	if known, all := knownKeys(t); !all && !known[strings.Split(field, ".")[0]] {
+		return fmt.Errorf("%w: %v", ErrUnknownField, field)
+	}
+
+	if limit < 1 {
+		limit = DefaultPerPage
+	}
+
+	proj := lazyProjection(i)
+	if proj == nil {
+		proj = bson.M{}
+	}
+	proj[field] = bson.M{"$slice": []int{skip, limit}}
+
+	return c.FindWithFields(i, q, proj)
+}
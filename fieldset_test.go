@@ -0,0 +1,53 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type fieldsetModel struct {
+	Id      bson.ObjectId `bson:"_id"`
+	Name    string        `bson:"name"`
+	Email   string        `bson:"email"`
+	Address struct {
+		City string `bson:"city"`
+	} `bson:"address"`
+}
+
+func TestFieldset(t *testing.T) {
+	proj, err := Fieldset(&[]fieldsetModel{}, "name, address.city,,")
+	if err != nil {
+		t.Fatal("Couldn't build the projection:", err)
+	}
+
+	expected := bson.M{"_id": 1, "name": 1, "address.city": 1}
+	if !reflect.DeepEqual(proj, expected) {
+		t.Fatal("Expected", expected, "got:", proj)
+	}
+
+	if proj, err := Fieldset(&fieldsetModel{}, ""); err != nil || proj != nil {
+		t.Fatal("Expected no projection got:", proj, err)
+	}
+}
+
+func TestFieldsetUnknown(t *testing.T) {
+	_, err := Fieldset(&fieldsetModel{}, "name,password")
+	if !errors.Is(err, ErrUnknownField) {
+		t.Fatal("Expected ErrUnknownField got:", err)
+	}
+
+	// Struct field names aren't document keys.
+	if _, err := Fieldset(&fieldsetModel{}, "Name"); !errors.Is(err, ErrUnknownField) {
+		t.Fatal("Expected ErrUnknownField got:", err)
+	}
+}
+
+func TestFindWithArraySliceUnknown(t *testing.T) {
+	err := FindWithArraySlice(&fieldsetModel{}, nil, "comments", 0, 20)
+	if !errors.Is(err, ErrUnknownField) {
+		t.Fatal("Expected ErrUnknownField got:", err)
+	}
+}
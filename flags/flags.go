@@ -0,0 +1,165 @@
+/*
+The flags package evaluates feature flags stored in a collection. Flags
+are cached in memory and refreshed by polling or by watching the oplog,
+so checking a flag doesn't touch the database:
+
+	store := flags.NewStore()
+	if err := store.Refresh(); err != nil {
+		...
+	}
+	go store.Run(30*time.Second, stop, logErr)
+
+	if store.Enabled("new-checkout", tenantId, userId) {
+		...
+	}
+
+A flag is on for Percent percent of subjects, such as users, chosen by a
+hash of the flag and subject so each subject gets the same answer every
+time. Tenants can be forced on or off whatever the percentage.
+*/
+package flags
+
+import (
+	"github.com/globalsign/mgo/bson"
+	"github.com/sfreiberg/mongo"
+
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// Collection holds the flags.
+var Collection = "flags"
+
+// Flag is a feature flag.
+type Flag struct {
+	Name string `bson:"_id" json:"name"`
+	// Percent is the share of subjects, from 0 to 100, the flag is on for.
+	Percent int `bson:"percent" json:"percent"`
+	// Tenants overrides Percent for the tenants listed.
+	Tenants   map[string]bool `bson:"tenants,omitempty" json:"tenants,omitempty"`
+	UpdatedAt time.Time       `bson:"updatedat" json:"updatedAt"`
+}
+
+// CollectionName stores flags in Collection.
+func (Flag) CollectionName() string {
+	return Collection
+}
+
+// Store or replace a flag.
+func Set(f Flag) error {
+	s, err := mongo.GetSession()
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	f.UpdatedAt = time.Now()
+	_, err = mongo.GetColl(s, Collection).UpsertId(f.Name, f)
+	return err
+}
+
+// Delete a flag, which turns it off for everyone.
+func Delete(name string) error {
+	s, err := mongo.GetSession()
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	return mongo.GetColl(s, Collection).RemoveId(name)
+}
+
+// Store is an in memory copy of the flags.
+type Store struct {
+	mu    sync.RWMutex
+	flags map[string]Flag
+}
+
+// Create an empty store. Call Refresh to load the flags.
+func NewStore() *Store {
+	return &Store{flags: map[string]Flag{}}
+}
+
+// Load every flag, replacing the cached ones.
+func (st *Store) Refresh() error {
+	var all []Flag
+	if err := mongo.Default().Find(&all, bson.M{}); err != nil {
+		return err
+	}
+
+	flags := make(map[string]Flag, len(all))
+	for _, f := range all {
+		flags[f.Name] = f
+	}
+
+	st.mu.Lock()
+	st.flags = flags
+	st.mu.Unlock()
+	return nil
+}
+
+// Refresh the flags every interval until stop is closed. Errors are passed
+// to onError, which may be nil, and the cached flags are kept.
+func (st *Store) Run(interval time.Duration, stop <-chan struct{}, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		if err := st.Refresh(); err != nil && onError != nil {
+			onError(err)
+		}
+	}
+}
+
+// Refresh the flags whenever they change by tailing the oplog, which needs a
+// replica set, until stop is closed. The name identifies the tailer's saved
+// position and should be unique to the process.
+func (st *Store) Watch(name string, stop <-chan struct{}) error {
+	if err := st.Refresh(); err != nil {
+		return err
+	}
+
+	t := mongo.NewOplogTailer(name, func(mongo.ChangeEvent) error {
+		return st.Refresh()
+	}, &Flag{})
+	return t.Run(stop)
+}
+
+// Returns the cached flag with name.
+func (st *Store) Get(name string) (Flag, bool) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	f, ok := st.flags[name]
+	return f, ok
+}
+
+// Returns whether the flag with name is on for subject in tenant. Unknown
+// flags are off. Either id may be empty.
+func (st *Store) Enabled(name, tenant, subject string) bool {
+	f, ok := st.Get(name)
+	if !ok {
+		return false
+	}
+
+	if on, ok := f.Tenants[tenant]; ok && tenant != "" {
+		return on
+	}
+
+	return bucket(name, subject) < f.Percent
+}
+
+// bucket places subject in one of 100 buckets for the flag. Hashing the flag
+// too means different flags roll out to different subjects first.
+func bucket(name, subject string) int {
+	h := fnv.New32a()
+	h.Write([]byte(name + "\x00" + subject))
+	return int(h.Sum32() % 100)
+}
@@ -0,0 +1,59 @@
+package flags
+
+import (
+	"strconv"
+	"testing"
+)
+
+func storeWith(flags ...Flag) *Store {
+	st := NewStore()
+	for _, f := range flags {
+		st.flags[f.Name] = f
+	}
+	return st
+}
+
+func TestEnabled(t *testing.T) {
+	st := storeWith(
+		Flag{Name: "on", Percent: 100},
+		Flag{Name: "off", Percent: 0, Tenants: map[string]bool{"beta": true}},
+		Flag{Name: "on-except", Percent: 100, Tenants: map[string]bool{"legacy": false}},
+	)
+
+	cases := []struct {
+		name, tenant string
+		expected     bool
+	}{
+		{"on", "", true},
+		{"off", "", false},
+		{"off", "beta", true},
+		{"on-except", "acme", true},
+		{"on-except", "legacy", false},
+		{"missing", "beta", false},
+	}
+
+	for _, c := range cases {
+		if on := st.Enabled(c.name, c.tenant, "user-1"); on != c.expected {
+			t.Errorf("Enabled(%q, %q): expected %v got: %v", c.name, c.tenant, c.expected, on)
+		}
+	}
+}
+
+func TestRollout(t *testing.T) {
+	st := storeWith(Flag{Name: "half", Percent: 50})
+
+	on := 0
+	for n := 0; n < 1000; n++ {
+		subject := "user-" + strconv.Itoa(n)
+		if st.Enabled("half", "", subject) {
+			on++
+		}
+		if st.Enabled("half", "", subject) != st.Enabled("half", "", subject) {
+			t.Fatal("Expected the same answer for the same subject")
+		}
+	}
+
+	if on < 400 || on > 600 {
+		t.Fatal("Expected about half of the subjects got:", on)
+	}
+}
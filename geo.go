@@ -0,0 +1,323 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// ErrInvalidGeometry is returned, wrapped with the field's key and what's
+// wrong, when a field tagged `mongo:"geo"` doesn't hold valid GeoJSON.
+var ErrInvalidGeometry = errors.New("Invalid geometry")
+
+// DistanceField is the field GeoNear stores the distance of each result in
+// when NearOptions doesn't name one. Add it to a model, e.g.
+// `bson:"distance,omitempty"`, to have it decoded.
+var DistanceField = "distance"
+
+// Point is a GeoJSON point, for fields with a 2dsphere index.
+type Point struct {
+	Type        string    `bson:"type" json:"type"`
+	Coordinates []float64 `bson:"coordinates" json:"coordinates"`
+}
+
+// Returns the point at a longitude and latitude. Note the order, which is
+// the one GeoJSON uses.
+func NewPoint(lng, lat float64) Point {
+	return Point{Type: "Point", Coordinates: []float64{lng, lat}}
+}
+
+// Polygon is a GeoJSON polygon. The first ring is the outer boundary and
+// must be counter-clockwise; any further rings are holes and must be
+// clockwise. Each ring ends with its first position.
+type Polygon struct {
+	Type        string        `bson:"type" json:"type"`
+	Coordinates [][][]float64 `bson:"coordinates" json:"coordinates"`
+}
+
+// Returns a polygon of the given rings.
+func NewPolygon(rings ...[][]float64) Polygon {
+	return Polygon{Type: "Polygon", Coordinates: rings}
+}
+
+// Returns the polygon covering the box between two corners.
+func NewBox(minLng, minLat, maxLng, maxLat float64) Polygon {
+	return NewPolygon([][]float64{
+		{minLng, minLat}, {maxLng, minLat}, {maxLng, maxLat}, {minLng, maxLat}, {minLng, minLat},
+	})
+}
+
+// NearOptions configures GeoNear.
+type NearOptions struct {
+	// Key is the field with the 2dsphere index to use. It's only needed
+	// when the collection has more than one.
+	Key string
+	// MinDistance and MaxDistance limit the results to a ring around the
+	// point, in meters. Zero leaves them out.
+	MinDistance float64
+	MaxDistance float64
+	// Skip and Limit page through the results, nearest first.
+	Skip  int
+	Limit int
+	// DistanceField is the field the distance is stored in. Defaults to
+	// DistanceField.
+	DistanceField string
+}
+
+// Find records matching q by distance from near, nearest first, using the
+// $geoNear aggregation stage. Like Find, pass in a pointer to a slice for
+// every record or to a struct for the nearest. Returns the distance of each
+// record in meters, in the same order, which $nearSphere queries can't:
+//
+//	var shops []Shop
+//	distances, err := mongo.GeoNear(&shops, mongo.NewPoint(-0.12, 51.5), nil,
+//		mongo.NearOptions{MaxDistance: 2000, Limit: 20})
+func GeoNear(i interface{}, near Point, q bson.M, opts NearOptions) ([]float64, error) {
+	return std.GeoNear(i, near, q, opts)
+}
+
+// Find records near a point using the client. See GeoNear.
+func (c *Client) GeoNear(i interface{}, near Point, q bson.M, opts NearOptions) ([]float64, error) {
+	if !isPtr(i) {
+		return nil, NoPtr
+	}
+
+	distField := opts.DistanceField
+	if distField == "" {
+		distField = DistanceField
+	}
+
+	if !isSlice(reflect.TypeOf(i)) {
+		opts.Limit = 1
+	}
+
+	q = scopeQuery(i, q)
+	op := c.startOp("aggregate", collName(i), q)
+
+	if err := op.allowed(); err != nil {
+		return nil, op.done(err)
+	}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return nil, op.done(err)
+	}
+	defer s.Close()
+
+	if q, err = op.check(c, s, q, opts.Limit > 0); err != nil {
+		return nil, op.done(err)
+	}
+
+	// $geoNear must be the first stage, so the scope of the type goes in its
+	// query rather than a $match before it.
+	p := Pipeline{{"$geoNear": geoNearSpec(near, q, opts, distField)}}
+	if opts.Skip > 0 {
+		p = p.Skip(opts.Skip)
+	}
+	if opts.Limit > 0 {
+		p = p.Limit(opts.Limit)
+	}
+
+	var raws []bson.Raw
+	if err := c.GetColl(s, op.coll).Pipe(p).All(&raws); err != nil {
+		return nil, op.done(err)
+	}
+
+	distances := make([]float64, len(raws))
+	for n, raw := range raws {
+		var doc bson.M
+		if err := raw.Unmarshal(&doc); err != nil {
+			return nil, op.done(err)
+		}
+		distances[n], _ = doc[distField].(float64)
+	}
+
+	if !isSlice(reflect.TypeOf(i)) {
+		if len(raws) == 0 {
+			return nil, op.done(mgo.ErrNotFound)
+		}
+		return distances[:1], op.done(unmarshalRecord(c, s, raws[0], i))
+	}
+	return distances, op.done(unmarshalAll(c, s, raws, i))
+}
+
+func geoNearSpec(near Point, q bson.M, opts NearOptions, distField string) bson.M {
+	spec := bson.M{
+		"near":          near,
+		"spherical":     true,
+		"distanceField": distField,
+	}
+	if len(q) > 0 {
+		spec["query"] = q
+	}
+	if opts.Key != "" {
+		spec["key"] = opts.Key
+	}
+	if opts.MinDistance > 0 {
+		spec["minDistance"] = opts.MinDistance
+	}
+	if opts.MaxDistance > 0 {
+		spec["maxDistance"] = opts.MaxDistance
+	}
+	return spec
+}
+
+// Fields holding a Point or a Polygon, or pointers to them, can be checked
+// before every write with a mongo tag, since the server only rejects invalid
+// geometries when it indexes them and then with an opaque error:
+//
+//	type Zone struct {
+//		Id   bson.ObjectId `bson:"_id"`
+//		Area Polygon       `bson:"area" mongo:"geo"`
+//	}
+//
+// Nil pointers are skipped, as are zero values of omitempty fields.
+
+// validateGeo checks the fields of i tagged as geometries, following inlined
+// structs.
+func validateGeo(i interface{}) error {
+	v := reflect.ValueOf(i)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	return validateGeoStruct(v)
+}
+
+func validateGeoStruct(v reflect.Value) error {
+	t := v.Type()
+	for n := 0; n < t.NumField(); n++ {
+		f := t.Field(n)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+
+		fv := v.Field(n)
+		if strings.Contains(f.Tag.Get("bson"), ",inline") {
+			if fv.Kind() == reflect.Ptr && !fv.IsNil() {
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct {
+				if err := validateGeoStruct(fv); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if !hasMongoTag(f, "geo") {
+			continue
+		}
+		if fv.Kind() == reflect.Ptr {
+			if fv.IsNil() {
+				continue
+			}
+			fv = fv.Elem()
+		}
+		if fv.IsZero() && strings.Contains(f.Tag.Get("bson"), ",omitempty") {
+			continue
+		}
+
+		var err error
+		switch g := fv.Interface().(type) {
+		case Point:
+			err = checkPoint(g)
+		case Polygon:
+			err = checkPolygon(g)
+		default:
+			err = fmt.Errorf("%v isn't a Point or a Polygon", fv.Type())
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v: %v", ErrInvalidGeometry, bsonKey(f), err)
+		}
+	}
+	return nil
+}
+
+// hasMongoTag reports whether the mongo tag of f has opt.
+func hasMongoTag(f reflect.StructField, opt string) bool {
+	for _, o := range strings.Split(f.Tag.Get("mongo"), ",") {
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
+
+func checkPoint(p Point) error {
+	if p.Type != "Point" {
+		return fmt.Errorf("type is %q, expected \"Point\"", p.Type)
+	}
+	return checkPosition(p.Coordinates)
+}
+
+func checkPolygon(p Polygon) error {
+	if p.Type != "Polygon" {
+		return fmt.Errorf("type is %q, expected \"Polygon\"", p.Type)
+	}
+	if len(p.Coordinates) == 0 {
+		return errors.New("polygon has no rings")
+	}
+
+	for r, ring := range p.Coordinates {
+		if len(ring) < 4 {
+			return fmt.Errorf("ring %v has %v positions, at least 4 are needed", r, len(ring))
+		}
+		for n, pos := range ring {
+			if err := checkPosition(pos); err != nil {
+				return fmt.Errorf("ring %v position %v: %v", r, n, err)
+			}
+		}
+
+		first, last := ring[0], ring[len(ring)-1]
+		if first[0] != last[0] || first[1] != last[1] {
+			return fmt.Errorf("ring %v isn't closed, it must end with %v", r, first)
+		}
+
+		area := ringArea(ring)
+		switch {
+		case area == 0:
+			return fmt.Errorf("ring %v has no area", r)
+		case r == 0 && area < 0:
+			return errors.New("outer ring is clockwise, it must be counter-clockwise")
+		case r > 0 && area > 0:
+			return fmt.Errorf("hole %v is counter-clockwise, it must be clockwise", r)
+		}
+	}
+	return nil
+}
+
+func checkPosition(pos []float64) error {
+	if len(pos) != 2 {
+		return fmt.Errorf("position has %v coordinates, expected longitude and latitude", len(pos))
+	}
+
+	lng, lat := pos[0], pos[1]
+	if math.IsNaN(lng) || lng < -180 || lng > 180 {
+		return fmt.Errorf("longitude %v is out of range", lng)
+	}
+	if math.IsNaN(lat) || lat < -90 || lat > 90 {
+		return fmt.Errorf("latitude %v is out of range", lat)
+	}
+	return nil
+}
+
+// ringArea returns twice the signed planar area of a closed ring, which is
+// positive when it's counter-clockwise.
+func ringArea(ring [][]float64) float64 {
+	var sum float64
+	for n := 0; n < len(ring)-1; n++ {
+		sum += ring[n][0]*ring[n+1][1] - ring[n+1][0]*ring[n][1]
+	}
+	return sum
+}
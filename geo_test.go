@@ -0,0 +1,74 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"errors"
+	"testing"
+)
+
+func TestGeoNearSpec(t *testing.T) {
+	near := NewPoint(-0.12, 51.5)
+	spec := geoNearSpec(near, bson.M{"open": true}, NearOptions{MaxDistance: 2000}, "dist")
+
+	if spec["distanceField"] != "dist" || spec["maxDistance"] != 2000.0 || spec["spherical"] != true {
+		t.Fatal("Expected the options in the spec got:", spec)
+	}
+	if _, ok := spec["minDistance"]; ok {
+		t.Fatal("Expected no minDistance got:", spec)
+	}
+	if q, ok := spec["query"].(bson.M); !ok || q["open"] != true {
+		t.Fatal("Expected the query in the spec got:", spec)
+	}
+	if near.Coordinates[0] != -0.12 || near.Coordinates[1] != 51.5 {
+		t.Fatal("Expected longitude first got:", near.Coordinates)
+	}
+}
+
+type shop struct {
+	Id       bson.ObjectId `bson:"_id"`
+	Location Point         `bson:"location"`
+}
+
+func (shop) CollectionName() string { return "shops" }
+
+func TestGeoNearAccessPolicy(t *testing.T) {
+	SetAccessPolicy("shops", AllowInsert)
+	defer ClearAccessPolicy("shops")
+
+	var shops []shop
+	if _, err := GeoNear(&shops, NewPoint(0, 0), nil, NearOptions{}); !errors.Is(err, ErrAccessDenied) {
+		t.Fatal("Expected ErrAccessDenied got:", err)
+	}
+}
+
+type zone struct {
+	Id     bson.ObjectId `bson:"_id"`
+	Area   Polygon       `bson:"area" mongo:"geo"`
+	Center *Point        `bson:"center" mongo:"geo"`
+}
+
+func TestValidateGeo(t *testing.T) {
+	box := NewBox(-1, 50, 1, 52)
+	if err := validate(&zone{Area: box}); err != nil {
+		t.Fatal("Expected a box to be valid:", err)
+	}
+
+	open := NewPolygon([][]float64{{0, 0}, {1, 0}, {1, 1}, {0, 1}})
+	clockwise := NewPolygon([][]float64{{0, 0}, {0, 1}, {1, 1}, {1, 0}, {0, 0}})
+	hole := NewPolygon(box.Coordinates[0], [][]float64{{0, 51}, {0.5, 51}, {0.5, 51.5}, {0, 51}})
+	far := NewPoint(0, 91)
+
+	for _, rec := range []*zone{
+		{Area: open},
+		{Area: clockwise},
+		{Area: hole},
+		{Area: box, Center: &far},
+		{},
+	} {
+		var verr *ValidationError
+		if err := validate(rec); !errors.Is(err, ErrInvalidGeometry) || !errors.As(err, &verr) {
+			t.Fatal("Expected ErrInvalidGeometry got:", err)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"errors"
+)
+
+// DefaultGraphDepth is the depth GraphLookup stops at when GraphOptions
+// doesn't set one.
+const DefaultGraphDepth = 10
+
+// GraphOptions describes a $graphLookup traversal. See
+// https://docs.mongodb.com/manual/reference/operator/aggregation/graphLookup/
+type GraphOptions struct {
+	// From is a model for the collection to traverse. Defaults to the
+	// collection being queried.
+	From interface{}
+	// StartWith is the expression the traversal starts from, e.g. "$_id".
+	StartWith string
+	// ConnectFromField and ConnectToField link one document to the next.
+	ConnectFromField string
+	ConnectToField   string
+	// As is the field the matched documents are written to.
+	As string
+	// MaxDepth limits the number of hops; zero only follows the links of
+	// the starting documents. nil means DefaultGraphDepth and a negative
+	// value removes the limit. See Depth.
+	MaxDepth *int
+	// DepthField, if set, records the number of hops on each match.
+	DepthField string
+	// Restrict filters the documents visited by the traversal.
+	Restrict bson.M
+}
+
+// Returns a MaxDepth of n, e.g. GraphOptions{MaxDepth: mongo.Depth(0)}.
+func Depth(n int) *int {
+	return &n
+}
+
+// Run a $graphLookup traversal for the documents in i's collection that
+// match q, e.g. every report in a management chain. The traversed documents
+// are written to opts.As on each result. $graphLookup never visits a document
+// twice so cycles in the data end the traversal rather than looping, and the
+// depth is limited unless explicitly disabled.
+func GraphLookup(i interface{}, q bson.M, opts GraphOptions, result interface{}) error {
+	return std.GraphLookup(i, q, opts, result)
+}
+
+// Run a recursive lookup using the client. See GraphLookup.
+func (c *Client) GraphLookup(i interface{}, q bson.M, opts GraphOptions, result interface{}) error {
+	p, err := graphPipeline(i, q, opts)
+	if err != nil {
+		return err
+	}
+	return c.Aggregate(i, p, result)
+}
+
+// graphPipeline builds the pipeline GraphLookup runs.
+func graphPipeline(i interface{}, q bson.M, opts GraphOptions) (Pipeline, error) {
+	if opts.StartWith == "" || opts.ConnectFromField == "" || opts.ConnectToField == "" || opts.As == "" {
+		return nil, errors.New("GraphLookup needs StartWith, ConnectFromField, ConnectToField and As")
+	}
+
+	from := opts.From
+	if from == nil {
+		from = i
+	}
+
+	spec := bson.M{
+		"from":             PhysicalCollection(collName(from)),
+		"startWith":        opts.StartWith,
+		"connectFromField": opts.ConnectFromField,
+		"connectToField":   opts.ConnectToField,
+		"as":               opts.As,
+	}
+
+	switch {
+	case opts.MaxDepth == nil:
+		spec["maxDepth"] = DefaultGraphDepth
+	case *opts.MaxDepth >= 0:
+		spec["maxDepth"] = *opts.MaxDepth
+	}
+
+	if opts.DepthField != "" {
+		spec["depthField"] = opts.DepthField
+	}
+
+	restrict := scopeQuery(from, opts.Restrict)
+	if len(restrict) > 0 {
+		spec["restrictSearchWithMatch"] = restrict
+	}
+
+	p := Pipeline{}
+	if len(q) > 0 {
+		p = p.Match(q)
+	}
+
+	return p.Stage("$graphLookup", spec), nil
+}
@@ -0,0 +1,71 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"testing"
+)
+
+type employee struct {
+	Id        bson.ObjectId `bson:"_id"`
+	Name      string        `bson:"name"`
+	ManagerId bson.ObjectId `bson:"managerid,omitempty"`
+}
+
+func graphSpec(t *testing.T, opts GraphOptions) bson.M {
+	t.Helper()
+
+	p, err := graphPipeline(&employee{}, bson.M{"name": "Judy"}, opts)
+	if err != nil {
+		t.Fatal("Couldn't build the pipeline:", err)
+	}
+	if len(p) != 2 || p[0]["$match"] == nil {
+		t.Fatal("Expected a match and a $graphLookup stage got:", p)
+	}
+	return p[1]["$graphLookup"].(bson.M)
+}
+
+func TestGraphPipeline(t *testing.T) {
+	opts := GraphOptions{StartWith: "$managerid", ConnectFromField: "managerid", ConnectToField: "_id", As: "chain", DepthField: "hops"}
+
+	spec := graphSpec(t, opts)
+	if spec["from"] != PhysicalCollection("employee") || spec["startWith"] != "$managerid" || spec["as"] != "chain" || spec["depthField"] != "hops" {
+		t.Fatal("Unexpected $graphLookup stage:", spec)
+	}
+	if spec["maxDepth"] != DefaultGraphDepth {
+		t.Fatal("Expected the default depth got:", spec["maxDepth"])
+	}
+	if _, ok := spec["restrictSearchWithMatch"]; ok {
+		t.Fatal("Expected no restriction got:", spec["restrictSearchWithMatch"])
+	}
+
+	opts.Restrict = bson.M{"active": true}
+	if spec := graphSpec(t, opts); spec["restrictSearchWithMatch"] == nil {
+		t.Fatal("Expected the restriction got:", spec)
+	}
+}
+
+func TestGraphPipelineMaxDepth(t *testing.T) {
+	opts := GraphOptions{StartWith: "$managerid", ConnectFromField: "managerid", ConnectToField: "_id", As: "chain"}
+
+	opts.MaxDepth = Depth(0)
+	if spec := graphSpec(t, opts); spec["maxDepth"] != 0 {
+		t.Fatal("Expected a depth of zero got:", spec["maxDepth"])
+	}
+
+	opts.MaxDepth = Depth(3)
+	if spec := graphSpec(t, opts); spec["maxDepth"] != 3 {
+		t.Fatal("Expected a depth of 3 got:", spec["maxDepth"])
+	}
+
+	opts.MaxDepth = Depth(-1)
+	if spec := graphSpec(t, opts); spec["maxDepth"] != nil {
+		t.Fatal("Expected no depth limit got:", spec["maxDepth"])
+	}
+}
+
+func TestGraphPipelineMissingOptions(t *testing.T) {
+	if _, err := graphPipeline(&employee{}, nil, GraphOptions{StartWith: "$managerid"}); err == nil {
+		t.Fatal("Expected an error for missing options")
+	}
+}
@@ -0,0 +1,232 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// Returns a stable hash of the stored representation of i, a struct or a
+// pointer to one. Key order doesn't matter, so a record hashes the same
+// before it's saved and after it's loaded, which makes the hash usable as a
+// cache key or an ETag.
+func Hash(i interface{}) (string, error) {
+	rec, err := marshalRecord(i)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := bson.Marshal(rec)
+	if err != nil {
+		return "", err
+	}
+
+	return hashDoc(data)
+}
+
+// Find records like Find and return the hash of what was found. For a slice
+// the hash covers every record in order. An HTTP handler can use it for
+// ETags:
+//
+//	etag, err := mongo.FindWithHash(user, bson.M{"_id": id})
+//	if r.Header.Get("If-None-Match") == `"`+etag+`"` {
+//		w.WriteHeader(http.StatusNotModified)
+//		return
+//	}
+func FindWithHash(i interface{}, q bson.M, sortFields ...string) (string, error) {
+	return std.FindWithHash(i, q, sortFields...)
+}
+
+// Find records and their hash using the client. See FindWithHash.
+func (c *Client) FindWithHash(i interface{}, q bson.M, sortFields ...string) (string, error) {
+	if !isPtr(i) {
+		return "", NoPtr
+	}
+
+	q = scopeQuery(i, q)
+	op := c.startOp("find", collName(i), q)
+
+	if err := op.allowed(); err != nil {
+		return "", op.done(err)
+	}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return "", op.done(err)
+	}
+	defer s.Close()
+
+	if q, err = op.check(c, s, q, !isSlice(reflect.TypeOf(i))); err != nil {
+		return "", op.done(err)
+	}
+
+	query := c.GetColl(s, op.coll).Find(q).Sort(sortFields...)
+
+	if !isSlice(reflect.TypeOf(i)) {
+		var raw bson.Raw
+		if err := query.One(&raw); err != nil {
+			return "", op.done(err)
+		}
+		if err := unmarshalRecord(c, s, raw, i); err != nil {
+			return "", op.done(err)
+		}
+
+		h, err := hashDoc(raw.Data)
+		return h, op.done(err)
+	}
+
+	var raws []bson.Raw
+	if err := query.All(&raws); err != nil {
+		return "", op.done(err)
+	}
+	if err := unmarshalAll(c, s, raws, i); err != nil {
+		return "", op.done(err)
+	}
+
+	sum := sha256.New()
+	for _, raw := range raws {
+		h, err := hashDoc(raw.Data)
+		if err != nil {
+			return "", op.done(err)
+		}
+		sum.Write([]byte(h))
+	}
+	return hex.EncodeToString(sum.Sum(nil)), op.done(nil)
+}
+
+// Returns a hash of the records like i matching q that doesn't depend on
+// their order, so two deployments can cheaply check they hold the same
+// reference data:
+//
+//	sum, err := mongo.Checksum(&Country{}, nil, "code", "name")
+//
+// Only the given fields, which are document keys, are hashed; the _id is
+// left out unless it's listed, since it usually differs between
+// deployments. With no fields every field is hashed, including the _id.
+func Checksum(i interface{}, q bson.M, fields ...string) (string, error) {
+	return std.Checksum(i, q, fields...)
+}
+
+// Checksum records using the client. See Checksum.
+func (c *Client) Checksum(i interface{}, q bson.M, fields ...string) (string, error) {
+	q = scopeQuery(i, q)
+	op := c.startOp("find", collName(i), q)
+
+	if err := op.allowed(); err != nil {
+		return "", op.done(err)
+	}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return "", op.done(err)
+	}
+	defer s.Close()
+
+	query := c.GetColl(s, op.coll).Find(q)
+	if len(fields) > 0 {
+		proj := bson.M{"_id": 0}
+		for _, f := range fields {
+			proj[f] = 1
+		}
+		query = query.Select(proj)
+	}
+
+	sum := newChecksum()
+	iter := query.Iter()
+
+	var raw bson.Raw
+	for iter.Next(&raw) {
+		h, err := hashDoc(raw.Data)
+		if err != nil {
+			iter.Close()
+			return "", op.done(err)
+		}
+		if err := sum.add(h); err != nil {
+			iter.Close()
+			return "", op.done(err)
+		}
+	}
+	if err := iter.Close(); err != nil {
+		return "", op.done(err)
+	}
+
+	return sum.String(), op.done(nil)
+}
+
+// checksum combines document hashes independently of their order by adding
+// them modulo 2^256. Unlike xor, identical documents don't cancel out.
+type checksum struct {
+	sum *big.Int
+	n   int
+}
+
+var checksumMod = new(big.Int).Lsh(big.NewInt(1), 256)
+
+func newChecksum() *checksum {
+	return &checksum{sum: new(big.Int)}
+}
+
+// add adds a hex encoded sha256 hash.
+func (c *checksum) add(h string) error {
+	b, err := hex.DecodeString(h)
+	if err != nil {
+		return err
+	}
+
+	c.sum.Add(c.sum, new(big.Int).SetBytes(b))
+	c.sum.Mod(c.sum, checksumMod)
+	c.n++
+	return nil
+}
+
+func (c *checksum) String() string {
+	sum := sha256.Sum256([]byte(strconv.Itoa(c.n) + ":" + c.sum.Text(16)))
+	return hex.EncodeToString(sum[:])
+}
+
+// hashDoc hashes a marshaled document after sorting its keys.
+func hashDoc(data []byte) (string, error) {
+	var doc bson.D
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		return "", err
+	}
+
+	canon, err := bson.Marshal(canonical(doc))
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(canon)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// canonical returns v with the keys of every document sorted.
+func canonical(v interface{}) interface{} {
+	switch t := v.(type) {
+	case bson.D:
+		d := make(bson.D, len(t))
+		for n, e := range t {
+			d[n] = bson.DocElem{Name: e.Name, Value: canonical(e.Value)}
+		}
+		sort.Slice(d, func(a, b int) bool { return d[a].Name < d[b].Name })
+		return d
+	case bson.M:
+		d := make(bson.D, 0, len(t))
+		for k, e := range t {
+			d = append(d, bson.DocElem{Name: k, Value: e})
+		}
+		return canonical(d)
+	case []interface{}:
+		arr := make([]interface{}, len(t))
+		for n, e := range t {
+			arr[n] = canonical(e)
+		}
+		return arr
+	}
+	return v
+}
@@ -0,0 +1,70 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"strings"
+	"testing"
+)
+
+func TestCanonical(t *testing.T) {
+	a := bson.D{{Name: "b", Value: 1}, {Name: "a", Value: bson.D{{Name: "y", Value: 2}, {Name: "x", Value: 3}}}}
+	b := bson.D{{Name: "a", Value: bson.M{"x": 3, "y": 2}}, {Name: "b", Value: 1}}
+
+	ca, cb := canonical(a).(bson.D), canonical(b).(bson.D)
+	if ca[0].Name != "a" || cb[0].Name != "a" {
+		t.Fatal("Expected keys to be sorted:", ca, cb)
+	}
+
+	inner := ca[0].Value.(bson.D)
+	if inner[0].Name != "x" || cb[0].Value.(bson.D)[0].Name != "x" {
+		t.Fatal("Expected nested keys to be sorted:", ca, cb)
+	}
+}
+
+func TestHashStable(t *testing.T) {
+	rec := &MongoTest{Id: bson.NewObjectId(), Name: "George"}
+
+	h1, err := Hash(rec)
+	if err != nil {
+		t.Fatal("Couldn't hash:", err)
+	}
+
+	h2, _ := Hash(rec)
+	if h1 != h2 || len(h1) != 64 {
+		t.Fatal("Expected a stable sha256 hex hash:", h1, h2)
+	}
+
+	rec.Name = "Jane"
+	if h3, _ := Hash(rec); h3 == h1 {
+		t.Fatal("Expected the hash to change with the record")
+	}
+}
+
+func TestChecksumOrder(t *testing.T) {
+	hashes := []string{
+		strings.Repeat("ff", 32),
+		strings.Repeat("01", 32),
+		strings.Repeat("ab", 32),
+	}
+
+	sum := func(hs ...string) string {
+		c := newChecksum()
+		for _, h := range hs {
+			if err := c.add(h); err != nil {
+				t.Fatal("Couldn't add the hash:", err)
+			}
+		}
+		return c.String()
+	}
+
+	if sum(hashes...) != sum(hashes[2], hashes[0], hashes[1]) {
+		t.Fatal("Expected the checksum not to depend on order")
+	}
+	if sum(hashes[0], hashes[0]) == sum() {
+		t.Fatal("Expected duplicates not to cancel out")
+	}
+	if sum(hashes[0]) == sum(hashes[0], hashes[0]) {
+		t.Fatal("Expected duplicates to count")
+	}
+}
@@ -0,0 +1,139 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// HealthTimeout bounds how long a health check waits for the servers.
+var HealthTimeout = 2 * time.Second
+
+// Replica set roles reported by CheckHealth.
+const (
+	RolePrimary    = "primary"
+	RoleSecondary  = "secondary"
+	RoleArbiter    = "arbiter"
+	RoleMongos     = "mongos"
+	RoleStandalone = "standalone"
+	RoleOther      = "other"
+)
+
+// Health is the result of a health check. Error is a short description of
+// what failed; it never includes connection details. LagMs is the
+// replication lag of a replica set, see ReplicationLag, and PrimaryFallback
+// is true while reads are sent to the primary because the lag exceeds the
+// max staleness of the read preference, or because it can't be measured, in
+// which case LagError says why.
+type Health struct {
+	OK              bool      `json:"ok"`
+	Role            string    `json:"role,omitempty"`
+	SetName         string    `json:"setName,omitempty"`
+	LatencyMs       float64   `json:"latencyMs"`
+	LagMs           float64   `json:"lagMs,omitempty"`
+	PrimaryFallback bool      `json:"primaryFallback,omitempty"`
+	LagError        string    `json:"lagError,omitempty"`
+	Error           string    `json:"error,omitempty"`
+	CheckedAt       time.Time `json:"checkedAt"`
+}
+
+// Check the connection used by the package level functions: that the
+// servers answer a ping, how long it took, the role of the server and, for
+// a replica set, how far the secondaries lag behind.
+func CheckHealth() *Health {
+	return std.CheckHealth()
+}
+
+// Check the health of the connection using the client. See CheckHealth.
+func (c *Client) CheckHealth() *Health {
+	h := &Health{CheckedAt: now()}
+
+	s, err := c.GetSession()
+	if err != nil {
+		h.Error = "Couldn't connect"
+		return h
+	}
+	defer s.Close()
+	s.SetSyncTimeout(HealthTimeout)
+	s.SetSocketTimeout(HealthTimeout)
+
+	start := time.Now()
+	if err := s.Ping(); err != nil {
+		h.Error = "Ping failed"
+		return h
+	}
+	h.LatencyMs = float64(time.Since(start)) / float64(time.Millisecond)
+
+	var im struct {
+		IsMaster    bool   `bson:"ismaster"`
+		Secondary   bool   `bson:"secondary"`
+		ArbiterOnly bool   `bson:"arbiterOnly"`
+		SetName     string `bson:"setName"`
+		Msg         string `bson:"msg"`
+	}
+	if err := s.Run(bson.D{{Name: "isMaster", Value: 1}}, &im); err != nil {
+		h.Error = "isMaster failed"
+		return h
+	}
+
+	h.OK = true
+	h.SetName = im.SetName
+	h.Role = role(im.IsMaster, im.Secondary, im.ArbiterOnly, im.SetName, im.Msg)
+
+	// A lag that can't be measured, e.g. for lack of a secondary, doesn't
+	// make the check fail.
+	if im.SetName != "" {
+		if lag, err := secondaryLag(s); err == nil {
+			h.LagMs = float64(lag) / float64(time.Millisecond)
+		}
+	}
+	h.PrimaryFallback = c.read.primaryFallback()
+	if err := c.LagError(); err != nil {
+		h.LagError = err.Error()
+	}
+	return h
+}
+
+func role(isMaster, secondary, arbiter bool, setName, msg string) string {
+	switch {
+	case msg == "isdbgrid":
+		return RoleMongos
+	case arbiter:
+		return RoleArbiter
+	case isMaster && setName != "":
+		return RolePrimary
+	case isMaster:
+		return RoleStandalone
+	case secondary:
+		return RoleSecondary
+	}
+	return RoleOther
+}
+
+// Returns an http.Handler that runs CheckHealth and writes the result as
+// JSON, with 200 OK when healthy and 503 Service Unavailable otherwise. It's
+// meant for readiness probes:
+//
+//	http.Handle("/healthz", mongo.HealthHandler())
+func HealthHandler() http.Handler {
+	return std.HealthHandler()
+}
+
+// Returns a health check handler using the client. See HealthHandler.
+func (c *Client) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := c.CheckHealth()
+
+		status := http.StatusOK
+		if !h.OK {
+			status = http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(h)
+	})
+}
@@ -0,0 +1,25 @@
+package mongo
+
+import (
+	"testing"
+)
+
+func TestRole(t *testing.T) {
+	cases := []struct {
+		isMaster, secondary, arbiter bool
+		setName, msg, expected       string
+	}{
+		{true, false, false, "rs0", "", RolePrimary},
+		{false, true, false, "rs0", "", RoleSecondary},
+		{false, false, true, "rs0", "", RoleArbiter},
+		{true, false, false, "", "", RoleStandalone},
+		{true, false, false, "", "isdbgrid", RoleMongos},
+		{false, false, false, "rs0", "", RoleOther},
+	}
+
+	for _, c := range cases {
+		if r := role(c.isMaster, c.secondary, c.arbiter, c.setName, c.msg); r != c.expected {
+			t.Errorf("Expected %v, got %v for %+v", c.expected, r, c)
+		}
+	}
+}
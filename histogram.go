@@ -0,0 +1,139 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"errors"
+	"sort"
+	"strconv"
+)
+
+// OtherBucket is the label of the bucket holding values outside the
+// boundaries passed to Histogram.
+const OtherBucket = "other"
+
+// Bucket is a single bar of a histogram. Min is inclusive and Max is
+// exclusive, except for the last bucket from HistogramAuto.
+type Bucket struct {
+	Label string
+	Min   float64
+	Max   float64
+	Count int
+}
+
+// Build a histogram of a numeric field across the documents matching q using
+// $bucket. Boundaries must be sorted and contain at least two values; each
+// pair of adjacent boundaries becomes a bucket, empty buckets included.
+// Values outside the boundaries, or that aren't numbers, are counted in a
+// final bucket labeled OtherBucket if there are any.
+func Histogram(i interface{}, field string, boundaries []float64, q bson.M) ([]Bucket, error) {
+	return std.Histogram(i, field, boundaries, q)
+}
+
+// Count records into buckets using the client. See Histogram.
+func (c *Client) Histogram(i interface{}, field string, boundaries []float64, q bson.M) ([]Bucket, error) {
+	if len(boundaries) < 2 || !sort.Float64sAreSorted(boundaries) {
+		return nil, errors.New("Histogram needs at least two sorted boundaries")
+	}
+
+	var res []histogramRow
+	if err := c.Aggregate(i, histogramPipeline(field, boundaries, q), &res); err != nil {
+		return nil, err
+	}
+
+	return histogramBuckets(boundaries, res), nil
+}
+
+// histogramRow is a bucket returned by $bucket.
+type histogramRow struct {
+	Id    interface{} `bson:"_id"`
+	Count int
+}
+
+func histogramPipeline(field string, boundaries []float64, q bson.M) Pipeline {
+	p := Pipeline{}
+	if len(q) > 0 {
+		p = p.Match(q)
+	}
+	return p.Stage("$bucket", bson.M{
+		"groupBy":    "$" + field,
+		"boundaries": boundaries,
+		"default":    OtherBucket,
+		"output":     bson.M{"count": bson.M{"$sum": 1}},
+	})
+}
+
+// histogramBuckets turns the rows returned by $bucket into a bucket for
+// every pair of boundaries, plus OtherBucket if it isn't empty.
+func histogramBuckets(boundaries []float64, res []histogramRow) []Bucket {
+	counts := map[float64]int{}
+	other := 0
+	for _, r := range res {
+		if f, ok := numeric(r.Id); ok {
+			counts[f] = r.Count
+		} else {
+			other = r.Count
+		}
+	}
+
+	buckets := make([]Bucket, 0, len(boundaries))
+	for n := 0; n < len(boundaries)-1; n++ {
+		min, max := boundaries[n], boundaries[n+1]
+		buckets = append(buckets, Bucket{
+			Label: bucketLabel(min, max),
+			Min:   min,
+			Max:   max,
+			Count: counts[min],
+		})
+	}
+
+	if other > 0 {
+		buckets = append(buckets, Bucket{Label: OtherBucket, Count: other})
+	}
+
+	return buckets
+}
+
+// Build a histogram of a numeric field with the given number of buckets
+// using $bucketAuto, which picks boundaries that spread the documents evenly.
+func HistogramAuto(i interface{}, field string, buckets int, q bson.M) ([]Bucket, error) {
+	return std.HistogramAuto(i, field, buckets, q)
+}
+
+// Count records into even buckets using the client. See HistogramAuto.
+func (c *Client) HistogramAuto(i interface{}, field string, buckets int, q bson.M) ([]Bucket, error) {
+	var res []struct {
+		Id struct {
+			Min interface{}
+			Max interface{}
+		} `bson:"_id"`
+		Count int
+	}
+	if err := c.Aggregate(i, histogramAutoPipeline(field, buckets, q), &res); err != nil {
+		return nil, err
+	}
+
+	out := make([]Bucket, len(res))
+	for n, r := range res {
+		min, max := toFloat(r.Id.Min), toFloat(r.Id.Max)
+		out[n] = Bucket{Label: bucketLabel(min, max), Min: min, Max: max, Count: r.Count}
+	}
+
+	return out, nil
+}
+
+func histogramAutoPipeline(field string, buckets int, q bson.M) Pipeline {
+	p := Pipeline{}
+	if len(q) > 0 {
+		p = p.Match(q)
+	}
+	return p.Stage("$bucketAuto", bson.M{
+		"groupBy": "$" + field,
+		"buckets": buckets,
+		"output":  bson.M{"count": bson.M{"$sum": 1}},
+	})
+}
+
+func bucketLabel(min, max float64) string {
+	return strconv.FormatFloat(min, 'g', -1, 64) + "-" + strconv.FormatFloat(max, 'g', -1, 64)
+}
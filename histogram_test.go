@@ -0,0 +1,58 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"reflect"
+	"testing"
+)
+
+func TestHistogramPipeline(t *testing.T) {
+	p := histogramPipeline("price", []float64{0, 10, 100}, bson.M{"active": true})
+
+	want := Pipeline{
+		{"$match": bson.M{"active": true}},
+		{"$bucket": bson.M{
+			"groupBy":    "$price",
+			"boundaries": []float64{0, 10, 100},
+			"default":    OtherBucket,
+			"output":     bson.M{"count": bson.M{"$sum": 1}},
+		}},
+	}
+	if !reflect.DeepEqual(p, want) {
+		t.Fatal("Unexpected pipeline:", p)
+	}
+
+	auto := histogramAutoPipeline("price", 4, nil)
+	if len(auto) != 1 || auto[0]["$bucketAuto"].(bson.M)["buckets"] != 4 {
+		t.Fatal("Unexpected $bucketAuto pipeline:", auto)
+	}
+}
+
+func TestHistogramBuckets(t *testing.T) {
+	res := []histogramRow{{Id: 0.0, Count: 3}, {Id: 100, Count: 1}, {Id: OtherBucket, Count: 2}}
+	buckets := histogramBuckets([]float64{0, 10, 100, 1000}, res)
+
+	want := []Bucket{
+		{Label: "0-10", Min: 0, Max: 10, Count: 3},
+		{Label: "10-100", Min: 10, Max: 100, Count: 0},
+		{Label: "100-1000", Min: 100, Max: 1000, Count: 1},
+		{Label: OtherBucket, Count: 2},
+	}
+	if !reflect.DeepEqual(buckets, want) {
+		t.Fatal("Expected empty buckets and the other bucket got:", buckets)
+	}
+
+	if buckets := histogramBuckets([]float64{0, 10}, nil); len(buckets) != 1 || buckets[0].Count != 0 {
+		t.Fatal("Expected a single empty bucket got:", buckets)
+	}
+}
+
+func TestHistogramBoundaries(t *testing.T) {
+	if _, err := Histogram(&MongoTest{}, "price", []float64{10, 0}, nil); err == nil {
+		t.Fatal("Expected an error for unsorted boundaries")
+	}
+	if _, err := Histogram(&MongoTest{}, "price", []float64{0}, nil); err == nil {
+		t.Fatal("Expected an error for a single boundary")
+	}
+}
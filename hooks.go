@@ -0,0 +1,110 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+)
+
+// The features that follow writes, write suppression, model caches,
+// CountsCache, RefCount and tombstones, are kept up to date by the hooks in
+// this file, which every write path calls once it has written.
+
+// written describes a write of a single record for afterWrite.
+type written struct {
+	id interface{}
+	// before is the stored record as countedDoc returned it, nil if the
+	// record was created or isn't counted.
+	before bson.M
+	// after is the document written, nil if the record was deleted.
+	after interface{}
+	// created is set if the write inserted the record.
+	created bool
+	// hash is the write suppression hash of after, see writeHash. Empty
+	// forgets the record.
+	hash string
+}
+
+// afterWrite runs the write hooks for a write of a single record of i's
+// type. Only writing a tombstone can fail; like countWrite, counting errors
+// are logged since the write has already happened.
+func (c *Client) afterWrite(s *mgo.Session, i interface{}, w written) error {
+	coll := collName(i)
+
+	// A created record has no earlier write to remember.
+	if !w.created {
+		rememberWrite(writeKey(c.Database(), coll, w.id), w.hash)
+	}
+	invalidateModelCaches(coll)
+
+	if w.before != nil || w.created {
+		countWrite(c.GetColl(s, CountsCollection), i, w.before, w.after)
+		countRefs(c, s, i, w.before, w.after)
+	}
+
+	if w.after == nil && deletesTracked(i) {
+		return writeTombstone(c.GetColl(s, TombstoneCollection), coll, w.id)
+	}
+	return nil
+}
+
+// afterCollectionWrite runs the write hooks for a write to coll by name, or
+// one that can't tell which records it changed. Write suppression forgets
+// the collection's records and their counters are marked dirty. deleted
+// holds the ids, from trackedIds, of the records the write may have
+// deleted; those that are gone get a tombstone.
+func (c *Client) afterCollectionWrite(s *mgo.Session, coll string, deleted []interface{}) error {
+	forgetWrites(writeKey(c.Database(), coll, ""))
+	invalidateModelCaches(coll)
+	markCountsDirty(coll)
+	markRefCountsDirty(coll)
+
+	if len(deleted) == 0 {
+		return nil
+	}
+
+	var left []struct {
+		Id interface{} `bson:"_id"`
+	}
+	err := c.GetColl(s, coll).Find(bson.M{"_id": bson.M{"$in": deleted}}).Select(bson.M{"_id": 1}).All(&left)
+	if err != nil {
+		return err
+	}
+
+	kept := map[string]bool{}
+	for _, doc := range left {
+		kept[cacheId(doc.Id)] = true
+	}
+
+	tombstones := c.GetColl(s, TombstoneCollection)
+	for _, id := range deleted {
+		if kept[cacheId(id)] {
+			continue
+		}
+		if err := writeTombstone(tombstones, coll, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// trackedIds returns the ids of the records of coll matching q if deletes
+// of them leave a tombstone, to pass to afterCollectionWrite after deleting
+// them.
+func (c *Client) trackedIds(s *mgo.Session, coll string, q interface{}) ([]interface{}, error) {
+	if !collectionDeletesTracked(coll) {
+		return nil, nil
+	}
+
+	var docs []struct {
+		Id interface{} `bson:"_id"`
+	}
+	if err := c.GetColl(s, coll).Find(q).Select(bson.M{"_id": 1}).All(&docs); err != nil {
+		return nil, err
+	}
+
+	ids := make([]interface{}, len(docs))
+	for n, doc := range docs {
+		ids[n] = doc.Id
+	}
+	return ids, nil
+}
@@ -0,0 +1,172 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// ErrRequestInProgress is returned by Idempotency.CheckAndStore when a
+// request with the key is still being handled.
+var ErrRequestInProgress = errors.New("Request with this idempotency key is in progress")
+
+// IdempotentRequest is the journal entry for an idempotency key.
+type IdempotentRequest struct {
+	Key  string `bson:"_id"`
+	Done bool   `bson:"done"`
+	// ResponseHash is the sha256 of the response, in hex, so a retry can be
+	// checked against it even if the response isn't kept.
+	ResponseHash string    `bson:"responsehash,omitempty"`
+	Response     []byte    `bson:"response,omitempty"`
+	CreatedAt    time.Time `bson:"createdat"`
+	ExpiresAt    time.Time `bson:"expiresat"`
+}
+
+// Idempotency is a journal of requests by idempotency key, e.g. the
+// Idempotency-Key header, so retried requests aren't handled twice:
+//
+//	prior, err := journal.CheckAndStore(key)
+//	switch {
+//	case errors.Is(err, mongo.ErrRequestInProgress):
+//		w.WriteHeader(http.StatusConflict)
+//	case err != nil:
+//		...
+//	case prior != nil:
+//		w.Write(prior.Response)
+//	default:
+//		resp, err := handle(r)
+//		if err != nil {
+//			journal.Release(key)
+//			...
+//		}
+//		journal.Complete(key, resp, true)
+//		w.Write(resp)
+//	}
+type Idempotency struct {
+	client     *Client
+	collection string
+	ttl        time.Duration
+}
+
+// Create a journal kept in the named collection. Keys are forgotten ttl
+// after they're first stored; call EnsureIndexes to have the server remove
+// them.
+func NewIdempotency(collection string, ttl time.Duration) *Idempotency {
+	return std.NewIdempotency(collection, ttl)
+}
+
+// Create an idempotency journal using the client. See NewIdempotency.
+func (c *Client) NewIdempotency(collection string, ttl time.Duration) *Idempotency {
+	return &Idempotency{client: c, collection: collection, ttl: ttl}
+}
+
+// Create the index that removes expired keys.
+func (j *Idempotency) EnsureIndexes() error {
+	s, err := j.client.GetSession()
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	return j.client.GetColl(s, j.collection).EnsureIndex(mgo.Index{Key: []string{"expiresat"}, ExpireAfter: time.Second})
+}
+
+// Store key if it's new and return nil, in which case the request should be
+// handled and then passed to Complete or Release. If the key was already
+// stored the finished request is returned instead, or ErrRequestInProgress
+// if it isn't finished yet. Storing is atomic, so only one of several
+// concurrent requests with the same key gets nil.
+func (j *Idempotency) CheckAndStore(key string) (*IdempotentRequest, error) {
+	op := j.client.startOp("insert", j.collection, bson.M{"_id": key})
+
+	if err := op.allowed(); err != nil {
+		return nil, op.done(err)
+	}
+
+	s, err := j.client.GetSession()
+	if err != nil {
+		return nil, op.done(err)
+	}
+	defer s.Close()
+
+	coll := j.client.GetColl(s, j.collection)
+	ts := now()
+	req := IdempotentRequest{Key: key, CreatedAt: ts, ExpiresAt: ts.Add(j.ttl)}
+
+	err = coll.Insert(req)
+	if err == nil || !mgo.IsDup(err) {
+		return nil, op.done(err)
+	}
+
+	var prior IdempotentRequest
+	if err := coll.FindId(key).One(&prior); err != nil {
+		return nil, op.done(err)
+	}
+
+	// The server removes expired keys only once a minute, so take over one
+	// that's expired but still there.
+	if !prior.ExpiresAt.After(ts) {
+		err := coll.Update(bson.M{"_id": key, "expiresat": prior.ExpiresAt}, req)
+		if err == nil {
+			return nil, op.done(nil)
+		}
+		if err != mgo.ErrNotFound {
+			return nil, op.done(err)
+		}
+		return nil, op.done(ErrRequestInProgress)
+	}
+
+	if !prior.Done {
+		return nil, op.done(ErrRequestInProgress)
+	}
+	return &prior, op.done(nil)
+}
+
+// Mark the request with key as finished with response. Only the hash of the
+// response is kept unless keep is true.
+func (j *Idempotency) Complete(key string, response []byte, keep bool) error {
+	op := j.client.startOp("update", j.collection, bson.M{"_id": key})
+
+	if err := op.allowed(); err != nil {
+		return op.done(err)
+	}
+
+	s, err := j.client.GetSession()
+	if err != nil {
+		return op.done(err)
+	}
+	defer s.Close()
+
+	sum := sha256.Sum256(response)
+	set := bson.M{"done": true, "responsehash": hex.EncodeToString(sum[:])}
+	if keep {
+		set["response"] = response
+	}
+
+	return op.done(j.client.GetColl(s, j.collection).UpdateId(key, bson.M{"$set": set}))
+}
+
+// Forget key, e.g. when handling the request failed and it may be retried.
+func (j *Idempotency) Release(key string) error {
+	op := j.client.startOp("delete", j.collection, bson.M{"_id": key})
+
+	if err := op.allowed(); err != nil {
+		return op.done(err)
+	}
+
+	s, err := j.client.GetSession()
+	if err != nil {
+		return op.done(err)
+	}
+	defer s.Close()
+
+	err = j.client.GetColl(s, j.collection).RemoveId(key)
+	if err == mgo.ErrNotFound {
+		err = nil
+	}
+	return op.done(err)
+}
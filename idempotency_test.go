@@ -0,0 +1,24 @@
+package mongo
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyAccessPolicy(t *testing.T) {
+	SetAccessPolicy("idempotency", AllowFind)
+	defer ClearAccessPolicy("idempotency")
+
+	j := NewIdempotency("idempotency", time.Hour)
+
+	if _, err := j.CheckAndStore("key"); !errors.Is(err, ErrAccessDenied) {
+		t.Fatal("Expected ErrAccessDenied got:", err)
+	}
+	if err := j.Complete("key", []byte("ok"), true); !errors.Is(err, ErrAccessDenied) {
+		t.Fatal("Expected ErrAccessDenied got:", err)
+	}
+	if err := j.Release("key"); !errors.Is(err, ErrAccessDenied) {
+		t.Fatal("Expected ErrAccessDenied got:", err)
+	}
+}
@@ -0,0 +1,214 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// Statuses of the records of an import.
+const (
+	ImportCreated = "created"
+	ImportUpdated = "updated"
+	ImportSkipped = "skipped"
+	ImportFailed  = "failed"
+)
+
+// ImportOptions configures ImportWithReport.
+type ImportOptions struct {
+	// Model is a pointer to a struct of the type to import.
+	Model interface{}
+	// Key is the document key records are matched on, e.g. "email".
+	// Defaults to "_id".
+	Key string
+	// SkipExisting leaves records that already exist alone rather than
+	// updating them.
+	SkipExisting bool
+}
+
+// ImportResult is what happened to a single record of an import. Record
+// counts from one in the order of the input.
+type ImportResult struct {
+	Record int         `json:"record"`
+	Key    interface{} `json:"key,omitempty"`
+	Status string      `json:"status"`
+	Reason string      `json:"reason,omitempty"`
+}
+
+// ImportReport describes an import record by record, for operator facing
+// import screens.
+type ImportReport struct {
+	Created int            `json:"created"`
+	Updated int            `json:"updated"`
+	Skipped int            `json:"skipped"`
+	Failed  int            `json:"failed"`
+	Results []ImportResult `json:"results"`
+}
+
+func (r *ImportReport) add(res ImportResult) {
+	switch res.Status {
+	case ImportCreated:
+		r.Created++
+	case ImportUpdated:
+		r.Updated++
+	case ImportSkipped:
+		r.Skipped++
+	case ImportFailed:
+		r.Failed++
+	}
+	r.Results = append(r.Results, res)
+}
+
+// Import JSON records from r, either an array or one object after another,
+// e.g. one per line. Each record is decoded into the model, failing on
+// fields the model doesn't have, normalized and validated, and then upserted
+// by opts.Key like Upsert. Records that can't be imported are reported as
+// failed with the reason and don't stop the import, and a record whose key
+// was already seen in the input is skipped:
+//
+//	report, err := mongo.ImportWithReport(file, mongo.ImportOptions{Model: &User{}, Key: "email"})
+//
+// An error is only returned when the input can't be read or the database
+// can't be reached; the report then covers the records handled before.
+func ImportWithReport(r io.Reader, opts ImportOptions) (*ImportReport, error) {
+	return std.ImportWithReport(r, opts)
+}
+
+// Import records using the client. See ImportWithReport.
+func (c *Client) ImportWithReport(r io.Reader, opts ImportOptions) (*ImportReport, error) {
+	t := structType(opts.Model)
+	if t == nil || !isPtr(opts.Model) {
+		return nil, NoPtr
+	}
+
+	key := opts.Key
+	if key == "" {
+		key = "_id"
+	}
+
+	report := &ImportReport{Results: []ImportResult{}}
+	seen := map[string]int{}
+
+	err := readJSONRecords(r, func(n int, data json.RawMessage) error {
+		res, err := c.importRecord(reflect.New(t).Interface(), data, key, opts.SkipExisting, seen, n)
+		res.Record = n
+		report.add(res)
+
+		if err != nil && isTimeout(err) {
+			return err
+		}
+		return nil
+	})
+	return report, err
+}
+
+// importRecord imports the nth record, decoding it into rec. The error is
+// the one that failed the record, if any.
+func (c *Client) importRecord(rec interface{}, data json.RawMessage, key string, skipExisting bool, seen map[string]int, n int) (ImportResult, error) {
+	failed := func(err error) (ImportResult, error) {
+		return ImportResult{Status: ImportFailed, Reason: err.Error()}, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(rec); err != nil {
+		return failed(err)
+	}
+
+	if err := normalize(rec); err != nil {
+		return failed(err)
+	}
+	if err := validate(rec); err != nil {
+		return failed(err)
+	}
+
+	doc, err := recordDoc(rec)
+	if err != nil {
+		return failed(err)
+	}
+	value := doc[key]
+	if isEmptyValue(value) {
+		return failed(fmt.Errorf("Missing %v", key))
+	}
+
+	res := ImportResult{Key: value}
+	dupKey := fmt.Sprintf("%#v", value)
+	if first, ok := seen[dupKey]; ok {
+		res.Status, res.Reason = ImportSkipped, fmt.Sprintf("Same %v as record %v", key, first)
+		return res, nil
+	}
+	seen[dupKey] = n
+
+	var created bool
+	if skipExisting {
+		created, err = c.GetOrCreate(rec, bson.M{key: value}, nil)
+	} else {
+		created, err = c.Upsert(rec, bson.M{key: value})
+	}
+
+	switch {
+	case err != nil:
+		res.Status, res.Reason = ImportFailed, err.Error()
+	case created:
+		res.Status = ImportCreated
+	case skipExisting:
+		res.Status, res.Reason = ImportSkipped, "Already exists"
+	default:
+		res.Status = ImportUpdated
+	}
+	return res, err
+}
+
+// readJSONRecords calls fn with each value of a JSON array, or each of a
+// sequence of JSON values, numbered from one.
+func readJSONRecords(r io.Reader, fn func(n int, data json.RawMessage) error) error {
+	br := bufio.NewReader(r)
+	array := false
+	for {
+		b, err := br.ReadByte()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if b == ' ' || b == '\t' || b == '\r' || b == '\n' {
+			continue
+		}
+		array = b == '['
+		br.UnreadByte()
+		break
+	}
+
+	dec := json.NewDecoder(br)
+	if array {
+		if _, err := dec.Token(); err != nil {
+			return err
+		}
+	}
+
+	for n := 1; ; n++ {
+		if array && !dec.More() {
+			_, err := dec.Token()
+			return err
+		}
+
+		var data json.RawMessage
+		err := dec.Decode(&data)
+		if err == io.EOF && !array {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := fn(n, data); err != nil {
+			return err
+		}
+	}
+}
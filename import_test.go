@@ -0,0 +1,46 @@
+package mongo
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestReadJSONRecords(t *testing.T) {
+	for _, input := range []string{
+		`[{"n": 1}, {"n": 2}]`,
+		"{\"n\": 1}\n{\"n\": 2}\n",
+	} {
+		var got []string
+		err := readJSONRecords(strings.NewReader(input), func(n int, data json.RawMessage) error {
+			got = append(got, string(data))
+			return nil
+		})
+		if err != nil {
+			t.Fatal("Couldn't read the records:", err)
+		}
+		if len(got) != 2 || got[1] != `{"n": 2}` {
+			t.Fatal("Expected two records got:", got)
+		}
+	}
+
+	if err := readJSONRecords(strings.NewReader("  "), nil); err != nil {
+		t.Fatal("Expected empty input to be fine got:", err)
+	}
+}
+
+func TestImportRecordFails(t *testing.T) {
+	seen := map[string]int{}
+
+	res, _ := std.importRecord(&validatedModel{}, json.RawMessage(`{"Name": "George", "Age": 30}`), "name", false, seen, 1)
+	if res.Status != ImportFailed || !strings.Contains(res.Reason, "Age") {
+		t.Fatal("Expected the unknown field to fail the record got:", res)
+	}
+
+	res, err := std.importRecord(&validatedModel{}, json.RawMessage(`{}`), "name", false, seen, 2)
+	var verr *ValidationError
+	if res.Status != ImportFailed || !errors.As(err, &verr) {
+		t.Fatal("Expected validation to fail the record got:", res, err)
+	}
+}
@@ -0,0 +1,622 @@
+//go:build integration
+// +build integration
+
+// Integration tests run against a real server and are only built with the
+// integration tag:
+//
+//	MONGO_TEST_URI=mongodb://localhost:27017 go test -tags integration ./...
+//
+// Each run uses a fresh database, MONGO_TEST_DB if set, which is dropped
+// afterwards. Without MONGO_TEST_URI the integration tests skip and the unit
+// tests still run. Run it against each server version supported, e.g. with
+// a container per version, since behavior differs between 3.6, 4.x and 5.x.
+package mongo
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// Environment variables configuring the integration tests.
+const (
+	EnvTestURI      = "MONGO_TEST_URI"
+	EnvTestDatabase = "MONGO_TEST_DB"
+)
+
+var (
+	testURI     string
+	testDB      string
+	testVersion mgo.BuildInfo
+	testConnErr error
+)
+
+func TestMain(m *testing.M) {
+	testURI = os.Getenv(EnvTestURI)
+	testDB = os.Getenv(EnvTestDatabase)
+	if testDB == "" {
+		testDB = "mongo_test_" + strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+
+	if testURI != "" {
+		testConnErr = connectTestServer()
+	}
+
+	code := m.Run()
+
+	if testURI != "" && testConnErr == nil {
+		if s, err := GetSession(); err == nil {
+			s.DB(testDB).DropDatabase()
+			s.Close()
+		}
+	}
+	os.Exit(code)
+}
+
+func connectTestServer() error {
+	if err := SetConfig(Config{Servers: testURI, Database: testDB, Timeout: 10 * time.Second, FailFast: true}); err != nil {
+		return err
+	}
+
+	s, err := GetSession()
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	testVersion, err = s.BuildInfo()
+	return err
+}
+
+// requireServer skips the test when no server is configured and fails it
+// when the configured one can't be reached.
+func requireServer(t *testing.T) {
+	t.Helper()
+
+	if testURI == "" {
+		t.Skip("Set " + EnvTestURI + " to run integration tests")
+	}
+	if testConnErr != nil {
+		t.Fatal("Couldn't connect to", testURI+":", testConnErr)
+	}
+}
+
+// requireVersion skips the test on servers older than version.
+func requireVersion(t *testing.T, version ...int) {
+	t.Helper()
+
+	requireServer(t)
+	if !testVersion.VersionAtLeast(version...) {
+		t.Skip("Needs server version", version, "got", testVersion.Version)
+	}
+}
+
+func TestServerConnection(t *testing.T) {
+	requireServer(t)
+	t.Log("Testing against MongoDB", testVersion.Version)
+}
+
+type integrationItem struct {
+	Id        bson.ObjectId `bson:"_id"`
+	Name      string        `bson:"name" normalize:"trim"`
+	NameSort  string        `bson:"name_sort" sortkey:"name"`
+	Qty       int           `bson:"qty"`
+	CreatedAt time.Time     `bson:"createdat"`
+	UpdatedAt time.Time     `bson:"updatedat"`
+}
+
+// optionMatrix lists the runtime options the CRUD cases run under. Every
+// case starts from the defaults.
+var optionMatrix = []struct {
+	name    string
+	options map[string]interface{}
+}{
+	{"defaults", nil},
+	{"safety", map[string]interface{}{"safety": true}},
+	{"normalizeonread", map[string]interface{}{"normalizeonread": true}},
+	{"tombstones", map[string]interface{}{"tombstones": true}},
+	{"writesuppression", map[string]interface{}{"writesuppression": "1m"}},
+	{"samplerate", map[string]interface{}{"samplerate": 1.0}},
+	{"slowquery", map[string]interface{}{"slowquery": "1ns"}},
+}
+
+// crudCases run in order against an empty collection, each depending on the
+// ones before.
+var crudCases = []struct {
+	name string
+	run  func(item *integrationItem) error
+}{
+	{"insert", func(item *integrationItem) error {
+		return Insert(item)
+	}},
+	{"find by id", func(item *integrationItem) error {
+		found := &integrationItem{}
+		if err := FindById(found, item.Id.Hex()); err != nil {
+			return err
+		}
+		if found.Name != "widget" || found.CreatedAt.IsZero() {
+			return fmt.Errorf("Expected the inserted record got: %+v", found)
+		}
+		return nil
+	}},
+	{"update", func(item *integrationItem) error {
+		item.Qty = 5
+		return Update(item)
+	}},
+	{"find updated", func(item *integrationItem) error {
+		var items []integrationItem
+		if err := Find(&items, bson.M{"qty": 5}); err != nil {
+			return err
+		}
+		if len(items) != 1 || items[0].Id != item.Id {
+			return fmt.Errorf("Expected the updated record got: %+v", items)
+		}
+		return nil
+	}},
+	{"upsert existing", func(item *integrationItem) error {
+		existing := &integrationItem{Name: "widget", Qty: 7}
+		created, err := Upsert(existing, bson.M{"name": "widget"})
+		if err != nil {
+			return err
+		}
+		if created || existing.Id != item.Id {
+			return fmt.Errorf("Expected the existing record to be updated got: %v %+v", created, existing)
+		}
+		return nil
+	}},
+	{"delete", func(item *integrationItem) error {
+		return Delete(item)
+	}},
+	{"find deleted", func(item *integrationItem) error {
+		if err := FindById(&integrationItem{}, item.Id.Hex()); !errors.Is(err, mgo.ErrNotFound) {
+			return fmt.Errorf("Expected mgo.ErrNotFound got: %v", err)
+		}
+		return nil
+	}},
+}
+
+func TestCRUDMatrix(t *testing.T) {
+	requireServer(t)
+
+	for _, o := range optionMatrix {
+		t.Run(o.name, func(t *testing.T) {
+			defer resetOptions()
+			for name, v := range o.options {
+				if err := SetOption(name, v); err != nil {
+					t.Fatal("Couldn't set option:", err)
+				}
+			}
+
+			if _, err := DeleteDocs(collName(&integrationItem{}), bson.M{}); err != nil {
+				t.Fatal("Couldn't empty the collection:", err)
+			}
+
+			item := &integrationItem{Name: " widget "}
+			for _, c := range crudCases {
+				if err := c.run(item); err != nil {
+					t.Fatal(c.name+":", err)
+				}
+			}
+		})
+	}
+}
+
+func TestReadOnlyServer(t *testing.T) {
+	requireServer(t)
+
+	SetReadOnly(true)
+	defer SetReadOnly(false)
+
+	if err := Insert(&integrationItem{Name: "blocked"}); !errors.Is(err, ErrReadOnly) {
+		t.Fatal("Expected ErrReadOnly got:", err)
+	}
+	if err := Find(&[]integrationItem{}, bson.M{}); err != nil {
+		t.Fatal("Couldn't find in read only mode:", err)
+	}
+}
+
+// FindSorted uses collations from 3.4 and sort key fields before, and both
+// must give the same order.
+func TestSortedAcrossVersions(t *testing.T) {
+	requireServer(t)
+
+	if _, err := DeleteDocs(collName(&integrationItem{}), bson.M{}); err != nil {
+		t.Fatal("Couldn't empty the collection:", err)
+	}
+	for _, name := range []string{"cherry", "Banana", "apple"} {
+		if err := Insert(&integrationItem{Name: name}); err != nil {
+			t.Fatal("Couldn't insert record:", err)
+		}
+	}
+
+	var items []integrationItem
+	if err := FindSorted(&items, nil, SortOptions{Locale: "en", Strength: 1}, "name"); err != nil {
+		t.Fatal("Couldn't find sorted:", err)
+	}
+
+	if len(items) != 3 || items[0].Name != "apple" || items[1].Name != "Banana" || items[2].Name != "cherry" {
+		t.Fatal("Expected apple, Banana, cherry got:", items)
+	}
+}
+
+type validatedItem struct {
+	Id   bson.ObjectId `bson:"_id"`
+	Name string        `bson:"name,omitempty"`
+}
+
+func (validatedItem) SchemaValidator() bson.M {
+	return bson.M{"$jsonSchema": bson.M{"bsonType": "object", "required": []string{"name"}}}
+}
+
+// $jsonSchema validators need 3.6.
+func TestSchemaValidatorDrift(t *testing.T) {
+	requireVersion(t, 3, 6)
+
+	s, err := GetSession()
+	if err != nil {
+		t.Fatal("Couldn't get a session:", err)
+	}
+	defer s.Close()
+
+	coll := GetColl(s, collName(&validatedItem{}))
+	coll.DropCollection()
+	if err := coll.Create(&mgo.CollectionInfo{Validator: validatedItem{}.SchemaValidator()}); err != nil {
+		t.Fatal("Couldn't create the collection:", err)
+	}
+
+	report, err := Bootstrap(&validatedItem{})
+	if err != nil {
+		t.Fatal("Couldn't bootstrap:", err)
+	}
+	if report.HasDrift() {
+		t.Fatal("Expected no drift got:", report.Drift)
+	}
+
+	if err := Insert(&validatedItem{}); err == nil {
+		t.Fatal("Expected the server to reject a record without a name")
+	}
+}
+
+// resetOptions restores the defaults of every runtime option.
+func resetOptions() {
+	defaults := map[string]interface{}{
+		"readonly":            false,
+		"slowquery":           "0s",
+		"samplerate":          0.0,
+		"safety":              false,
+		"normalizeonread":     false,
+		"tombstones":          false,
+		"writesuppression":    "0s",
+		"deprecationwarnings": false,
+	}
+	for name, v := range defaults {
+		SetOption(name, v)
+	}
+}
+
+func TestSyncToDatabase(t *testing.T) {
+	requireServer(t)
+
+	target := std.UseDatabase(testDB + "_sync")
+	defer func() {
+		if s, err := GetSession(); err == nil {
+			s.DB(target.Database()).DropDatabase()
+			s.Close()
+		}
+	}()
+
+	if _, err := DeleteDocs(collName(&integrationItem{}), bson.M{}); err != nil {
+		t.Fatal("Couldn't empty the collection:", err)
+	}
+	for _, name := range []string{"one", "two"} {
+		if err := Insert(&integrationItem{Name: name}); err != nil {
+			t.Fatal("Couldn't insert record:", err)
+		}
+	}
+
+	since, err := Sync(std, target, &integrationItem{})
+	if err != nil {
+		t.Fatal("Couldn't sync:", err)
+	}
+	if n, err := target.Count(&integrationItem{}); err != nil || n != 2 {
+		t.Fatal("Expected 2 synced records got:", n, err)
+	}
+
+	if err := Insert(&integrationItem{Name: "three"}); err != nil {
+		t.Fatal("Couldn't insert record:", err)
+	}
+	if _, err := SyncSince(std, target, since, &integrationItem{}); err != nil {
+		t.Fatal("Couldn't sync incrementally:", err)
+	}
+	if n, err := target.Count(&integrationItem{}); err != nil || n != 3 {
+		t.Fatal("Expected 3 synced records got:", n, err)
+	}
+}
+
+// The oplog needs a replica set.
+func TestOplogTailer(t *testing.T) {
+	requireServer(t)
+
+	s, err := GetSession()
+	if err != nil {
+		t.Fatal("Couldn't get a session:", err)
+	}
+	var status struct {
+		SetName string `bson:"setName"`
+	}
+	err = s.Run("isMaster", &status)
+	s.Close()
+	if err != nil || status.SetName == "" {
+		t.Skip("Needs a replica set")
+	}
+
+	events := make(chan ChangeEvent, 10)
+	tailer := NewOplogTailer("integration", func(ev ChangeEvent) error {
+		events <- ev
+		return nil
+	}, &integrationItem{})
+	tailer.PollTimeout = 100 * time.Millisecond
+
+	// Save the current position so the writes below are picked up.
+	stop := make(chan struct{})
+	close(stop)
+	if err := tailer.Run(stop); err != nil {
+		t.Fatal("Couldn't save the position:", err)
+	}
+
+	item := &integrationItem{Name: "tailed"}
+	if err := Insert(item); err != nil {
+		t.Fatal("Couldn't insert record:", err)
+	}
+	item.Qty = 3
+	if err := Update(item); err != nil {
+		t.Fatal("Couldn't update record:", err)
+	}
+
+	stop = make(chan struct{})
+	done := make(chan error, 1)
+	go func() { done <- tailer.Run(stop) }()
+	defer func() {
+		close(stop)
+		if err := <-done; err != nil {
+			t.Error("Tailer failed:", err)
+		}
+	}()
+
+	for _, want := range []ChangeOp{ChangeInsert, ChangeUpdate} {
+		select {
+		case ev := <-events:
+			if ev.Op != want || ev.Id != item.Id {
+				t.Fatalf("Expected %v of %v got: %+v", want, item.Id, ev)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatal("Timed out waiting for", want)
+		}
+	}
+}
+
+func TestTrashChanges(t *testing.T) {
+	requireServer(t)
+
+	SetTombstones(true)
+	defer SetTombstones(false)
+
+	item := &integrationItem{Name: "trashed"}
+	if err := Insert(item); err != nil {
+		t.Fatal("Couldn't insert record:", err)
+	}
+
+	since := time.Now()
+	time.Sleep(10 * time.Millisecond)
+
+	if err := MoveToTrash(item); err != nil {
+		t.Fatal("Couldn't trash record:", err)
+	}
+
+	var items []integrationItem
+	changes, err := ChangesSince(&items, since)
+	if err != nil {
+		t.Fatal("Couldn't get changes:", err)
+	}
+	if len(changes.Deleted) != 1 || changes.Deleted[0] != item.Id {
+		t.Fatal("Expected the trashed record to be deleted got:", changes.Deleted)
+	}
+
+	restored := &integrationItem{}
+	if err := RestoreFromTrash(restored, item.Id.Hex()); err != nil {
+		t.Fatal("Couldn't restore record:", err)
+	}
+
+	items = nil
+	if changes, err = ChangesSince(&items, since); err != nil {
+		t.Fatal("Couldn't get changes:", err)
+	}
+	if len(changes.Deleted) != 0 || len(items) != 1 || items[0].Id != item.Id {
+		t.Fatal("Expected the restored record to be changed got:", items, changes.Deleted)
+	}
+	if !restored.UpdatedAt.After(since) {
+		t.Fatal("Expected a new UpdatedAt got:", restored.UpdatedAt)
+	}
+}
+
+func TestCloneFreshRecord(t *testing.T) {
+	requireServer(t)
+
+	item := &integrationItem{Name: "original", Qty: 2}
+	if err := Insert(item); err != nil {
+		t.Fatal("Couldn't insert record:", err)
+	}
+	source := &integrationItem{}
+	if err := FindById(source, item.Id.Hex()); err != nil {
+		t.Fatal("Couldn't find record:", err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+
+	id, err := Clone(item)
+	if err != nil {
+		t.Fatal("Couldn't clone record:", err)
+	}
+	if id == item.Id.Hex() {
+		t.Fatal("Expected the clone to get a new Id got:", id)
+	}
+
+	clone := &integrationItem{}
+	if err := FindById(clone, id); err != nil {
+		t.Fatal("Couldn't find the clone:", err)
+	}
+	if clone.Name != "original" || clone.Qty != 2 {
+		t.Fatal("Expected the fields to be copied got:", clone)
+	}
+	if !clone.CreatedAt.After(source.CreatedAt) || !clone.UpdatedAt.After(source.UpdatedAt) {
+		t.Fatal("Expected fresh timestamps got:", clone.CreatedAt, clone.UpdatedAt)
+	}
+
+	after := &integrationItem{}
+	if err := FindById(after, item.Id.Hex()); err != nil {
+		t.Fatal("Couldn't find the source:", err)
+	}
+	if after.Name != source.Name || after.Qty != source.Qty || !after.UpdatedAt.Equal(source.UpdatedAt) {
+		t.Fatal("Expected the source to be unchanged got:", after)
+	}
+}
+
+type truncatedItem struct {
+	Id   bson.ObjectId `bson:"_id"`
+	Name string        `bson:"name"`
+}
+
+func TestTruncateDrop(t *testing.T) {
+	requireServer(t)
+
+	coll := collName(&truncatedItem{})
+	for _, name := range []string{"a", "b"} {
+		if err := Insert(&truncatedItem{Name: name}); err != nil {
+			t.Fatal("Couldn't insert record:", err)
+		}
+	}
+
+	s, err := GetSession()
+	if err != nil {
+		t.Fatal("Couldn't get a session:", err)
+	}
+	defer s.Close()
+	if err := GetColl(s, coll).EnsureIndex(mgo.Index{Key: []string{"name"}, Unique: true}); err != nil {
+		t.Fatal("Couldn't create the index:", err)
+	}
+
+	if err := TruncateWith(&truncatedItem{}, coll, TruncateOptions{Drop: true}); err != nil {
+		t.Fatal("Couldn't truncate:", err)
+	}
+
+	if n, err := CountCollection(coll); err != nil || n != 0 {
+		t.Fatal("Expected an empty collection got:", n, err)
+	}
+
+	indexes, err := CollectionIndexes(coll)
+	if err != nil {
+		t.Fatal("Couldn't list the indexes:", err)
+	}
+	found := false
+	for _, idx := range indexes {
+		if len(idx.Key) == 1 && idx.Key[0] == "name" && idx.Unique {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("Expected the unique name index to be recreated got:", indexes)
+	}
+}
+
+func TestTrashRoundTrip(t *testing.T) {
+	requireServer(t)
+
+	item := &integrationItem{Name: "round trip", Qty: 4}
+	if err := Insert(item); err != nil {
+		t.Fatal("Couldn't insert record:", err)
+	}
+
+	if err := MoveToTrash(item); err != nil {
+		t.Fatal("Couldn't trash record:", err)
+	}
+
+	if err := FindById(&integrationItem{}, item.Id.Hex()); !errors.Is(err, mgo.ErrNotFound) {
+		t.Fatal("Expected mgo.ErrNotFound for the trashed record got:", err)
+	}
+	var items []integrationItem
+	if err := Find(&items, bson.M{"name": "round trip"}); err != nil || len(items) != 0 {
+		t.Fatal("Expected finds to leave out the trashed record got:", items, err)
+	}
+
+	restored := &integrationItem{}
+	if err := RestoreFromTrash(restored, item.Id.Hex()); err != nil {
+		t.Fatal("Couldn't restore record:", err)
+	}
+
+	found := &integrationItem{}
+	if err := FindById(found, item.Id.Hex()); err != nil {
+		t.Fatal("Couldn't find the restored record:", err)
+	}
+	if found.Name != "round trip" || found.Qty != 4 {
+		t.Fatal("Expected the record as it was trashed got:", found)
+	}
+	if err := Find(&items, bson.M{"name": "round trip"}); err != nil || len(items) != 1 {
+		t.Fatal("Expected finds to return the restored record got:", items, err)
+	}
+}
+
+type mergeOrder struct {
+	Id         bson.ObjectId `bson:"_id"`
+	CustomerId bson.ObjectId `bson:"customerid"`
+}
+
+func TestMergeReferences(t *testing.T) {
+	requireServer(t)
+
+	keep := &integrationItem{Name: "keep"}
+	dup := &integrationItem{Name: "dup", Qty: 3}
+	if err := Insert(keep, dup); err != nil {
+		t.Fatal("Couldn't insert records:", err)
+	}
+
+	order := &mergeOrder{CustomerId: dup.Id}
+	if err := Insert(order); err != nil {
+		t.Fatal("Couldn't insert order:", err)
+	}
+
+	report, err := Merge(keep, dup, MergeStrategy{
+		Refs: []MergeRef{{Collection: collName(order), Key: "customerid"}},
+	})
+	if err != nil {
+		t.Fatal("Couldn't merge:", err)
+	}
+	if len(report.Refs) != 1 || report.Refs[0].Count != 1 {
+		t.Fatal("Expected one reference rewritten got:", report.Refs)
+	}
+
+	found := &mergeOrder{}
+	if err := FindById(found, order.Id.Hex()); err != nil {
+		t.Fatal("Couldn't find order:", err)
+	}
+	if found.CustomerId != keep.Id {
+		t.Fatal("Expected the order to reference the survivor got:", found.CustomerId)
+	}
+
+	if err := FindById(&integrationItem{}, dup.Id.Hex()); !errors.Is(err, mgo.ErrNotFound) {
+		t.Fatal("Expected the merged record to be removed got:", err)
+	}
+
+	survivor := &integrationItem{}
+	if err := FindById(survivor, keep.Id.Hex()); err != nil {
+		t.Fatal("Couldn't find the survivor:", err)
+	}
+	if survivor.Name != "keep" || survivor.Qty != 3 {
+		t.Fatal("Expected the survivor's name and the merged quantity got:", survivor)
+	}
+}
@@ -0,0 +1,125 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"strings"
+)
+
+// ErrInvalidCursor is returned by FindAfter and DecodeCursor for cursors
+// they didn't produce.
+var ErrInvalidCursor = errors.New("Invalid cursor")
+
+// Find up to limit records sorted by sortField, prefixed with "-" for
+// descending order, that come after cursor. Must pass in a pointer to a
+// slice. Pass an empty cursor for the first page. The returned cursor
+// fetches the next page and is empty when there are no more records.
+//
+// Unlike Paginate the cost doesn't grow with the depth of the page: the
+// cursor holds the sort value and _id of the last record and the next page
+// starts from them, using an index on sortField and _id if there is one. Ties
+// on sortField are broken by _id, so sortField should be set on every record.
+func FindAfter(i interface{}, q bson.M, sortField, cursor string, limit int) (next string, err error) {
+	return std.FindAfter(i, q, sortField, cursor, limit)
+}
+
+// Find the records after a cursor using the client. See FindAfter.
+func (c *Client) FindAfter(i interface{}, q bson.M, sortField, cursor string, limit int) (next string, err error) {
+	if !isPtr(i) || !isSlice(reflect.TypeOf(i)) {
+		return "", NoPtr
+	}
+
+	if limit < 1 {
+		limit = DefaultPerPage
+	}
+
+	field, idSort, cmp := sortField, "_id", "$gt"
+	if strings.HasPrefix(sortField, "-") {
+		field, idSort, cmp = sortField[1:], "-_id", "$lt"
+	}
+
+	q = scopeQuery(i, q)
+	if cursor != "" {
+		value, id, err := DecodeCursor(cursor)
+		if err != nil {
+			return "", err
+		}
+
+		after := bson.M{"$or": []bson.M{
+			{field: bson.M{cmp: value}},
+			{field: value, "_id": bson.M{cmp: id}},
+		}}
+		if len(q) > 0 {
+			after = bson.M{"$and": []bson.M{q, after}}
+		}
+		q = after
+	}
+
+	op := c.startOp("find", collName(i), q)
+
+	if err := op.allowed(); err != nil {
+		return "", op.done(err)
+	}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return "", op.done(err)
+	}
+	defer s.Close()
+
+	if q, err = op.check(c, s, q, true); err != nil {
+		return "", op.done(err)
+	}
+
+	// One more than asked for tells whether there's a next page.
+	var raws []bson.Raw
+	if err := withoutLazy(c.GetColl(s, op.coll).Find(q), i).Sort(sortField, idSort).Limit(limit + 1).All(&raws); err != nil {
+		return "", op.done(err)
+	}
+
+	if len(raws) > limit {
+		raws = raws[:limit]
+
+		last := bson.M{}
+		if err := raws[limit-1].Unmarshal(&last); err != nil {
+			return "", op.done(err)
+		}
+
+		value, _ := getDocPath(last, field)
+		if next, err = EncodeCursor(value, last["_id"]); err != nil {
+			return "", op.done(err)
+		}
+	}
+
+	return next, op.done(unmarshalAll(c, s, raws, i))
+}
+
+// Returns an opaque cursor for the record with the sort value and id. See
+// FindAfter.
+func EncodeCursor(value, id interface{}) (string, error) {
+	data, err := bson.Marshal(bson.M{"v": value, "id": id})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// Returns the sort value and id held by a cursor from EncodeCursor.
+func DecodeCursor(cursor string) (value, id interface{}, err error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, nil, ErrInvalidCursor
+	}
+
+	var c struct {
+		V  interface{} `bson:"v"`
+		Id interface{} `bson:"id"`
+	}
+	if err := bson.Unmarshal(data, &c); err != nil || c.Id == nil {
+		return nil, nil, ErrInvalidCursor
+	}
+	return c.V, c.Id, nil
+}
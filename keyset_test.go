@@ -0,0 +1,40 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"testing"
+	"time"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	id := bson.NewObjectId()
+	at := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	cursor, err := EncodeCursor(at, id)
+	if err != nil {
+		t.Fatal("Couldn't encode the cursor:", err)
+	}
+
+	value, gotId, err := DecodeCursor(cursor)
+	if err != nil {
+		t.Fatal("Couldn't decode the cursor:", err)
+	}
+	if !value.(time.Time).Equal(at) || gotId != id {
+		t.Fatal("Expected", at, id, "got:", value, gotId)
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	for _, cursor := range []string{"not a cursor!", "AAAA"} {
+		if _, _, err := DecodeCursor(cursor); err != ErrInvalidCursor {
+			t.Fatal("Expected ErrInvalidCursor got:", err)
+		}
+	}
+}
+
+func TestFindAfterNoPtr(t *testing.T) {
+	if _, err := FindAfter(&MongoTest{}, nil, "name", "", 10); err != NoPtr {
+		t.Fatal("Expected NoPtr got:", err)
+	}
+}
@@ -0,0 +1,213 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// ErrNotLoaded is returned by Lazy.Decode for a field that wasn't loaded.
+var ErrNotLoaded = errors.New("Lazy field isn't loaded")
+
+// ErrLazyType is returned, wrapped with the field's name, when reading or
+// writing a record with a field tagged `lazy:"true"` that isn't a Lazy. Only
+// a Lazy knows whether it was loaded, so other types would be written back
+// with their zero value after a find.
+var ErrLazyType = errors.New("Lazy fields must be of type Lazy")
+
+// Lazy holds a heavy field that finds leave out until it's loaded with
+// LoadField. Tag the field `lazy:"true"`:
+//
+//	type Article struct {
+//		Id    bson.ObjectId `bson:"_id"`
+//		Title string        `bson:"title"`
+//		Body  mongo.Lazy    `bson:"body" lazy:"true"`
+//	}
+//
+//	err := mongo.FindById(article, id)
+//	err = mongo.LoadField(article, "Body")
+//
+//	var body string
+//	err = article.Body.Decode(&body)
+//
+// Writing a record whose lazy fields weren't loaded, with Update, Upsert or
+// any other write, keeps their stored values.
+type Lazy struct {
+	raw    bson.Raw
+	loaded bool
+}
+
+// Returns whether the field was loaded or set.
+func (l *Lazy) Loaded() bool {
+	return l.loaded
+}
+
+// Decode the field's value into v, which must be a pointer. A field missing
+// from the record leaves v unchanged.
+func (l *Lazy) Decode(v interface{}) error {
+	if !isPtr(v) {
+		return NoPtr
+	}
+	if !l.loaded {
+		return ErrNotLoaded
+	}
+	if l.raw.Kind == 0 {
+		return nil
+	}
+	return l.raw.Unmarshal(v)
+}
+
+// Set the field's value.
+func (l *Lazy) Set(v interface{}) error {
+	data, err := bson.Marshal(bson.M{"v": v})
+	if err != nil {
+		return err
+	}
+
+	var doc struct {
+		V bson.Raw `bson:"v"`
+	}
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+
+	l.raw, l.loaded = doc.V, true
+	return nil
+}
+
+func (l Lazy) GetBSON() (interface{}, error) {
+	if l.raw.Kind == 0 {
+		return nil, nil
+	}
+	return l.raw, nil
+}
+
+func (l *Lazy) SetBSON(raw bson.Raw) error {
+	l.raw, l.loaded = raw, true
+	return nil
+}
+
+var lazyType = reflect.TypeOf(Lazy{})
+
+// Load a lazy field of i, a pointer to a struct with an Id, by its Go name.
+// Works for any other field as well, e.g. to refresh it.
+func LoadField(i interface{}, field string) error {
+	return std.LoadField(i, field)
+}
+
+// Load a lazy field using the client. See LoadField.
+func (c *Client) LoadField(i interface{}, field string) error {
+	if !isPtr(i) || structType(i) == nil {
+		return NoPtr
+	}
+
+	fv := reflect.ValueOf(i).Elem().FieldByName(field)
+	if !fv.IsValid() {
+		return fmt.Errorf("%w: %v", ErrUnknownField, field)
+	}
+	key := fieldKey(i, field)
+
+	op := c.startOp("find", collName(i), nil)
+
+	if err := op.allowed(); err != nil {
+		return op.done(err)
+	}
+
+	id, err := getObjIdFromStruct(i)
+	if err != nil {
+		return op.done(err)
+	}
+	op.query = bson.M{"_id": id}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return op.done(err)
+	}
+	defer s.Close()
+
+	var doc map[string]bson.Raw
+	if err := c.GetColl(s, op.coll).FindId(id).Select(bson.M{key: 1}).One(&doc); err != nil {
+		return op.done(err)
+	}
+
+	raw, ok := doc[key]
+	if !ok {
+		if fv.Type() == lazyType {
+			fv.Set(reflect.ValueOf(Lazy{loaded: true}))
+		}
+		return op.done(nil)
+	}
+	return op.done(raw.Unmarshal(fv.Addr().Interface()))
+}
+
+// checkLazy returns an error wrapping ErrLazyType if i has a field tagged
+// lazy that isn't a Lazy.
+func checkLazy(i interface{}) error {
+	t := structType(i)
+	if t == nil {
+		return nil
+	}
+
+	for n := 0; n < t.NumField(); n++ {
+		f := t.Field(n)
+		if f.PkgPath == "" && f.Tag.Get("lazy") == "true" && f.Type != lazyType {
+			return fmt.Errorf("%w: %v", ErrLazyType, f.Name)
+		}
+	}
+	return nil
+}
+
+// lazyKeys returns the document keys of the fields of i tagged as lazy, and
+// of those the ones that aren't loaded.
+func lazyKeys(i interface{}) (keys, unloaded []string) {
+	t := structType(i)
+	if t == nil {
+		return nil, nil
+	}
+
+	v := reflect.ValueOf(i)
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+
+	for n := 0; n < t.NumField(); n++ {
+		f := t.Field(n)
+		if f.PkgPath != "" || f.Tag.Get("lazy") != "true" {
+			continue
+		}
+
+		key := bsonKey(f)
+		keys = append(keys, key)
+
+		if v.Kind() == reflect.Struct && f.Type == lazyType && !v.Field(n).Interface().(Lazy).loaded {
+			unloaded = append(unloaded, key)
+		}
+	}
+	return keys, unloaded
+}
+
+// lazyProjection returns the projection leaving out the lazy fields of i,
+// or nil if it has none.
+func lazyProjection(i interface{}) bson.M {
+	keys, _ := lazyKeys(i)
+	if len(keys) == 0 {
+		return nil
+	}
+
+	proj := bson.M{}
+	for _, k := range keys {
+		proj[k] = 0
+	}
+	return proj
+}
+
+// withoutLazy makes query leave out the lazy fields of i.
+func withoutLazy(query *mgo.Query, i interface{}) *mgo.Query {
+	if proj := lazyProjection(i); proj != nil {
+		return query.Select(proj)
+	}
+	return query
+}
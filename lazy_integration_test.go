@@ -0,0 +1,87 @@
+//go:build integration
+// +build integration
+
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"testing"
+)
+
+type lazyItem struct {
+	Id    bson.ObjectId `bson:"_id"`
+	Slug  string        `bson:"slug"`
+	Title string        `bson:"title"`
+	Body  Lazy          `bson:"body" lazy:"true"`
+}
+
+func (lazyItem) KeyFields() []string {
+	return []string{"slug"}
+}
+
+// Each write starts from a record found without its body and must leave the
+// stored body alone.
+func TestLazyWriteBack(t *testing.T) {
+	requireServer(t)
+
+	item := &lazyItem{Slug: "lazy", Title: "Lazy"}
+	if err := item.Body.Set("Long text"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Insert(item); err != nil {
+		t.Fatal("Couldn't insert record:", err)
+	}
+
+	writes := []struct {
+		name  string
+		write func(found *lazyItem) error
+	}{
+		{"update", func(found *lazyItem) error {
+			return Update(found)
+		}},
+		{"upsert", func(found *lazyItem) error {
+			_, err := Upsert(found, bson.M{"slug": found.Slug})
+			return err
+		}},
+		{"upsert by key", func(found *lazyItem) error {
+			_, err := UpsertBy(found)
+			return err
+		}},
+		{"update each", func(found *lazyItem) error {
+			_, err := UpdateEach(&lazyItem{}, bson.M{"_id": found.Id}, func(rec interface{}) error {
+				rec.(*lazyItem).Title = found.Title
+				return nil
+			})
+			return err
+		}},
+	}
+
+	for _, w := range writes {
+		found := &lazyItem{}
+		if err := FindById(found, item.Id.Hex()); err != nil {
+			t.Fatal(w.name+": couldn't find record:", err)
+		}
+		if found.Body.Loaded() {
+			t.Fatal(w.name + ": expected the body to be left out")
+		}
+
+		found.Title = w.name
+		if err := w.write(found); err != nil {
+			t.Fatal(w.name+": couldn't write record:", err)
+		}
+
+		stored := &lazyItem{}
+		if err := FindById(stored, item.Id.Hex()); err != nil {
+			t.Fatal(w.name+": couldn't find record:", err)
+		}
+		if err := LoadField(stored, "Body"); err != nil {
+			t.Fatal(w.name+": couldn't load the body:", err)
+		}
+
+		var body string
+		if err := stored.Body.Decode(&body); err != nil || body != "Long text" || stored.Title != w.name {
+			t.Fatal(w.name+": expected the stored body to be unchanged got:", body, stored.Title, err)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type article struct {
+	Id    bson.ObjectId `bson:"_id"`
+	Title string        `bson:"title"`
+	Body  Lazy          `bson:"body" lazy:"true"`
+	Notes Lazy          `bson:"notes" lazy:"true"`
+}
+
+func TestLazyKeys(t *testing.T) {
+	rec := &article{}
+	rec.Notes.loaded = true
+
+	keys, unloaded := lazyKeys(rec)
+	if !reflect.DeepEqual(keys, []string{"body", "notes"}) || !reflect.DeepEqual(unloaded, []string{"body"}) {
+		t.Fatal("Expected body and notes with body unloaded got:", keys, unloaded)
+	}
+
+	if proj := lazyProjection(&[]article{}); !reflect.DeepEqual(proj, bson.M{"body": 0, "notes": 0}) {
+		t.Fatal("Expected the lazy fields left out got:", proj)
+	}
+	if proj := lazyProjection(&fieldsetModel{}); proj != nil {
+		t.Fatal("Expected no projection got:", proj)
+	}
+}
+
+func TestLazyNotLoaded(t *testing.T) {
+	var body string
+	if err := (&Lazy{}).Decode(&body); !errors.Is(err, ErrNotLoaded) {
+		t.Fatal("Expected ErrNotLoaded got:", err)
+	}
+}
+
+func TestLoadFieldUnknown(t *testing.T) {
+	if err := LoadField(&article{}, "Summary"); !errors.Is(err, ErrUnknownField) {
+		t.Fatal("Expected ErrUnknownField got:", err)
+	}
+}
+
+type plainLazy struct {
+	Id   bson.ObjectId `bson:"_id"`
+	Body string        `bson:"body" lazy:"true"`
+}
+
+func TestLazyType(t *testing.T) {
+	if _, err := marshalRecord(&plainLazy{}); !errors.Is(err, ErrLazyType) {
+		t.Fatal("Expected ErrLazyType writing got:", err)
+	}
+
+	data, err := bson.Marshal(bson.M{"_id": bson.NewObjectId()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := unmarshalRecord(nil, nil, bson.Raw{Kind: 3, Data: data}, &plainLazy{}); !errors.Is(err, ErrLazyType) {
+		t.Fatal("Expected ErrLazyType reading got:", err)
+	}
+
+	if err := checkLazy(&article{}); err != nil {
+		t.Fatal("Expected Lazy fields to be accepted got:", err)
+	}
+}
+
+func TestEachDocKeepsUnloadedLazy(t *testing.T) {
+	id := bson.NewObjectId()
+	data, err := bson.Marshal(bson.M{"_id": id, "title": "Old", "body": "Long text"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The body was left out when the record was read.
+	rec := &article{Id: id, Title: "New"}
+	doc, err := eachDoc(rec, bson.Raw{Kind: 3, Data: data})
+	if err != nil {
+		t.Fatal("Couldn't prepare the record:", err)
+	}
+
+	m := doc.(bson.M)
+	if m["body"] != "Long text" || m["title"] != "New" {
+		t.Fatal("Expected the stored body and the new title got:", m)
+	}
+	if _, ok := m["notes"]; ok {
+		t.Fatal("Expected the missing notes to stay missing got:", m)
+	}
+}
@@ -0,0 +1,165 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+
+	"errors"
+	"sync"
+	"time"
+)
+
+// DefaultLoaderWait is how long a Loader collects ids before querying.
+const DefaultLoaderWait = 2 * time.Millisecond
+
+// ErrInvalidId is returned by Loader.Load for ids that aren't ObjectId hex.
+var ErrInvalidId = errors.New("Id must be an ObjectId in hex")
+
+// Loader batches FindById calls made within a short window into a single $in
+// query, dropping duplicate ids. It's meant for GraphQL resolvers which
+// otherwise load the same kind of record once per field:
+//
+//	users := mongo.NewLoader(&User{}, 0)
+//
+//	// in each resolver
+//	user := &User{}
+//	err := users.Load(user, post.AuthorId)
+//
+// A Loader is safe for concurrent use and caches nothing between batches, so
+// one can be shared across requests.
+type Loader struct {
+	// MaxBatch sends a batch as soon as it holds this many ids. Zero means
+	// no limit.
+	MaxBatch int
+
+	client *Client
+	model  interface{}
+	wait   time.Duration
+
+	mu    sync.Mutex
+	batch *loadBatch
+
+	// fetch loads a batch. It's a field so tests can swap out the query.
+	fetch func(ids []bson.ObjectId) (map[bson.ObjectId]bson.Raw, error)
+}
+
+type loadBatch struct {
+	ids  []bson.ObjectId
+	seen map[bson.ObjectId]bool
+	sent bool
+	done chan struct{}
+	docs map[bson.ObjectId]bson.Raw
+	err  error
+}
+
+// Returns a Loader for model, which is only used for its type. A wait of
+// zero uses DefaultLoaderWait.
+func NewLoader(model interface{}, wait time.Duration) *Loader {
+	return std.NewLoader(model, wait)
+}
+
+// Create a loader using the client. See NewLoader.
+func (c *Client) NewLoader(model interface{}, wait time.Duration) *Loader {
+	if wait <= 0 {
+		wait = DefaultLoaderWait
+	}
+
+	l := &Loader{client: c, model: model, wait: wait}
+	l.fetch = l.query
+	return l
+}
+
+// Load the record with the given id into dst, which must be a pointer to a
+// struct. Blocks until the batch the id joined has been queried. Returns
+// mgo.ErrNotFound if there's no such record.
+func (l *Loader) Load(dst interface{}, id string) error {
+	if !isPtr(dst) {
+		return NoPtr
+	}
+
+	if !bson.IsObjectIdHex(id) {
+		return ErrInvalidId
+	}
+	oid := bson.ObjectIdHex(id)
+
+	l.mu.Lock()
+	b := l.batch
+	if b == nil {
+		b = &loadBatch{seen: map[bson.ObjectId]bool{}, done: make(chan struct{})}
+		l.batch = b
+		time.AfterFunc(l.wait, func() { l.send(b) })
+	}
+	if !b.seen[oid] {
+		b.seen[oid] = true
+		b.ids = append(b.ids, oid)
+	}
+	full := l.MaxBatch > 0 && len(b.ids) >= l.MaxBatch
+	l.mu.Unlock()
+
+	if full {
+		l.send(b)
+	}
+
+	<-b.done
+	if b.err != nil {
+		return b.err
+	}
+
+	raw, ok := b.docs[oid]
+	if !ok {
+		return mgo.ErrNotFound
+	}
+	return unmarshalRecord(l.client, nil, raw, dst)
+}
+
+// send queries a batch once, whether it's triggered by the timer or by
+// MaxBatch.
+func (l *Loader) send(b *loadBatch) {
+	l.mu.Lock()
+	if b.sent {
+		l.mu.Unlock()
+		return
+	}
+	b.sent = true
+	if l.batch == b {
+		l.batch = nil
+	}
+	ids := b.ids
+	l.mu.Unlock()
+
+	b.docs, b.err = l.fetch(ids)
+	close(b.done)
+}
+
+func (l *Loader) query(ids []bson.ObjectId) (map[bson.ObjectId]bson.Raw, error) {
+	q := scopeQuery(l.model, bson.M{"_id": bson.M{"$in": ids}})
+	op := l.client.startOp("find", collName(l.model), q)
+
+	if err := op.allowed(); err != nil {
+		return nil, op.done(err)
+	}
+
+	s, err := l.client.GetSession()
+	if err != nil {
+		return nil, op.done(err)
+	}
+	defer s.Close()
+
+	var raws []bson.Raw
+	if err := l.client.GetColl(s, op.coll).Find(q).All(&raws); err != nil {
+		return nil, op.done(err)
+	}
+
+	docs := make(map[bson.ObjectId]bson.Raw, len(raws))
+	for _, raw := range raws {
+		var doc struct {
+			Id bson.ObjectId `bson:"_id"`
+		}
+		if err := raw.Unmarshal(&doc); err != nil {
+			return nil, op.done(err)
+		}
+		docs[doc.Id] = raw
+	}
+
+	return docs, op.done(nil)
+}
@@ -0,0 +1,63 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLoaderBatches(t *testing.T) {
+	l := NewLoader(&MongoTest{}, 20*time.Millisecond)
+
+	var mu sync.Mutex
+	var batches [][]bson.ObjectId
+	l.fetch = func(ids []bson.ObjectId) (map[bson.ObjectId]bson.Raw, error) {
+		mu.Lock()
+		batches = append(batches, ids)
+		mu.Unlock()
+		return map[bson.ObjectId]bson.Raw{}, nil
+	}
+
+	a, b := bson.NewObjectId().Hex(), bson.NewObjectId().Hex()
+
+	var wg sync.WaitGroup
+	for _, id := range []string{a, b, a, b, a} {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			if err := l.Load(&MongoTest{}, id); err != mgo.ErrNotFound {
+				t.Error("Expected not found, got:", err)
+			}
+		}(id)
+	}
+	wg.Wait()
+
+	if len(batches) != 1 || len(batches[0]) != 2 {
+		t.Fatal("Expected one batch of two distinct ids, got:", batches)
+	}
+}
+
+func TestLoaderMaxBatch(t *testing.T) {
+	l := NewLoader(&MongoTest{}, time.Hour)
+	l.MaxBatch = 1
+
+	calls := 0
+	l.fetch = func(ids []bson.ObjectId) (map[bson.ObjectId]bson.Raw, error) {
+		calls++
+		return nil, nil
+	}
+
+	if err := l.Load(&MongoTest{}, bson.NewObjectId().Hex()); err != mgo.ErrNotFound {
+		t.Fatal("Expected not found, got:", err)
+	}
+	if calls != 1 {
+		t.Fatal("Expected a full batch to be sent without waiting")
+	}
+
+	if err := l.Load(&MongoTest{}, "nope"); err != ErrInvalidId {
+		t.Fatal("Expected ErrInvalidId, got:", err)
+	}
+}
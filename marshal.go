@@ -0,0 +1,141 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+
+	"errors"
+	"reflect"
+	"sync/atomic"
+)
+
+// Marshaler is implemented by models that build their own stored
+// representation, for example to flatten nested structs or to write a
+// versioned layout. MarshalMongo is called before the record is written and
+// its result is stored instead of the model. Models implementing bson.Getter
+// are also honored.
+type Marshaler interface {
+	MarshalMongo() (interface{}, error)
+}
+
+// Unmarshaler is implemented by models that decode their own stored
+// representation. UnmarshalMongo is called with the raw document after a
+// find. Models implementing bson.Setter are also honored.
+type Unmarshaler interface {
+	UnmarshalMongo(raw bson.Raw) error
+}
+
+// marshalRecord returns the value that should be written to the database
+// for i.
+func marshalRecord(i interface{}) (interface{}, error) {
+	if err := checkLazy(i); err != nil {
+		return nil, err
+	}
+
+	var rec interface{} = i
+	if m, ok := i.(Marshaler); ok {
+		var err error
+		if rec, err = m.MarshalMongo(); err != nil {
+			return nil, err
+		}
+	} else if _, ok := i.(bson.Getter); !ok {
+		var err error
+		if rec, err = applyZeroPolicy(i); err != nil {
+			return nil, err
+		}
+	}
+
+	return addDiscriminator(i, rec)
+}
+
+// unmarshalRecord decodes a single document into i which must be a pointer.
+// c and s are where the document was read, for migrate; c is nil if it isn't
+// a stored record.
+func unmarshalRecord(c *Client, s *mgo.Session, raw bson.Raw, i interface{}) error {
+	if err := checkLazy(i); err != nil {
+		return err
+	}
+
+	raw, err := migrate(c, s, raw, i)
+	if err != nil {
+		return err
+	}
+
+	if err := checkUnknownFields(raw, i); err != nil {
+		return err
+	}
+
+	if u, ok := i.(Unmarshaler); ok {
+		err = u.UnmarshalMongo(raw)
+	} else {
+		err = raw.Unmarshal(i)
+	}
+	if err != nil {
+		return err
+	}
+
+	if atomic.LoadInt32(&normalizeOnRead) == 1 {
+		if err := normalize(i); err != nil {
+			return err
+		}
+	}
+
+	return setFieldsPresent(raw, i)
+}
+
+// unmarshalAll decodes the documents into i which must be a pointer to a
+// slice of structs or a slice of pointers to structs. See unmarshalRecord.
+func unmarshalAll(c *Client, s *mgo.Session, raws []bson.Raw, i interface{}) error {
+	sv := reflect.ValueOf(i).Elem()
+	et := sv.Type().Elem()
+
+	out := reflect.MakeSlice(sv.Type(), 0, len(raws))
+	for _, raw := range raws {
+		ev := reflect.New(et)
+		target := ev.Interface()
+		if et.Kind() == reflect.Ptr {
+			ev.Elem().Set(reflect.New(et.Elem()))
+			target = ev.Elem().Interface()
+		}
+
+		if err := unmarshalRecord(c, s, raw, target); err != nil {
+			return err
+		}
+		out = reflect.Append(out, ev.Elem())
+	}
+
+	sv.Set(out)
+	return nil
+}
+
+// idFromDoc returns the _id of the stored representation of i. It's used for
+// models that don't have an Id field because they marshal themselves.
+func idFromDoc(i interface{}) (bson.ObjectId, error) {
+	rec, err := marshalRecord(i)
+	if err != nil {
+		return bson.ObjectId(""), err
+	}
+
+	data, err := bson.Marshal(rec)
+	if err != nil {
+		return bson.ObjectId(""), err
+	}
+
+	var doc struct {
+		Id interface{} `bson:"_id"`
+	}
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		return bson.ObjectId(""), err
+	}
+
+	switch id := doc.Id.(type) {
+	case bson.ObjectId:
+		return id, nil
+	case string:
+		if bson.IsObjectIdHex(id) {
+			return bson.ObjectIdHex(id), nil
+		}
+	}
+
+	return bson.ObjectId(""), errors.New("Record doesn't have a valid _id")
+}
@@ -0,0 +1,70 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"testing"
+)
+
+type flatModel struct {
+	Key  bson.ObjectId
+	Name struct {
+		First string
+		Last  string
+	}
+}
+
+func (m *flatModel) MarshalMongo() (interface{}, error) {
+	return bson.M{"_id": m.Key, "first": m.Name.First, "last": m.Name.Last}, nil
+}
+
+func (m *flatModel) UnmarshalMongo(raw bson.Raw) error {
+	var doc struct {
+		Id    bson.ObjectId `bson:"_id"`
+		First string
+		Last  string
+	}
+	if err := raw.Unmarshal(&doc); err != nil {
+		return err
+	}
+
+	m.Key, m.Name.First, m.Name.Last = doc.Id, doc.First, doc.Last
+	return nil
+}
+
+func TestMarshalerRoundTrip(t *testing.T) {
+	m := &flatModel{Key: bson.NewObjectId()}
+	m.Name.First, m.Name.Last = "George", "Jetson"
+
+	rec, err := marshalRecord(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := bson.Marshal(rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out []*flatModel
+	if err := unmarshalAll(nil, nil, []bson.Raw{{Kind: 3, Data: data}}, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(out) != 1 || *out[0] != *m {
+		t.Fatalf("Round trip mismatch: %+v", out)
+	}
+}
+
+func TestIdFromMarshaler(t *testing.T) {
+	m := &flatModel{Key: bson.NewObjectId()}
+
+	id, err := getObjIdFromStruct(m)
+	if err != nil {
+		t.Fatal("Couldn't get the id of a Marshaler:", err)
+	}
+
+	if id != m.Key {
+		t.Fatal("Got the wrong id:", id.Hex())
+	}
+}
@@ -0,0 +1,198 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"errors"
+	"reflect"
+)
+
+// ErrMergeTypes is returned by Merge when the records aren't of the same
+// type, or are the same record.
+var ErrMergeTypes = errors.New("Can only merge two different records of the same type")
+
+// MergeFields decides which record's values Merge keeps.
+type MergeFields int
+
+const (
+	// FillEmpty keeps the values of the surviving record and only takes
+	// those of the merged one for fields that are empty.
+	FillEmpty MergeFields = iota
+	// PreferSource takes every non-empty value of the merged record.
+	PreferSource
+)
+
+// MergeRef is a field of another collection holding the Id of records of
+// the merged type, e.g. the customer id of orders.
+type MergeRef struct {
+	Collection string
+	Key        string
+}
+
+// MergeStrategy configures Merge.
+type MergeStrategy struct {
+	Fields MergeFields
+	// Refs are rewritten from the merged record's Id to the survivor's.
+	// Only fields holding the Id itself are rewritten, not arrays of Ids.
+	Refs []MergeRef
+	// DryRun reports what Merge would do without writing anything.
+	DryRun bool
+}
+
+// MergedRefs is the number of references of a MergeRef rewritten by Merge,
+// or that would be for a dry run.
+type MergedRefs struct {
+	MergeRef
+	Count int
+}
+
+// MergeReport describes a merge.
+type MergeReport struct {
+	// Changes are the fields of the surviving record that changed.
+	Changes []FieldChange
+	Refs    []MergedRefs
+	DryRun  bool
+}
+
+// Merge src into dst, two records of the same type identified by their Ids,
+// e.g. duplicate customers. Their stored fields are combined into dst
+// according to the strategy and written with Update, references to src are
+// rewritten to dst, and src is moved to the trash, from which it can be
+// restored with RestoreFromTrash:
+//
+//	report, err := mongo.Merge(keep, dup, mongo.MergeStrategy{
+//		Refs:   []mongo.MergeRef{{Collection: "orders", Key: "customerid"}},
+//		DryRun: true,
+//	})
+//
+// The steps aren't atomic, but each can be repeated, so a merge that failed
+// part way is finished by running it again. dst is left holding the merged
+// record.
+func Merge(dst, src interface{}, strategy MergeStrategy) (*MergeReport, error) {
+	return std.Merge(dst, src, strategy)
+}
+
+// Merge two records using the client. See Merge.
+func (c *Client) Merge(dst, src interface{}, strategy MergeStrategy) (*MergeReport, error) {
+	if !isPtr(dst) || !isPtr(src) {
+		return nil, NoPtr
+	}
+	if structType(dst) == nil || structType(dst) != structType(src) {
+		return nil, ErrMergeTypes
+	}
+
+	dstId, err := getObjIdFromStruct(dst)
+	if err != nil {
+		return nil, err
+	}
+	srcId, err := getObjIdFromStruct(src)
+	if err != nil {
+		return nil, err
+	}
+	if dstId == srcId {
+		return nil, ErrMergeTypes
+	}
+
+	op := c.startOp("find", collName(dst), bson.M{"_id": bson.M{"$in": []bson.ObjectId{dstId, srcId}}})
+
+	if err := op.allowed(); err != nil {
+		return nil, op.done(err)
+	}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return nil, op.done(err)
+	}
+	defer s.Close()
+
+	coll := c.GetColl(s, op.coll)
+
+	var dstRaw bson.Raw
+	stored, from := bson.M{}, bson.M{}
+	if err := coll.FindId(dstId).One(&dstRaw); err != nil {
+		return nil, op.done(err)
+	}
+	if err := dstRaw.Unmarshal(&stored); err != nil {
+		return nil, op.done(err)
+	}
+	if err := coll.FindId(srcId).One(&from); err != nil {
+		return nil, op.done(err)
+	}
+
+	merged := mergeDocs(stored, from, strategy.Fields, fieldKey(dst, "CreatedAt"), fieldKey(dst, "UpdatedAt"))
+	report := &MergeReport{Changes: diffDocs(stored, merged, ""), DryRun: strategy.DryRun}
+
+	for _, ref := range strategy.Refs {
+		n, err := c.GetColl(s, ref.Collection).Find(bson.M{ref.Key: srcId}).Count()
+		if err != nil {
+			return nil, op.done(err)
+		}
+		report.Refs = append(report.Refs, MergedRefs{MergeRef: ref, Count: n})
+	}
+	op.done(nil)
+
+	if strategy.DryRun {
+		return report, nil
+	}
+
+	data, err := bson.Marshal(merged)
+	if err != nil {
+		return nil, err
+	}
+	if err := unmarshalRecord(nil, nil, bson.Raw{Kind: 0x03, Data: data}, dst); err != nil {
+		return nil, err
+	}
+	if err := c.Update(dst); err != nil {
+		return nil, err
+	}
+
+	for n, ref := range strategy.Refs {
+		report.Refs[n].Count, err = c.reassign(ref.Collection, bson.M{ref.Key: srcId}, ref.Key, dstId, ReassignOptions{})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := c.MoveToTrash(src); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// mergeDocs returns dst with the values of src combined into it. The Id and
+// the skipped keys, the timestamps, always keep the values of dst.
+func mergeDocs(dst, src bson.M, fields MergeFields, skip ...string) bson.M {
+	merged := bson.M{}
+	for k, v := range dst {
+		merged[k] = v
+	}
+
+	skipped := map[string]bool{"_id": true, TypeKey: true}
+	for _, k := range skip {
+		skipped[k] = true
+	}
+
+	for k, v := range src {
+		if skipped[k] || isEmptyValue(v) {
+			continue
+		}
+		if fields == PreferSource || isEmptyValue(merged[k]) {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+// isEmptyValue returns true for nil, zero values and empty slices and maps.
+func isEmptyValue(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Map:
+		return rv.Len() == 0
+	}
+	return rv.IsZero()
+}
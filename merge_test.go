@@ -0,0 +1,38 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"reflect"
+	"testing"
+)
+
+func TestMergeDocs(t *testing.T) {
+	dst := bson.M{"_id": 1, "name": "George", "email": "", "tags": []interface{}{}, "createdat": 1}
+	src := bson.M{"_id": 2, "name": "G. Smith", "email": "george@example.com", "tags": []interface{}{"vip"}, "createdat": 2}
+
+	merged := mergeDocs(dst, src, FillEmpty, "createdat")
+	expected := bson.M{"_id": 1, "name": "George", "email": "george@example.com", "tags": []interface{}{"vip"}, "createdat": 1}
+	if !reflect.DeepEqual(merged, expected) {
+		t.Fatal("Expected", expected, "got:", merged)
+	}
+
+	merged = mergeDocs(dst, src, PreferSource, "createdat")
+	if merged["name"] != "G. Smith" || merged["_id"] != 1 || merged["createdat"] != 1 {
+		t.Fatal("Expected the source's name only got:", merged)
+	}
+
+	if dst["email"] != "" {
+		t.Fatal("Expected dst to be left unchanged got:", dst)
+	}
+}
+
+func TestMergeTypes(t *testing.T) {
+	id := bson.NewObjectId()
+	if _, err := Merge(&MongoTest{Id: id}, &fieldsetModel{Id: id}, MergeStrategy{}); err != ErrMergeTypes {
+		t.Fatal("Expected ErrMergeTypes got:", err)
+	}
+	if _, err := Merge(&MongoTest{Id: id}, &MongoTest{Id: id}, MergeStrategy{}); err != ErrMergeTypes {
+		t.Fatal("Expected ErrMergeTypes got:", err)
+	}
+}
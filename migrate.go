@@ -0,0 +1,203 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Migration upgrades a stored document by a single schema version. It's
+// free to add, rename or remove keys. The version key itself is updated by
+// the package.
+type Migration func(doc bson.M) error
+
+var (
+	migrationsMu sync.RWMutex
+	migrations   = map[string]map[int]Migration{}
+	writeBack    bool
+)
+
+// Register a migration that upgrades documents in the collection for i from
+// version `from` to from+1. Models opt in to versioning with an int field
+// named SchemaVersion. Older documents are upgraded when they're read and new
+// records are inserted with the latest version.
+func RegisterMigration(i interface{}, from int, fn Migration) {
+	migrationsMu.Lock()
+	defer migrationsMu.Unlock()
+
+	coll := typeName(i)
+	if migrations[coll] == nil {
+		migrations[coll] = map[int]Migration{}
+	}
+	migrations[coll][from] = fn
+}
+
+// Set whether documents upgraded on read are written back to the database.
+// It's off by default so reads never cause writes.
+func SetMigrationWriteBack(b bool) {
+	migrationsMu.Lock()
+	defer migrationsMu.Unlock()
+
+	writeBack = b
+}
+
+// latestVersion returns the newest schema version for the collection or 0 if
+// it has no migrations.
+func latestVersion(coll string) int {
+	migrationsMu.RLock()
+	defer migrationsMu.RUnlock()
+
+	latest := 0
+	for from := range migrations[coll] {
+		if from+1 > latest {
+			latest = from + 1
+		}
+	}
+	return latest
+}
+
+// setSchemaVersion stamps a new record with the latest schema version unless
+// it already has one.
+func setSchemaVersion(i interface{}) error {
+	if !hasStructField(i, "SchemaVersion") {
+		return nil
+	}
+
+	latest := latestVersion(typeName(i))
+	if latest == 0 {
+		return nil
+	}
+
+	f := reflect.ValueOf(i).Elem().FieldByName("SchemaVersion")
+	switch f.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if f.Int() == 0 {
+			f.SetInt(int64(latest))
+		}
+		return nil
+	}
+
+	return fmt.Errorf("SchemaVersion must be an int type.")
+}
+
+// migrate upgrades raw to the latest schema version of i's collection. The
+// original raw is returned if there's nothing to do. Upgraded documents are
+// written back through c, using s if it isn't nil, when write-back is on and
+// c is; c is nil for documents that aren't stored records.
+func migrate(c *Client, s *mgo.Session, raw bson.Raw, i interface{}) (bson.Raw, error) {
+	coll := typeName(i)
+	if !hasStructField(i, "SchemaVersion") || latestVersion(coll) == 0 {
+		return raw, nil
+	}
+
+	doc := bson.M{}
+	if err := raw.Unmarshal(&doc); err != nil {
+		return raw, err
+	}
+
+	key := fieldKey(i, "SchemaVersion")
+	from := toInt(doc[key])
+
+	migrationsMu.RLock()
+	steps := migrations[coll]
+	wb := writeBack
+	migrationsMu.RUnlock()
+
+	if steps[from] == nil {
+		return raw, nil
+	}
+
+	stored := bson.M{}
+	if err := raw.Unmarshal(&stored); err != nil {
+		return raw, err
+	}
+
+	v := from
+	for fn := steps[v]; fn != nil; fn = steps[v] {
+		if err := fn(doc); err != nil {
+			return raw, fmt.Errorf("Migrating %v from version %v: %v", coll, v, err)
+		}
+		v++
+		doc[key] = v
+	}
+
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return raw, err
+	}
+
+	if wb && c != nil {
+		if err := writeBackDoc(c, s, collName(i), key, from, stored, doc); err != nil {
+			return raw, err
+		}
+	}
+
+	return bson.Raw{Kind: raw.Kind, Data: data}, nil
+}
+
+// writeBackDoc applies the changes a migration made to the document stored
+// as it was read to the stored copy, as long as nobody else has changed its
+// version in the meantime. Only the keys the migration changed are written,
+// so documents read with a projection can be written back safely. A nil s
+// opens a session of c.
+func writeBackDoc(c *Client, s *mgo.Session, coll, key string, from int, stored, doc bson.M) error {
+	if s == nil {
+		var err error
+		if s, err = c.GetSession(); err != nil {
+			return err
+		}
+		defer s.Close()
+	}
+
+	selector := bson.M{"_id": stored["_id"], key: from}
+	if from == 0 {
+		selector[key] = bson.M{"$in": []interface{}{nil, 0}}
+	}
+
+	// Not finding the document means another writer changed its version
+	// first, so the upgrade is left to them.
+	err := c.GetColl(s, coll).Update(selector, migrationUpdate(stored, doc))
+	if err == mgo.ErrNotFound {
+		return nil
+	}
+	return err
+}
+
+// migrationUpdate returns the update that turns stored into doc, leaving
+// alone the keys a migration didn't change.
+func migrationUpdate(stored, doc bson.M) bson.M {
+	set, unset := bson.M{}, bson.M{}
+	for k, v := range doc {
+		if old, ok := stored[k]; k != "_id" && (!ok || !reflect.DeepEqual(old, v)) {
+			set[k] = v
+		}
+	}
+	for k := range stored {
+		if _, ok := doc[k]; !ok {
+			unset[k] = ""
+		}
+	}
+
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	return update
+}
+
+func toInt(v interface{}) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case int32:
+		return int(n)
+	case int64:
+		return int(n)
+	case float64:
+		return int(n)
+	}
+	return 0
+}
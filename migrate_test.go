@@ -0,0 +1,59 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"testing"
+)
+
+type VersionedTest struct {
+	Id            bson.ObjectId `bson:"_id"`
+	FullName      string
+	SchemaVersion int `bson:"v"`
+}
+
+func TestMigrateOnRead(t *testing.T) {
+	RegisterMigration(&VersionedTest{}, 0, func(doc bson.M) error {
+		doc["fullname"] = doc["name"]
+		delete(doc, "name")
+		return nil
+	})
+	RegisterMigration(&VersionedTest{}, 1, func(doc bson.M) error {
+		doc["fullname"] = doc["fullname"].(string) + " Jetson"
+		return nil
+	})
+
+	data, err := bson.Marshal(bson.M{"_id": bson.NewObjectId(), "name": "George"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := &VersionedTest{}
+	if err := unmarshalRecord(nil, nil, bson.Raw{Kind: 3, Data: data}, v); err != nil {
+		t.Fatal("Couldn't migrate document:", err)
+	}
+
+	if v.FullName != "George Jetson" || v.SchemaVersion != 2 {
+		t.Fatalf("Document wasn't upgraded: %+v", v)
+	}
+
+	n := &VersionedTest{}
+	if err := setSchemaVersion(n); err != nil || n.SchemaVersion != 2 {
+		t.Fatalf("New records should get the latest version: %+v %v", n, err)
+	}
+}
+
+func TestMigrationUpdate(t *testing.T) {
+	id := bson.NewObjectId()
+	stored := bson.M{"_id": id, "name": "George", "dist": 1.5}
+	doc := bson.M{"_id": id, "fullname": "George", "dist": 1.5, "v": 1}
+
+	update := migrationUpdate(stored, doc)
+	set := update["$set"].(bson.M)
+	if len(set) != 2 || set["fullname"] != "George" || set["v"] != 1 {
+		t.Fatal("Expected only the changed keys to be set, got:", set)
+	}
+	if unset := update["$unset"].(bson.M); len(unset) != 1 || unset["name"] != "" {
+		t.Fatal("Expected the removed key to be unset, got:", unset)
+	}
+}
@@ -0,0 +1,100 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"math"
+)
+
+// Returns the smallest and largest numeric value of field across the records
+// matching q. If an index can serve q sorted by field only the ends of the
+// index are read, otherwise it falls back to an aggregation that scans the
+// matching records. Values that aren't numbers are ignored. Returns
+// mgo.ErrNotFound if no record has a number in field. Field is the document
+// key.
+func MinMax(i interface{}, field string, q bson.M) (min, max float64, err error) {
+	return std.MinMax(i, field, q)
+}
+
+// Find the range of a field using the client. See MinMax.
+func (c *Client) MinMax(i interface{}, field string, q bson.M) (min, max float64, err error) {
+	idx, err := c.ExistingIndex(i, minMaxKey(i, q, field))
+	if err != nil {
+		return 0, 0, err
+	}
+	if idx == nil {
+		return c.minMaxAggregate(i, field, q)
+	}
+
+	// Every number is at least -Inf, which leaves out nulls, missing fields
+	// and other types while still using the index.
+	numbers := bson.M{field: bson.M{"$gte": math.Inf(-1)}}
+	if len(q) > 0 {
+		numbers = bson.M{"$and": []bson.M{q, numbers}}
+	}
+	numbers = scopeQuery(i, numbers)
+
+	op := c.startOp("find", collName(i), numbers)
+
+	if err := op.allowed(); err != nil {
+		return 0, 0, op.done(err)
+	}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return 0, 0, op.done(err)
+	}
+	defer s.Close()
+
+	if numbers, err = op.check(c, s, numbers, true); err != nil {
+		return 0, 0, op.done(err)
+	}
+
+	ends := make([]float64, 2)
+	for n, sort := range []string{field, "-" + field} {
+		var doc bson.M
+		err := c.GetColl(s, op.coll).Find(numbers).Sort(sort).Select(bson.M{"_id": 0, field: 1}).One(&doc)
+		if err != nil {
+			return 0, 0, op.done(err)
+		}
+
+		v, _ := getDocPath(doc, field)
+		ends[n] = toFloat(v)
+	}
+
+	return ends[0], ends[1], op.done(nil)
+}
+
+// minMaxKey returns the index key needed to read the records matching q in
+// the order of field: the fields q matches by equality followed by field.
+func minMaxKey(i interface{}, q bson.M, field string) []string {
+	key := SuggestIndex(i, q, field)
+	for n, k := range key {
+		if k == field {
+			return key[:n+1]
+		}
+	}
+	return key
+}
+
+func (c *Client) minMaxAggregate(i interface{}, field string, q bson.M) (min, max float64, err error) {
+	match := bson.M{field: bson.M{"$gte": math.Inf(-1)}}
+	if len(q) > 0 {
+		match = bson.M{"$and": []bson.M{q, match}}
+	}
+
+	p := Pipeline{}.Match(match).Group(bson.M{
+		"_id": nil,
+		"min": bson.M{"$min": "$" + field},
+		"max": bson.M{"$max": "$" + field},
+	})
+
+	var res struct {
+		Min interface{}
+		Max interface{}
+	}
+	if err := c.Aggregate(i, p, &res); err != nil {
+		return 0, 0, err
+	}
+	return toFloat(res.Min), toFloat(res.Max), nil
+}
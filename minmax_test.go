@@ -0,0 +1,22 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"reflect"
+	"testing"
+)
+
+func TestMinMaxKey(t *testing.T) {
+	q := bson.M{"status": "paid", "total": bson.M{"$gt": 10}, "createdat": bson.M{"$lt": 5}}
+
+	key := minMaxKey(&MongoTest{}, q, "total")
+	if !reflect.DeepEqual(key, []string{"status", "total"}) {
+		t.Fatal("Expected [status total] got:", key)
+	}
+
+	key = minMaxKey(&MongoTest{}, nil, "total")
+	if !reflect.DeepEqual(key, []string{"total"}) {
+		t.Fatal("Expected [total] got:", key)
+	}
+}
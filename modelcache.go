@@ -0,0 +1,202 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+
+	"fmt"
+	"log"
+	"reflect"
+	"sync"
+	"time"
+)
+
+var (
+	modelCachesMu sync.RWMutex
+	modelCaches   = map[string][]*ModelCache{}
+)
+
+// ModelCache holds every record of a small collection in memory, e.g.
+// countries or plans, so lookups never wait for the database:
+//
+//	plans := mongo.NewModelCache(&Plan{}, 10*time.Minute)
+//	if err := plans.Refresh(); err != nil {
+//		...
+//	}
+//
+//	var plan Plan
+//	err := plans.ById(&plan, id)
+//
+// The records are reloaded once they're older than the TTL, or after
+// Insert, Update or Delete write to the collection. Reloads happen in the
+// background while the old records keep being served; only the first
+// lookup waits for them, unless Refresh was called before.
+type ModelCache struct {
+	client *Client
+	model  interface{}
+	coll   string
+	ttl    time.Duration
+
+	mu         sync.RWMutex
+	raws       []bson.Raw
+	ids        map[string]int
+	loadedAt   time.Time
+	loaded     bool
+	stale      bool
+	refreshing bool
+}
+
+// Create a cache of the records like model, a pointer to a struct. A ttl
+// of zero only reloads them after writes.
+func NewModelCache(model interface{}, ttl time.Duration) *ModelCache {
+	return std.NewModelCache(model, ttl)
+}
+
+// Create a model cache using the client. See NewModelCache.
+func (c *Client) NewModelCache(model interface{}, ttl time.Duration) *ModelCache {
+	mc := &ModelCache{client: c, model: model, coll: collName(model), ttl: ttl}
+
+	modelCachesMu.Lock()
+	defer modelCachesMu.Unlock()
+
+	modelCaches[mc.coll] = append(modelCaches[mc.coll], mc)
+	return mc
+}
+
+// Stop reloading the cache after writes.
+func (c *ModelCache) Close() {
+	modelCachesMu.Lock()
+	defer modelCachesMu.Unlock()
+
+	caches := modelCaches[c.coll][:0]
+	for _, other := range modelCaches[c.coll] {
+		if other != c {
+			caches = append(caches, other)
+		}
+	}
+	modelCaches[c.coll] = caches
+}
+
+// Decode every cached record into i, a pointer to a slice.
+func (c *ModelCache) All(i interface{}) error {
+	if !isPtr(i) || !isSlice(reflect.TypeOf(i)) {
+		return NoPtr
+	}
+
+	raws, _, err := c.get()
+	if err != nil {
+		return err
+	}
+	return unmarshalAll(c.client, nil, raws, i)
+}
+
+// Decode the cached record with id into i, a pointer to a struct. Returns
+// mgo.ErrNotFound if there's none.
+func (c *ModelCache) ById(i interface{}, id string) error {
+	if !isPtr(i) {
+		return NoPtr
+	}
+
+	raws, ids, err := c.get()
+	if err != nil {
+		return err
+	}
+
+	n, ok := ids[id]
+	if !ok {
+		return mgo.ErrNotFound
+	}
+	return unmarshalRecord(c.client, nil, raws[n], i)
+}
+
+// Load the records now, e.g. at startup so no lookup waits for them.
+func (c *ModelCache) Refresh() error {
+	op := c.client.startOp("find", c.coll, scopeQuery(c.model, nil))
+
+	if err := op.allowed(); err != nil {
+		return op.done(err)
+	}
+
+	s, err := c.client.GetSession()
+	if err != nil {
+		return op.done(err)
+	}
+	defer s.Close()
+
+	var raws []bson.Raw
+	if err := c.client.GetColl(s, c.coll).Find(op.query).All(&raws); err != nil {
+		return op.done(err)
+	}
+
+	ids := make(map[string]int, len(raws))
+	for n, raw := range raws {
+		var rec struct {
+			Id interface{} `bson:"_id"`
+		}
+		if err := raw.Unmarshal(&rec); err != nil {
+			return op.done(err)
+		}
+		ids[cacheId(rec.Id)] = n
+	}
+
+	c.mu.Lock()
+	c.raws, c.ids, c.loadedAt, c.loaded, c.stale = raws, ids, now(), true, false
+	c.mu.Unlock()
+
+	return op.done(nil)
+}
+
+// get returns the cached records, loading them if they never were and
+// starting a reload if they're out of date.
+func (c *ModelCache) get() ([]bson.Raw, map[string]int, error) {
+	c.mu.Lock()
+	loaded := c.loaded
+	reload := loaded && !c.refreshing && (c.stale || (c.ttl > 0 && now().Sub(c.loadedAt) > c.ttl))
+	if reload {
+		c.refreshing = true
+	}
+	c.mu.Unlock()
+
+	if !loaded {
+		if err := c.Refresh(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if reload {
+		go func() {
+			if err := c.Refresh(); err != nil {
+				log.Println("mongo: model cache:", c.coll, err)
+			}
+
+			c.mu.Lock()
+			c.refreshing = false
+			c.mu.Unlock()
+		}()
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.raws, c.ids, nil
+}
+
+// invalidateModelCaches marks the caches of coll out of date after a write.
+func invalidateModelCaches(coll string) {
+	modelCachesMu.RLock()
+	defer modelCachesMu.RUnlock()
+
+	for _, c := range modelCaches[coll] {
+		c.mu.Lock()
+		c.stale = true
+		c.mu.Unlock()
+	}
+}
+
+// cacheId returns the lookup key of an id, the hex form for ObjectIds.
+func cacheId(id interface{}) string {
+	if oid, ok := id.(bson.ObjectId); ok {
+		return oid.Hex()
+	}
+	return fmt.Sprint(id)
+}
@@ -0,0 +1,45 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+
+	"testing"
+	"time"
+)
+
+func TestModelCacheInvalidate(t *testing.T) {
+	c := NewModelCache(&fieldsetModel{}, time.Hour)
+	defer c.Close()
+
+	// Pretend the records were loaded so lookups don't need the database.
+	id := bson.NewObjectId()
+	c.raws, c.ids, c.loaded, c.loadedAt = []bson.Raw{}, map[string]int{}, true, now()
+
+	var rec fieldsetModel
+	if err := c.ById(&rec, id.Hex()); err != mgo.ErrNotFound {
+		t.Fatal("Expected mgo.ErrNotFound got:", err)
+	}
+
+	invalidateModelCaches(collName(&fieldsetModel{}))
+	if !c.stale {
+		t.Fatal("Expected a write to mark the cache stale")
+	}
+
+	c.Close()
+	c.stale = false
+	invalidateModelCaches(collName(&fieldsetModel{}))
+	if c.stale {
+		t.Fatal("Expected a closed cache to be left alone")
+	}
+}
+
+func TestCacheId(t *testing.T) {
+	id := bson.NewObjectId()
+	if k := cacheId(id); k != id.Hex() {
+		t.Fatal("Expected the hex id got:", k)
+	}
+	if k := cacheId("gb"); k != "gb" {
+		t.Fatal("Expected the string id got:", k)
+	}
+}
@@ -12,52 +12,90 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
-	"time"
+	"sync"
 )
 
 var (
-	mgoSession *mgo.Session
-	servers    string
-	database   string
-	NoPtr      = errors.New("You must pass in a pointer")
+	// conn is the connection used by the package level functions. It's
+	// dialed lazily and guarded by connMu along with the config it's dialed
+	// with.
+	connMu sync.Mutex
+	conn   *Client
+	config Config
+	// connGen counts calls to SetConfig, so a dial that finishes after the
+	// config changed is discarded. dialing is the lazy dial in progress.
+	connGen uint64
+	dialing *dialCall
+
+	// NoPtr is returned as is, rather than wrapped in an *OpError, so it can
+	// still be compared directly.
+	NoPtr = errors.New("You must pass in a pointer")
 )
 
-// Set the mongo servers and the database
+// Set the mongo servers and the database. If the servers can't be reached
+// the error is returned and dialing is retried on first use. It's safe to
+// call concurrently with other functions.
 func SetServers(servers, db string) error {
-	var err error
-
-	database = db
-
-	mgoSession, err = mgo.Dial(servers)
-	return err
+	return SetConfig(Config{Servers: servers, Database: db})
 }
 
 // Insert one or more structs. Must pass in a pointer to a struct. The struct must
 // contain an Id field of type bson.ObjectId with a tag of `bson:"_id"`.
 func Insert(records ...interface{}) error {
+	deprecated("Insert")
+	return std.Insert(records...)
+}
+
+// Insert one or more structs using the client. See Insert.
+func (c *Client) Insert(records ...interface{}) error {
 	for _, rec := range records {
 		if !isPtr(rec) {
 			return NoPtr
 		}
 
-		if err := addNewFields(rec); err != nil {
+		if err := c.insert(rec); err != nil {
 			return err
 		}
+	}
 
-		s, err := GetSession()
-		if err != nil {
-			return err
-		}
-		defer s.Close()
+	return nil
+}
 
-		coll := GetColl(s, typeName(rec))
-		err = coll.Insert(rec)
-		if err != nil {
-			return err
-		}
+func (c *Client) insert(rec interface{}) error {
+	op := c.startOp("insert", collName(rec), nil)
+
+	if err := op.allowed(); err != nil {
+		return op.done(err)
 	}
 
-	return nil
+	if err := normalize(rec); err != nil {
+		return op.done(err)
+	}
+
+	if err := validate(rec); err != nil {
+		return op.done(err)
+	}
+
+	if err := c.addNewFields(rec); err != nil {
+		return op.done(err)
+	}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return op.done(err)
+	}
+	defer s.Close()
+
+	doc, err := marshalRecord(rec)
+	if err != nil {
+		return op.done(err)
+	}
+
+	if err := c.GetColl(s, op.coll).Insert(doc); err != nil {
+		return op.done(err)
+	}
+
+	return op.done(c.afterWrite(s, rec, written{after: doc, created: true}))
 }
 
 // Find one or more records. If a single struct is passed in we'll return one record.
@@ -65,111 +103,363 @@ func Insert(records ...interface{}) error {
 // struct or slice of structs. Use sortFields to sort the results. See
 // http://www.mongodb.org/display/DOCS/Sorting+and+Natural+Order for more info.
 func Find(i interface{}, q bson.M, sortFields ...string) error {
+	deprecated("Find")
+	return std.Find(i, q, sortFields...)
+}
+
+// Find one or more records using the client. See Find.
+func (c *Client) Find(i interface{}, q bson.M, sortFields ...string) error {
 	if !isPtr(i) {
 		return NoPtr
 	}
 
-	s, err := GetSession()
+	q = scopeQuery(i, q)
+	op := c.startOp("find", collName(i), q)
+
+	if err := op.allowed(); err != nil {
+		return op.done(err)
+	}
+
+	s, err := c.GetSession()
 	if err != nil {
-		return err
+		return op.done(err)
 	}
 	defer s.Close()
 
-	coll := GetColl(s, typeName(i))
+	if q, err = op.check(c, s, q, !isSlice(reflect.TypeOf(i))); err != nil {
+		return op.done(err)
+	}
 
-	query := coll.Find(q).Sort(sortFields...)
+	return op.done(findInto(c, s, withoutLazy(c.GetColl(s, op.coll).Find(q).Sort(sortFields...), i), i))
+}
 
+// findInto runs query, made with c and s, into i, a pointer to a struct or
+// slice of structs.
+func findInto(c *Client, s *mgo.Session, query *mgo.Query, i interface{}) error {
+	_, err := findRaws(c, s, query, i)
+	return err
+}
+
+// findRaws runs query into i like findInto and returns the documents found.
+func findRaws(c *Client, s *mgo.Session, query *mgo.Query, i interface{}) ([]bson.Raw, error) {
 	if isSlice(reflect.TypeOf(i)) {
-		err = query.All(i)
-	} else {
-		err = query.One(i)
+		var raws []bson.Raw
+		if err := query.All(&raws); err != nil {
+			return nil, err
+		}
+		return raws, unmarshalAll(c, s, raws, i)
 	}
-	return err
+
+	var raw bson.Raw
+	if err := query.One(&raw); err != nil {
+		return nil, err
+	}
+	return []bson.Raw{raw}, unmarshalRecord(c, s, raw, i)
 }
 
 // Find a single record by id. Must pass a pointer to a struct.
 func FindById(i interface{}, id string) error {
-	return Find(i, bson.M{"_id": bson.ObjectIdHex(id)})
+	deprecated("FindById")
+	return std.FindById(i, id)
+}
+
+// Find a single record by id using the client. See FindById.
+func (c *Client) FindById(i interface{}, id string) error {
+	return c.Find(i, bson.M{"_id": bson.ObjectIdHex(id)})
 }
 
 // Updates a record. Uses the Id to identify the record to update. Must pass in a pointer
 // to a struct.
 func Update(i interface{}) error {
+	deprecated("Update")
+	return std.Update(i)
+}
+
+// Updates a record using the client. See Update.
+func (c *Client) Update(i interface{}) error {
 	if !isPtr(i) {
 		return NoPtr
 	}
 
+	op := c.startOp("update", collName(i), nil)
+
+	if err := op.allowed(); err != nil {
+		return op.done(err)
+	}
+
+	if err := normalize(i); err != nil {
+		return op.done(err)
+	}
+
+	if err := validate(i); err != nil {
+		return op.done(err)
+	}
+
 	err := addCurrentDateTime(i, "UpdatedAt")
 	if err != nil {
-		return err
+		return op.done(err)
 	}
 
-	s, err := GetSession()
+	id, err := getObjIdFromStruct(i)
 	if err != nil {
-		return err
+		return op.done(err)
+	}
+	op.query = bson.M{"_id": id}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return op.done(err)
 	}
 	defer s.Close()
 
-	id, err := getObjIdFromStruct(i)
+	coll := c.GetColl(s, op.coll)
+
+	doc, err := replacementDoc(coll, i, id)
 	if err != nil {
-		return err
+		return op.done(err)
 	}
 
-	return GetColl(s, typeName(i)).Update(bson.M{"_id": id}, i)
+	key := writeKey(c.Database(), op.coll, id)
+	hash, err := writeHash(i, doc)
+	if err != nil {
+		return op.done(err)
+	}
+	if hash != "" && duplicateWrite(key, hash) {
+		return op.done(nil)
+	}
+
+	before := countedDoc(coll, i, id)
+	if err := coll.Update(op.query, doc); err != nil {
+		return op.done(err)
+	}
+	return op.done(c.afterWrite(s, i, written{id: id, before: before, after: doc, hash: hash}))
 }
 
 // Deletes a record. Uses the Id to identify the record to delete. Must pass in a pointer
 // to a struct.
 func Delete(i interface{}) error {
+	deprecated("Delete")
+	return std.Delete(i)
+}
+
+// Deletes a record using the client. See Delete.
+func (c *Client) Delete(i interface{}) error {
 	if !isPtr(i) {
 		return NoPtr
 	}
 
-	s, err := GetSession()
+	op := c.startOp("delete", collName(i), nil)
+
+	if err := op.allowed(); err != nil {
+		return op.done(err)
+	}
+
+	id, err := getObjIdFromStruct(i)
 	if err != nil {
-		return err
+		return op.done(err)
+	}
+	op.query = bson.M{"_id": id}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return op.done(err)
 	}
 	defer s.Close()
 
-	id, err := getObjIdFromStruct(i)
+	coll := c.GetColl(s, op.coll)
+
+	before := countedDoc(coll, i, id)
+	if err := coll.RemoveId(id); err != nil {
+		return op.done(err)
+	}
+	return op.done(c.afterWrite(s, i, written{id: id, before: before}))
+}
+
+// Delete the records of i's type with the given ids in a single request.
+// Returns how many were deleted; ids without a record are skipped.
+func DeleteByIds(i interface{}, ids ...string) (int, error) {
+	return std.DeleteByIds(i, ids...)
+}
+
+// Delete records by id using the client. See DeleteByIds.
+func (c *Client) DeleteByIds(i interface{}, ids ...string) (int, error) {
+	oids := make([]bson.ObjectId, len(ids))
+	for n, id := range ids {
+		if !bson.IsObjectIdHex(id) {
+			return 0, ErrInvalidId
+		}
+		oids[n] = bson.ObjectIdHex(id)
+	}
+	if len(oids) == 0 {
+		return 0, nil
+	}
+
+	q := scopeQuery(i, bson.M{"_id": bson.M{"$in": oids}})
+	op := c.startOp("delete", collName(i), q)
+
+	if err := op.allowed(); err != nil {
+		return 0, op.done(err)
+	}
+
+	s, err := c.GetSession()
 	if err != nil {
-		return err
+		return 0, op.done(err)
+	}
+	defer s.Close()
+
+	coll := c.GetColl(s, op.coll)
+
+	// The records are only read first when counters or tombstones need
+	// them, and then ids without a record are skipped.
+	read := counted(i) || deletesTracked(i)
+	before := map[bson.ObjectId]bson.M{}
+	if read {
+		var docs []bson.M
+		if err := coll.Find(q).All(&docs); err != nil {
+			return 0, op.done(err)
+		}
+		for _, doc := range docs {
+			if id, ok := doc["_id"].(bson.ObjectId); ok {
+				before[id] = doc
+			}
+		}
 	}
 
-	return GetColl(s, typeName(i)).RemoveId(id)
+	info, err := coll.RemoveAll(q)
+	if err != nil {
+		return 0, op.done(err)
+	}
+
+	for _, id := range oids {
+		doc, found := before[id]
+		if read && !found {
+			continue
+		}
+		if err := c.afterWrite(s, i, written{id: id, before: doc}); err != nil {
+			return info.Removed, op.done(err)
+		}
+	}
+
+	return info.Removed, op.done(nil)
 }
 
 // Does a count on the collection for the struct that is passed in.
 func Count(i interface{}) (int, error) {
-	s, err := GetSession()
+	deprecated("Count")
+	return std.Count(i)
+}
+
+// Does a count using the client. See Count.
+func (c *Client) Count(i interface{}) (int, error) {
+	q := scopeQuery(i, nil)
+	op := c.startOp("count", collName(i), q)
+
+	if err := op.allowed(); err != nil {
+		return 0, op.done(err)
+	}
+
+	s, err := c.GetSession()
 	if err != nil {
-		return 0, err
+		return 0, op.done(err)
 	}
 	defer s.Close()
 
-	coll := GetColl(s, typeName(i))
-
-	return coll.Count()
+	n, err := c.GetColl(s, op.coll).Find(q).Count()
+	return n, op.done(err)
 }
 
 // Returns a Mongo session. You must call Session.Close() when you're done.
 func GetSession() (*mgo.Session, error) {
-	var err error
+	c, err := defaultClient()
+	if err != nil {
+		return nil, err
+	}
 
-	if mgoSession == nil {
-		mgoSession, err = mgo.Dial(servers)
-		if err != nil {
-			return nil, err
+	s, err := c.GetSession()
+	if err != nil {
+		return nil, err
+	}
+
+	std.read.apply(s)
+	return s, nil
+}
+
+// defaultClient returns the package's connection, dialing it if needed.
+// Concurrent first use dials only once, without holding connMu while it
+// waits for the servers.
+func defaultClient() (*Client, error) {
+	for {
+		connMu.Lock()
+		if conn != nil {
+			c := conn
+			connMu.Unlock()
+			return c, nil
+		}
+
+		call := dialing
+		if call != nil {
+			connMu.Unlock()
+			<-call.done
+		} else {
+			call = &dialCall{done: make(chan struct{})}
+			dialing = call
+			cfg, gen := config, connGen
+			connMu.Unlock()
+
+			c, err := Dial(cfg)
+
+			var stale *Client
+			connMu.Lock()
+			if dialing == call {
+				dialing = nil
+			}
+			if err != nil {
+				call.err = err
+			} else if gen == connGen && conn == nil {
+				conn, call.c = c, c
+			} else {
+				// The config changed while dialing; try again with the new
+				// one.
+				stale = c
+			}
+			connMu.Unlock()
+			close(call.done)
+
+			if stale != nil {
+				stale.Close()
+			}
+		}
+
+		if call.c != nil || call.err != nil {
+			return call.c, call.err
 		}
 	}
+}
 
-	return mgoSession.Clone(), nil
+// dialCall is a dial of the package's connection in progress. c and err are
+// set before done is closed; both are nil if the result was discarded.
+type dialCall struct {
+	done chan struct{}
+	c    *Client
+	err  error
+}
+
+// currentDatabase returns the name of the database used by the package
+// level functions.
+func currentDatabase() string {
+	connMu.Lock()
+	defer connMu.Unlock()
+
+	if conn != nil {
+		return conn.database
+	}
+	return config.Database
 }
 
 // We pass in the session because that is a clone of the original and the
-// caller will need to close it when finished.
+// caller will need to close it when finished. See SetCollectionAffixes.
 func GetColl(session *mgo.Session, coll string) *mgo.Collection {
-	return session.DB(database).C(coll)
+	return session.DB(currentDatabase()).C(PhysicalCollection(coll))
 }
 
 func getObjIdFromStruct(i interface{}) (bson.ObjectId, error) {
@@ -184,6 +474,10 @@ func getObjIdFromStruct(i interface{}) (bson.ObjectId, error) {
 	}
 
 	f := v.FieldByName("Id")
+	if !f.IsValid() {
+		return idFromDoc(i)
+	}
+
 	if f.Kind() == reflect.Ptr {
 		f = f.Elem()
 	}
@@ -234,12 +528,20 @@ func isSlice(t reflect.Type) bool {
 	return t.Kind() == reflect.Slice
 }
 
-func addNewFields(i interface{}) error {
+func (c *Client) addNewFields(i interface{}) error {
 	err := addId(i)
 	if err != nil {
 		return err
 	}
 
+	if err := c.setPath(i); err != nil {
+		return err
+	}
+
+	if err := setSchemaVersion(i); err != nil {
+		return err
+	}
+
 	if err := addCurrentDateTime(i, "CreatedAt"); err != nil {
 		return err
 	}
@@ -252,7 +554,7 @@ func addCurrentDateTime(i interface{}, name string) error {
 		return nil
 	}
 
-	now := time.Now()
+	ts := now()
 
 	v := reflect.ValueOf(i)
 	if v.Kind() == reflect.Ptr {
@@ -264,7 +566,7 @@ func addCurrentDateTime(i interface{}, name string) error {
 		f = f.Elem()
 	}
 
-	if reflect.TypeOf(now) != f.Type() {
+	if reflect.TypeOf(ts) != f.Type() {
 		return fmt.Errorf("%v must be time.Time type.", name)
 	}
 
@@ -272,7 +574,7 @@ func addCurrentDateTime(i interface{}, name string) error {
 		return fmt.Errorf("Couldn't set time for field: %v", name)
 	}
 
-	f.Set(reflect.ValueOf(now))
+	f.Set(reflect.ValueOf(ts))
 
 	return nil
 }
@@ -310,9 +612,9 @@ func addId(i interface{}) error {
 	if f.Kind() == reflect.String {
 		id := f.Interface()
 		if _, ok := id.(bson.ObjectId); ok {
-			f.Set(reflect.ValueOf(bson.NewObjectId()))
+			f.Set(reflect.ValueOf(newObjectId()))
 		} else {
-			f.SetString(bson.NewObjectId().Hex())
+			f.SetString(newObjectId().Hex())
 		}
 	}
 
@@ -0,0 +1,67 @@
+//go:build integration
+// +build integration
+
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"testing"
+)
+
+func TestInsert(t *testing.T) {
+	requireServer(t)
+
+	if err := Insert(testObj); err != nil {
+		t.Fatal("Couldn't insert record:", err)
+	}
+}
+
+func TestInsertWithoutId(t *testing.T) {
+	requireServer(t)
+
+	err := Insert(testObjNoId)
+	if err != nil {
+		t.Fatal("Wasn't able to insert a record without an Id:", err)
+	}
+
+	if !testObjNoId.Id.Valid() {
+		t.Fatal("Didn't receive a valid id:", testObjNoId.Id.Hex())
+	}
+}
+
+func TestFind(t *testing.T) {
+	requireServer(t)
+
+	m := &MongoTest{}
+	q := bson.M{"_id": testObj.Id}
+	err := Find(m, q)
+	if err != nil {
+		t.Fatal("Couldn't find record. Received the following error:", err)
+	}
+
+	if m.Name != "testing" {
+		t.Fatal("Couldn't find a record saved earlier.")
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	requireServer(t)
+
+	testObj.Name = "testing update"
+	if err := Update(testObj); err != nil {
+		t.Fatal("Couldn't update a record saved earlier:", err)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	requireServer(t)
+
+	if err := Delete(testObj); err != nil {
+		t.Fatal("Couldn't delete record saved earlier:", err)
+	}
+
+	if err := Delete(testObjNoId); err != nil {
+		t.Fatal("Couldn't delete record saved earlier:", err)
+	}
+}
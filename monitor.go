@@ -0,0 +1,73 @@
+package mongo
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CommandStartedEvent is sent to a CommandMonitor when an operation starts.
+type CommandStartedEvent struct {
+	RequestId  int64
+	Op         string
+	Collection string
+	// Query is sanitized with SanitizeQuery.
+	Query string
+	Time  time.Time
+}
+
+// CommandSucceededEvent is sent to a CommandMonitor when an operation
+// completes without error.
+type CommandSucceededEvent struct {
+	RequestId  int64
+	Op         string
+	Collection string
+	Duration   time.Duration
+}
+
+// CommandFailedEvent is sent to a CommandMonitor when an operation fails.
+type CommandFailedEvent struct {
+	RequestId  int64
+	Op         string
+	Collection string
+	Duration   time.Duration
+	Err        error
+}
+
+// CommandMonitor receives an event when each operation starts and another
+// when it succeeds or fails, so APM integrations can trace the database calls
+// an application makes through the package. The events mirror the command
+// monitoring events of the official drivers; RequestId pairs them up.
+// Methods are called synchronously and must be safe for concurrent use.
+type CommandMonitor interface {
+	Started(CommandStartedEvent)
+	Succeeded(CommandSucceededEvent)
+	Failed(CommandFailedEvent)
+}
+
+var (
+	monitorMu sync.RWMutex
+	monitor   CommandMonitor
+	requestId int64
+)
+
+// Set the CommandMonitor that receives operation events. Pass nil to stop
+// monitoring.
+func SetCommandMonitor(m CommandMonitor) {
+	monitorMu.Lock()
+	defer monitorMu.Unlock()
+
+	monitor = m
+}
+
+func currentMonitor() CommandMonitor {
+	monitorMu.RLock()
+	defer monitorMu.RUnlock()
+
+	return monitor
+}
+
+// nextRequestId returns a unique id for an operation.
+func nextRequestId() int64 {
+	return atomic.AddInt64(&requestId, 1)
+}
@@ -0,0 +1,42 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo"
+
+	"testing"
+)
+
+type recordingMonitor struct {
+	started   []CommandStartedEvent
+	succeeded []CommandSucceededEvent
+	failed    []CommandFailedEvent
+}
+
+func (m *recordingMonitor) Started(e CommandStartedEvent)     { m.started = append(m.started, e) }
+func (m *recordingMonitor) Succeeded(e CommandSucceededEvent) { m.succeeded = append(m.succeeded, e) }
+func (m *recordingMonitor) Failed(e CommandFailedEvent)       { m.failed = append(m.failed, e) }
+
+func TestCommandMonitor(t *testing.T) {
+	m := &recordingMonitor{}
+	SetCommandMonitor(m)
+	defer SetCommandMonitor(nil)
+
+	ok := startOp("find", "MongoTest", nil)
+	ok.done(nil)
+
+	bad := startOp("update", "MongoTest", nil)
+	bad.done(mgo.ErrNotFound)
+	bad.done(mgo.ErrNotFound)
+
+	if len(m.started) != 2 || len(m.succeeded) != 1 || len(m.failed) != 1 {
+		t.Fatalf("Unexpected events: %+v", m)
+	}
+
+	if m.succeeded[0].RequestId != m.started[0].RequestId || m.failed[0].RequestId != m.started[1].RequestId {
+		t.Fatal("Events should be paired by RequestId")
+	}
+
+	if m.failed[0].Err != mgo.ErrNotFound {
+		t.Fatal("Failed event should carry the unwrapped error. Got:", m.failed[0].Err)
+	}
+}
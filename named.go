@@ -0,0 +1,77 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ErrUnknownQuery is returned, wrapped with the name, for queries that
+// weren't registered.
+var ErrUnknownQuery = errors.New("Unknown named query")
+
+var (
+	namedMu sync.RWMutex
+	named   = map[string]func(params bson.M) bson.M{}
+)
+
+// Register a query under a name, replacing any with the same name, so
+// commonly used filters are defined in one place:
+//
+//	mongo.RegisterQuery("active_users", func(p bson.M) bson.M {
+//		return bson.M{"active": true, "lastseen": bson.M{"$gte": p["since"]}}
+//	})
+//
+//	err := mongo.FindNamed(&users, "active_users", bson.M{"since": cutoff})
+//
+// build is called with the params of each use and must return a new query.
+func RegisterQuery(name string, build func(params bson.M) bson.M) {
+	namedMu.Lock()
+	defer namedMu.Unlock()
+
+	named[name] = build
+}
+
+// Returns the names of the registered queries, sorted.
+func QueryNames() []string {
+	namedMu.RLock()
+	defer namedMu.RUnlock()
+
+	names := make([]string, 0, len(named))
+	for name := range named {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Returns the query registered under name built with params, e.g. to log
+// or explain it.
+func BuildQuery(name string, params bson.M) (bson.M, error) {
+	namedMu.RLock()
+	build, ok := named[name]
+	namedMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", ErrUnknownQuery, name)
+	}
+	return build(params), nil
+}
+
+// Find records like Find with the query registered under name built with
+// params.
+func FindNamed(i interface{}, name string, params bson.M, sortFields ...string) error {
+	return std.FindNamed(i, name, params, sortFields...)
+}
+
+// Find records with a named query using the client. See FindNamed.
+func (c *Client) FindNamed(i interface{}, name string, params bson.M, sortFields ...string) error {
+	q, err := BuildQuery(name, params)
+	if err != nil {
+		return err
+	}
+	return c.Find(i, q, sortFields...)
+}
@@ -0,0 +1,38 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestNamedQuery(t *testing.T) {
+	RegisterQuery("test_active", func(p bson.M) bson.M {
+		return bson.M{"active": true, "plan": p["plan"]}
+	})
+
+	q, err := BuildQuery("test_active", bson.M{"plan": "pro"})
+	if err != nil {
+		t.Fatal("Couldn't build the query:", err)
+	}
+	if expected := (bson.M{"active": true, "plan": "pro"}); !reflect.DeepEqual(q, expected) {
+		t.Fatal("Expected", expected, "got:", q)
+	}
+
+	found := false
+	for _, name := range QueryNames() {
+		found = found || name == "test_active"
+	}
+	if !found {
+		t.Fatal("Expected test_active to be listed got:", QueryNames())
+	}
+}
+
+func TestFindNamedUnknown(t *testing.T) {
+	var recs []validatedModel
+	if err := FindNamed(&recs, "test_missing", nil); !errors.Is(err, ErrUnknownQuery) {
+		t.Fatal("Expected ErrUnknownQuery got:", err)
+	}
+}
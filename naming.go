@@ -0,0 +1,47 @@
+package mongo
+
+import (
+	"strings"
+	"sync"
+)
+
+var (
+	affixMu    sync.RWMutex
+	collPrefix string
+	collSuffix string
+)
+
+// Set a prefix and suffix, e.g. "staging_", added to the name of every
+// collection so several environments can share one database. Models and
+// functions taking a collection name keep using the plain name; the affixes
+// are only added when the collection is opened. That includes the package's
+// own collections such as TrashCollection. Set them before doing anything
+// else.
+func SetCollectionAffixes(prefix, suffix string) {
+	affixMu.Lock()
+	defer affixMu.Unlock()
+
+	collPrefix, collSuffix = prefix, suffix
+}
+
+// Returns the name coll is stored under once the prefix and suffix are
+// added. Use it when referring to a collection inside a query or pipeline
+// stage, e.g. the from of a $lookup.
+func PhysicalCollection(coll string) string {
+	affixMu.RLock()
+	defer affixMu.RUnlock()
+
+	return collPrefix + coll + collSuffix
+}
+
+// logicalCollection strips the prefix and suffix from a stored collection
+// name. It returns false if name doesn't have them.
+func logicalCollection(name string) (string, bool) {
+	affixMu.RLock()
+	defer affixMu.RUnlock()
+
+	if len(name) < len(collPrefix)+len(collSuffix) || !strings.HasPrefix(name, collPrefix) || !strings.HasSuffix(name, collSuffix) {
+		return "", false
+	}
+	return name[len(collPrefix) : len(name)-len(collSuffix)], true
+}
@@ -0,0 +1,34 @@
+package mongo
+
+import (
+	"testing"
+)
+
+func TestCollectionAffixes(t *testing.T) {
+	SetCollectionAffixes("staging_", "_v2")
+	defer SetCollectionAffixes("", "")
+
+	if name := PhysicalCollection("MongoTest"); name != "staging_MongoTest_v2" {
+		t.Fatal("Expected staging_MongoTest_v2 got:", name)
+	}
+
+	if name, ok := logicalCollection("staging_MongoTest_v2"); !ok || name != "MongoTest" {
+		t.Fatal("Expected MongoTest got:", name, ok)
+	}
+
+	for _, name := range []string{"MongoTest", "prod_MongoTest_v2", "staging_MongoTest", "staging_v2"} {
+		if _, ok := logicalCollection(name); ok {
+			t.Fatal("Expected the collection to belong to another environment:", name)
+		}
+	}
+}
+
+func TestNoCollectionAffixes(t *testing.T) {
+	if name := PhysicalCollection("MongoTest"); name != "MongoTest" {
+		t.Fatal("Expected MongoTest got:", name)
+	}
+
+	if name, ok := logicalCollection("MongoTest"); !ok || name != "MongoTest" {
+		t.Fatal("Expected MongoTest got:", name, ok)
+	}
+}
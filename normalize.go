@@ -0,0 +1,148 @@
+package mongo
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// String fields can be cleaned up before they're written with a normalize
+// tag listing normalizers to apply in order:
+//
+//	type User struct {
+//		Email string `bson:"email" normalize:"trim,lower"`
+//	}
+//
+// The built in normalizers are trim, lower, upper and squash, which
+// collapses runs of whitespace into a single space. Add others with
+// RegisterNormalizer. Tags apply to string, *string and []string fields,
+// including those of nested and inlined structs.
+
+var (
+	normalizersMu sync.RWMutex
+	normalizers   = map[string]func(string) string{
+		"trim":   strings.TrimSpace,
+		"lower":  strings.ToLower,
+		"upper":  strings.ToUpper,
+		"squash": func(s string) string { return strings.Join(strings.Fields(s), " ") },
+	}
+
+	normalizeOnRead int32
+)
+
+// Register a normalizer for use in normalize tags, replacing any with the
+// same name.
+func RegisterNormalizer(name string, fn func(string) string) {
+	normalizersMu.Lock()
+	defer normalizersMu.Unlock()
+
+	normalizers[name] = fn
+}
+
+// Set whether records are also normalized after they're read, which cleans
+// up documents written before the tags were added. Off by default.
+func SetNormalizeOnRead(on bool) {
+	var v int32
+	if on {
+		v = 1
+	}
+	atomic.StoreInt32(&normalizeOnRead, v)
+}
+
+// normalize applies the normalize tags of the record i points to and fills
+// in its sort key fields. See FindSorted.
+func normalize(i interface{}) error {
+	v := reflect.ValueOf(i)
+	if v.Kind() != reflect.Ptr {
+		return nil
+	}
+
+	if err := normalizeValue(v.Elem()); err != nil {
+		return err
+	}
+
+	fillSortKeys(v)
+	return nil
+}
+
+func normalizeValue(v reflect.Value) error {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	t := v.Type()
+	for n := 0; n < t.NumField(); n++ {
+		f := t.Field(n)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+
+		fv := v.Field(n)
+		tag := f.Tag.Get("normalize")
+		if tag == "" {
+			if err := normalizeValue(fv); err != nil {
+				return err
+			}
+			continue
+		}
+
+		fns, err := normalizersFor(tag)
+		if err != nil {
+			return fmt.Errorf("%v.%v: %v", t.Name(), f.Name, err)
+		}
+
+		if err := normalizeField(fv, fns); err != nil {
+			return fmt.Errorf("%v.%v: %v", t.Name(), f.Name, err)
+		}
+	}
+	return nil
+}
+
+func normalizeField(v reflect.Value, fns []func(string) string) error {
+	apply := func(s reflect.Value) {
+		str := s.String()
+		for _, fn := range fns {
+			str = fn(str)
+		}
+		s.SetString(str)
+	}
+
+	switch {
+	case v.Kind() == reflect.String:
+		apply(v)
+	case v.Kind() == reflect.Ptr && v.Type().Elem().Kind() == reflect.String:
+		if !v.IsNil() {
+			apply(v.Elem())
+		}
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		for n := 0; n < v.Len(); n++ {
+			apply(v.Index(n))
+		}
+	default:
+		return fmt.Errorf("normalize tag on a %v", v.Type())
+	}
+	return nil
+}
+
+func normalizersFor(tag string) ([]func(string) string, error) {
+	normalizersMu.RLock()
+	defer normalizersMu.RUnlock()
+
+	var fns []func(string) string
+	for _, name := range strings.Split(tag, ",") {
+		fn, ok := normalizers[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("Unknown normalizer %q", name)
+		}
+		fns = append(fns, fn)
+	}
+	return fns, nil
+}
@@ -0,0 +1,73 @@
+package mongo
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type normalizeAddress struct {
+	City string `bson:"city" normalize:"squash,upper"`
+}
+
+type normalizeModel struct {
+	Email   string   `bson:"email" normalize:"trim,lower"`
+	Nick    *string  `bson:"nick" normalize:"trim"`
+	Tags    []string `bson:"tags" normalize:"lower"`
+	Bio     string   `bson:"bio"`
+	Address normalizeAddress
+}
+
+func TestNormalize(t *testing.T) {
+	nick := "  george "
+	rec := &normalizeModel{
+		Email:   "  George@Example.COM ",
+		Nick:    &nick,
+		Tags:    []string{"Go", "MONGO"},
+		Bio:     "  untouched ",
+		Address: normalizeAddress{City: " orbit   city "},
+	}
+
+	if err := normalize(rec); err != nil {
+		t.Fatal("Couldn't normalize:", err)
+	}
+
+	expected := &normalizeModel{
+		Email:   "george@example.com",
+		Nick:    &nick,
+		Tags:    []string{"go", "mongo"},
+		Bio:     "  untouched ",
+		Address: normalizeAddress{City: "ORBIT CITY"},
+	}
+	if !reflect.DeepEqual(rec, expected) || nick != "george" {
+		t.Fatalf("Expected %+v got: %+v", expected, rec)
+	}
+}
+
+func TestRegisterNormalizer(t *testing.T) {
+	RegisterNormalizer("nodash", func(s string) string { return strings.Replace(s, "-", "", -1) })
+
+	rec := &struct {
+		Phone string `normalize:"nodash"`
+	}{"555-1234"}
+
+	if err := normalize(rec); err != nil || rec.Phone != "5551234" {
+		t.Fatal("Expected 5551234 got:", rec.Phone, err)
+	}
+}
+
+func TestNormalizeErrors(t *testing.T) {
+	unknown := &struct {
+		Name string `normalize:"nope"`
+	}{}
+	if err := normalize(unknown); err == nil {
+		t.Fatal("Expected an error for an unknown normalizer")
+	}
+
+	wrongType := &struct {
+		Age int `normalize:"trim"`
+	}{}
+	if err := normalize(wrongType); err == nil {
+		t.Fatal("Expected an error for a tag on an int")
+	}
+}
@@ -0,0 +1,367 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrOplogEntry is returned, wrapped with details, by OplogTailer.Run for
+// oplog entries it can't convert into a ChangeEvent.
+var ErrOplogEntry = errors.New("Unsupported oplog entry")
+
+// ResumeCollection stores the resume position of each oplog tailer.
+var ResumeCollection = "oplog_resume"
+
+// How often the resume position is saved, in events.
+const resumeEvery = 100
+
+// ChangeOp is the kind of write a ChangeEvent describes.
+type ChangeOp string
+
+const (
+	ChangeInsert ChangeOp = "insert"
+	ChangeUpdate ChangeOp = "update"
+	ChangeDelete ChangeOp = "delete"
+)
+
+// ChangeEvent is a single write read from the oplog.
+type ChangeEvent struct {
+	Op         ChangeOp
+	Collection string
+	Id         interface{}
+	// Doc is the inserted document for inserts and the update document,
+	// either operators or a full replacement, for updates. It's empty for
+	// deletes. The delta updates servers from 5.0 log are converted to $set
+	// and $unset.
+	Doc       bson.Raw
+	Timestamp bson.MongoTimestamp
+}
+
+// Decode the event's document into v.
+func (e ChangeEvent) Decode(v interface{}) error {
+	return e.Doc.Unmarshal(v)
+}
+
+// OplogTailer converts the oplog entries of selected collections into
+// ChangeEvents for change-data-capture. It needs a replica set. The position
+// of the last handled event is saved in ResumeCollection under the tailer's
+// name so a restarted tailer carries on where it left off. Delivery is
+// at-least-once: events handled after the last save are handled again after
+// a restart.
+//
+// Writes made in a transaction, which the oplog records in a single
+// applyOps entry, are delivered one event per write. Other commands, like
+// dropping a collection, aren't delivered. Delta updates that change arrays
+// in place can't be expressed as update operators, so Run stops with an
+// error wrapping ErrOplogEntry when it meets one.
+type OplogTailer struct {
+	client      *Client
+	name        string
+	handler     func(ChangeEvent) error
+	collections []string
+	// PollTimeout is how long the tailer waits for new entries before
+	// checking whether it should stop.
+	PollTimeout time.Duration
+}
+
+// Create a tailer that calls handler for every write to the collections of
+// models. The name identifies the tailer's saved resume position.
+func NewOplogTailer(name string, handler func(ChangeEvent) error, models ...interface{}) *OplogTailer {
+	return std.NewOplogTailer(name, handler, models...)
+}
+
+// Create an oplog tailer using the client. See NewOplogTailer.
+func (c *Client) NewOplogTailer(name string, handler func(ChangeEvent) error, models ...interface{}) *OplogTailer {
+	return &OplogTailer{
+		client:      c,
+		name:        name,
+		handler:     handler,
+		collections: uniqueCollections(models),
+		PollTimeout: time.Second,
+	}
+}
+
+// Tail the oplog until stop is closed or the handler returns an error. If
+// the tailer has no saved position it starts with the next write. The
+// position is saved on the way out too; if that fails and nothing else did,
+// Run returns the error.
+func (t *OplogTailer) Run(stop <-chan struct{}) (err error) {
+	s, err := t.client.GetSession()
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	namespaces := map[string]string{}
+	var nsList []string
+	for _, c := range t.collections {
+		ns := t.client.GetColl(s, c).FullName
+		namespaces[ns] = c
+		nsList = append(nsList, ns)
+	}
+
+	oplog := s.DB("local").C("oplog.rs")
+	resume := t.client.GetColl(s, ResumeCollection)
+
+	ts, err := t.resumePosition(resume, oplog)
+	if err != nil {
+		return err
+	}
+
+	save := func() error {
+		_, err := resume.UpsertId(t.name, bson.M{"$set": bson.M{"ts": ts, "updatedat": now()}})
+		return err
+	}
+	defer func() {
+		if serr := save(); serr != nil && err == nil {
+			err = serr
+		}
+	}()
+
+	unsaved := 0
+	for {
+		q := bson.M{"ts": bson.M{"$gt": ts}, "$or": []bson.M{
+			{"ns": bson.M{"$in": nsList}, "op": bson.M{"$in": []string{"i", "u", "d"}}},
+			{"op": "c", "o.applyOps.ns": bson.M{"$in": nsList}},
+		}}
+		iter := oplog.Find(q).LogReplay().Tail(t.PollTimeout)
+
+		for {
+			if stopped(stop) {
+				iter.Close()
+				return nil
+			}
+
+			var entry oplogEntry
+			if !iter.Next(&entry) {
+				break
+			}
+
+			events, err := oplogEvents(entry, namespaces)
+			if err != nil {
+				iter.Close()
+				return err
+			}
+
+			for _, ev := range events {
+				if err := t.handler(ev); err != nil {
+					iter.Close()
+					return err
+				}
+			}
+
+			ts = entry.Ts
+			if unsaved++; unsaved >= resumeEvery {
+				if err := save(); err != nil {
+					iter.Close()
+					return err
+				}
+				unsaved = 0
+			}
+		}
+
+		if err := iter.Close(); err != nil {
+			return err
+		}
+
+		if stopped(stop) {
+			return nil
+		}
+	}
+}
+
+// resumePosition returns the saved position of the tailer or the timestamp
+// of the newest oplog entry if there isn't one.
+func (t *OplogTailer) resumePosition(resume, oplog *mgo.Collection) (bson.MongoTimestamp, error) {
+	var saved struct {
+		Ts bson.MongoTimestamp `bson:"ts"`
+	}
+
+	err := resume.FindId(t.name).One(&saved)
+	if err == nil {
+		return saved.Ts, nil
+	}
+	if err != mgo.ErrNotFound {
+		return 0, err
+	}
+
+	var last struct {
+		Ts bson.MongoTimestamp `bson:"ts"`
+	}
+	if err := oplog.Find(nil).Sort("-$natural").Limit(1).One(&last); err != nil {
+		return 0, err
+	}
+	return last.Ts, nil
+}
+
+// oplogEntry is an entry of the oplog, or one of the writes of an applyOps
+// entry.
+type oplogEntry struct {
+	Ts bson.MongoTimestamp `bson:"ts"`
+	Op string              `bson:"op"`
+	Ns string              `bson:"ns"`
+	O  bson.Raw            `bson:"o"`
+	O2 struct {
+		Id interface{} `bson:"_id"`
+	} `bson:"o2"`
+}
+
+// oplogEvents converts an oplog entry into the events for the writes it
+// records to the collections in namespaces, keyed by namespace.
+func oplogEvents(e oplogEntry, namespaces map[string]string) ([]ChangeEvent, error) {
+	if e.Op == "c" {
+		var cmd struct {
+			ApplyOps []oplogEntry `bson:"applyOps"`
+		}
+		if err := e.O.Unmarshal(&cmd); err != nil {
+			return nil, err
+		}
+
+		var events []ChangeEvent
+		for _, op := range cmd.ApplyOps {
+			op.Ts = e.Ts
+			evs, err := oplogEvents(op, namespaces)
+			if err != nil {
+				return nil, err
+			}
+			events = append(events, evs...)
+		}
+		return events, nil
+	}
+
+	coll, ok := namespaces[e.Ns]
+	if !ok || (e.Op != "i" && e.Op != "u" && e.Op != "d") {
+		return nil, nil
+	}
+
+	ev, err := changeEvent(e.Op, coll, e.O, e.O2.Id)
+	if err != nil {
+		return nil, err
+	}
+	ev.Timestamp = e.Ts
+	return []ChangeEvent{ev}, nil
+}
+
+func changeEvent(op, coll string, o bson.Raw, updateId interface{}) (ChangeEvent, error) {
+	ev := ChangeEvent{Collection: coll}
+
+	var doc struct {
+		Id interface{} `bson:"_id"`
+	}
+	if err := o.Unmarshal(&doc); err != nil {
+		return ev, err
+	}
+
+	switch op {
+	case "i":
+		ev.Op, ev.Id, ev.Doc = ChangeInsert, doc.Id, o
+	case "u":
+		update, err := updateDoc(o)
+		if err != nil {
+			return ev, err
+		}
+		ev.Op, ev.Id, ev.Doc = ChangeUpdate, updateId, update
+	case "d":
+		ev.Op, ev.Id = ChangeDelete, doc.Id
+	}
+
+	return ev, nil
+}
+
+// updateDoc returns the update document of an update entry, converting the
+// delta format servers from 5.0 log, {$v: 2, diff: ...}, to $set and $unset.
+func updateDoc(o bson.Raw) (bson.Raw, error) {
+	var delta struct {
+		V    int    `bson:"$v"`
+		Diff bson.M `bson:"diff"`
+	}
+	if err := o.Unmarshal(&delta); err != nil {
+		return o, err
+	}
+	if delta.V != 2 {
+		return o, nil
+	}
+
+	update, err := diffUpdate(delta.Diff)
+	if err != nil {
+		return o, err
+	}
+
+	data, err := bson.Marshal(update)
+	if err != nil {
+		return o, err
+	}
+	return bson.Raw{Kind: 3, Data: data}, nil
+}
+
+// diffUpdate converts a $v: 2 oplog diff into $set and $unset operators.
+func diffUpdate(diff bson.M) (bson.M, error) {
+	set, unset := bson.M{}, bson.M{}
+	if err := flattenDiff(diff, "", set, unset); err != nil {
+		return nil, err
+	}
+
+	update := bson.M{}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	return update, nil
+}
+
+// flattenDiff adds the changes in diff, a diff of the subdocument at prefix,
+// to set and unset. In a diff u holds updated fields, i inserted ones and d
+// deleted ones, and keys starting with s hold the diff of a subdocument.
+func flattenDiff(diff bson.M, prefix string, set, unset bson.M) error {
+	if a, _ := diff["a"].(bool); a {
+		return fmt.Errorf("%w: array diff of %v", ErrOplogEntry, strings.TrimSuffix(prefix, "."))
+	}
+
+	for k, v := range diff {
+		switch {
+		case k == "u" || k == "i":
+			fields, ok := v.(bson.M)
+			if !ok {
+				return fmt.Errorf("%w: diff field %v%v", ErrOplogEntry, prefix, k)
+			}
+			for f, val := range fields {
+				set[prefix+f] = val
+			}
+		case k == "d":
+			fields, ok := v.(bson.M)
+			if !ok {
+				return fmt.Errorf("%w: diff field %v%v", ErrOplogEntry, prefix, k)
+			}
+			for f := range fields {
+				unset[prefix+f] = ""
+			}
+		case strings.HasPrefix(k, "s") && len(k) > 1:
+			sub, ok := v.(bson.M)
+			if !ok {
+				return fmt.Errorf("%w: diff field %v%v", ErrOplogEntry, prefix, k)
+			}
+			if err := flattenDiff(sub, prefix+k[1:]+".", set, unset); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("%w: diff field %v%v", ErrOplogEntry, prefix, k)
+		}
+	}
+	return nil
+}
+
+func stopped(stop <-chan struct{}) bool {
+	select {
+	case <-stop:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,89 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDiffUpdate(t *testing.T) {
+	diff := bson.M{
+		"u": bson.M{"name": "George"},
+		"i": bson.M{"nickname": "G"},
+		"d": bson.M{"legacy": false},
+		"saddress": bson.M{
+			"u":    bson.M{"city": "Orbit City"},
+			"d":    bson.M{"zip": false},
+			"sgeo": bson.M{"i": bson.M{"lat": 1.5}},
+		},
+	}
+
+	update, err := diffUpdate(diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := bson.M{
+		"$set":   bson.M{"name": "George", "nickname": "G", "address.city": "Orbit City", "address.geo.lat": 1.5},
+		"$unset": bson.M{"legacy": "", "address.zip": ""},
+	}
+	if !reflect.DeepEqual(update, want) {
+		t.Fatal("Unexpected update:", update)
+	}
+}
+
+func TestDiffUpdateArrays(t *testing.T) {
+	diff := bson.M{"stags": bson.M{"a": true, "u1": "b"}}
+
+	if _, err := diffUpdate(diff); !errors.Is(err, ErrOplogEntry) {
+		t.Fatal("Expected ErrOplogEntry for an array diff, got:", err)
+	}
+	if _, err := diffUpdate(bson.M{"x": 1}); !errors.Is(err, ErrOplogEntry) {
+		t.Fatal("Expected ErrOplogEntry for an unknown diff field, got:", err)
+	}
+}
+
+func rawDoc(t *testing.T, doc interface{}) bson.Raw {
+	t.Helper()
+
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bson.Raw{Kind: 3, Data: data}
+}
+
+func TestOplogEventsApplyOps(t *testing.T) {
+	namespaces := map[string]string{"app.orders": "orders"}
+
+	entry := oplogEntry{Ts: 42, Op: "c", Ns: "admin.$cmd", O: rawDoc(t, bson.M{"applyOps": []bson.M{
+		{"op": "i", "ns": "app.orders", "o": bson.M{"_id": 1, "total": 5}},
+		{"op": "i", "ns": "app.users", "o": bson.M{"_id": 2}},
+		{"op": "u", "ns": "app.orders", "o": bson.M{"$v": 2, "diff": bson.M{"u": bson.M{"total": 6}}}, "o2": bson.M{"_id": 1}},
+		{"op": "d", "ns": "app.orders", "o": bson.M{"_id": 3}},
+	}})}
+
+	events, err := oplogEvents(entry, namespaces)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 3 {
+		t.Fatal("Expected the 3 writes to orders got:", events)
+	}
+	for n, op := range []ChangeOp{ChangeInsert, ChangeUpdate, ChangeDelete} {
+		if events[n].Op != op || events[n].Collection != "orders" || events[n].Timestamp != 42 {
+			t.Fatalf("Unexpected event %v: %+v", n, events[n])
+		}
+	}
+
+	var update bson.M
+	if err := events[1].Decode(&update); err != nil {
+		t.Fatal(err)
+	}
+	if set, _ := update["$set"].(bson.M); set["total"] != 6 {
+		t.Fatal("Expected the diff to be converted to $set got:", update)
+	}
+}
@@ -0,0 +1,261 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// OptionsId is the document in SettingsCollection that PublishOption writes
+// and WatchOptions applies.
+var OptionsId = "options"
+
+// ErrUnknownOption is returned, wrapped with the name, for options that
+// don't exist.
+var ErrUnknownOption = errors.New("Unknown option")
+
+// ErrInvalidOption is returned, wrapped with the name and value, for option
+// values of the wrong type.
+var ErrInvalidOption = errors.New("Invalid option value")
+
+// ErrReadOnly is returned, wrapped with the operation and collection, for
+// writes made while read only mode is on.
+var ErrReadOnly = errors.New("Writes are disabled by read only mode")
+
+var (
+	readOnly  int32
+	slowQuery int64
+)
+
+// options maps the names accepted by SetOption to functions checking their
+// value and, if apply is true, calling the setter.
+var options = map[string]func(v interface{}, apply bool) error{
+	"readonly":            boolOption(SetReadOnly),
+	"slowquery":           durationOption(SetSlowQueryLog),
+	"samplerate":          floatOption(SetSampleRate),
+	"safety":              boolOption(SetSafetyMode),
+	"normalizeonread":     boolOption(SetNormalizeOnRead),
+	"tombstones":          boolOption(SetTombstones),
+	"writesuppression":    durationOption(SetWriteSuppression),
+	"deprecationwarnings": boolOption(SetDeprecationWarnings),
+}
+
+var (
+	optionsMu    sync.Mutex
+	optionValues = map[string]interface{}{}
+)
+
+// Turn read only mode on or off. When it's on, inserts, updates and deletes
+// are rejected with ErrReadOnly, e.g. during a migration or an incident.
+// Writes to SettingsCollection are still permitted so the mode can be turned
+// off through PublishOption.
+func SetReadOnly(on bool) {
+	var v int32
+	if on {
+		v = 1
+	}
+	atomic.StoreInt32(&readOnly, v)
+}
+
+// Log operations that take at least threshold with the standard logger,
+// along with their sanitized query. Zero, the default, turns it off.
+func SetSlowQueryLog(threshold time.Duration) {
+	atomic.StoreInt64(&slowQuery, int64(threshold))
+}
+
+// Set a runtime option by name, so features can be toggled from an admin
+// endpoint or a config file without a redeploy:
+//
+//	err := mongo.SetOption("slowquery", "250ms")
+//
+// The options and the setters they call are:
+//
+//	readonly             bool      SetReadOnly
+//	slowquery            duration  SetSlowQueryLog
+//	samplerate           number    SetSampleRate
+//	safety               bool      SetSafetyMode
+//	normalizeonread      bool      SetNormalizeOnRead
+//	tombstones           bool      SetTombstones
+//	writesuppression     duration  SetWriteSuppression
+//	deprecationwarnings  bool      SetDeprecationWarnings
+//
+// Durations are a time.Duration or a string like "1.5s".
+func SetOption(name string, value interface{}) error {
+	set, ok := options[name]
+	if !ok {
+		return fmt.Errorf("%w: %v", ErrUnknownOption, name)
+	}
+
+	if err := set(value, true); err != nil {
+		return fmt.Errorf("%w: %v = %v", ErrInvalidOption, name, value)
+	}
+
+	optionsMu.Lock()
+	defer optionsMu.Unlock()
+
+	optionValues[name] = value
+	return nil
+}
+
+// Returns the values set through SetOption by name. Options that were never
+// set keep their defaults and aren't listed.
+func Options() map[string]interface{} {
+	optionsMu.Lock()
+	defer optionsMu.Unlock()
+
+	values := make(map[string]interface{}, len(optionValues))
+	for k, v := range optionValues {
+		values[k] = v
+	}
+	return values
+}
+
+// Returns the names accepted by SetOption, sorted.
+func OptionNames() []string {
+	names := make([]string, 0, len(options))
+	for name := range options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Store an option in the options document, for every process running
+// WatchOptions to apply. It isn't applied here until WatchOptions next
+// reloads the document; call SetOption as well for that.
+func PublishOption(name string, value interface{}) error {
+	set, ok := options[name]
+	if !ok {
+		return fmt.Errorf("%w: %v", ErrUnknownOption, name)
+	}
+
+	// Durations are stored as strings, since they'd otherwise read back as
+	// plain numbers.
+	if d, ok := value.(time.Duration); ok {
+		value = d.String()
+	}
+
+	if err := set(value, false); err != nil {
+		return fmt.Errorf("%w: %v = %v", ErrInvalidOption, name, value)
+	}
+
+	op := startOp("update", SettingsCollection, bson.M{"_id": OptionsId})
+
+	if err := op.allowed(); err != nil {
+		return op.done(err)
+	}
+
+	s, err := GetSession()
+	if err != nil {
+		return op.done(err)
+	}
+	defer s.Close()
+
+	_, err = GetColl(s, SettingsCollection).UpsertId(OptionsId, bson.M{"$set": bson.M{name: value}})
+	return op.done(err)
+}
+
+// Apply the options document now and whenever it changes, checking every
+// interval until stop is closed. Errors, including invalid options in the
+// document, are passed to onError, which may be nil. Options removed from
+// the document keep their current value.
+//
+//	go mongo.WatchOptions(10*time.Second, stop, logErr)
+func WatchOptions(interval time.Duration, stop <-chan struct{}, onError func(error)) {
+	st := NewSettings(OptionsId, nil)
+	st.OnChange(func() { applyOptions(st, onError) })
+
+	// The first load isn't a change, so apply it here.
+	if err := st.Reload(); err != nil {
+		if onError != nil {
+			onError(err)
+		}
+	} else {
+		applyOptions(st, onError)
+	}
+
+	st.Run(interval, stop, onError)
+}
+
+// applyOptions calls SetOption for every option in the cached document.
+func applyOptions(st *Settings, onError func(error)) {
+	var doc bson.M
+	if err := st.Get(&doc); err != nil {
+		if onError != nil {
+			onError(err)
+		}
+		return
+	}
+
+	for name, v := range doc {
+		if name == "_id" {
+			continue
+		}
+		if err := SetOption(name, v); err != nil && onError != nil {
+			onError(err)
+		}
+	}
+}
+
+func boolOption(fn func(bool)) func(interface{}, bool) error {
+	return func(v interface{}, apply bool) error {
+		on, ok := v.(bool)
+		if !ok {
+			return ErrInvalidOption
+		}
+		if apply {
+			fn(on)
+		}
+		return nil
+	}
+}
+
+func floatOption(fn func(float64)) func(interface{}, bool) error {
+	return func(v interface{}, apply bool) error {
+		f, ok := numeric(v)
+		if !ok {
+			return ErrInvalidOption
+		}
+		if apply {
+			fn(f)
+		}
+		return nil
+	}
+}
+
+func durationOption(fn func(time.Duration)) func(interface{}, bool) error {
+	return func(v interface{}, apply bool) error {
+		var d time.Duration
+		switch v := v.(type) {
+		case time.Duration:
+			d = v
+		case string:
+			var err error
+			if d, err = time.ParseDuration(v); err != nil {
+				return err
+			}
+		default:
+			return ErrInvalidOption
+		}
+		if apply {
+			fn(d)
+		}
+		return nil
+	}
+}
+
+// logSlow logs o if it took at least the slow query threshold.
+func (o *operation) logSlow(d time.Duration) {
+	threshold := time.Duration(atomic.LoadInt64(&slowQuery))
+	if threshold == 0 || d < threshold {
+		return
+	}
+
+	log.Printf("mongo: slow %v on %v took %v: %v", o.op, o.coll, d, SanitizeQuery(o.query))
+}
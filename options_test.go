@@ -0,0 +1,73 @@
+package mongo
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSetOption(t *testing.T) {
+	defer SetSlowQueryLog(0)
+
+	if err := SetOption("nosuchoption", true); !errors.Is(err, ErrUnknownOption) {
+		t.Fatal("Expected ErrUnknownOption got:", err)
+	}
+
+	if err := SetOption("readonly", "yes"); !errors.Is(err, ErrInvalidOption) {
+		t.Fatal("Expected ErrInvalidOption got:", err)
+	}
+
+	if err := SetOption("slowquery", "250ms"); err != nil {
+		t.Fatal("Couldn't set slowquery:", err)
+	}
+
+	if got := Options()["slowquery"]; got != "250ms" {
+		t.Fatal("Expected slowquery to be listed got:", got)
+	}
+	if _, ok := Options()["readonly"]; ok {
+		t.Fatal("Expected the invalid readonly not to be listed")
+	}
+}
+
+func TestOptionParsing(t *testing.T) {
+	var got time.Duration
+	set := durationOption(func(d time.Duration) { got = d })
+
+	if err := set("1.5s", false); err != nil || got != 0 {
+		t.Fatal("Expected the duration to be checked but not applied got:", got, err)
+	}
+	if err := set(2*time.Second, true); err != nil || got != 2*time.Second {
+		t.Fatal("Expected 2s got:", got, err)
+	}
+	if err := set(1500, true); err == nil {
+		t.Fatal("Expected a number to be rejected as a duration")
+	}
+
+	var rate float64
+	if err := floatOption(func(f float64) { rate = f })(int32(1), true); err != nil || rate != 1 {
+		t.Fatal("Expected an int32 to be accepted as a number got:", rate, err)
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	if err := SetOption("readonly", true); err != nil {
+		t.Fatal("Couldn't turn on read only mode:", err)
+	}
+	defer SetOption("readonly", false)
+
+	for _, op := range []string{"insert", "update", "delete", "restore"} {
+		if err := startOp(op, "posts", nil).allowed(); !errors.Is(err, ErrReadOnly) {
+			t.Fatal("Expected ErrReadOnly for", op, "got:", err)
+		}
+	}
+
+	if err := startOp("find", "posts", nil).allowed(); err != nil {
+		t.Fatal("Expected finds to be permitted got:", err)
+	}
+	if err := startOp("update", SettingsCollection, nil).allowed(); err != nil {
+		t.Fatal("Expected settings to stay writable got:", err)
+	}
+	if err := bulkAllowed("posts", bulkDelete); !errors.Is(err, ErrReadOnly) {
+		t.Fatal("Expected ErrReadOnly for a bulk delete got:", err)
+	}
+}
@@ -0,0 +1,104 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+)
+
+// DefaultPerPage is used by Paginate when perPage is less than one.
+var DefaultPerPage = 20
+
+// Page describes the page of results returned by Paginate. Total and Pages
+// are -1 when the count was skipped.
+type Page struct {
+	Page    int  `json:"page"`
+	PerPage int  `json:"perPage"`
+	Total   int  `json:"total"`
+	Pages   int  `json:"pages"`
+	HasNext bool `json:"hasNext"`
+}
+
+// PaginateOptions configures PaginateWith.
+type PaginateOptions struct {
+	// SkipCount leaves out counting the matching records, which is slow on
+	// very large collections. Whether there's a next page is still known.
+	SkipCount bool
+}
+
+// Find a page of records. Must pass in a pointer to a slice. Pages start at
+// one; a page less than one is treated as the first.
+func Paginate(i interface{}, q bson.M, page, perPage int, sortFields ...string) (*Page, error) {
+	return std.Paginate(i, q, page, perPage, sortFields...)
+}
+
+// Find a page of records using the client. See Paginate.
+func (c *Client) Paginate(i interface{}, q bson.M, page, perPage int, sortFields ...string) (*Page, error) {
+	return c.PaginateWith(i, q, page, perPage, PaginateOptions{}, sortFields...)
+}
+
+// Find a page of records like Paginate with options.
+func PaginateWith(i interface{}, q bson.M, page, perPage int, opts PaginateOptions, sortFields ...string) (*Page, error) {
+	return std.PaginateWith(i, q, page, perPage, opts, sortFields...)
+}
+
+// Find a page of records with options using the client. See PaginateWith.
+func (c *Client) PaginateWith(i interface{}, q bson.M, page, perPage int, opts PaginateOptions, sortFields ...string) (*Page, error) {
+	if !isPtr(i) {
+		return nil, NoPtr
+	}
+
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 {
+		perPage = DefaultPerPage
+	}
+
+	q = scopeQuery(i, q)
+	op := c.startOp("find", collName(i), q)
+
+	if err := op.allowed(); err != nil {
+		return nil, op.done(err)
+	}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return nil, op.done(err)
+	}
+	defer s.Close()
+
+	if q, err = op.check(c, s, q, true); err != nil {
+		return nil, op.done(err)
+	}
+
+	query := withoutLazy(c.GetColl(s, op.coll).Find(q), i)
+
+	p := &Page{Page: page, PerPage: perPage, Total: -1, Pages: -1}
+	if !opts.SkipCount {
+		if p.Total, err = query.Count(); err != nil {
+			return nil, op.done(err)
+		}
+		p.Pages = pageCount(p.Total, perPage)
+	}
+
+	// One more than asked for tells whether there's a next page.
+	var raws []bson.Raw
+	err = query.Sort(sortFields...).Skip((page - 1) * perPage).Limit(perPage + 1).All(&raws)
+	if err != nil {
+		return nil, op.done(err)
+	}
+
+	if len(raws) > perPage {
+		raws = raws[:perPage]
+		p.HasNext = true
+	}
+
+	if err := unmarshalAll(c, s, raws, i); err != nil {
+		return nil, op.done(err)
+	}
+
+	return p, op.done(nil)
+}
+
+func pageCount(total, perPage int) int {
+	return (total + perPage - 1) / perPage
+}
@@ -0,0 +1,12 @@
+package mongo
+
+import (
+	"testing"
+)
+
+func TestPaginateWithNoPtr(t *testing.T) {
+	_, err := PaginateWith([]MongoTest{}, nil, 1, 10, PaginateOptions{SkipCount: true})
+	if err != NoPtr {
+		t.Fatal("Expected NoPtr got:", err)
+	}
+}
@@ -0,0 +1,164 @@
+//go:build integration
+// +build integration
+
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"testing"
+	"time"
+)
+
+func TestGraphLookupChain(t *testing.T) {
+	requireServer(t)
+
+	if _, err := DeleteDocs(collName(&employee{}), bson.M{}); err != nil {
+		t.Fatal("Couldn't empty the collection:", err)
+	}
+
+	ceo := &employee{Id: bson.NewObjectId(), Name: "Cosmo"}
+	boss := &employee{Id: bson.NewObjectId(), Name: "George", ManagerId: ceo.Id}
+	judy := &employee{Id: bson.NewObjectId(), Name: "Judy", ManagerId: boss.Id}
+	if err := Insert(ceo, boss, judy); err != nil {
+		t.Fatal("Couldn't insert records:", err)
+	}
+
+	var rows []struct {
+		Chain []employee `bson:"chain"`
+	}
+	opts := GraphOptions{StartWith: "$managerid", ConnectFromField: "managerid", ConnectToField: "_id", As: "chain"}
+
+	if err := GraphLookup(&employee{}, bson.M{"name": "Judy"}, opts, &rows); err != nil {
+		t.Fatal("Couldn't run GraphLookup:", err)
+	}
+	if len(rows) != 1 || len(rows[0].Chain) != 2 {
+		t.Fatal("Expected the whole management chain got:", rows)
+	}
+
+	opts.MaxDepth = Depth(0)
+	if err := GraphLookup(&employee{}, bson.M{"name": "Judy"}, opts, &rows); err != nil {
+		t.Fatal("Couldn't run GraphLookup:", err)
+	}
+	if len(rows) != 1 || len(rows[0].Chain) != 1 || rows[0].Chain[0].Id != boss.Id {
+		t.Fatal("Expected only the direct manager got:", rows)
+	}
+}
+
+// resetItems empties the integrationItem collection and inserts items.
+func resetItems(t *testing.T, items ...*integrationItem) {
+	t.Helper()
+
+	if _, err := DeleteDocs(collName(&integrationItem{}), bson.M{}); err != nil {
+		t.Fatal("Couldn't empty the collection:", err)
+	}
+	for _, item := range items {
+		if err := Insert(item); err != nil {
+			t.Fatal("Couldn't insert record:", err)
+		}
+	}
+}
+
+func TestCountDistinct(t *testing.T) {
+	requireServer(t)
+
+	resetItems(t, &integrationItem{Name: "a", Qty: 1}, &integrationItem{Name: "b", Qty: 1}, &integrationItem{Name: "c", Qty: 2})
+
+	if n, err := CountDistinct(&integrationItem{}, "qty", nil); err != nil || n != 2 {
+		t.Fatal("Expected 2 distinct quantities got:", n, err)
+	}
+	if n, err := CountDistinct(&integrationItem{}, "qty", bson.M{"name": "none"}); err != nil || n != 0 {
+		t.Fatal("Expected 0 when nothing matches got:", n, err)
+	}
+
+	// With a sample as big as the collection the count is exact.
+	if n, err := CountDistinctApprox(&integrationItem{}, "name", nil, 10); err != nil || n != 3 {
+		t.Fatal("Expected 3 distinct names got:", n, err)
+	}
+}
+
+// $bucket needs 3.4.
+func TestHistogram(t *testing.T) {
+	requireVersion(t, 3, 4)
+
+	resetItems(t, &integrationItem{Name: "a", Qty: 1}, &integrationItem{Name: "b", Qty: 5}, &integrationItem{Name: "c", Qty: 50})
+
+	buckets, err := Histogram(&integrationItem{}, "qty", []float64{0, 10, 20}, nil)
+	if err != nil {
+		t.Fatal("Couldn't build the histogram:", err)
+	}
+	if len(buckets) != 3 || buckets[0].Count != 2 || buckets[1].Count != 0 || buckets[2].Label != OtherBucket || buckets[2].Count != 1 {
+		t.Fatal("Unexpected buckets:", buckets)
+	}
+
+	auto, err := HistogramAuto(&integrationItem{}, "qty", 3, nil)
+	if err != nil {
+		t.Fatal("Couldn't build the histogram:", err)
+	}
+	total := 0
+	for _, b := range auto {
+		total += b.Count
+	}
+	if total != 3 {
+		t.Fatal("Expected every record in a bucket got:", auto)
+	}
+}
+
+type rollupEvent struct {
+	Id     bson.ObjectId `bson:"_id"`
+	At     time.Time     `bson:"at"`
+	Amount int           `bson:"amount"`
+}
+
+func TestRollupByTimeBeforeEpoch(t *testing.T) {
+	requireServer(t)
+
+	if _, err := DeleteDocs(collName(&rollupEvent{}), bson.M{}); err != nil {
+		t.Fatal("Couldn't empty the collection:", err)
+	}
+	for _, at := range []time.Time{
+		time.Date(1969, 12, 31, 23, 10, 0, 0, time.UTC),
+		time.Date(1969, 12, 31, 23, 50, 0, 0, time.UTC),
+		time.Date(1970, 1, 1, 0, 20, 0, 0, time.UTC),
+	} {
+		if err := Insert(&rollupEvent{At: at, Amount: 5}); err != nil {
+			t.Fatal("Couldn't insert record:", err)
+		}
+	}
+
+	rollups, err := RollupByTime(&rollupEvent{}, "at", time.Hour, map[string]Agg{"total": {Op: AggSum, Field: "amount"}}, nil)
+	if err != nil {
+		t.Fatal("Couldn't roll up:", err)
+	}
+
+	if len(rollups) != 2 {
+		t.Fatal("Expected 2 intervals got:", rollups)
+	}
+	if !rollups[0].Start.Equal(time.Date(1969, 12, 31, 23, 0, 0, 0, time.UTC)) || rollups[0].Count != 2 || rollups[0].Values["total"] != 10 {
+		t.Fatal("Expected the last hour of 1969 got:", rollups[0])
+	}
+	if !rollups[1].Start.Equal(time.Unix(0, 0)) || rollups[1].Count != 1 {
+		t.Fatal("Expected the first hour of 1970 got:", rollups[1])
+	}
+}
+
+func TestAggregateIter(t *testing.T) {
+	requireServer(t)
+
+	resetItems(t, &integrationItem{Name: "a", Qty: 1}, &integrationItem{Name: "b", Qty: 2}, &integrationItem{Name: "c", Qty: 3})
+
+	c := AggregateIter(&integrationItem{}, Pipeline{}.Sort("qty"), AggregateOptions{BatchSize: 1, AllowDiskUse: true})
+	defer c.Close()
+
+	var names []string
+	var item integrationItem
+	for c.Next(&item) {
+		names = append(names, item.Name)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatal("Couldn't iterate:", err)
+	}
+	if len(names) != 3 || names[0] != "a" || names[2] != "c" {
+		t.Fatal("Expected a, b, c got:", names)
+	}
+}
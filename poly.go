@@ -0,0 +1,207 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// TypeKey is the document key that holds the type discriminator for types
+// sharing a collection.
+const TypeKey = "_t"
+
+type polyType struct {
+	collection    string
+	discriminator string
+}
+
+var (
+	polyMu        sync.RWMutex
+	polyTypes     = map[reflect.Type]polyType{}
+	polyFactories = map[string]map[string]func() interface{}{}
+)
+
+// Register a type that shares the named collection with other types. The
+// factory must return a pointer to a new, empty record of the type. Records
+// are stored with their type name under TypeKey, finds on the type are scoped
+// to it automatically and FindAny decodes each document into its concrete
+// type.
+func RegisterType(collection string, factory func() interface{}) {
+	rec := factory()
+	disc := typeName(rec)
+
+	polyMu.Lock()
+	defer polyMu.Unlock()
+
+	polyTypes[structType(rec)] = polyType{collection: collection, discriminator: disc}
+	if polyFactories[collection] == nil {
+		polyFactories[collection] = map[string]func() interface{}{}
+	}
+	polyFactories[collection][disc] = factory
+}
+
+// CollectionNamer is implemented by models that choose their own collection
+// instead of using their type name. Because methods are promoted, variants
+// embedding a base model that implements it share the base's collection.
+type CollectionNamer interface {
+	CollectionName() string
+}
+
+// Register variants of a base model. The base is usually embedded in each
+// variant with a `bson:",inline"` tag. Variants are stored in the base's
+// collection with a type discriminator, so Find on a variant only returns
+// that variant, Find on the base returns every variant decoded as the base
+// and FindVariants returns each record as its concrete variant.
+func RegisterVariants(base interface{}, variants ...interface{}) {
+	coll := collName(base)
+
+	for _, v := range variants {
+		t := structType(v)
+		RegisterType(coll, func() interface{} {
+			return reflect.New(t).Interface()
+		})
+	}
+}
+
+// Find records of every variant registered for base. See RegisterVariants.
+func FindVariants(base interface{}, q bson.M, sortFields ...string) ([]interface{}, error) {
+	return std.FindVariants(base, q, sortFields...)
+}
+
+// Find records of every variant using the client. See FindVariants.
+func (c *Client) FindVariants(base interface{}, q bson.M, sortFields ...string) ([]interface{}, error) {
+	return c.FindAny(collName(base), q, sortFields...)
+}
+
+// Find records of every registered type in a shared collection. Each element
+// of the result is a pointer to the concrete type registered for the
+// document's discriminator.
+func FindAny(collection string, q bson.M, sortFields ...string) ([]interface{}, error) {
+	return std.FindAny(collection, q, sortFields...)
+}
+
+// Find records of any registered type using the client. See FindAny.
+func (c *Client) FindAny(collection string, q bson.M, sortFields ...string) ([]interface{}, error) {
+	op := c.startOp("find", collection, q)
+
+	if err := op.allowed(); err != nil {
+		return nil, op.done(err)
+	}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return nil, op.done(err)
+	}
+	defer s.Close()
+
+	if q, err = op.check(c, s, q, false); err != nil {
+		return nil, op.done(err)
+	}
+
+	var raws []bson.Raw
+	if err := c.GetColl(s, collection).Find(q).Sort(sortFields...).All(&raws); err != nil {
+		return nil, op.done(err)
+	}
+
+	polyMu.RLock()
+	factories := polyFactories[collection]
+	polyMu.RUnlock()
+
+	records := make([]interface{}, 0, len(raws))
+	for _, raw := range raws {
+		var doc struct {
+			Type string `bson:"_t"`
+		}
+		if err := raw.Unmarshal(&doc); err != nil {
+			return nil, op.done(err)
+		}
+
+		factory, ok := factories[doc.Type]
+		if !ok {
+			return nil, op.done(fmt.Errorf("Unknown type %q in collection %v", doc.Type, collection))
+		}
+
+		rec := factory()
+		if err := unmarshalRecord(c, s, raw, rec); err != nil {
+			return nil, op.done(err)
+		}
+		records = append(records, rec)
+	}
+
+	return records, op.done(nil)
+}
+
+func lookupPoly(i interface{}) (polyType, bool) {
+	polyMu.RLock()
+	defer polyMu.RUnlock()
+
+	pt, ok := polyTypes[structType(i)]
+	return pt, ok
+}
+
+// CollectionFor returns the name of the collection that records like i are
+// stored in.
+func CollectionFor(i interface{}) string {
+	return collName(i)
+}
+
+// collName returns the name of the collection that records like i are
+// stored in.
+func collName(i interface{}) string {
+	if pt, ok := lookupPoly(i); ok {
+		return pt.collection
+	}
+
+	if t := structType(i); t != nil {
+		if namer, ok := reflect.New(t).Interface().(CollectionNamer); ok {
+			return namer.CollectionName()
+		}
+	}
+
+	return typeName(i)
+}
+
+// scopeQuery restricts q to the type of i if it shares its collection with
+// other types. q itself is never modified.
+func scopeQuery(i interface{}, q bson.M) bson.M {
+	pt, ok := lookupPoly(i)
+	if !ok {
+		return q
+	}
+
+	scoped := bson.M{TypeKey: pt.discriminator}
+	for k, v := range q {
+		scoped[k] = v
+	}
+	return scoped
+}
+
+// addDiscriminator returns the stored representation of rec with the type
+// discriminator added if rec shares its collection with other types.
+func addDiscriminator(i interface{}, rec interface{}) (interface{}, error) {
+	pt, ok := lookupPoly(i)
+	if !ok {
+		return rec, nil
+	}
+
+	data, err := bson.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc bson.D
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	for n, e := range doc {
+		if e.Name == TypeKey {
+			doc[n].Value = pt.discriminator
+			return doc, nil
+		}
+	}
+
+	return append(doc, bson.DocElem{Name: TypeKey, Value: pt.discriminator}), nil
+}
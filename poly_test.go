@@ -0,0 +1,76 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"testing"
+)
+
+type ClickEvent struct {
+	Id  bson.ObjectId `bson:"_id"`
+	URL string
+}
+
+type ViewEvent struct {
+	Id   bson.ObjectId `bson:"_id"`
+	Page string
+}
+
+func TestRegisterType(t *testing.T) {
+	RegisterType("events", func() interface{} { return &ClickEvent{} })
+	RegisterType("events", func() interface{} { return &ViewEvent{} })
+
+	if c := collName(&[]*ClickEvent{}); c != "events" {
+		t.Fatal("Expected the shared collection. Got:", c)
+	}
+
+	q := scopeQuery(&ViewEvent{}, bson.M{"page": "/"})
+	if q[TypeKey] != "ViewEvent" || q["page"] != "/" {
+		t.Fatal("Query wasn't scoped to the type:", q)
+	}
+
+	rec, err := marshalRecord(&ClickEvent{Id: bson.NewObjectId(), URL: "/x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc, ok := rec.(bson.D)
+	if !ok || doc.Map()[TypeKey] != "ClickEvent" {
+		t.Fatal("Stored record is missing its discriminator:", rec)
+	}
+}
+
+type Event struct {
+	Id bson.ObjectId `bson:"_id"`
+	At string
+}
+
+func (Event) CollectionName() string { return "event_log" }
+
+type LoginEvent struct {
+	Event `bson:",inline"`
+	User  string
+}
+
+type LogoutEvent struct {
+	Event  `bson:",inline"`
+	Reason string
+}
+
+func TestRegisterVariants(t *testing.T) {
+	RegisterVariants(&Event{}, &LoginEvent{}, &LogoutEvent{})
+
+	for _, i := range []interface{}{&Event{}, &LoginEvent{}, &[]LogoutEvent{}} {
+		if c := collName(i); c != "event_log" {
+			t.Fatalf("Expected %T in the base collection. Got: %v", i, c)
+		}
+	}
+
+	if q := scopeQuery(&Event{}, nil); q != nil {
+		t.Fatal("Finds on the base shouldn't be scoped:", q)
+	}
+
+	if q := scopeQuery(&LogoutEvent{}, nil); q[TypeKey] != "LogoutEvent" {
+		t.Fatal("Finds on a variant should be scoped:", q)
+	}
+}
@@ -0,0 +1,60 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"reflect"
+)
+
+// FieldSet holds the keys of a stored document. Add a field of this type
+// named FieldsPresent to a model to have it filled in on decode, so a field
+// that's missing from the document can be told apart from one stored with
+// its zero value. It must not be stored:
+//
+//	type Account struct {
+//		Id            bson.ObjectId `bson:"_id"`
+//		Limit         int
+//		FieldsPresent mongo.FieldSet `bson:"-"`
+//	}
+//
+//	if !account.FieldsPresent.Has("limit") {
+//		// no limit was ever set
+//	}
+type FieldSet map[string]bool
+
+// Has returns true if the document had the key.
+func (fs FieldSet) Has(key string) bool {
+	return fs[key]
+}
+
+var fieldSetType = reflect.TypeOf(FieldSet{})
+
+// setFieldsPresent fills in the FieldsPresent field of i, if it has one,
+// with the keys of raw.
+func setFieldsPresent(raw bson.Raw, i interface{}) error {
+	v := reflect.ValueOf(i)
+	for v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	f := v.FieldByName("FieldsPresent")
+	if !f.IsValid() || f.Type() != fieldSetType || !f.CanSet() {
+		return nil
+	}
+
+	var doc bson.RawD
+	if err := raw.Unmarshal(&doc); err != nil {
+		return err
+	}
+
+	fs := make(FieldSet, len(doc))
+	for _, e := range doc {
+		fs[e.Name] = true
+	}
+	f.Set(reflect.ValueOf(fs))
+
+	return nil
+}
@@ -0,0 +1,18 @@
+package mongo
+
+import (
+	"testing"
+)
+
+func TestFieldSet(t *testing.T) {
+	fs := FieldSet{"limit": true}
+
+	if !fs.Has("limit") || fs.Has("name") {
+		t.Fatal("Unexpected FieldSet:", fs)
+	}
+
+	var empty FieldSet
+	if empty.Has("limit") {
+		t.Fatal("Expected a nil FieldSet to have nothing")
+	}
+}
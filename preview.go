@@ -0,0 +1,272 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// PreviewSampleSize is the number of matching records PreviewUpdateWhere
+// shows the changes for.
+var PreviewSampleSize = 5
+
+// UpdatePreview is what an update would do, without doing it.
+type UpdatePreview struct {
+	// Matched is the number of records the update would apply to.
+	Matched int
+	// Samples shows the changes to the first PreviewSampleSize records.
+	Samples []DocPreview
+}
+
+// DocPreview lists the changes an update would make to one record.
+type DocPreview struct {
+	Id      interface{}
+	Changes []FieldChange
+}
+
+// FieldChange is a single changed field. Field is a dotted path; Before or
+// After is nil when the field is added or removed.
+type FieldChange struct {
+	Field  string
+	Before interface{}
+	After  interface{}
+}
+
+// Report how many records matching q an update would change and what it
+// would do to a sample of them, without writing anything. It's meant for
+// confirmation screens in admin tools. The update is applied in memory and
+// supports $set, $unset, $inc, $mul, $min, $max, $rename, $push, $addToSet
+// and $pull with plain values; other operators return an error.
+func PreviewUpdateWhere(i interface{}, q, change bson.M) (*UpdatePreview, error) {
+	return std.PreviewUpdateWhere(i, q, change)
+}
+
+// Preview an update using the client. See PreviewUpdateWhere.
+func (c *Client) PreviewUpdateWhere(i interface{}, q, change bson.M) (*UpdatePreview, error) {
+	q = scopeQuery(i, q)
+	op := c.startOp("find", collName(i), q)
+
+	if err := op.allowed(); err != nil {
+		return nil, op.done(err)
+	}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return nil, op.done(err)
+	}
+	defer s.Close()
+
+	if q, err = op.check(c, s, q, true); err != nil {
+		return nil, op.done(err)
+	}
+
+	query := c.GetColl(s, op.coll).Find(q)
+
+	n, err := query.Count()
+	if err != nil {
+		return nil, op.done(err)
+	}
+
+	var docs []bson.M
+	if err := query.Sort("_id").Limit(PreviewSampleSize).All(&docs); err != nil {
+		return nil, op.done(err)
+	}
+
+	preview := &UpdatePreview{Matched: n}
+	for _, before := range docs {
+		after := copyValue(before).(bson.M)
+		if err := applyUpdate(after, change); err != nil {
+			return nil, op.done(err)
+		}
+
+		preview.Samples = append(preview.Samples, DocPreview{Id: before["_id"], Changes: diffDocs(before, after, "")})
+	}
+
+	return preview, op.done(nil)
+}
+
+// applyUpdate applies the update operators in update to doc.
+func applyUpdate(doc bson.M, update bson.M) error {
+	ops := make([]string, 0, len(update))
+	for k := range update {
+		ops = append(ops, k)
+	}
+	sort.Strings(ops)
+
+	for _, name := range ops {
+		fields, ok := update[name].(bson.M)
+		if !ok {
+			return fmt.Errorf("%v must be a document", name)
+		}
+
+		for path, v := range fields {
+			cur, exists := getDocPath(doc, path)
+
+			switch name {
+			case "$set":
+				setDocPath(doc, path, v)
+			case "$unset":
+				unsetDocPath(doc, path)
+			case "$inc", "$mul":
+				a, _ := numeric(cur)
+				b, ok := numeric(v)
+				if !ok || (exists && !isNumber(cur)) {
+					return fmt.Errorf("%v on %v needs numbers", name, path)
+				}
+				if name == "$inc" {
+					setDocPath(doc, path, a+b)
+				} else {
+					setDocPath(doc, path, a*b)
+				}
+			case "$min", "$max":
+				a, aok := numeric(cur)
+				b, bok := numeric(v)
+				if !exists || (aok && bok && ((name == "$min" && b < a) || (name == "$max" && b > a))) {
+					setDocPath(doc, path, v)
+				}
+			case "$rename":
+				to, ok := v.(string)
+				if !ok {
+					return fmt.Errorf("$rename of %v needs a string", path)
+				}
+				if exists {
+					unsetDocPath(doc, path)
+					setDocPath(doc, to, cur)
+				}
+			case "$push", "$addToSet", "$pull":
+				arr, ok := cur.([]interface{})
+				if exists && !ok {
+					return fmt.Errorf("%v on %v needs an array", name, path)
+				}
+				setDocPath(doc, path, arrayUpdate(name, arr, v))
+			default:
+				return fmt.Errorf("%v isn't supported by previews", name)
+			}
+		}
+	}
+
+	return nil
+}
+
+func arrayUpdate(name string, arr []interface{}, v interface{}) []interface{} {
+	out := append([]interface{}{}, arr...)
+
+	switch name {
+	case "$push":
+		return append(out, v)
+	case "$addToSet":
+		for _, e := range out {
+			if reflect.DeepEqual(e, v) {
+				return out
+			}
+		}
+		return append(out, v)
+	}
+
+	kept := out[:0]
+	for _, e := range out {
+		if !reflect.DeepEqual(e, v) {
+			kept = append(kept, e)
+		}
+	}
+	return kept
+}
+
+func isNumber(v interface{}) bool {
+	_, ok := numeric(v)
+	return ok
+}
+
+func getDocPath(doc bson.M, path string) (interface{}, bool) {
+	parts := strings.Split(path, ".")
+	var cur interface{} = doc
+	for _, p := range parts {
+		m, ok := cur.(bson.M)
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = m[p]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+func setDocPath(doc bson.M, path string, v interface{}) {
+	parts := strings.Split(path, ".")
+	m := doc
+	for _, p := range parts[:len(parts)-1] {
+		next, ok := m[p].(bson.M)
+		if !ok {
+			next = bson.M{}
+			m[p] = next
+		}
+		m = next
+	}
+	m[parts[len(parts)-1]] = v
+}
+
+func unsetDocPath(doc bson.M, path string) {
+	parts := strings.Split(path, ".")
+	m := doc
+	for _, p := range parts[:len(parts)-1] {
+		next, ok := m[p].(bson.M)
+		if !ok {
+			return
+		}
+		m = next
+	}
+	delete(m, parts[len(parts)-1])
+}
+
+// copyValue deep copies documents and arrays.
+func copyValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case bson.M:
+		c := make(bson.M, len(t))
+		for k, e := range t {
+			c[k] = copyValue(e)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(t))
+		for n, e := range t {
+			c[n] = copyValue(e)
+		}
+		return c
+	}
+	return v
+}
+
+// diffDocs lists the fields that differ between two documents, descending
+// into nested documents. Fields are sorted.
+func diffDocs(before, after bson.M, prefix string) []FieldChange {
+	keys := map[string]bool{}
+	for k := range before {
+		keys[k] = true
+	}
+	for k := range after {
+		keys[k] = true
+	}
+
+	var changes []FieldChange
+	for _, k := range sortedKeys(keys) {
+		b, bok := before[k]
+		a, aok := after[k]
+
+		bm, bIsDoc := b.(bson.M)
+		am, aIsDoc := a.(bson.M)
+		if bIsDoc && aIsDoc {
+			changes = append(changes, diffDocs(bm, am, prefix+k+".")...)
+			continue
+		}
+
+		if bok != aok || !reflect.DeepEqual(b, a) {
+			changes = append(changes, FieldChange{Field: prefix + k, Before: b, After: a})
+		}
+	}
+	return changes
+}
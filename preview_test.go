@@ -0,0 +1,60 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"reflect"
+	"testing"
+)
+
+func TestApplyUpdate(t *testing.T) {
+	before := bson.M{
+		"_id":     1,
+		"name":    "George",
+		"visits":  2,
+		"tags":    []interface{}{"a", "b"},
+		"address": bson.M{"city": "Orbit City", "zip": "123"},
+		"old":     true,
+	}
+	after := copyValue(before).(bson.M)
+
+	err := applyUpdate(after, bson.M{
+		"$set":      bson.M{"address.city": "Springfield", "plan": "gold"},
+		"$inc":      bson.M{"visits": 1},
+		"$unset":    bson.M{"address.zip": ""},
+		"$addToSet": bson.M{"tags": "a"},
+		"$pull":     bson.M{"tags": "b"},
+		"$rename":   bson.M{"old": "legacy"},
+	})
+	if err != nil {
+		t.Fatal("Couldn't apply update:", err)
+	}
+
+	expected := []FieldChange{
+		{Field: "address.city", Before: "Orbit City", After: "Springfield"},
+		{Field: "address.zip", Before: "123", After: nil},
+		{Field: "legacy", Before: nil, After: true},
+		{Field: "old", Before: true, After: nil},
+		{Field: "plan", Before: nil, After: "gold"},
+		{Field: "tags", Before: []interface{}{"a", "b"}, After: []interface{}{"a"}},
+		{Field: "visits", Before: 2, After: float64(3)},
+	}
+
+	if changes := diffDocs(before, after, ""); !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("Unexpected changes:\n%v\nexpected:\n%v", changes, expected)
+	}
+
+	if before["address"].(bson.M)["city"] != "Orbit City" {
+		t.Fatal("Expected the original document to be untouched")
+	}
+}
+
+func TestApplyUpdateUnsupported(t *testing.T) {
+	if err := applyUpdate(bson.M{}, bson.M{"$bit": bson.M{"flags": bson.M{"and": 1}}}); err == nil {
+		t.Fatal("Expected an error for an unsupported operator")
+	}
+
+	if err := applyUpdate(bson.M{"name": "x"}, bson.M{"$inc": bson.M{"name": 1}}); err == nil {
+		t.Fatal("Expected an error for $inc on a string")
+	}
+}
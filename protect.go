@@ -0,0 +1,166 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ErrImmutableField is returned, wrapped with the field's key, when a write
+// would change a field tagged `mongo:"immutable"`.
+var ErrImmutableField = errors.New("Immutable field can't be changed")
+
+// Fields can be protected from being clobbered by structs bound to API
+// requests with a mongo tag:
+//
+//	type Account struct {
+//		Id      bson.ObjectId `bson:"_id"`
+//		Owner   string        `bson:"owner" mongo:"immutable"`
+//		Balance int           `bson:"balance" mongo:"readonly"`
+//	}
+//
+// Both are written on insert. Updates never write readonly fields back, the
+// stored value is kept whatever the struct holds, and return
+// ErrImmutableField if an immutable field differs from the stored value.
+// Upserts only write either kind when they create the record.
+
+// protectedKeys returns the document keys of the readonly and immutable
+// fields of i, following inlined structs.
+func protectedKeys(i interface{}) (readonly, immutable []string) {
+	if t := structType(i); t != nil {
+		readonly, immutable = protectedTypeKeys(t)
+	}
+	return readonly, immutable
+}
+
+func protectedTypeKeys(t reflect.Type) (readonly, immutable []string) {
+	for n := 0; n < t.NumField(); n++ {
+		f := t.Field(n)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+
+		if strings.Contains(f.Tag.Get("bson"), ",inline") {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				r, m := protectedTypeKeys(ft)
+				readonly, immutable = append(readonly, r...), append(immutable, m...)
+			}
+			continue
+		}
+
+		for _, opt := range strings.Split(f.Tag.Get("mongo"), ",") {
+			switch opt {
+			case "readonly":
+				readonly = append(readonly, bsonKey(f))
+			case "immutable":
+				immutable = append(immutable, bsonKey(f))
+			}
+		}
+	}
+	return readonly, immutable
+}
+
+// replacementDoc returns the document replacing the stored record for i.
+// If i has protected fields, or lazy fields that weren't loaded, their stored
+// values are read through coll and applied with protectedDoc.
+func replacementDoc(coll *mgo.Collection, i interface{}, id interface{}) (interface{}, error) {
+	readonly, immutable := protectedKeys(i)
+	_, unloaded := lazyKeys(i)
+	if len(readonly) == 0 && len(immutable) == 0 && len(unloaded) == 0 {
+		return marshalRecord(i)
+	}
+
+	fields := bson.M{}
+	for _, k := range append(append(readonly, immutable...), unloaded...) {
+		fields[k] = 1
+	}
+
+	stored := bson.M{}
+	if err := coll.FindId(id).Select(fields).One(&stored); err != nil {
+		return nil, err
+	}
+
+	return protectedDoc(i, stored)
+}
+
+// protectedDoc returns the stored representation of i with the stored
+// values of its readonly and unloaded lazy fields in place of its own. It
+// fails if an immutable field differs from its stored value.
+func protectedDoc(i interface{}, stored bson.M) (interface{}, error) {
+	doc, err := recordDoc(i)
+	if err != nil {
+		return nil, err
+	}
+
+	readonly, immutable := protectedKeys(i)
+
+	// Lazy fields that weren't loaded keep their stored values too.
+	_, unloaded := lazyKeys(i)
+	readonly = append(readonly, unloaded...)
+
+	for _, k := range immutable {
+		v, ok := stored[k]
+		if ok && !reflect.DeepEqual(doc[k], v) {
+			return nil, fmt.Errorf("%w: %v", ErrImmutableField, k)
+		}
+	}
+
+	for _, k := range readonly {
+		if v, ok := stored[k]; ok {
+			doc[k] = v
+		} else {
+			delete(doc, k)
+		}
+	}
+
+	return doc, nil
+}
+
+// protectUpdate returns update, a document of update operators, without the
+// readonly fields of i. It fails if update sets an immutable field.
+func protectUpdate(i interface{}, update bson.M) (bson.M, error) {
+	readonly, immutable := protectedKeys(i)
+	if len(readonly) == 0 && len(immutable) == 0 {
+		return update, nil
+	}
+
+	touches := func(field string, keys []string) bool {
+		for _, k := range keys {
+			if field == k || strings.HasPrefix(field, k+".") {
+				return true
+			}
+		}
+		return false
+	}
+
+	out := bson.M{}
+	for name, v := range update {
+		fields, ok := v.(bson.M)
+		if !ok {
+			out[name] = v
+			continue
+		}
+
+		kept := bson.M{}
+		for field, fv := range fields {
+			if touches(field, immutable) && name != "$setOnInsert" {
+				return nil, fmt.Errorf("%w: %v", ErrImmutableField, field)
+			}
+			if !touches(field, readonly) || name == "$setOnInsert" {
+				kept[field] = fv
+			}
+		}
+		if len(kept) > 0 {
+			out[name] = kept
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,73 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type protectedBase struct {
+	Owner string `bson:"owner" mongo:"immutable"`
+}
+
+type protectedModel struct {
+	Id            bson.ObjectId `bson:"_id"`
+	protectedBase `bson:",inline"`
+	Name          string `bson:"name"`
+	Balance       int    `bson:"balance" mongo:"readonly"`
+}
+
+func TestProtectedKeys(t *testing.T) {
+	readonly, immutable := protectedKeys(&protectedModel{})
+
+	if !reflect.DeepEqual(readonly, []string{"balance"}) {
+		t.Fatal("Expected [balance] got:", readonly)
+	}
+	if !reflect.DeepEqual(immutable, []string{"owner"}) {
+		t.Fatal("Expected [owner] got:", immutable)
+	}
+
+	if readonly, immutable := protectedKeys(&MongoTest{}); readonly != nil || immutable != nil {
+		t.Fatal("Expected no protected fields got:", readonly, immutable)
+	}
+}
+
+func TestProtectedDoc(t *testing.T) {
+	rec := &protectedModel{Id: bson.NewObjectId(), Name: "George", Balance: 1000000}
+	rec.Owner = "george"
+
+	doc, err := protectedDoc(rec, bson.M{"owner": "george", "balance": 10})
+	if err != nil {
+		t.Fatal("Couldn't protect the record:", err)
+	}
+	if balance := doc.(bson.M)["balance"]; balance != 10 {
+		t.Fatal("Expected the stored balance got:", balance)
+	}
+
+	rec.Owner = "jane"
+	if _, err := protectedDoc(rec, bson.M{"owner": "george"}); !errors.Is(err, ErrImmutableField) {
+		t.Fatal("Expected ErrImmutableField got:", err)
+	}
+}
+
+func TestProtectUpdate(t *testing.T) {
+	update, err := protectUpdate(&protectedModel{}, bson.M{
+		"$set":         bson.M{"name": "Jane", "balance": 5},
+		"$inc":         bson.M{"balance": 1},
+		"$setOnInsert": bson.M{"owner": "jane"},
+	})
+	if err != nil {
+		t.Fatal("Couldn't protect the update:", err)
+	}
+
+	expected := bson.M{"$set": bson.M{"name": "Jane"}, "$setOnInsert": bson.M{"owner": "jane"}}
+	if !reflect.DeepEqual(update, expected) {
+		t.Fatal("Expected", expected, "got:", update)
+	}
+
+	if _, err := protectUpdate(&protectedModel{}, bson.M{"$set": bson.M{"owner": "jane"}}); !errors.Is(err, ErrImmutableField) {
+		t.Fatal("Expected ErrImmutableField got:", err)
+	}
+}
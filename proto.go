@@ -0,0 +1,557 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProtoIdField is the proto JSON name of the field stored as _id by the
+// protobuf adapter. Hex strings in it are stored as ObjectIds.
+var ProtoIdField = "id"
+
+// Insert one or more protobuf generated messages. Each message must be a
+// pointer. Fields are stored under their proto JSON names (first_name is
+// stored as firstName), google.protobuf.Timestamp values as dates and an
+// empty id gets a new ObjectId. Map keys are stored as strings and 64 bit
+// integers exactly; uint64 values above the largest int64 can't be stored.
+// oneof fields aren't supported.
+func InsertProto(msgs ...interface{}) error {
+	return std.InsertProto(msgs...)
+}
+
+// Insert protobuf messages using the client. See InsertProto.
+func (c *Client) InsertProto(msgs ...interface{}) error {
+	for _, msg := range msgs {
+		if !isPtr(msg) {
+			return NoPtr
+		}
+
+		op := c.startOp("insert", collName(msg), nil)
+
+		if err := op.allowed(); err != nil {
+			return op.done(err)
+		}
+
+		if err := setProtoId(msg); err != nil {
+			return op.done(err)
+		}
+
+		doc, err := MarshalProto(msg)
+		if err != nil {
+			return op.done(err)
+		}
+
+		s, err := c.GetSession()
+		if err != nil {
+			return op.done(err)
+		}
+
+		err = c.GetColl(s, op.coll).Insert(doc)
+		if err == nil {
+			err = c.afterWrite(s, msg, written{after: doc, created: true})
+		}
+		s.Close()
+		if err := op.done(err); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Find protobuf generated messages. Pass a pointer to a message for a single
+// record or a pointer to a slice of message pointers for all of them. See
+// InsertProto for how fields are mapped.
+func FindProto(i interface{}, q bson.M, sortFields ...string) error {
+	return std.FindProto(i, q, sortFields...)
+}
+
+// Find protobuf messages using the client. See FindProto.
+func (c *Client) FindProto(i interface{}, q bson.M, sortFields ...string) error {
+	if !isPtr(i) {
+		return NoPtr
+	}
+
+	op := c.startOp("find", collName(i), q)
+
+	if err := op.allowed(); err != nil {
+		return op.done(err)
+	}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return op.done(err)
+	}
+	defer s.Close()
+
+	if q, err = op.check(c, s, q, !isSlice(reflect.TypeOf(i))); err != nil {
+		return op.done(err)
+	}
+
+	query := c.GetColl(s, op.coll).Find(q).Sort(sortFields...)
+
+	if !isSlice(reflect.TypeOf(i)) {
+		var raw bson.Raw
+		if err := query.One(&raw); err != nil {
+			return op.done(err)
+		}
+		return op.done(UnmarshalProto(raw, i))
+	}
+
+	var raws []bson.Raw
+	if err := query.All(&raws); err != nil {
+		return op.done(err)
+	}
+
+	sv := reflect.ValueOf(i).Elem()
+	et := sv.Type().Elem()
+	if et.Kind() != reflect.Ptr {
+		return op.done(errors.New("FindProto needs a slice of message pointers"))
+	}
+
+	out := reflect.MakeSlice(sv.Type(), 0, len(raws))
+	for _, raw := range raws {
+		msg := reflect.New(et.Elem())
+		if err := UnmarshalProto(raw, msg.Interface()); err != nil {
+			return op.done(err)
+		}
+		out = reflect.Append(out, msg)
+	}
+	sv.Set(out)
+
+	return op.done(nil)
+}
+
+// Replace a stored protobuf generated message, identified by its id.
+func UpdateProto(msg interface{}) error {
+	return std.UpdateProto(msg)
+}
+
+// Replace a protobuf message using the client. See UpdateProto.
+func (c *Client) UpdateProto(msg interface{}) error {
+	if !isPtr(msg) {
+		return NoPtr
+	}
+
+	op := c.startOp("update", collName(msg), nil)
+
+	if err := op.allowed(); err != nil {
+		return op.done(err)
+	}
+
+	doc, err := MarshalProto(msg)
+	if err != nil {
+		return op.done(err)
+	}
+
+	id := doc.Map()["_id"]
+	if id == nil {
+		return op.done(errors.New("Message doesn't have an id"))
+	}
+	op.query = bson.M{"_id": id}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return op.done(err)
+	}
+	defer s.Close()
+
+	coll := c.GetColl(s, op.coll)
+
+	before := countedDoc(coll, msg, id)
+	if err := coll.Update(op.query, doc); err != nil {
+		return op.done(err)
+	}
+	return op.done(c.afterWrite(s, msg, written{id: id, before: before, after: doc}))
+}
+
+// MarshalProto converts a protobuf generated message into the document
+// stored by InsertProto.
+func MarshalProto(msg interface{}) (bson.D, error) {
+	v := reflect.ValueOf(msg)
+	for v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct {
+		return nil, errors.New("Message must be a struct")
+	}
+
+	return protoToDoc(v, true)
+}
+
+// UnmarshalProto decodes a document stored by InsertProto into a protobuf
+// generated message, which must be a pointer.
+func UnmarshalProto(raw bson.Raw, msg interface{}) error {
+	v := reflect.ValueOf(msg)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return errors.New("Message must be a pointer to a struct")
+	}
+
+	doc := bson.M{}
+	if err := raw.Unmarshal(&doc); err != nil {
+		return err
+	}
+
+	return docToProto(doc, v.Elem(), true)
+}
+
+// protoKey returns the document key for a generated field or "" if the field
+// isn't a proto field.
+func protoKey(f reflect.StructField, top bool) string {
+	tag := f.Tag.Get("protobuf")
+	if tag == "" {
+		return ""
+	}
+
+	name, json := "", ""
+	for _, part := range strings.Split(tag, ",") {
+		if strings.HasPrefix(part, "name=") {
+			name = part[len("name="):]
+		} else if strings.HasPrefix(part, "json=") {
+			json = part[len("json="):]
+		}
+	}
+
+	if json == "" {
+		json = name
+	}
+
+	if top && json == ProtoIdField {
+		return "_id"
+	}
+	return json
+}
+
+func protoToDoc(v reflect.Value, top bool) (bson.D, error) {
+	t := v.Type()
+	doc := bson.D{}
+
+	for n := 0; n < t.NumField(); n++ {
+		f := t.Field(n)
+
+		if f.Tag.Get("protobuf_oneof") != "" {
+			if !v.Field(n).IsNil() {
+				return nil, fmt.Errorf("oneof field %v isn't supported", f.Name)
+			}
+			continue
+		}
+
+		key := protoKey(f, top)
+		if key == "" {
+			continue
+		}
+
+		val, ok, err := protoValue(v.Field(n))
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", f.Name, err)
+		}
+		if !ok {
+			continue
+		}
+
+		if key == "_id" {
+			if s, isStr := val.(string); isStr && bson.IsObjectIdHex(s) {
+				val = bson.ObjectIdHex(s)
+			}
+		}
+
+		doc = append(doc, bson.DocElem{Name: key, Value: val})
+	}
+
+	return doc, nil
+}
+
+// protoValue converts a field to its stored value. The bool is false for
+// nil messages, which aren't stored.
+func protoValue(v reflect.Value) (interface{}, bool, error) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil, false, nil
+		}
+		if t, ok := protoTimestamp(v.Elem()); ok {
+			return t, true, nil
+		}
+		doc, err := protoToDoc(v.Elem(), false)
+		return doc, true, err
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Bytes(), true, nil
+		}
+		arr := make([]interface{}, 0, v.Len())
+		for n := 0; n < v.Len(); n++ {
+			el, ok, err := protoValue(v.Index(n))
+			if err != nil {
+				return nil, false, err
+			}
+			if ok {
+				arr = append(arr, el)
+			}
+		}
+		return arr, true, nil
+	case reflect.Map:
+		m := bson.M{}
+		for _, k := range v.MapKeys() {
+			el, ok, err := protoValue(v.MapIndex(k))
+			if err != nil {
+				return nil, false, err
+			}
+			if !ok {
+				continue
+			}
+			key, err := protoMapKey(k)
+			if err != nil {
+				return nil, false, err
+			}
+			m[key] = el
+		}
+		return m, true, nil
+	case reflect.Int32:
+		return int32(v.Int()), true, nil
+	case reflect.Int64:
+		return v.Int(), true, nil
+	case reflect.Uint32:
+		return int64(v.Uint()), true, nil
+	case reflect.Uint64:
+		if v.Uint() > math.MaxInt64 {
+			return nil, false, fmt.Errorf("uint64 %v is too large to store", v.Uint())
+		}
+		return int64(v.Uint()), true, nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true, nil
+	case reflect.Bool:
+		return v.Bool(), true, nil
+	case reflect.String:
+		return v.String(), true, nil
+	}
+
+	return nil, false, fmt.Errorf("unsupported type %v", v.Type())
+}
+
+// protoTimestamp converts a google.protobuf.Timestamp to a time.
+func protoTimestamp(v reflect.Value) (time.Time, bool) {
+	if v.Type().Name() != "Timestamp" {
+		return time.Time{}, false
+	}
+
+	secs, nanos := v.FieldByName("Seconds"), v.FieldByName("Nanos")
+	if !secs.IsValid() || !nanos.IsValid() {
+		return time.Time{}, false
+	}
+
+	return time.Unix(secs.Int(), nanos.Int()).UTC(), true
+}
+
+func docToProto(doc bson.M, v reflect.Value, top bool) error {
+	t := v.Type()
+
+	for n := 0; n < t.NumField(); n++ {
+		key := protoKey(t.Field(n), top)
+		if key == "" {
+			continue
+		}
+
+		val, ok := doc[key]
+		if !ok || val == nil {
+			continue
+		}
+
+		if err := setProto(v.Field(n), val); err != nil {
+			return fmt.Errorf("%v: %v", t.Field(n).Name, err)
+		}
+	}
+
+	return nil
+}
+
+func setProto(f reflect.Value, val interface{}) error {
+	switch f.Kind() {
+	case reflect.Ptr:
+		el := reflect.New(f.Type().Elem())
+		if at, ok := val.(time.Time); ok {
+			if _, isTs := protoTimestamp(el.Elem()); isTs {
+				el.Elem().FieldByName("Seconds").SetInt(at.Unix())
+				el.Elem().FieldByName("Nanos").SetInt(int64(at.Nanosecond()))
+				f.Set(el)
+				return nil
+			}
+		}
+
+		sub, ok := val.(bson.M)
+		if !ok {
+			return fmt.Errorf("expected a document, got %T", val)
+		}
+		if err := docToProto(sub, el.Elem(), false); err != nil {
+			return err
+		}
+		f.Set(el)
+	case reflect.Slice:
+		if b, ok := val.([]byte); ok && f.Type().Elem().Kind() == reflect.Uint8 {
+			f.SetBytes(b)
+			return nil
+		}
+
+		arr, ok := val.([]interface{})
+		if !ok {
+			return fmt.Errorf("expected an array, got %T", val)
+		}
+		s := reflect.MakeSlice(f.Type(), len(arr), len(arr))
+		for n, el := range arr {
+			if err := setProto(s.Index(n), el); err != nil {
+				return err
+			}
+		}
+		f.Set(s)
+	case reflect.Map:
+		sub, ok := val.(bson.M)
+		if !ok {
+			return fmt.Errorf("expected a document, got %T", val)
+		}
+		m := reflect.MakeMap(f.Type())
+		for k, el := range sub {
+			kv := reflect.New(f.Type().Key()).Elem()
+			if err := setProtoMapKey(kv, k); err != nil {
+				return err
+			}
+			ev := reflect.New(f.Type().Elem()).Elem()
+			if err := setProto(ev, el); err != nil {
+				return err
+			}
+			m.SetMapIndex(kv, ev)
+		}
+		f.Set(m)
+	case reflect.String:
+		switch s := val.(type) {
+		case string:
+			f.SetString(s)
+		case bson.ObjectId:
+			f.SetString(s.Hex())
+		default:
+			return fmt.Errorf("expected a string, got %T", val)
+		}
+	case reflect.Int32, reflect.Int64:
+		n, ok := protoInt(val)
+		if !ok || f.OverflowInt(n) {
+			return fmt.Errorf("expected an integer that fits %v, got %v", f.Type(), val)
+		}
+		f.SetInt(n)
+	case reflect.Uint32, reflect.Uint64:
+		n, ok := protoInt(val)
+		if !ok || n < 0 || f.OverflowUint(uint64(n)) {
+			return fmt.Errorf("expected an integer that fits %v, got %v", f.Type(), val)
+		}
+		f.SetUint(uint64(n))
+	case reflect.Float32, reflect.Float64:
+		n, ok := numeric(val)
+		if !ok {
+			return fmt.Errorf("expected a number, got %T", val)
+		}
+		f.SetFloat(n)
+	case reflect.Bool:
+		b, ok := val.(bool)
+		if !ok {
+			return fmt.Errorf("expected a bool, got %T", val)
+		}
+		f.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported type %v", f.Type())
+	}
+
+	return nil
+}
+
+// protoInt returns a stored integer exactly. Doubles are accepted if they
+// hold a whole number, since documents written by other clients may use
+// them.
+func protoInt(val interface{}) (int64, bool) {
+	switch n := val.(type) {
+	case int:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	case float64:
+		if n != math.Trunc(n) || n < math.MinInt64 || n >= math.MaxInt64 {
+			return 0, false
+		}
+		return int64(n), true
+	}
+	return 0, false
+}
+
+// protoMapKey returns the document key for a map key. Proto map keys are
+// strings, integers or bools.
+func protoMapKey(k reflect.Value) (string, error) {
+	switch k.Kind() {
+	case reflect.String:
+		return k.String(), nil
+	case reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10), nil
+	case reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(k.Uint(), 10), nil
+	case reflect.Bool:
+		return strconv.FormatBool(k.Bool()), nil
+	}
+	return "", fmt.Errorf("unsupported map key type %v", k.Type())
+}
+
+// setProtoMapKey parses a document key written by protoMapKey into k.
+func setProtoMapKey(k reflect.Value, key string) error {
+	var err error
+
+	switch k.Kind() {
+	case reflect.String:
+		k.SetString(key)
+	case reflect.Int32, reflect.Int64:
+		var n int64
+		if n, err = strconv.ParseInt(key, 10, k.Type().Bits()); err == nil {
+			k.SetInt(n)
+		}
+	case reflect.Uint32, reflect.Uint64:
+		var n uint64
+		if n, err = strconv.ParseUint(key, 10, k.Type().Bits()); err == nil {
+			k.SetUint(n)
+		}
+	case reflect.Bool:
+		var b bool
+		if b, err = strconv.ParseBool(key); err == nil {
+			k.SetBool(b)
+		}
+	default:
+		return fmt.Errorf("unsupported map key type %v", k.Type())
+	}
+
+	if err != nil {
+		return fmt.Errorf("invalid %v map key %q", k.Type(), key)
+	}
+	return nil
+}
+
+// setProtoId gives a message without an id a new ObjectId in hex.
+func setProtoId(msg interface{}) error {
+	v := reflect.ValueOf(msg).Elem()
+	t := v.Type()
+
+	for n := 0; n < t.NumField(); n++ {
+		if protoKey(t.Field(n), true) != "_id" {
+			continue
+		}
+
+		f := v.Field(n)
+		if f.Kind() == reflect.String && f.String() == "" {
+			f.SetString(newObjectId().Hex())
+		}
+		return nil
+	}
+
+	return nil
+}
@@ -0,0 +1,142 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// These mimic the structs protoc-gen-go generates.
+type Timestamp struct {
+	Seconds int64 `protobuf:"varint,1,opt,name=seconds,proto3" json:"seconds,omitempty"`
+	Nanos   int32 `protobuf:"varint,2,opt,name=nanos,proto3" json:"nanos,omitempty"`
+}
+
+type ProtoAddress struct {
+	sizeCache int32
+	City      string `protobuf:"bytes,1,opt,name=city,proto3" json:"city,omitempty"`
+}
+
+type ProtoUser struct {
+	sizeCache int32
+	Id        string            `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	FirstName string            `protobuf:"bytes,2,opt,name=first_name,json=firstName,proto3" json:"first_name,omitempty"`
+	Age       int32             `protobuf:"varint,3,opt,name=age,proto3" json:"age,omitempty"`
+	Tags      []string          `protobuf:"bytes,4,rep,name=tags,proto3" json:"tags,omitempty"`
+	Address   *ProtoAddress     `protobuf:"bytes,5,opt,name=address,proto3" json:"address,omitempty"`
+	Joined    *Timestamp        `protobuf:"bytes,6,opt,name=joined,proto3" json:"joined,omitempty"`
+	Labels    map[string]string `protobuf:"bytes,7,rep,name=labels,proto3" json:"labels,omitempty"`
+}
+
+func TestProtoRoundTrip(t *testing.T) {
+	in := &ProtoUser{
+		Id:        bson.NewObjectId().Hex(),
+		FirstName: "George",
+		Age:       40,
+		Tags:      []string{"a", "b"},
+		Address:   &ProtoAddress{City: "Orbit City"},
+		Joined:    &Timestamp{Seconds: 1500000000, Nanos: 1000000},
+		Labels:    map[string]string{"plan": "gold"},
+	}
+
+	doc, err := MarshalProto(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := doc.Map()
+	if _, ok := m["_id"].(bson.ObjectId); !ok {
+		t.Fatal("Expected the id to be stored as an ObjectId:", m["_id"])
+	}
+	if m["firstName"] != "George" {
+		t.Fatal("Expected fields under their proto JSON names:", m)
+	}
+	if _, ok := m["joined"].(time.Time); !ok {
+		t.Fatal("Expected timestamps to be stored as dates:", m["joined"])
+	}
+
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := &ProtoUser{}
+	if err := UnmarshalProto(bson.Raw{Kind: 3, Data: data}, out); err != nil {
+		t.Fatal(err)
+	}
+
+	if out.Id != in.Id || out.FirstName != in.FirstName || out.Age != in.Age || len(out.Tags) != 2 ||
+		out.Address.City != "Orbit City" || *out.Joined != *in.Joined || out.Labels["plan"] != "gold" {
+		t.Fatalf("Round trip mismatch: %+v", out)
+	}
+}
+
+type ProtoCounters struct {
+	Big      int64             `protobuf:"varint,1,opt,name=big,proto3" json:"big,omitempty"`
+	Unsigned uint64            `protobuf:"varint,2,opt,name=unsigned,proto3" json:"unsigned,omitempty"`
+	Small    int32             `protobuf:"varint,3,opt,name=small,proto3" json:"small,omitempty"`
+	ByDay    map[int32]int64   `protobuf:"bytes,4,rep,name=by_day,json=byDay,proto3" json:"by_day,omitempty"`
+	ByUser   map[uint64]string `protobuf:"bytes,5,rep,name=by_user,json=byUser,proto3" json:"by_user,omitempty"`
+	Flags    map[bool]string   `protobuf:"bytes,6,rep,name=flags,proto3" json:"flags,omitempty"`
+}
+
+func TestProtoIntegersExact(t *testing.T) {
+	in := &ProtoCounters{Big: 1<<62 + 1, Unsigned: 1<<53 + 1, Small: -7}
+
+	doc, err := MarshalProto(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := &ProtoCounters{}
+	if err := docToProto(doc.Map(), reflect.ValueOf(out).Elem(), true); err != nil {
+		t.Fatal(err)
+	}
+	if out.Big != in.Big || out.Unsigned != in.Unsigned || out.Small != in.Small {
+		t.Fatalf("Expected %+v got: %+v", in, out)
+	}
+
+	if _, err := MarshalProto(&ProtoCounters{Unsigned: math.MaxUint64}); err == nil {
+		t.Fatal("Expected an error for a uint64 above the largest int64")
+	}
+
+	for _, bad := range []bson.M{{"small": int64(math.MaxInt32) + 1}, {"unsigned": int64(-1)}, {"big": 1.5}} {
+		if err := docToProto(bad, reflect.ValueOf(&ProtoCounters{}).Elem(), true); err == nil {
+			t.Fatal("Expected an error decoding", bad)
+		}
+	}
+}
+
+func TestProtoMapKeys(t *testing.T) {
+	in := &ProtoCounters{
+		ByDay:  map[int32]int64{-1: 3, 20: 1 << 60},
+		ByUser: map[uint64]string{math.MaxUint64: "max"},
+		Flags:  map[bool]string{true: "on"},
+	}
+
+	doc, err := MarshalProto(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := doc.Map()
+	if _, ok := m["byDay"].(bson.M)["-1"]; !ok {
+		t.Fatal("Expected integer keys to be stored as strings got:", m["byDay"])
+	}
+
+	out := &ProtoCounters{}
+	if err := docToProto(m, reflect.ValueOf(out).Elem(), true); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out.ByDay, in.ByDay) || !reflect.DeepEqual(out.ByUser, in.ByUser) || !reflect.DeepEqual(out.Flags, in.Flags) {
+		t.Fatalf("Expected %+v got: %+v", in, out)
+	}
+
+	bad := bson.M{"byDay": bson.M{"monday": int64(1)}}
+	if err := docToProto(bad, reflect.ValueOf(&ProtoCounters{}).Elem(), true); err == nil {
+		t.Fatal("Expected an error for a key that isn't an integer")
+	}
+}
@@ -0,0 +1,200 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+)
+
+// ErrInvalidPublicId is returned, wrapped with the id, by FromPublicId and
+// FindByPublicId for ids the codec can't decode.
+var ErrInvalidPublicId = errors.New("Invalid public id")
+
+// IdCodec turns ObjectIds into the ids shown in URLs and back, so internal
+// ids, which reveal when records were created and roughly how many there
+// are, aren't exposed. Decode must reverse Encode.
+type IdCodec interface {
+	Encode(id bson.ObjectId) string
+	Decode(public string) (bson.ObjectId, error)
+}
+
+var (
+	idCodecMu sync.RWMutex
+	idCodec   IdCodec
+)
+
+// Set the codec used by PublicId, FromPublicId and FindByPublicId. Pass nil,
+// the default, to use the ids in hex. Changing it breaks every public id
+// handed out before.
+//
+//	mongo.SetIdCodec(mongo.NewBase58Codec(os.Getenv("ID_SALT")))
+func SetIdCodec(c IdCodec) {
+	idCodecMu.Lock()
+	defer idCodecMu.Unlock()
+
+	idCodec = c
+}
+
+func currentIdCodec() IdCodec {
+	idCodecMu.RLock()
+	defer idCodecMu.RUnlock()
+
+	return idCodec
+}
+
+// Returns the public form of id.
+func PublicId(id bson.ObjectId) string {
+	if c := currentIdCodec(); c != nil {
+		return c.Encode(id)
+	}
+	return id.Hex()
+}
+
+// Returns the ObjectId of a public id made by PublicId.
+func FromPublicId(public string) (bson.ObjectId, error) {
+	c := currentIdCodec()
+	if c == nil {
+		if !bson.IsObjectIdHex(public) {
+			return "", fmt.Errorf("%w: %v", ErrInvalidPublicId, public)
+		}
+		return bson.ObjectIdHex(public), nil
+	}
+
+	id, err := c.Decode(public)
+	if err != nil || !id.Valid() {
+		return "", fmt.Errorf("%w: %v", ErrInvalidPublicId, public)
+	}
+	return id, nil
+}
+
+// Find a record by its public id, e.g. from a URL. Ids that don't decode
+// return an error wrapping ErrInvalidPublicId, which handlers can treat as
+// not found.
+func FindByPublicId(i interface{}, public string) error {
+	return std.FindByPublicId(i, public)
+}
+
+// Find a record by its public id using the client. See FindByPublicId.
+func (c *Client) FindByPublicId(i interface{}, public string) error {
+	id, err := FromPublicId(public)
+	if err != nil {
+		return err
+	}
+	return c.Find(i, bson.M{"_id": id})
+}
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// feistelRounds is the number of rounds Base58Codec shuffles ids with.
+const feistelRounds = 4
+
+// Base58Codec is an IdCodec that shuffles the bytes of ids with a keyed
+// permutation derived from a salt and writes them in base58, about 17
+// characters long. Ids created one after another don't look alike, but
+// it's obfuscation rather than encryption: keep the salt secret and don't
+// rely on public ids for access control.
+type Base58Codec struct {
+	salt []byte
+}
+
+// Create a codec keyed by salt. Different salts give unrelated public ids.
+func NewBase58Codec(salt string) *Base58Codec {
+	return &Base58Codec{salt: []byte(salt)}
+}
+
+func (c *Base58Codec) Encode(id bson.ObjectId) string {
+	if !id.Valid() {
+		return ""
+	}
+	return base58Encode(c.shuffle([]byte(id), false))
+}
+
+func (c *Base58Codec) Decode(public string) (bson.ObjectId, error) {
+	b, err := base58Decode(public)
+	if err != nil {
+		return "", err
+	}
+	if len(b) != 12 {
+		return "", ErrInvalidPublicId
+	}
+	return bson.ObjectId(c.shuffle(b, true)), nil
+}
+
+// shuffle runs the 12 bytes of an id through a Feistel network keyed by the
+// salt, or back through it when reverse is true.
+func (c *Base58Codec) shuffle(b []byte, reverse bool) []byte {
+	left, right := append([]byte{}, b[:6]...), append([]byte{}, b[6:]...)
+
+	for n := 0; n < feistelRounds; n++ {
+		round := n
+		if reverse {
+			round = feistelRounds - 1 - n
+			left, right = right, left
+		}
+
+		mac := hmac.New(sha256.New, c.salt)
+		mac.Write([]byte{byte(round)})
+		mac.Write(right)
+		f := mac.Sum(nil)
+
+		for k := range left {
+			left[k] ^= f[k]
+		}
+
+		if !reverse {
+			left, right = right, left
+		}
+	}
+
+	return append(left, right...)
+}
+
+// base58Encode writes b in base58, keeping leading zero bytes as "1"s.
+func base58Encode(b []byte) string {
+	n := new(big.Int).SetBytes(b)
+	base, mod := big.NewInt(58), new(big.Int)
+
+	var out []byte
+	for n.Sign() > 0 {
+		n.DivMod(n, base, mod)
+		out = append(out, base58Alphabet[mod.Int64()])
+	}
+	for _, x := range b {
+		if x != 0 {
+			break
+		}
+		out = append(out, base58Alphabet[0])
+	}
+
+	for l, r := 0, len(out)-1; l < r; l, r = l+1, r-1 {
+		out[l], out[r] = out[r], out[l]
+	}
+	return string(out)
+}
+
+func base58Decode(s string) ([]byte, error) {
+	if s == "" {
+		return nil, ErrInvalidPublicId
+	}
+
+	n, base := new(big.Int), big.NewInt(58)
+	for _, r := range s {
+		k := strings.IndexRune(base58Alphabet, r)
+		if k < 0 {
+			return nil, ErrInvalidPublicId
+		}
+		n.Mul(n, base).Add(n, big.NewInt(int64(k)))
+	}
+
+	zeros := 0
+	for zeros < len(s) && s[zeros] == base58Alphabet[0] {
+		zeros++
+	}
+	return append(make([]byte, zeros), n.Bytes()...), nil
+}
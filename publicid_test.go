@@ -0,0 +1,66 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"errors"
+	"testing"
+)
+
+func TestBase58Codec(t *testing.T) {
+	c := NewBase58Codec("pepper")
+	a := bson.ObjectIdHex("5f1a2b3c4d5e6f7081920001")
+	b := bson.ObjectIdHex("5f1a2b3c4d5e6f7081920002")
+
+	pa, pb := c.Encode(a), c.Encode(b)
+	if pa == "" || pa[:4] == pb[:4] {
+		t.Fatal("Expected consecutive ids to look unrelated got:", pa, pb)
+	}
+
+	id, err := c.Decode(pa)
+	if err != nil || id != a {
+		t.Fatal("Expected the id back got:", id, err)
+	}
+
+	if other := NewBase58Codec("salt").Encode(a); other == pa {
+		t.Fatal("Expected a different salt to give a different id")
+	}
+
+	if _, err := c.Decode("0OIl"); err == nil {
+		t.Fatal("Expected an error for characters outside base58")
+	}
+}
+
+func TestBase58Zeros(t *testing.T) {
+	b := []byte{0, 0, 1, 2}
+	s := base58Encode(b)
+	if s[:2] != "11" {
+		t.Fatal("Expected leading zeros as 1s got:", s)
+	}
+
+	got, err := base58Decode(s)
+	if err != nil || string(got) != string(b) {
+		t.Fatal("Expected the bytes back got:", got, err)
+	}
+}
+
+func TestFromPublicId(t *testing.T) {
+	id := bson.ObjectIdHex("5f1a2b3c4d5e6f7081920001")
+
+	if got, err := FromPublicId(PublicId(id)); err != nil || got != id {
+		t.Fatal("Expected hex ids without a codec got:", got, err)
+	}
+
+	SetIdCodec(NewBase58Codec("pepper"))
+	defer SetIdCodec(nil)
+
+	if PublicId(id) == id.Hex() {
+		t.Fatal("Expected the codec to be used")
+	}
+	if got, err := FromPublicId(PublicId(id)); err != nil || got != id {
+		t.Fatal("Expected the id back got:", got, err)
+	}
+	if _, err := FromPublicId(id.Hex()); !errors.Is(err, ErrInvalidPublicId) {
+		t.Fatal("Expected ErrInvalidPublicId for a hex id got:", err)
+	}
+}
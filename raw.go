@@ -0,0 +1,60 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"reflect"
+)
+
+// Find records like Find and also return the documents exactly as they were
+// stored, one per record in the same order. Use it to forward the original
+// bytes, e.g. for auditing or replication, without a lossy decode and
+// encode. raws[n].Data is the complete BSON document.
+func FindRaw(i interface{}, q bson.M, sortFields ...string) (raws []bson.Raw, err error) {
+	return std.FindRaw(i, q, sortFields...)
+}
+
+// Find raw records using the client. See FindRaw.
+func (c *Client) FindRaw(i interface{}, q bson.M, sortFields ...string) (raws []bson.Raw, err error) {
+	if !isPtr(i) {
+		return nil, NoPtr
+	}
+
+	q = scopeQuery(i, q)
+	op := c.startOp("find", collName(i), q)
+
+	if err := op.allowed(); err != nil {
+		return nil, op.done(err)
+	}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return nil, op.done(err)
+	}
+	defer s.Close()
+
+	if q, err = op.check(c, s, q, !isSlice(reflect.TypeOf(i))); err != nil {
+		return nil, op.done(err)
+	}
+
+	raws, err = findRaws(c, s, c.GetColl(s, op.coll).Find(q).Sort(sortFields...), i)
+	if err != nil {
+		return nil, op.done(err)
+	}
+	return raws, op.done(nil)
+}
+
+// Find a single record by id like FindById and also return the document as
+// it was stored.
+func FindByIdRaw(i interface{}, id string) (bson.Raw, error) {
+	return std.FindByIdRaw(i, id)
+}
+
+// Find a raw record by id using the client. See FindByIdRaw.
+func (c *Client) FindByIdRaw(i interface{}, id string) (bson.Raw, error) {
+	raws, err := c.FindRaw(i, bson.M{"_id": bson.ObjectIdHex(id)})
+	if err != nil {
+		return bson.Raw{}, err
+	}
+	return raws[0], nil
+}
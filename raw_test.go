@@ -0,0 +1,11 @@
+package mongo
+
+import (
+	"testing"
+)
+
+func TestFindRawNoPtr(t *testing.T) {
+	if _, err := FindRaw(MongoTest{}, nil); err != NoPtr {
+		t.Fatal("Expected NoPtr, got:", err)
+	}
+}
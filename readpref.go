@@ -0,0 +1,147 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo"
+
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// StalenessCheckInterval is how long the replication lag measured for
+// max staleness is reused before it's checked again.
+var StalenessCheckInterval = 5 * time.Second
+
+// readPref is a client's read preference. The zero value leaves sessions
+// in mgo's default mode.
+type readPref struct {
+	mu           sync.Mutex
+	set          bool
+	mode         mgo.Mode
+	maxStaleness time.Duration
+	checkedAt    time.Time
+	stale        bool
+	// measuring is true while a lag measurement runs, so only one does at a
+	// time. lagErr is why the last one failed.
+	measuring bool
+	lagErr    error
+}
+
+// Set the read preference of the package level functions. See
+// Client.SetReadPreference.
+func SetReadPreference(mode mgo.Mode, maxStaleness time.Duration) {
+	std.SetReadPreference(mode, maxStaleness)
+}
+
+// Set the read preference of sessions returned by the client, e.g.
+// mgo.SecondaryPreferred. If maxStaleness is more than zero, reads that could
+// go to a secondary are sent to the primary instead while the secondaries
+// are further behind than that, so data older than the bound is never
+// returned. Unlike the server's maxStalenessSeconds there's no 90 second
+// minimum.
+func (c *Client) SetReadPreference(mode mgo.Mode, maxStaleness time.Duration) {
+	c.read.mu.Lock()
+	defer c.read.mu.Unlock()
+
+	c.read.set = true
+	c.read.mode = mode
+	c.read.maxStaleness = maxStaleness
+	c.read.checkedAt = time.Time{}
+}
+
+// Returns how far the slowest secondary is behind the most recent member of
+// the replica set of the package level functions.
+func ReplicationLag() (time.Duration, error) {
+	return std.ReplicationLag()
+}
+
+// Returns how far the slowest secondary is behind the most recent member of
+// the client's replica set. This is the lag the max staleness of the read
+// preference is checked against.
+func (c *Client) ReplicationLag() (time.Duration, error) {
+	s, err := c.GetSession()
+	if err != nil {
+		return 0, err
+	}
+	defer s.Close()
+
+	return secondaryLag(s)
+}
+
+// apply sets the mode of s. s must still be in its default mode, which is
+// used to measure the lag.
+func (p *readPref) apply(s *mgo.Session) {
+	p.mu.Lock()
+	set := p.set
+	p.mu.Unlock()
+
+	if set {
+		s.SetMode(p.modeFor(func() (time.Duration, error) { return secondaryLag(s) }), true)
+	}
+}
+
+// modeFor returns the mode to read with, measuring the lag with lag when
+// the cached measurement is out of date. The lag is measured without holding
+// the lock, by one caller at a time; the others use the last measurement
+// meanwhile. If the lag can't be measured, e.g. because the user isn't
+// allowed to run replSetGetStatus, the secondaries are treated as too stale
+// and the error is logged and kept for LagError.
+func (p *readPref) modeFor(lag func() (time.Duration, error)) mgo.Mode {
+	p.mu.Lock()
+	if p.maxStaleness <= 0 || p.mode == mgo.Primary {
+		mode := p.mode
+		p.mu.Unlock()
+		return mode
+	}
+
+	if !p.measuring && time.Since(p.checkedAt) > StalenessCheckInterval {
+		p.measuring = true
+		p.mu.Unlock()
+
+		d, err := lag()
+
+		p.mu.Lock()
+		if err != nil && p.lagErr == nil {
+			log.Printf("mongo: reading from the primary, the replication lag can't be measured: %v", err)
+		}
+		p.stale = err != nil || d > p.maxStaleness
+		p.lagErr = err
+		p.checkedAt = time.Now()
+		p.measuring = false
+	}
+	defer p.mu.Unlock()
+
+	if p.stale {
+		return mgo.Primary
+	}
+	return p.mode
+}
+
+// Returns why the replication lag couldn't be measured the last time max
+// staleness was checked, or nil if it could. While it can't, reads are sent
+// to the primary.
+func LagError() error {
+	return std.LagError()
+}
+
+// Returns why the client's replication lag couldn't be measured. See
+// LagError.
+func (c *Client) LagError() error {
+	c.read.mu.Lock()
+	defer c.read.mu.Unlock()
+
+	if c.read.lagErr == nil {
+		return nil
+	}
+	return fmt.Errorf("Couldn't measure replication lag: %w", c.read.lagErr)
+}
+
+// primaryFallback reports whether reads that could go to a secondary are
+// currently sent to the primary because the secondaries are too stale.
+func (p *readPref) primaryFallback() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.set && p.maxStaleness > 0 && p.mode != mgo.Primary && p.stale
+}
@@ -0,0 +1,107 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo"
+
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestReadPrefMaxStaleness(t *testing.T) {
+	c := &Client{}
+	c.SetReadPreference(mgo.SecondaryPreferred, 10*time.Second)
+
+	checks := 0
+	lag := func(d time.Duration, err error) func() (time.Duration, error) {
+		return func() (time.Duration, error) {
+			checks++
+			return d, err
+		}
+	}
+
+	if m := c.read.modeFor(lag(time.Second, nil)); m != mgo.SecondaryPreferred {
+		t.Fatal("Expected secondaries within the bound to be used, got:", m)
+	}
+
+	// The measurement is cached.
+	if m := c.read.modeFor(lag(time.Minute, nil)); m != mgo.SecondaryPreferred || checks != 1 {
+		t.Fatal("Expected the cached lag to be used, got:", m, checks)
+	}
+
+	c.read.checkedAt = time.Time{}
+	if m := c.read.modeFor(lag(time.Minute, nil)); m != mgo.Primary {
+		t.Fatal("Expected stale secondaries to fail over to the primary, got:", m)
+	}
+
+	c.read.checkedAt = time.Time{}
+	if m := c.read.modeFor(lag(0, errors.New("not a replica set"))); m != mgo.Primary {
+		t.Fatal("Expected an unknown lag to fail over to the primary, got:", m)
+	}
+}
+
+func TestReadPrefPrimaryFallback(t *testing.T) {
+	c := &Client{}
+	if c.read.primaryFallback() {
+		t.Fatal("Expected no fallback without a read preference")
+	}
+
+	c.SetReadPreference(mgo.SecondaryPreferred, 10*time.Second)
+	c.read.modeFor(func() (time.Duration, error) { return time.Minute, nil })
+	if !c.read.primaryFallback() {
+		t.Fatal("Expected stale secondaries to be reported")
+	}
+
+	c.read.checkedAt = time.Time{}
+	c.read.modeFor(func() (time.Duration, error) { return time.Second, nil })
+	if c.read.primaryFallback() {
+		t.Fatal("Expected secondaries within the bound not to be reported")
+	}
+}
+
+func TestReadPrefLagError(t *testing.T) {
+	c := &Client{}
+	c.SetReadPreference(mgo.SecondaryPreferred, 10*time.Second)
+
+	denied := errors.New("not authorized on admin to execute command")
+	c.read.modeFor(func() (time.Duration, error) { return 0, denied })
+	if err := c.LagError(); !errors.Is(err, denied) {
+		t.Fatal("Expected the measurement error got:", err)
+	}
+
+	c.read.checkedAt = time.Time{}
+	c.read.modeFor(func() (time.Duration, error) { return time.Second, nil })
+	if err := c.LagError(); err != nil {
+		t.Fatal("Expected the error to clear got:", err)
+	}
+}
+
+func TestReadPrefMeasuresOnce(t *testing.T) {
+	c := &Client{}
+	c.SetReadPreference(mgo.SecondaryPreferred, 10*time.Second)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	done := make(chan mgo.Mode)
+	go func() {
+		done <- c.read.modeFor(func() (time.Duration, error) {
+			close(started)
+			<-release
+			return time.Second, nil
+		})
+	}()
+	<-started
+
+	// A measurement is running, so this uses the last one without waiting.
+	m := c.read.modeFor(func() (time.Duration, error) {
+		t.Error("Expected only one measurement at a time")
+		return 0, nil
+	})
+	if m != mgo.SecondaryPreferred {
+		t.Fatal("Expected the last measurement to be used, got:", m)
+	}
+
+	close(release)
+	if m := <-done; m != mgo.SecondaryPreferred {
+		t.Fatal("Expected secondaries within the bound to be used, got:", m)
+	}
+}
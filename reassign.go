@@ -0,0 +1,118 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"reflect"
+)
+
+// ReassignBatch is the number of records Reassign updates per request when
+// ReassignOptions doesn't set one.
+var ReassignBatch = 1000
+
+// ReassignOptions configures ReassignWith.
+type ReassignOptions struct {
+	BatchSize int
+	// Progress, if set, is called after each batch with the number of
+	// records reassigned so far.
+	Progress func(reassigned int)
+}
+
+// Point every record of i's type whose field, a document key, holds fromId
+// at toId instead, e.g. to move a user's projects to another team. Returns
+// the number of records changed. See ReassignWith.
+func Reassign(i interface{}, field string, fromId, toId interface{}) (int, error) {
+	return std.Reassign(i, field, fromId, toId)
+}
+
+// Reassign references using the client. See Reassign.
+func (c *Client) Reassign(i interface{}, field string, fromId, toId interface{}) (int, error) {
+	return c.ReassignWith(i, field, fromId, toId, ReassignOptions{})
+}
+
+// Reassign records like Reassign with options. Records are updated in
+// batches, so a large reassignment doesn't hold up other writes, and it can
+// be run again to finish one that failed part way. Only fields holding the
+// id itself are reassigned, not arrays of ids. A RefCount on field is moved
+// along with the records.
+func ReassignWith(i interface{}, field string, fromId, toId interface{}, opts ReassignOptions) (int, error) {
+	return std.ReassignWith(i, field, fromId, toId, opts)
+}
+
+// Reassign references with options using the client. See ReassignWith.
+func (c *Client) ReassignWith(i interface{}, field string, fromId, toId interface{}, opts ReassignOptions) (int, error) {
+	return c.reassign(collName(i), scopeQuery(i, bson.M{field: fromId}), field, toId, opts)
+}
+
+// reassign sets field to toId on the records of coll matching q, a batch at
+// a time.
+func (c *Client) reassign(coll string, q bson.M, field string, toId interface{}, opts ReassignOptions) (int, error) {
+	op := c.startOp("update", coll, q)
+
+	if err := op.allowed(); err != nil {
+		return 0, op.done(err)
+	}
+
+	// Records that already hold toId would match every batch.
+	if reflect.DeepEqual(q[field], toId) {
+		return 0, op.done(nil)
+	}
+
+	size := opts.BatchSize
+	if size < 1 {
+		size = ReassignBatch
+	}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return 0, op.done(err)
+	}
+	defer s.Close()
+
+	records := c.GetColl(s, coll)
+
+	// Reference counts are moved with each batch; the other counters of the
+	// records can't be, so they're marked dirty.
+	defer func() {
+		forgetWrites(writeKey(c.Database(), coll, ""))
+		invalidateModelCaches(coll)
+		markCountsDirty(coll)
+	}()
+
+	total := 0
+	for {
+		var docs []struct {
+			Id interface{} `bson:"_id"`
+		}
+		if err := records.Find(q).Select(bson.M{"_id": 1}).Limit(size).All(&docs); err != nil {
+			return total, op.done(err)
+		}
+		if len(docs) == 0 {
+			return total, op.done(nil)
+		}
+
+		ids := make([]interface{}, len(docs))
+		for n, d := range docs {
+			ids[n] = d.Id
+		}
+
+		// Matching q again skips records changed since they were read.
+		batch := bson.M{"_id": bson.M{"$in": ids}}
+		for k, v := range q {
+			if k != "_id" {
+				batch[k] = v
+			}
+		}
+
+		info, err := records.UpdateAll(batch, bson.M{"$set": bson.M{field: toId}})
+		if err != nil {
+			return total, op.done(err)
+		}
+		total += info.Updated
+		moveRefs(c, s, coll, field, q[field], toId, info.Updated)
+
+		if opts.Progress != nil {
+			opts.Progress(total)
+		}
+	}
+}
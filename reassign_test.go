@@ -0,0 +1,27 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"errors"
+	"testing"
+)
+
+func TestReassignSameId(t *testing.T) {
+	id := bson.NewObjectId()
+
+	// Nothing to do, so the database isn't needed.
+	if n, err := Reassign(&refComment{}, "postid", id, id); n != 0 || err != nil {
+		t.Fatal("Expected nothing to reassign got:", n, err)
+	}
+}
+
+func TestReassignAccessPolicy(t *testing.T) {
+	SetAccessPolicy("refComment", AllowFind)
+	defer ClearAccessPolicy("refComment")
+
+	_, err := Reassign(&refComment{}, "postid", bson.NewObjectId(), bson.NewObjectId())
+	if !errors.Is(err, ErrAccessDenied) {
+		t.Fatal("Expected ErrAccessDenied got:", err)
+	}
+}
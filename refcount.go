@@ -0,0 +1,235 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+
+	"log"
+	"reflect"
+	"sync"
+	"time"
+)
+
+var (
+	refCountsMu sync.RWMutex
+	refCounts   = map[reflect.Type][]*RefCount{}
+)
+
+// RefCount keeps a counter on parent records of the number of child records
+// referencing them, so it can be shown without counting:
+//
+//	type Comment struct {
+//		Id     bson.ObjectId `bson:"_id"`
+//		PostId bson.ObjectId `bson:"postid"`
+//	}
+//
+//	mongo.NewRefCount(&Comment{}, "postid", &Post{}, "commentscount")
+//
+// Insert and Delete of a comment increment and decrement the commentscount
+// of its post, and Update moves the count when the comment is moved to
+// another post, as do the other writes of a single record. Reassign moves
+// the counts of the records it reassigns. Writes that can't tell which
+// records they changed, like UpdateDocs or BulkWrite, mark the counters
+// dirty for Run to rebuild.
+type RefCount struct {
+	client     *Client
+	child      interface{}
+	childColl  string
+	refKey     string
+	parentColl string
+	counterKey string
+	dirty      chan struct{}
+}
+
+// Create a counter in the field counterKey of records like parent of the
+// records like child whose field refKey holds their Id. Both keys are
+// document keys.
+func NewRefCount(child interface{}, refKey string, parent interface{}, counterKey string) *RefCount {
+	return std.NewRefCount(child, refKey, parent, counterKey)
+}
+
+// Create a reference counter using the client. See NewRefCount.
+func (c *Client) NewRefCount(child interface{}, refKey string, parent interface{}, counterKey string) *RefCount {
+	r := &RefCount{
+		client:     c,
+		child:      child,
+		childColl:  collName(child),
+		refKey:     refKey,
+		parentColl: collName(parent),
+		counterKey: counterKey,
+		dirty:      make(chan struct{}, 1),
+	}
+
+	refCountsMu.Lock()
+	defer refCountsMu.Unlock()
+
+	t := structType(child)
+	refCounts[t] = append(refCounts[t], r)
+	return r
+}
+
+// Stop maintaining the counter on writes.
+func (r *RefCount) Close() {
+	refCountsMu.Lock()
+	defer refCountsMu.Unlock()
+
+	t := structType(r.child)
+	counts := refCounts[t][:0]
+	for _, other := range refCounts[t] {
+		if other != r {
+			counts = append(counts, other)
+		}
+	}
+	refCounts[t] = counts
+}
+
+// Rebuild the counters by counting the child records. Every counter is set
+// to zero first and then to its count, so parents that no child references
+// end up at zero. Counts read while it runs may be low, and writes made while
+// it runs may be miscounted until the next time.
+func (r *RefCount) Reconcile() error {
+	var rows []struct {
+		Id interface{} `bson:"_id"`
+		N  int         `bson:"n"`
+	}
+	p := Pipeline{}.
+		Match(bson.M{r.refKey: bson.M{"$ne": nil}}).
+		Group(bson.M{"_id": "$" + r.refKey, "n": bson.M{"$sum": 1}})
+	if err := r.client.Aggregate(r.child, p, &rows); err != nil {
+		return err
+	}
+
+	s, err := r.client.GetSession()
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	parents := r.client.GetColl(s, r.parentColl)
+	if _, err := parents.UpdateAll(bson.M{r.counterKey: bson.M{"$ne": 0}}, bson.M{"$set": bson.M{r.counterKey: 0}}); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		if r.ref(bson.M{r.refKey: row.Id}) == nil {
+			continue
+		}
+		err := parents.UpdateId(row.Id, bson.M{"$set": bson.M{r.counterKey: row.N}})
+		if err != nil && err != mgo.ErrNotFound {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Reconcile the counters now, every interval and whenever they're marked
+// dirty, until stop is closed. Errors are passed to onError, if it isn't
+// nil, and don't stop it.
+func (r *RefCount) Run(interval time.Duration, stop <-chan struct{}, onError func(error)) {
+	reconcileLoop(r.Reconcile, r.dirty, interval, stop, onError)
+}
+
+func refCountsFor(i interface{}) []*RefCount {
+	refCountsMu.RLock()
+	defer refCountsMu.RUnlock()
+
+	return refCounts[structType(i)]
+}
+
+// refCountsOn returns the counters of references held in key by the records
+// of coll.
+func refCountsOn(coll, key string) []*RefCount {
+	refCountsMu.RLock()
+	defer refCountsMu.RUnlock()
+
+	var found []*RefCount
+	for _, counts := range refCounts {
+		for _, r := range counts {
+			if r.childColl == coll && r.refKey == key {
+				found = append(found, r)
+			}
+		}
+	}
+	return found
+}
+
+// markRefCountsDirty marks the counters of references held by the records
+// of coll dirty.
+func markRefCountsDirty(coll string) {
+	refCountsMu.RLock()
+	defer refCountsMu.RUnlock()
+
+	for _, counts := range refCounts {
+		for _, r := range counts {
+			if r.childColl == coll {
+				markDirty(r.dirty)
+			}
+		}
+	}
+}
+
+// countRefs moves a record written through i from the counter of the parent
+// it referenced before to that of the one referenced by after, the document
+// written. Before is nil for inserts and after for deletes. Like countWrite
+// errors are logged rather than failing the write.
+func countRefs(c *Client, s *mgo.Session, i interface{}, before bson.M, after interface{}) {
+	counts := refCountsFor(i)
+	if len(counts) == 0 {
+		return
+	}
+
+	var written bson.M
+	if after != nil {
+		data, err := bson.Marshal(after)
+		if err == nil {
+			err = bson.Unmarshal(data, &written)
+		}
+		if err != nil {
+			log.Println("mongo: ref counts:", err)
+			return
+		}
+	}
+
+	for _, r := range counts {
+		r.move(c.GetColl(s, r.parentColl), r.ref(before), r.ref(written), 1)
+	}
+}
+
+// moveRefs moves n references held in key by the records of coll from the
+// counter of the parent from to that of to, for Reassign.
+func moveRefs(c *Client, s *mgo.Session, coll, key string, from, to interface{}, n int) {
+	for _, r := range refCountsOn(coll, key) {
+		r.move(c.GetColl(s, r.parentColl), r.ref(bson.M{key: from}), r.ref(bson.M{key: to}), n)
+	}
+}
+
+// move takes n from the counter of the parent from and adds it to that of
+// to. Either may be nil. Errors are logged like in countRefs.
+func (r *RefCount) move(parents *mgo.Collection, from, to interface{}, n int) {
+	if reflect.DeepEqual(from, to) {
+		return
+	}
+
+	for _, inc := range []struct {
+		id interface{}
+		n  int
+	}{{from, -n}, {to, n}} {
+		if inc.id == nil {
+			continue
+		}
+		err := parents.UpdateId(inc.id, bson.M{"$inc": bson.M{r.counterKey: inc.n}})
+		if err != nil && err != mgo.ErrNotFound {
+			log.Println("mongo: ref counts:", err)
+		}
+	}
+}
+
+// ref returns the parent id doc references, or nil if it doesn't.
+func (r *RefCount) ref(doc bson.M) interface{} {
+	id := doc[r.refKey]
+	if id == nil || reflect.ValueOf(id).IsZero() {
+		return nil
+	}
+	return id
+}
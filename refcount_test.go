@@ -0,0 +1,56 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"testing"
+)
+
+type refComment struct {
+	Id     bson.ObjectId `bson:"_id"`
+	PostId bson.ObjectId `bson:"postid"`
+}
+
+func TestRefCountRegistry(t *testing.T) {
+	r := NewRefCount(&refComment{}, "postid", &fieldsetModel{}, "commentscount")
+
+	if counts := refCountsFor(&[]refComment{}); len(counts) != 1 || counts[0] != r {
+		t.Fatal("Expected the ref count to be registered got:", counts)
+	}
+
+	r.Close()
+	if counts := refCountsFor(&refComment{}); len(counts) != 0 {
+		t.Fatal("Expected no ref counts after Close got:", counts)
+	}
+}
+
+func TestRefCountRef(t *testing.T) {
+	r := &RefCount{refKey: "postid"}
+	id := bson.NewObjectId()
+
+	if ref := r.ref(bson.M{"postid": id}); ref != id {
+		t.Fatal("Expected the post id got:", ref)
+	}
+	for _, doc := range []bson.M{nil, {}, {"postid": ""}, {"postid": bson.ObjectId("")}} {
+		if ref := r.ref(doc); ref != nil {
+			t.Fatal("Expected no reference got:", ref)
+		}
+	}
+}
+
+func TestRefCountsOn(t *testing.T) {
+	r := NewRefCount(&refComment{}, "postid", &fieldsetModel{}, "commentscount")
+	defer r.Close()
+
+	if counts := refCountsOn(r.childColl, "postid"); len(counts) != 1 || counts[0] != r {
+		t.Fatal("Expected the ref count got:", counts)
+	}
+	if counts := refCountsOn(r.childColl, "authorid"); len(counts) != 0 {
+		t.Fatal("Expected no ref counts on another key got:", counts)
+	}
+
+	markRefCountsDirty(r.childColl)
+	if len(r.dirty) != 1 {
+		t.Fatal("Expected the ref count to be marked dirty")
+	}
+}
@@ -0,0 +1,303 @@
+/*
+The rest package mounts CRUD HTTP handlers for models onto an
+http.ServeMux. It's meant for quick admin APIs and prototypes:
+
+	mux := http.NewServeMux()
+	rest.Mount(mux, "/api", &User{}, &Post{})
+
+For each model the following routes are served under the name of its
+collection, e.g. /api/User:
+
+	GET    /api/User?page=2&perPage=50&sort=-createdat&count=false&name=George
+	GET    /api/User/{id}
+	POST   /api/User
+	PUT    /api/User/{id}
+	DELETE /api/User/{id}
+
+Records are read and written as JSON through the mongo package, so
+models that implement mongo.Validator are validated and failures are
+answered with 422 Unprocessable Entity, as are updates changing an
+immutable field. Writes made while the mongo package is in read only
+mode are answered with 503 Service Unavailable.
+*/
+package rest
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/sfreiberg/mongo"
+
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// MaxPerPage caps the perPage parameter of list requests.
+var MaxPerPage = 100
+
+// Reserved list parameters. Every other parameter filters on the field
+// with that key.
+const (
+	PageParam    = "page"
+	PerPageParam = "perPage"
+	SortParam    = "sort"
+	// CountParam set to false skips counting the matching records, which
+	// is slow on very large collections.
+	CountParam = "count"
+)
+
+// Mount handlers for each model on mux under prefix. Models must be
+// pointers to structs.
+func Mount(mux *http.ServeMux, prefix string, models ...interface{}) {
+	prefix = strings.TrimSuffix(prefix, "/")
+
+	for _, m := range models {
+		path := prefix + "/" + mongo.CollectionFor(m)
+		h := &handler{typ: reflect.TypeOf(m).Elem(), path: path}
+
+		mux.Handle(path, h)
+		mux.Handle(path+"/", h)
+	}
+}
+
+type handler struct {
+	typ  reflect.Type
+	path string
+}
+
+// listResponse is the body of a list request.
+type listResponse struct {
+	Items interface{} `json:"items"`
+	*mongo.Page
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, h.path), "/")
+
+	switch {
+	case id == "" && r.Method == "GET":
+		h.list(w, r)
+	case id == "" && r.Method == "POST":
+		h.create(w, r)
+	case id == "":
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	case strings.Contains(id, "/") || !bson.IsObjectIdHex(id):
+		writeError(w, http.StatusNotFound, "Not found")
+	case r.Method == "GET":
+		h.get(w, id)
+	case r.Method == "PUT":
+		h.update(w, r, id)
+	case r.Method == "DELETE":
+		h.delete(w, id)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+func (h *handler) list(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+
+	page, _ := strconv.Atoi(params.Get(PageParam))
+	perPage, _ := strconv.Atoi(params.Get(PerPageParam))
+	if perPage > MaxPerPage {
+		perPage = MaxPerPage
+	}
+
+	q, err := filters(h.typ, params)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var sort []string
+	if s := params.Get(SortParam); s != "" {
+		sort = strings.Split(s, ",")
+	}
+
+	opts := mongo.PaginateOptions{SkipCount: params.Get(CountParam) == "false"}
+
+	items := reflect.New(reflect.SliceOf(reflect.PtrTo(h.typ)))
+	p, err := mongo.PaginateWith(items.Interface(), q, page, perPage, opts, sort...)
+	if err != nil {
+		writeErr(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, listResponse{Items: items.Elem().Interface(), Page: p})
+}
+
+func (h *handler) get(w http.ResponseWriter, id string) {
+	rec := reflect.New(h.typ).Interface()
+	if err := mongo.Default().FindById(rec, id); err != nil {
+		writeErr(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, rec)
+}
+
+func (h *handler) create(w http.ResponseWriter, r *http.Request) {
+	rec := reflect.New(h.typ).Interface()
+	if err := json.NewDecoder(r.Body).Decode(rec); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid JSON: "+err.Error())
+		return
+	}
+
+	if err := mongo.Default().Insert(rec); err != nil {
+		writeErr(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, rec)
+}
+
+func (h *handler) update(w http.ResponseWriter, r *http.Request, id string) {
+	rec := reflect.New(h.typ)
+	if err := mongo.Default().FindById(rec.Interface(), id); err != nil {
+		writeErr(w, err)
+		return
+	}
+
+	// The id in the path wins over one in the body.
+	var stored reflect.Value
+	if f := rec.Elem().FieldByName("Id"); f.IsValid() {
+		stored = reflect.ValueOf(f.Interface())
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(rec.Interface()); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid JSON: "+err.Error())
+		return
+	}
+
+	if stored.IsValid() {
+		rec.Elem().FieldByName("Id").Set(stored)
+	}
+
+	if err := mongo.Default().Update(rec.Interface()); err != nil {
+		writeErr(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, rec.Interface())
+}
+
+func (h *handler) delete(w http.ResponseWriter, id string) {
+	rec := reflect.New(h.typ).Interface()
+	if err := mongo.Default().FindById(rec, id); err != nil {
+		writeErr(w, err)
+		return
+	}
+
+	if err := mongo.Default().Delete(rec); err != nil {
+		writeErr(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// filters builds a query from the non reserved parameters. Only fields of
+// the model can be filtered on and values are converted to the field's type.
+func filters(t reflect.Type, params map[string][]string) (bson.M, error) {
+	q := bson.M{}
+
+	for key, values := range params {
+		if key == PageParam || key == PerPageParam || key == SortParam || key == CountParam {
+			continue
+		}
+
+		f, ok := fieldByKey(t, key)
+		if !ok {
+			return nil, errors.New("Unknown filter: " + key)
+		}
+
+		v, err := convert(f.Type, values[0])
+		if err != nil {
+			return nil, errors.New("Invalid value for " + key + ": " + err.Error())
+		}
+		q[key] = v
+	}
+
+	return q, nil
+}
+
+func fieldByKey(t reflect.Type, key string) (reflect.StructField, bool) {
+	for n := 0; n < t.NumField(); n++ {
+		f := t.Field(n)
+		if f.PkgPath != "" {
+			continue
+		}
+
+		name := strings.Split(f.Tag.Get("bson"), ",")[0]
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		if name == key {
+			return f, true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
+
+func convert(t reflect.Type, s string) (interface{}, error) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == reflect.TypeOf(bson.ObjectId("")) || t == reflect.TypeOf(mongo.Id("")) {
+		if !bson.IsObjectIdHex(s) {
+			return nil, errors.New("not an id")
+		}
+		return bson.ObjectIdHex(s), nil
+	}
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.ParseInt(s, 10, 64)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.ParseUint(s, 10, 64)
+	case reflect.Float32, reflect.Float64:
+		return strconv.ParseFloat(s, 64)
+	case reflect.Bool:
+		return strconv.ParseBool(s)
+	}
+
+	return s, nil
+}
+
+func writeErr(w http.ResponseWriter, err error) {
+	var verr *mongo.ValidationError
+
+	switch {
+	case errors.Is(err, mgo.ErrNotFound):
+		writeError(w, http.StatusNotFound, "Not found")
+	case errors.As(err, &verr):
+		writeError(w, http.StatusUnprocessableEntity, verr.Err.Error())
+	case errors.Is(err, mongo.ErrImmutableField):
+		writeError(w, http.StatusUnprocessableEntity, mongo.ErrImmutableField.Error())
+	case errors.Is(err, mongo.ErrReadOnly):
+		writeError(w, http.StatusServiceUnavailable, "Read only")
+	case errors.Is(err, mongo.ErrAccessDenied):
+		writeError(w, http.StatusForbidden, "Forbidden")
+	case errors.Is(err, mongo.ErrUnsafeQuery):
+		writeError(w, http.StatusBadRequest, mongo.ErrUnsafeQuery.Error())
+	default:
+		log.Println("rest:", err)
+		writeError(w, http.StatusInternalServerError, "Internal error")
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
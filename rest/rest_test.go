@@ -0,0 +1,107 @@
+package rest
+
+import (
+	"github.com/globalsign/mgo/bson"
+	"github.com/sfreiberg/mongo"
+
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type Widget struct {
+	Id    bson.ObjectId `bson:"_id"`
+	Name  string
+	Count int `bson:"n"`
+}
+
+func TestRoutes(t *testing.T) {
+	mux := http.NewServeMux()
+	Mount(mux, "/api/", &Widget{})
+
+	cases := []struct {
+		method, path string
+		status       int
+	}{
+		{"GET", "/api/Widget/nope", http.StatusNotFound},
+		{"GET", "/api/Widget/" + bson.NewObjectId().Hex() + "/x", http.StatusNotFound},
+		{"PATCH", "/api/Widget", http.StatusMethodNotAllowed},
+		{"GET", "/api/Widget?color=red", http.StatusBadRequest},
+		{"GET", "/api/Widget?n=many", http.StatusBadRequest},
+	}
+
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(c.method, c.path, nil))
+		if w.Code != c.status {
+			t.Errorf("%v %v: expected %v, got %v", c.method, c.path, c.status, w.Code)
+		}
+	}
+}
+
+func TestFilters(t *testing.T) {
+	q, err := filters(reflect.TypeOf(Widget{}), map[string][]string{
+		"name":    {"gear"},
+		"n":       {"3"},
+		"page":    {"2"},
+		"perPage": {"10"},
+		"count":   {"false"},
+	})
+	if err != nil {
+		t.Fatal("Couldn't build filters:", err)
+	}
+
+	if len(q) != 2 || q["name"] != "gear" || q["n"] != int64(3) {
+		t.Fatal("Unexpected filters:", q)
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	mux := http.NewServeMux()
+	Mount(mux, "/api/", &Widget{})
+
+	mongo.SetReadOnly(true)
+	defer mongo.SetReadOnly(false)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("POST", "/api/Widget", strings.NewReader(`{"Name": "gear"}`)))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatal("Expected 503, got:", w.Code)
+	}
+}
+
+func TestErrorStatus(t *testing.T) {
+	cases := []struct {
+		err    error
+		status int
+	}{
+		{&mongo.OpError{Op: "find", Err: mongo.ErrAccessDenied}, http.StatusForbidden},
+		{&mongo.OpError{Op: "find", Err: fmt.Errorf("%w: $where", mongo.ErrUnsafeQuery)}, http.StatusBadRequest},
+		{&mongo.OpError{Op: "insert", Err: mongo.ErrReadOnly}, http.StatusServiceUnavailable},
+		{errors.New("boom"), http.StatusInternalServerError},
+	}
+
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		writeErr(w, c.err)
+		if w.Code != c.status {
+			t.Errorf("%v: expected %v, got %v", c.err, c.status, w.Code)
+		}
+	}
+}
+
+func TestConvertUnsigned(t *testing.T) {
+	v, err := convert(reflect.TypeOf(uint64(0)), "18446744073709551615")
+	if err != nil || v != uint64(math.MaxUint64) {
+		t.Fatal("Expected the largest uint64 got:", v, err)
+	}
+
+	if _, err := convert(reflect.TypeOf(uint(0)), "-1"); err == nil {
+		t.Fatal("Expected negative values to be rejected for unsigned fields")
+	}
+}
@@ -0,0 +1,108 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+
+	"time"
+)
+
+// RevocationList stores the ids of revoked session tokens or JWTs, e.g. the
+// jti claim, until the tokens would have expired anyway:
+//
+//	revoked := mongo.NewRevocationList("revoked_tokens")
+//	err := revoked.Add(claims.Id, time.Unix(claims.ExpiresAt, 0))
+//	...
+//	if ok, err := revoked.IsRevoked(claims.Id); ok || err != nil {
+//		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+//	}
+type RevocationList struct {
+	client     *Client
+	collection string
+}
+
+// Create a revocation list kept in the named collection. Call EnsureIndexes
+// to have the server remove expired entries or call Purge periodically.
+func NewRevocationList(collection string) *RevocationList {
+	return std.NewRevocationList(collection)
+}
+
+// Create a revocation list using the client. See NewRevocationList.
+func (c *Client) NewRevocationList(collection string) *RevocationList {
+	return &RevocationList{client: c, collection: collection}
+}
+
+// Create the index that removes entries once their token has expired.
+func (l *RevocationList) EnsureIndexes() error {
+	s, err := l.client.GetSession()
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	return l.client.GetColl(s, l.collection).EnsureIndex(mgo.Index{Key: []string{"expiresat"}, ExpireAfter: time.Second})
+}
+
+// Revoke the token with id, which expires at expiresAt. Revoking a token
+// again updates its expiry.
+func (l *RevocationList) Add(id string, expiresAt time.Time) error {
+	op := l.client.startOp("update", l.collection, bson.M{"_id": id})
+
+	if err := op.allowed(); err != nil {
+		return op.done(err)
+	}
+
+	s, err := l.client.GetSession()
+	if err != nil {
+		return op.done(err)
+	}
+	defer s.Close()
+
+	_, err = l.client.GetColl(s, l.collection).UpsertId(id, bson.M{
+		"$set":         bson.M{"expiresat": expiresAt},
+		"$setOnInsert": bson.M{"revokedat": now()},
+	})
+	return op.done(err)
+}
+
+// Returns whether the token with id has been revoked and hasn't expired yet.
+func (l *RevocationList) IsRevoked(id string) (bool, error) {
+	q := bson.M{"_id": id, "expiresat": bson.M{"$gt": now()}}
+	op := l.client.startOp("count", l.collection, q)
+
+	if err := op.allowed(); err != nil {
+		return false, op.done(err)
+	}
+
+	s, err := l.client.GetSession()
+	if err != nil {
+		return false, op.done(err)
+	}
+	defer s.Close()
+
+	n, err := l.client.GetColl(s, l.collection).Find(q).Limit(1).Count()
+	return n > 0, op.done(err)
+}
+
+// Delete the entries of tokens that have expired. Returns how many were
+// deleted.
+func (l *RevocationList) Purge() (int, error) {
+	q := bson.M{"expiresat": bson.M{"$lte": now()}}
+	op := l.client.startOp("delete", l.collection, q)
+
+	if err := op.allowed(); err != nil {
+		return 0, op.done(err)
+	}
+
+	s, err := l.client.GetSession()
+	if err != nil {
+		return 0, op.done(err)
+	}
+	defer s.Close()
+
+	info, err := l.client.GetColl(s, l.collection).RemoveAll(q)
+	if err != nil {
+		return 0, op.done(err)
+	}
+	return info.Removed, op.done(nil)
+}
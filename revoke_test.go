@@ -0,0 +1,21 @@
+package mongo
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRevocationListAccessPolicy(t *testing.T) {
+	SetAccessPolicy("revoked", AllowFind)
+	defer ClearAccessPolicy("revoked")
+
+	l := NewRevocationList("revoked")
+
+	if err := l.Add("jti", time.Now().Add(time.Hour)); !errors.Is(err, ErrAccessDenied) {
+		t.Fatal("Expected ErrAccessDenied got:", err)
+	}
+	if _, err := l.Purge(); !errors.Is(err, ErrAccessDenied) {
+		t.Fatal("Expected ErrAccessDenied got:", err)
+	}
+}
@@ -0,0 +1,110 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Accumulators for Agg.
+const (
+	AggSum   = "$sum"
+	AggAvg   = "$avg"
+	AggMin   = "$min"
+	AggMax   = "$max"
+	AggCount = "count"
+)
+
+// Agg is a single value computed for each interval by RollupByTime, e.g.
+// Agg{Op: AggSum, Field: "amount"}. Field is ignored for AggCount.
+type Agg struct {
+	Op    string
+	Field string
+}
+
+// Rollup holds the values computed for a single interval.
+type Rollup struct {
+	Start  time.Time
+	Count  int
+	Values map[string]float64
+}
+
+// Group the documents matching q into fixed intervals of timeField and
+// compute the aggregations for each one. Intervals are aligned to the Unix
+// epoch and computed with $subtract/$mod date math rather than $dateTrunc,
+// so results are the same on every server version. Intervals without any
+// documents are omitted. Results are sorted by Start.
+func RollupByTime(i interface{}, timeField string, interval time.Duration, aggregations map[string]Agg, q bson.M) ([]Rollup, error) {
+	return std.RollupByTime(i, timeField, interval, aggregations, q)
+}
+
+// Roll up records by time using the client. See RollupByTime.
+func (c *Client) RollupByTime(i interface{}, timeField string, interval time.Duration, aggregations map[string]Agg, q bson.M) ([]Rollup, error) {
+	p, err := rollupPipeline(timeField, interval, aggregations, q)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []bson.M
+	if err := c.Aggregate(i, p, &res); err != nil {
+		return nil, err
+	}
+
+	rollups := make([]Rollup, len(res))
+	for n, r := range res {
+		start, _ := r["_id"].(time.Time)
+		rollups[n] = Rollup{Start: start, Count: toInt(r["_count"]), Values: map[string]float64{}}
+
+		for name := range aggregations {
+			rollups[n].Values[name] = toFloat(r[name])
+		}
+	}
+
+	return rollups, nil
+}
+
+// rollupPipeline builds the pipeline RollupByTime runs.
+func rollupPipeline(timeField string, interval time.Duration, aggregations map[string]Agg, q bson.M) (Pipeline, error) {
+	ms := int64(interval / time.Millisecond)
+	if ms <= 0 {
+		return nil, errors.New("RollupByTime needs an interval of at least a millisecond")
+	}
+
+	// $mod keeps the sign of the dividend, so the offset into the interval
+	// is taken modulo ms twice to keep it positive before 1970.
+	ts := "$" + timeField
+	since := bson.M{"$subtract": []interface{}{ts, time.Unix(0, 0)}}
+	offset := bson.M{"$mod": []interface{}{
+		bson.M{"$add": []interface{}{bson.M{"$mod": []interface{}{since, ms}}, ms}},
+		ms,
+	}}
+	group := bson.M{
+		"_id":    bson.M{"$subtract": []interface{}{ts, offset}},
+		"_count": bson.M{"$sum": 1},
+	}
+
+	for name, agg := range aggregations {
+		if name == "" || strings.ContainsAny(name, ".$") || strings.HasPrefix(name, "_") {
+			return nil, fmt.Errorf("Invalid aggregation name: %q", name)
+		}
+
+		switch agg.Op {
+		case AggCount:
+			group[name] = bson.M{"$sum": 1}
+		case AggSum, AggAvg, AggMin, AggMax:
+			group[name] = bson.M{agg.Op: "$" + agg.Field}
+		default:
+			return nil, fmt.Errorf("Unknown aggregation operator: %q", agg.Op)
+		}
+	}
+
+	match := bson.M{timeField: bson.M{"$type": 9}}
+	if len(q) > 0 {
+		match = bson.M{"$and": []bson.M{q, match}}
+	}
+
+	return Pipeline{}.Match(match).Group(group).Sort("_id"), nil
+}
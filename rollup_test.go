@@ -0,0 +1,108 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"testing"
+	"time"
+)
+
+// evalDateExpr evaluates the $subtract, $add and $mod date math used by
+// rollupPipeline the way the server does, with "$at" bound to at.
+func evalDateExpr(t *testing.T, expr interface{}, at time.Time) interface{} {
+	t.Helper()
+
+	ms := func(v interface{}) int64 {
+		switch v := v.(type) {
+		case time.Time:
+			return v.UnixNano() / int64(time.Millisecond)
+		case int64:
+			return v
+		}
+		t.Fatalf("Unexpected operand %T", v)
+		return 0
+	}
+
+	switch e := expr.(type) {
+	case string:
+		return at
+	case bson.M:
+		for op, args := range e {
+			a := args.([]interface{})
+			x, y := evalDateExpr(t, a[0], at), evalDateExpr(t, a[1], at)
+			switch op {
+			case "$add":
+				return ms(x) + ms(y)
+			case "$mod":
+				// Like Go's %, the server's $mod keeps the sign of the
+				// dividend.
+				return ms(x) % ms(y)
+			case "$subtract":
+				if xt, ok := x.(time.Time); ok {
+					if _, ok := y.(time.Time); ok {
+						return ms(x) - ms(y)
+					}
+					return xt.Add(-time.Duration(ms(y)) * time.Millisecond)
+				}
+				return ms(x) - ms(y)
+			}
+			t.Fatal("Unexpected operator", op)
+		}
+	}
+	return expr
+}
+
+func TestRollupPipelineIntervals(t *testing.T) {
+	p, err := rollupPipeline("at", time.Hour, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := p[1]["$group"].(bson.M)["_id"]
+
+	for at, want := range map[time.Time]time.Time{
+		time.Date(2020, 5, 1, 10, 30, 0, 0, time.UTC):   time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC),
+		time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC):    time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC),
+		time.Date(1969, 12, 31, 23, 30, 0, 0, time.UTC): time.Date(1969, 12, 31, 23, 0, 0, 0, time.UTC),
+		time.Date(1950, 7, 4, 12, 59, 0, 0, time.UTC):   time.Date(1950, 7, 4, 12, 0, 0, 0, time.UTC),
+		time.Date(1950, 7, 4, 12, 0, 0, 0, time.UTC):    time.Date(1950, 7, 4, 12, 0, 0, 0, time.UTC),
+	} {
+		got := evalDateExpr(t, id, at)
+		if got != want {
+			t.Errorf("Expected %v to start at %v got: %v", at, want, got)
+		}
+	}
+}
+
+func TestRollupPipelineAggregations(t *testing.T) {
+	p, err := rollupPipeline("at", time.Minute, map[string]Agg{
+		"total":  {Op: AggSum, Field: "amount"},
+		"orders": {Op: AggCount},
+	}, bson.M{"status": "paid"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(p) != 3 || p[2]["$sort"] == nil {
+		t.Fatal("Expected $match, $group and $sort stages got:", p)
+	}
+	if _, ok := p[0]["$match"].(bson.M)["$and"]; !ok {
+		t.Fatal("Expected the query to be combined with the date type check got:", p[0])
+	}
+
+	group := p[1]["$group"].(bson.M)
+	if group["total"].(bson.M)["$sum"] != "$amount" || group["orders"].(bson.M)["$sum"] != 1 {
+		t.Fatal("Unexpected accumulators:", group)
+	}
+}
+
+func TestRollupPipelineErrors(t *testing.T) {
+	if _, err := rollupPipeline("at", time.Microsecond, nil, nil); err == nil {
+		t.Fatal("Expected an error for an interval under a millisecond")
+	}
+	if _, err := rollupPipeline("at", time.Hour, map[string]Agg{"_id": {Op: AggCount}}, nil); err == nil {
+		t.Fatal("Expected an error for a reserved name")
+	}
+	if _, err := rollupPipeline("at", time.Hour, map[string]Agg{"x": {Op: "$median"}}, nil); err == nil {
+		t.Fatal("Expected an error for an unknown operator")
+	}
+}
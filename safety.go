@@ -0,0 +1,195 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrUnsafeQuery is returned, wrapped with the reason, for queries rejected
+// by safety mode.
+var ErrUnsafeQuery = errors.New("Query rejected by safety mode")
+
+var (
+	// SafetyLargeCollection is the number of documents from which safety
+	// mode considers a collection large.
+	SafetyLargeCollection = 10000
+
+	// SafetySizeTTL is how long safety mode reuses a collection's
+	// document count.
+	SafetySizeTTL = time.Minute
+)
+
+// unsafeKey marks a query exempt from safety mode. It's removed before the
+// query is sent.
+const unsafeKey = "$allowUnsafe"
+
+var safetyMode int32
+
+var (
+	collSizesMu sync.Mutex
+	collSizes   = map[string]collSize{}
+)
+
+type collSize struct {
+	n         int
+	checkedAt time.Time
+}
+
+// Turn production safety mode on or off. When it's on, queries are rejected
+// with ErrUnsafeQuery if they use $where, use a regex that isn't anchored to
+// the start of the string, or have an empty filter on a collection of at
+// least SafetyLargeCollection documents where the results aren't limited.
+// Use AllowUnsafe to let a single query through.
+func SetSafetyMode(on bool) {
+	var v int32
+	if on {
+		v = 1
+	}
+	atomic.StoreInt32(&safetyMode, v)
+}
+
+// Returns a copy of q that safety mode lets through:
+//
+//	err := mongo.Find(&users, mongo.AllowUnsafe(bson.M{"name": bson.RegEx{Pattern: "smith"}}))
+func AllowUnsafe(q bson.M) bson.M {
+	c := bson.M{unsafeKey: true}
+	for k, v := range q {
+		c[k] = v
+	}
+	return c
+}
+
+// check applies safety mode to the query of the operation and returns it
+// without the AllowUnsafe marker, which must not reach the server. bounded
+// is true when the operation limits its results, making an empty filter
+// harmless.
+func (o *operation) check(c *Client, s *mgo.Session, q bson.M, bounded bool) (bson.M, error) {
+	allowed := false
+	if _, ok := q[unsafeKey]; ok {
+		allowed = true
+		plain := make(bson.M, len(q))
+		for k, v := range q {
+			if k != unsafeKey {
+				plain[k] = v
+			}
+		}
+		q = plain
+		o.query = q
+	}
+
+	if allowed || atomic.LoadInt32(&safetyMode) == 0 {
+		return q, nil
+	}
+
+	if reason := unsafeReason(q); reason != "" {
+		return q, fmt.Errorf("%w: %v", ErrUnsafeQuery, reason)
+	}
+
+	if bounded || !emptyFilter(q) {
+		return q, nil
+	}
+
+	n, err := collectionSize(c.GetColl(s, o.coll))
+	if err != nil {
+		return q, err
+	}
+	if n >= SafetyLargeCollection {
+		return q, fmt.Errorf("%w: empty filter on %v which has %v documents", ErrUnsafeQuery, o.coll, n)
+	}
+
+	return q, nil
+}
+
+// unsafeReason returns why q is unsafe regardless of the collection, or ""
+// if it isn't.
+func unsafeReason(v interface{}) string {
+	switch val := v.(type) {
+	case bson.M:
+		return unsafeMap(val)
+	case map[string]interface{}:
+		return unsafeMap(val)
+	case bson.D:
+		return unsafeMap(val.Map())
+	case bson.RegEx:
+		if !anchored(val.Pattern) {
+			return fmt.Sprintf("regex /%v/ isn't anchored with ^", val.Pattern)
+		}
+		return ""
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() != reflect.Uint8 {
+		for n := 0; n < rv.Len(); n++ {
+			if r := unsafeReason(rv.Index(n).Interface()); r != "" {
+				return r
+			}
+		}
+	}
+	return ""
+}
+
+func unsafeMap(m map[string]interface{}) string {
+	for k, v := range m {
+		if k == "$where" {
+			return "$where runs JavaScript against every document"
+		}
+
+		if k == "$regex" {
+			if p, ok := v.(string); ok && !anchored(p) {
+				return fmt.Sprintf("regex /%v/ isn't anchored with ^", p)
+			}
+		}
+
+		if r := unsafeReason(v); r != "" {
+			return r
+		}
+	}
+	return ""
+}
+
+// anchored returns true for regexes that can use an index: anchored to the
+// start and not starting with a wildcard.
+func anchored(pattern string) bool {
+	return strings.HasPrefix(pattern, "^") && !strings.HasPrefix(pattern, "^.*") && !strings.HasPrefix(pattern, "^.+")
+}
+
+// emptyFilter returns true if q matches every document of its type.
+func emptyFilter(q bson.M) bool {
+	for k := range q {
+		if k != TypeKey {
+			return false
+		}
+	}
+	return true
+}
+
+// collectionSize returns the number of documents in coll, cached by its
+// full name for SafetySizeTTL.
+func collectionSize(coll *mgo.Collection) (int, error) {
+	collSizesMu.Lock()
+	cs, ok := collSizes[coll.FullName]
+	collSizesMu.Unlock()
+
+	if ok && time.Since(cs.checkedAt) < SafetySizeTTL {
+		return cs.n, nil
+	}
+
+	n, err := coll.Count()
+	if err != nil {
+		return 0, err
+	}
+
+	collSizesMu.Lock()
+	collSizes[coll.FullName] = collSize{n: n, checkedAt: time.Now()}
+	collSizesMu.Unlock()
+
+	return n, nil
+}
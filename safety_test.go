@@ -0,0 +1,98 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestUnsafeReason(t *testing.T) {
+	unsafe := []bson.M{
+		{"$where": "this.a > 1"},
+		{"name": bson.RegEx{Pattern: "smith"}},
+		{"name": bson.RegEx{Pattern: "^.*smith"}},
+		{"name": bson.M{"$regex": "smith$"}},
+		{"$or": []bson.M{{"a": 1}, {"name": bson.RegEx{Pattern: "x"}}}},
+		{"tags": bson.M{"$elemMatch": bson.D{{Name: "$where", Value: "x"}}}},
+	}
+	for _, q := range unsafe {
+		if unsafeReason(q) == "" {
+			t.Errorf("Expected %v to be unsafe", q)
+		}
+	}
+
+	safe := []bson.M{
+		{"name": bson.RegEx{Pattern: "^smith"}},
+		{"name": bson.M{"$regex": "^smi"}, "age": bson.M{"$gt": 3}},
+		{"data": []byte("$where")},
+	}
+	for _, q := range safe {
+		if r := unsafeReason(q); r != "" {
+			t.Errorf("Expected %v to be safe, got: %v", q, r)
+		}
+	}
+}
+
+func TestCheckAllowUnsafe(t *testing.T) {
+	SetSafetyMode(true)
+	defer SetSafetyMode(false)
+
+	op := startOp("find", "people", nil)
+	defer op.done(nil)
+
+	q := bson.M{"$where": "true"}
+	if _, err := op.check(std, nil, q, true); !errors.Is(err, ErrUnsafeQuery) {
+		t.Fatal("Expected ErrUnsafeQuery, got:", err)
+	}
+
+	allowed, err := op.check(std, nil, AllowUnsafe(q), true)
+	if err != nil {
+		t.Fatal("Expected AllowUnsafe to let the query through:", err)
+	}
+	if _, ok := allowed[unsafeKey]; ok || len(allowed) != 1 {
+		t.Fatal("Expected the marker to be removed:", allowed)
+	}
+	if len(q) != 1 {
+		t.Fatal("Expected the original query to be untouched:", q)
+	}
+}
+
+func TestEmptyFilter(t *testing.T) {
+	if !emptyFilter(nil) || !emptyFilter(bson.M{TypeKey: "Click"}) || emptyFilter(bson.M{"a": 1}) {
+		t.Fatal("Unexpected emptyFilter result")
+	}
+}
+
+// FuzzUnsafeReason checks that nesting a query in $and or $or doesn't hide
+// why it's unsafe, and that $where is always caught.
+func FuzzUnsafeReason(f *testing.F) {
+	for _, seed := range []string{
+		`{"name": {"$regex": "smith"}}`,
+		`{"name": {"$regex": "^smith"}}`,
+		`{"$or": [{"a": 1}, {"$where": "this.a > 1"}]}`,
+		`{"tags": {"$elemMatch": {"$regex": "^.*x"}}}`,
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, data string) {
+		var q map[string]interface{}
+		if err := json.Unmarshal([]byte(data), &q); err != nil {
+			return
+		}
+
+		unsafe := unsafeReason(bson.M(q)) != ""
+		for _, op := range []string{"$and", "$or"} {
+			if nested := unsafeReason(bson.M{op: []interface{}{q}}) != ""; nested != unsafe {
+				t.Fatalf("Expected unsafe=%v under %v got %v for: %s", unsafe, op, nested, data)
+			}
+		}
+
+		q["$where"] = "true"
+		if unsafeReason(bson.M(q)) == "" {
+			t.Fatal("Expected $where to be unsafe in:", data)
+		}
+	})
+}
@@ -0,0 +1,225 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"fmt"
+	"time"
+)
+
+var (
+	// SagaCollection holds the state of every saga run so it can be
+	// recovered after a crash.
+	SagaCollection = "sagas"
+	// SagaTimeout is how long a run must go without progress before Recover
+	// treats it as abandoned.
+	SagaTimeout = 5 * time.Minute
+)
+
+// Status of a saga run.
+const (
+	SagaRunning      = "running"
+	SagaCompensating = "compensating"
+	SagaDone         = "done"
+	SagaCompensated  = "compensated"
+	SagaFailed       = "failed"
+)
+
+// SagaStep is one step of a saga. Do performs the step and Compensate undoes
+// it. Both get the run's data, which they can change, e.g. to record the id
+// of a record created; it's saved after every step. After a crash Recover
+// may compensate a step whose Do didn't finish, so Compensate must cope with
+// that.
+type SagaStep struct {
+	Name       string
+	Do         func(data bson.M) error
+	Compensate func(data bson.M) error
+}
+
+// Saga runs steps that write to several documents one after another and
+// undoes the finished ones if a step fails, as a substitute for a
+// transaction:
+//
+//	order := mongo.NewSaga("order",
+//		mongo.SagaStep{Name: "reserve", Do: reserveStock, Compensate: releaseStock},
+//		mongo.SagaStep{Name: "charge", Do: chargeCard, Compensate: refundCard},
+//	)
+//	err := order.Run(bson.M{"orderid": id})
+//
+// Progress is saved in SagaCollection so Recover can compensate runs
+// abandoned by a crash.
+type Saga struct {
+	client *Client
+	name   string
+	steps  []SagaStep
+	// persist saves a run, inserting it the first time.
+	persist func(run *sagaRun, insert bool) error
+}
+
+// SagaError is returned when a saga step fails.
+type SagaError struct {
+	Step string
+	Err  error
+	// CompensateErr is the error from compensating, if compensating failed
+	// too. The run is then left in SagaFailed for someone to look at.
+	CompensateErr error
+}
+
+func (e *SagaError) Error() string {
+	msg := fmt.Sprintf("Saga step %v failed: %v", e.Step, e.Err)
+	if e.CompensateErr != nil {
+		msg += fmt.Sprintf(" (compensating failed: %v)", e.CompensateErr)
+	}
+	return msg
+}
+
+func (e *SagaError) Unwrap() error {
+	return e.Err
+}
+
+type sagaRun struct {
+	Id        bson.ObjectId `bson:"_id"`
+	Saga      string        `bson:"saga"`
+	Status    string        `bson:"status"`
+	Step      int           `bson:"step"`
+	Data      bson.M        `bson:"data"`
+	Error     string        `bson:"error,omitempty"`
+	UpdatedAt time.Time     `bson:"updatedat"`
+}
+
+// Create a saga. The name identifies its runs in SagaCollection.
+func NewSaga(name string, steps ...SagaStep) *Saga {
+	return std.NewSaga(name, steps...)
+}
+
+// Create a saga using the client. See NewSaga.
+func (c *Client) NewSaga(name string, steps ...SagaStep) *Saga {
+	sg := &Saga{client: c, name: name, steps: steps}
+	sg.persist = sg.save
+	return sg
+}
+
+// Run the steps in order with data. If a step fails the steps before it are
+// compensated in reverse order and a *SagaError is returned.
+func (sg *Saga) Run(data bson.M) error {
+	if data == nil {
+		data = bson.M{}
+	}
+
+	run := &sagaRun{Id: newObjectId(), Saga: sg.name, Status: SagaRunning, Data: data}
+	if err := sg.persist(run, true); err != nil {
+		return err
+	}
+
+	for run.Step < len(sg.steps) {
+		step := sg.steps[run.Step]
+		if err := step.Do(run.Data); err != nil {
+			run.Error = err.Error()
+			return &SagaError{Step: step.Name, Err: err, CompensateErr: sg.compensate(run, run.Step-1)}
+		}
+
+		run.Step++
+		if err := sg.persist(run, false); err != nil {
+			return err
+		}
+	}
+
+	run.Status = SagaDone
+	return sg.persist(run, false)
+}
+
+// Compensate the runs of the saga that have made no progress for
+// SagaTimeout, which were abandoned by a crash. The step that was in
+// progress is compensated too since it may have been done. Returns how
+// many runs were compensated.
+func (sg *Saga) Recover() (int, error) {
+	s, err := sg.client.GetSession()
+	if err != nil {
+		return 0, err
+	}
+	defer s.Close()
+
+	var runs []sagaRun
+	err = sg.client.GetColl(s, SagaCollection).Find(bson.M{
+		"saga":      sg.name,
+		"status":    bson.M{"$in": []string{SagaRunning, SagaCompensating}},
+		"updatedat": bson.M{"$lt": now().Add(-SagaTimeout)},
+	}).All(&runs)
+	if err != nil {
+		return 0, err
+	}
+
+	recovered := 0
+	for n := range runs {
+		run := &runs[n]
+		if run.Data == nil {
+			run.Data = bson.M{}
+		}
+
+		last := run.Step
+		if last >= len(sg.steps) {
+			last = len(sg.steps) - 1
+		}
+		if err := sg.compensate(run, last); err != nil {
+			return recovered, err
+		}
+		recovered++
+	}
+	return recovered, nil
+}
+
+// compensate undoes steps from last down to the first, saving progress
+// after each.
+func (sg *Saga) compensate(run *sagaRun, last int) error {
+	run.Status = SagaCompensating
+	run.Step = last + 1
+	if err := sg.persist(run, false); err != nil {
+		return err
+	}
+
+	for run.Step > 0 {
+		step := sg.steps[run.Step-1]
+		if step.Compensate != nil {
+			if err := step.Compensate(run.Data); err != nil {
+				run.Status = SagaFailed
+				run.Error = err.Error()
+				sg.persist(run, false)
+				return err
+			}
+		}
+
+		run.Step--
+		if err := sg.persist(run, false); err != nil {
+			return err
+		}
+	}
+
+	run.Status = SagaCompensated
+	return sg.persist(run, false)
+}
+
+func (sg *Saga) save(run *sagaRun, insert bool) error {
+	name := "update"
+	if insert {
+		name = "insert"
+	}
+	op := sg.client.startOp(name, SagaCollection, bson.M{"_id": run.Id})
+
+	if err := op.allowed(); err != nil {
+		return op.done(err)
+	}
+
+	s, err := sg.client.GetSession()
+	if err != nil {
+		return op.done(err)
+	}
+	defer s.Close()
+
+	run.UpdatedAt = now()
+
+	coll := sg.client.GetColl(s, SagaCollection)
+	if insert {
+		return op.done(coll.Insert(run))
+	}
+	return op.done(coll.UpdateId(run.Id, run))
+}
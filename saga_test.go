@@ -0,0 +1,75 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func testSaga(log *[]string, failAt string, steps ...string) *Saga {
+	var ss []SagaStep
+	for _, name := range steps {
+		name := name
+		ss = append(ss, SagaStep{
+			Name: name,
+			Do: func(data bson.M) error {
+				if name == failAt {
+					return errors.New("boom")
+				}
+				*log = append(*log, "do "+name)
+				data[name] = true
+				return nil
+			},
+			Compensate: func(data bson.M) error {
+				*log = append(*log, "undo "+name)
+				return nil
+			},
+		})
+	}
+
+	sg := NewSaga("test", ss...)
+	sg.persist = func(run *sagaRun, insert bool) error {
+		*log = append(*log, "save "+run.Status)
+		return nil
+	}
+	return sg
+}
+
+func TestSagaRun(t *testing.T) {
+	var log []string
+	data := bson.M{}
+
+	if err := testSaga(&log, "", "a", "b").Run(data); err != nil {
+		t.Fatal("Couldn't run the saga:", err)
+	}
+
+	expected := []string{"save running", "do a", "save running", "do b", "save running", "save done"}
+	if !reflect.DeepEqual(log, expected) {
+		t.Fatal("Expected", expected, "got:", log)
+	}
+	if data["a"] != true || data["b"] != true {
+		t.Fatal("Expected the steps to record their data got:", data)
+	}
+}
+
+func TestSagaCompensates(t *testing.T) {
+	var log []string
+
+	err := testSaga(&log, "c", "a", "b", "c").Run(nil)
+
+	var serr *SagaError
+	if !errors.As(err, &serr) || serr.Step != "c" || serr.CompensateErr != nil {
+		t.Fatal("Expected a SagaError for step c got:", err)
+	}
+
+	expected := []string{
+		"save running", "do a", "save running", "do b", "save running",
+		"save compensating", "undo b", "save compensating", "undo a", "save compensating",
+		"save compensated",
+	}
+	if !reflect.DeepEqual(log, expected) {
+		t.Fatal("Expected", expected, "got:", log)
+	}
+}
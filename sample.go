@@ -0,0 +1,141 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"log"
+	"math"
+	"math/rand"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// DiagnosticsCollection holds the operations sampled by SetSampleRate.
+var DiagnosticsCollection = "diagnostics"
+
+// SampledOp is an operation recorded in DiagnosticsCollection. Query is
+// sanitized like in an *OpError. Plan summarizes how the server ran the
+// query, e.g. "FETCH > IXSCAN email_1" or "COLLSCAN", and is empty for
+// operations without a query.
+type SampledOp struct {
+	Id           bson.ObjectId `bson:"_id"`
+	Op           string        `bson:"op"`
+	Collection   string        `bson:"collection"`
+	Query        string        `bson:"query,omitempty"`
+	Plan         string        `bson:"plan,omitempty"`
+	KeysExamined int           `bson:"keysexamined,omitempty"`
+	DocsExamined int           `bson:"docsexamined,omitempty"`
+	DurationMs   float64       `bson:"durationms"`
+	Err          string        `bson:"err,omitempty"`
+	At           time.Time     `bson:"at"`
+}
+
+// sampleRate holds the bits of the float64 rate so it can be changed while
+// operations run.
+var sampleRate uint64
+
+// sampleSlots bounds the samples being written at once. Samples beyond it
+// are dropped rather than queued.
+var sampleSlots = make(chan struct{}, 4)
+
+// Record a fraction of operations, between 0 and 1, in
+// DiagnosticsCollection for later analysis, e.g. 0.01 for one in a hundred.
+// Sampled operations with a query are explained to summarize their plan,
+// which runs the query again, so keep the rate low. Zero, the default, turns
+// sampling off. Safe to call at any time.
+func SetSampleRate(rate float64) {
+	atomic.StoreUint64(&sampleRate, math.Float64bits(math.Max(0, math.Min(1, rate))))
+}
+
+// sample records o in the background if it's picked.
+func (o *operation) sample(d time.Duration, err error) {
+	rate := math.Float64frombits(atomic.LoadUint64(&sampleRate))
+	if rate == 0 || o.coll == DiagnosticsCollection || rand.Float64() >= rate {
+		return
+	}
+
+	select {
+	case sampleSlots <- struct{}{}:
+	default:
+		return
+	}
+
+	rec := SampledOp{
+		Id:         newObjectId(),
+		Op:         o.op,
+		Collection: o.coll,
+		Query:      SanitizeQuery(o.query),
+		DurationMs: float64(d) / float64(time.Millisecond),
+		At:         o.start,
+	}
+	if err != nil {
+		rec.Err = err.Error()
+	}
+
+	c := o.client
+	if c == nil {
+		c = std
+	}
+
+	go func() {
+		defer func() { <-sampleSlots }()
+
+		if err := c.writeSample(rec, o.query); err != nil {
+			log.Println("mongo: sampling:", err)
+		}
+	}()
+}
+
+// writeSample explains q if there is one and stores rec in the client's
+// database, where the operation ran. It talks to the database directly,
+// since sampling its own operations would never end.
+func (c *Client) writeSample(rec SampledOp, q bson.M) error {
+	s, err := c.GetSession()
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	if len(q) > 0 {
+		plain := bson.M{}
+		for k, v := range q {
+			if k != unsafeKey {
+				plain[k] = v
+			}
+		}
+
+		explain := bson.M{}
+		if err := c.GetColl(s, rec.Collection).Find(plain).Explain(&explain); err == nil {
+			rec.Plan, rec.KeysExamined, rec.DocsExamined = planSummary(explain)
+		}
+	}
+
+	return c.GetColl(s, DiagnosticsCollection).Insert(rec)
+}
+
+// planSummary returns the stages of the winning plan of an explain result,
+// outermost first, and how many index keys and documents were examined.
+// Servers before 3.0 only report a cursor, which is returned as is.
+func planSummary(explain bson.M) (plan string, keys, docs int) {
+	if qp, ok := explain["queryPlanner"].(bson.M); ok {
+		var stages []string
+		for stage, _ := qp["winningPlan"].(bson.M); stage != nil; stage, _ = stage["inputStage"].(bson.M) {
+			name, _ := stage["stage"].(string)
+			if index, ok := stage["indexName"].(string); ok {
+				name += " " + index
+			}
+			stages = append(stages, name)
+		}
+		plan = strings.Join(stages, " > ")
+
+		if stats, ok := explain["executionStats"].(bson.M); ok {
+			keys = int(toFloat(stats["totalKeysExamined"]))
+			docs = int(toFloat(stats["totalDocsExamined"]))
+		}
+		return plan, keys, docs
+	}
+
+	plan, _ = explain["cursor"].(string)
+	return plan, int(toFloat(explain["nscanned"])), int(toFloat(explain["nscannedObjects"]))
+}
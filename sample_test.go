@@ -0,0 +1,54 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"testing"
+)
+
+func TestPlanSummary(t *testing.T) {
+	explain := bson.M{
+		"queryPlanner": bson.M{
+			"winningPlan": bson.M{
+				"stage":      "FETCH",
+				"inputStage": bson.M{"stage": "IXSCAN", "indexName": "email_1"},
+			},
+		},
+		"executionStats": bson.M{"totalKeysExamined": 1, "totalDocsExamined": 1},
+	}
+
+	plan, keys, docs := planSummary(explain)
+	if plan != "FETCH > IXSCAN email_1" || keys != 1 || docs != 1 {
+		t.Fatal("Expected an index scan got:", plan, keys, docs)
+	}
+
+	plan, keys, docs = planSummary(bson.M{"cursor": "BasicCursor", "nscanned": 250, "nscannedObjects": 250})
+	if plan != "BasicCursor" || keys != 250 || docs != 250 {
+		t.Fatal("Expected the legacy cursor got:", plan, keys, docs)
+	}
+}
+
+func TestSampleRateOff(t *testing.T) {
+	SetSampleRate(0)
+
+	// With sampling off nothing is written, so no database is needed.
+	op := startOp("find", "samples", bson.M{"a": 1})
+	op.done(nil)
+
+	if n := len(sampleSlots); n != 0 {
+		t.Fatal("Expected nothing sampled got:", n)
+	}
+}
+
+func TestSampleClient(t *testing.T) {
+	c := (&Client{database: "main"}).UseDatabase("reporting")
+
+	// Samples are explained and stored through the client that ran the
+	// operation.
+	if op := c.startOp("find", "samples", nil); op.client != c {
+		t.Fatal("Expected the operation to record its client got:", op.client)
+	}
+	if op := startOp("find", "samples", nil); op.client != nil {
+		t.Fatal("Expected no client for the package's own operations got:", op.client)
+	}
+}
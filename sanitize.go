@@ -0,0 +1,130 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Redaction controls how SanitizeQuery renders the values in a query. Keys and
+// operators are always kept.
+type Redaction int
+
+const (
+	// RedactHash replaces each value with a short HMAC so equal values can
+	// still be correlated across log lines, but can't be recovered by
+	// hashing guesses without the key. This is the default. See
+	// SetRedactionKey.
+	RedactHash Redaction = iota
+	// RedactAll replaces each value with a question mark.
+	RedactAll
+	// RedactNone leaves values as they are. Only use this in development.
+	RedactNone
+)
+
+var redaction int32 = int32(RedactHash)
+
+// Set how values are redacted when queries are rendered for logs and errors.
+func SetRedaction(r Redaction) {
+	atomic.StoreInt32(&redaction, int32(r))
+}
+
+var (
+	redactionKeyMu sync.RWMutex
+	redactionKey   = randomKey()
+)
+
+// Set the key RedactHash uses. By default it's random for each process, so
+// values only correlate within one process's logs; share a secret key
+// between processes to correlate across them.
+func SetRedactionKey(key []byte) {
+	redactionKeyMu.Lock()
+	defer redactionKeyMu.Unlock()
+
+	redactionKey = append([]byte{}, key...)
+}
+
+func randomKey() []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return key
+}
+
+// SanitizeQuery renders a query for logging. Keys and operators are kept and
+// values are redacted according to SetRedaction so PII never lands in logs.
+// Keys are sorted to make the output stable.
+func SanitizeQuery(q bson.M) string {
+	if len(q) == 0 {
+		return ""
+	}
+	return sanitizeValue(q, Redaction(atomic.LoadInt32(&redaction)))
+}
+
+func sanitizeValue(v interface{}, r Redaction) string {
+	switch val := v.(type) {
+	case bson.M:
+		return sanitizeMap(val, r)
+	case map[string]interface{}:
+		return sanitizeMap(val, r)
+	case bson.D:
+		parts := make([]string, len(val))
+		for n, e := range val {
+			parts[n] = e.Name + ": " + sanitizeValue(e.Value, r)
+		}
+		return "{" + strings.Join(parts, ", ") + "}"
+	case []byte:
+		return redactValue(val, r)
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+		parts := make([]string, rv.Len())
+		for n := range parts {
+			parts[n] = sanitizeValue(rv.Index(n).Interface(), r)
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	}
+
+	return redactValue(v, r)
+}
+
+func sanitizeMap(m map[string]interface{}, r Redaction) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, len(keys))
+	for n, k := range keys {
+		parts[n] = k + ": " + sanitizeValue(m[k], r)
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+func redactValue(v interface{}, r Redaction) string {
+	switch r {
+	case RedactNone:
+		return fmt.Sprintf("%#v", v)
+	case RedactAll:
+		return "?"
+	}
+
+	redactionKeyMu.RLock()
+	mac := hmac.New(sha256.New, redactionKey)
+	redactionKeyMu.RUnlock()
+
+	mac.Write([]byte(fmt.Sprintf("%T:%v", v, v)))
+	return "#" + hex.EncodeToString(mac.Sum(nil)[:8])
+}
@@ -0,0 +1,116 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeQueryKeepsKeys(t *testing.T) {
+	q := bson.M{
+		"email": "george@example.com",
+		"age":   bson.M{"$gt": "thirty"},
+		"$or":   []interface{}{bson.M{"name": "George"}, bson.M{"name": "Judy"}},
+	}
+
+	s := SanitizeQuery(q)
+	for _, want := range []string{"email", "age", "$gt", "$or", "name"} {
+		if !strings.Contains(s, want) {
+			t.Fatalf("Expected %q in sanitized query: %s", want, s)
+		}
+	}
+
+	for _, leak := range []string{"george@example.com", "George", "thirty"} {
+		if strings.Contains(s, leak) {
+			t.Fatalf("Value %q leaked into sanitized query: %s", leak, s)
+		}
+	}
+}
+
+func TestSanitizeQueryStable(t *testing.T) {
+	q := bson.M{"a": 1, "b": "two", "c": []string{"x", "y"}}
+	if SanitizeQuery(q) != SanitizeQuery(q) {
+		t.Fatal("Sanitized query isn't stable")
+	}
+
+	if sanitizeValue("same", RedactHash) != sanitizeValue("same", RedactHash) {
+		t.Fatal("Equal values should hash the same")
+	}
+}
+
+func TestRedactionKey(t *testing.T) {
+	defer SetRedactionKey(randomKey())
+
+	SetRedactionKey([]byte("one"))
+	a := sanitizeValue("george@example.com", RedactHash)
+	SetRedactionKey([]byte("two"))
+	b := sanitizeValue("george@example.com", RedactHash)
+
+	if a == b {
+		t.Fatal("Expected different keys to give different hashes got:", a)
+	}
+
+	SetRedactionKey([]byte("one"))
+	if c := sanitizeValue("george@example.com", RedactHash); c != a {
+		t.Fatal("Expected the same key to give the same hash got:", a, c)
+	}
+}
+
+func TestSanitizeQueryRedactAll(t *testing.T) {
+	s := sanitizeValue(bson.M{"name": "George", "tags": []string{"a"}}, RedactAll)
+	if s != "{name: ?, tags: [?]}" {
+		t.Fatal("Unexpected output:", s)
+	}
+}
+
+// FuzzSanitizeQuery checks that RedactAll renders a query exactly like the
+// same query with every value blanked, so no value can leak, and that hashed
+// output is stable.
+func FuzzSanitizeQuery(f *testing.F) {
+	for _, seed := range []string{
+		`{"email": "george@example.com"}`,
+		`{"$or": [{"name": "George"}, {"age": {"$gt": 30}}]}`,
+		`{"tags": {"$in": ["a", ["b", null]]}, "n": true}`,
+		`{"": {"": {}}}`,
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, data string) {
+		var q map[string]interface{}
+		if err := json.Unmarshal([]byte(data), &q); err != nil {
+			return
+		}
+
+		got, want := sanitizeValue(bson.M(q), RedactAll), sanitizeValue(blankValues(q), RedactAll)
+		if got != want {
+			t.Fatalf("Expected %s got: %s", want, got)
+		}
+
+		if sanitizeValue(bson.M(q), RedactHash) != sanitizeValue(bson.M(q), RedactHash) {
+			t.Fatal("Sanitized query isn't stable:", data)
+		}
+	})
+}
+
+// blankValues returns a copy of v with every value other than documents and
+// arrays replaced.
+func blankValues(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		m := bson.M{}
+		for k, e := range val {
+			m[k] = blankValues(e)
+		}
+		return m
+	case []interface{}:
+		a := make([]interface{}, len(val))
+		for n, e := range val {
+			a[n] = blankValues(e)
+		}
+		return a
+	}
+	return "blank"
+}
@@ -0,0 +1,208 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+
+	"sync"
+	"time"
+)
+
+// SettingsCollection holds the documents behind Settings.
+var SettingsCollection = "settings"
+
+// Settings maps a single document in SettingsCollection to a struct and
+// caches it, for application wide configuration:
+//
+//	type Config struct {
+//		SignupsOpen bool   `bson:"signupsopen"`
+//		Banner      string `bson:"banner"`
+//	}
+//
+//	settings := mongo.NewSettings("app", &Config{SignupsOpen: true})
+//	settings.OnChange(func() { log.Println("settings changed") })
+//	go settings.Run(time.Minute, stop, logErr)
+//
+//	var cfg Config
+//	err := settings.Get(&cfg)
+type Settings struct {
+	// MaxAge makes Get reload the document once the cached copy is older.
+	// Zero keeps it until Reload, Set or Run refresh it.
+	MaxAge time.Duration
+
+	client   *Client
+	id       string
+	defaults interface{}
+
+	mu       sync.Mutex
+	data     []byte
+	hash     string
+	loadedAt time.Time
+	onChange []func()
+}
+
+// Create settings stored in the document with id. Until the document
+// exists Get returns defaults, a pointer to a struct.
+func NewSettings(id string, defaults interface{}) *Settings {
+	return std.NewSettings(id, defaults)
+}
+
+// Create settings using the client. See NewSettings.
+func (c *Client) NewSettings(id string, defaults interface{}) *Settings {
+	return &Settings{client: c, id: id, defaults: defaults}
+}
+
+// Decode the settings into dst, a pointer to a struct, loading them first if
+// they aren't cached. Each call decodes a fresh copy, so dst can be changed
+// without affecting other callers.
+func (st *Settings) Get(dst interface{}) error {
+	if !isPtr(dst) {
+		return NoPtr
+	}
+
+	st.mu.Lock()
+	stale := st.data == nil || (st.MaxAge > 0 && now().Sub(st.loadedAt) > st.MaxAge)
+	st.mu.Unlock()
+
+	if stale {
+		if err := st.Reload(); err != nil {
+			return err
+		}
+	}
+
+	st.mu.Lock()
+	data := st.data
+	st.mu.Unlock()
+
+	return bson.Unmarshal(data, dst)
+}
+
+// Store v, a struct or a pointer to one, as the settings.
+func (st *Settings) Set(v interface{}) error {
+	op := st.client.startOp("update", SettingsCollection, bson.M{"_id": st.id})
+
+	if err := op.allowed(); err != nil {
+		return op.done(err)
+	}
+
+	doc, err := settingsDoc(st.id, v)
+	if err != nil {
+		return op.done(err)
+	}
+
+	s, err := st.client.GetSession()
+	if err != nil {
+		return op.done(err)
+	}
+	defer s.Close()
+
+	if _, err := st.client.GetColl(s, SettingsCollection).UpsertId(st.id, doc); err != nil {
+		return op.done(err)
+	}
+
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return op.done(err)
+	}
+	return op.done(st.update(data))
+}
+
+// Load the settings from the database, notifying the OnChange functions if
+// they changed.
+func (st *Settings) Reload() error {
+	op := st.client.startOp("find", SettingsCollection, bson.M{"_id": st.id})
+
+	if err := op.allowed(); err != nil {
+		return op.done(err)
+	}
+
+	s, err := st.client.GetSession()
+	if err != nil {
+		return op.done(err)
+	}
+	defer s.Close()
+
+	var raw bson.Raw
+	err = st.client.GetColl(s, SettingsCollection).FindId(st.id).One(&raw)
+	switch {
+	case err == mgo.ErrNotFound:
+		doc, err := settingsDoc(st.id, st.defaults)
+		if err != nil {
+			return op.done(err)
+		}
+		if raw.Data, err = bson.Marshal(doc); err != nil {
+			return op.done(err)
+		}
+	case err != nil:
+		return op.done(err)
+	}
+
+	return op.done(st.update(raw.Data))
+}
+
+// Call fn whenever the settings change. It's called after the new settings
+// are cached, so it can call Get.
+func (st *Settings) OnChange(fn func()) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.onChange = append(st.onChange, fn)
+}
+
+// Reload the settings every interval until stop is closed. Errors are passed
+// to onError, which may be nil, and the cached settings are kept.
+func (st *Settings) Run(interval time.Duration, stop <-chan struct{}, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := st.Reload(); err != nil && onError != nil {
+			onError(err)
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// update caches data and notifies the OnChange functions if it differs from
+// what was cached. The first load isn't a change.
+func (st *Settings) update(data []byte) error {
+	h, err := hashDoc(data)
+	if err != nil {
+		return err
+	}
+
+	st.mu.Lock()
+	changed := st.data != nil && h != st.hash
+	st.data, st.hash, st.loadedAt = data, h, now()
+	fns := append([]func(){}, st.onChange...)
+	st.mu.Unlock()
+
+	if changed {
+		for _, fn := range fns {
+			fn()
+		}
+	}
+	return nil
+}
+
+// settingsDoc returns v as a document with the settings' id.
+func settingsDoc(id string, v interface{}) (bson.M, error) {
+	doc := bson.M{}
+	if v != nil {
+		data, err := bson.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		if err := bson.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+	}
+
+	doc["_id"] = id
+	return doc, nil
+}
@@ -0,0 +1,39 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"errors"
+	"testing"
+)
+
+func TestSettingsOnChange(t *testing.T) {
+	st := NewSettings("app", nil)
+
+	changes := 0
+	st.OnChange(func() { changes++ })
+
+	for _, n := range []int{1, 1, 2, 2} {
+		data, err := bson.Marshal(bson.M{"_id": "app", "n": n})
+		if err != nil {
+			t.Fatal("Couldn't marshal the settings:", err)
+		}
+		if err := st.update(data); err != nil {
+			t.Fatal("Couldn't update the settings:", err)
+		}
+	}
+
+	if changes != 1 {
+		t.Fatal("Expected one change got:", changes)
+	}
+}
+
+func TestSettingsAccessPolicy(t *testing.T) {
+	SetAccessPolicy(SettingsCollection, AllowInsert)
+	defer ClearAccessPolicy(SettingsCollection)
+
+	var cfg struct{}
+	if err := NewSettings("app", &cfg).Get(&cfg); !errors.Is(err, ErrAccessDenied) {
+		t.Fatal("Expected ErrAccessDenied got:", err)
+	}
+}
@@ -0,0 +1,133 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxQueryShapes bounds the number of distinct shapes QueryShapes tracks.
+// Shapes first seen after the limit is reached aren't recorded.
+var MaxQueryShapes = 1000
+
+// QueryShape is a normalized query, keeping keys and operators but not
+// values, with how often it was issued. Shape looks like
+// {age: {$gt: ?}, tags: {$in: [?]}}; an empty shape is an operation
+// without a query, such as an insert.
+type QueryShape struct {
+	Op         string
+	Collection string
+	Shape      string
+	Count      int64
+	FirstSeen  time.Time
+	LastSeen   time.Time
+}
+
+var (
+	shapesMu sync.Mutex
+	shapes   = map[string]*QueryShape{}
+)
+
+// Returns every query shape issued through the package since it started or
+// since ResetQueryShapes, most frequent first.
+func QueryShapes() []QueryShape {
+	shapesMu.Lock()
+	defer shapesMu.Unlock()
+
+	list := make([]QueryShape, 0, len(shapes))
+	for _, s := range shapes {
+		list = append(list, *s)
+	}
+
+	sort.Slice(list, func(a, b int) bool {
+		if list[a].Count != list[b].Count {
+			return list[a].Count > list[b].Count
+		}
+		return list[a].Op+list[a].Collection+list[a].Shape < list[b].Op+list[b].Collection+list[b].Shape
+	})
+	return list
+}
+
+// Forget every recorded query shape.
+func ResetQueryShapes() {
+	shapesMu.Lock()
+	shapes = map[string]*QueryShape{}
+	shapesMu.Unlock()
+}
+
+func recordShape(op, coll string, q bson.M) {
+	shape := ""
+	if len(q) > 0 {
+		shape = queryShape(q)
+	}
+	key := op + "\x00" + coll + "\x00" + shape
+	ts := time.Now()
+
+	shapesMu.Lock()
+	defer shapesMu.Unlock()
+
+	s := shapes[key]
+	if s == nil {
+		if len(shapes) >= MaxQueryShapes {
+			return
+		}
+		s = &QueryShape{Op: op, Collection: coll, Shape: shape, FirstSeen: ts}
+		shapes[key] = s
+	}
+	s.Count++
+	s.LastSeen = ts
+}
+
+// queryShape renders v with every value replaced by a question mark. Arrays
+// of values collapse to [?] so queries differing only in the number of
+// values share a shape.
+func queryShape(v interface{}) string {
+	switch val := v.(type) {
+	case bson.M:
+		return shapeMap(val)
+	case map[string]interface{}:
+		return shapeMap(val)
+	case bson.D:
+		parts := make([]string, len(val))
+		for n, e := range val {
+			parts[n] = e.Name + ": " + queryShape(e.Value)
+		}
+		return "{" + strings.Join(parts, ", ") + "}"
+	case []byte:
+		return "?"
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+		var docs []string
+		for n := 0; n < rv.Len(); n++ {
+			if s := queryShape(rv.Index(n).Interface()); s != "?" {
+				docs = append(docs, s)
+			}
+		}
+		if len(docs) == 0 {
+			return "[?]"
+		}
+		return "[" + strings.Join(docs, ", ") + "]"
+	}
+
+	return "?"
+}
+
+func shapeMap(m map[string]interface{}) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, len(keys))
+	for n, k := range keys {
+		parts[n] = k + ": " + queryShape(m[k])
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
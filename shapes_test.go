@@ -0,0 +1,41 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"errors"
+	"testing"
+)
+
+func TestQueryShape(t *testing.T) {
+	cases := map[string]bson.M{
+		"{age: {$gt: ?}, name: ?}":           {"name": "George", "age": bson.M{"$gt": 30}},
+		"{tags: {$in: [?]}}":                 {"tags": bson.M{"$in": []string{"a", "b", "c"}}},
+		"{$or: [{a: ?}, {b: {$exists: ?}}]}": {"$or": []bson.M{{"a": 1}, {"b": bson.M{"$exists": true}}}},
+		"{address: {city: ?}, raw: ?}":       {"address": bson.D{{Name: "city", Value: "x"}}, "raw": []byte("x")},
+	}
+
+	for expected, q := range cases {
+		if s := queryShape(q); s != expected {
+			t.Errorf("Expected %v, got %v", expected, s)
+		}
+	}
+}
+
+func TestQueryShapes(t *testing.T) {
+	ResetQueryShapes()
+	defer ResetQueryShapes()
+
+	startOp("find", "people", bson.M{"name": "George"}).done(nil)
+	startOp("find", "people", bson.M{"name": "Jane"}).done(errors.New("boom"))
+	startOp("find", "people", bson.M{"age": 3}).done(nil)
+
+	shapes := QueryShapes()
+	if len(shapes) != 2 {
+		t.Fatal("Expected two shapes:", shapes)
+	}
+
+	if shapes[0].Shape != "{name: ?}" || shapes[0].Count != 2 || shapes[0].Collection != "people" {
+		t.Fatalf("Unexpected most frequent shape: %+v", shapes[0])
+	}
+}
@@ -0,0 +1,147 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"sort"
+)
+
+// DefaultSizeSample is the number of documents AnalyzeSizes samples when
+// sample is less than one.
+const DefaultSizeSample = 1000
+
+// SizeReport describes the sizes of a sample of documents, in bytes.
+type SizeReport struct {
+	Sampled int
+	AvgSize float64
+	MaxSize int
+	// Percentiles maps each of StatsPercentiles to the document size.
+	Percentiles map[float64]float64
+	// Fields lists every top level field seen, largest total first.
+	Fields []FieldSize
+}
+
+// FieldSize is what a top level field contributes to document size.
+type FieldSize struct {
+	Field string
+	// Present is the number of sampled documents with the field.
+	Present int
+	// AvgSize is the average size of the field where it's present,
+	// including its key.
+	AvgSize float64
+	MaxSize int
+	// Share is the fraction of all sampled bytes taken by the field.
+	Share float64
+}
+
+// Sample documents matching q and report their sizes and the fields that
+// take up the most space, to guide slimming a schema down. sample is the
+// number of documents sampled, DefaultSizeSample if less than one.
+func AnalyzeSizes(i interface{}, q bson.M, sample int) (*SizeReport, error) {
+	return std.AnalyzeSizes(i, q, sample)
+}
+
+// Analyze record sizes using the client. See AnalyzeSizes.
+func (c *Client) AnalyzeSizes(i interface{}, q bson.M, sample int) (*SizeReport, error) {
+	if sample < 1 {
+		sample = DefaultSizeSample
+	}
+
+	q = scopeQuery(i, q)
+	op := c.startOp("aggregate", collName(i), q)
+
+	if err := op.allowed(); err != nil {
+		return nil, op.done(err)
+	}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return nil, op.done(err)
+	}
+	defer s.Close()
+
+	if q, err = op.check(c, s, q, true); err != nil {
+		return nil, op.done(err)
+	}
+
+	p := Pipeline{}
+	if len(q) > 0 {
+		p = p.Match(q)
+	}
+	p = p.Stage("$sample", bson.M{"size": sample})
+
+	var raws []bson.Raw
+	if err := c.GetColl(s, op.coll).Pipe(p).AllowDiskUse().All(&raws); err != nil {
+		return nil, op.done(err)
+	}
+
+	docs := make([][]byte, len(raws))
+	for n, raw := range raws {
+		docs[n] = raw.Data
+	}
+
+	report, err := sizeReport(docs)
+	return report, op.done(err)
+}
+
+// sizeReport analyzes marshaled documents.
+func sizeReport(docs [][]byte) (*SizeReport, error) {
+	report := &SizeReport{Sampled: len(docs), Percentiles: map[float64]float64{}}
+	if len(docs) == 0 {
+		return report, nil
+	}
+
+	fields := map[string]*FieldSize{}
+	sizes := make([]float64, len(docs))
+	total := 0
+
+	for n, data := range docs {
+		sizes[n] = float64(len(data))
+		total += len(data)
+		if len(data) > report.MaxSize {
+			report.MaxSize = len(data)
+		}
+
+		var doc bson.RawD
+		if err := bson.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+
+		for _, e := range doc {
+			// type byte, key, key terminator and value
+			size := 1 + len(e.Name) + 1 + len(e.Value.Data)
+
+			f := fields[e.Name]
+			if f == nil {
+				f = &FieldSize{Field: e.Name}
+				fields[e.Name] = f
+			}
+			f.Present++
+			f.AvgSize += float64(size)
+			if size > f.MaxSize {
+				f.MaxSize = size
+			}
+		}
+	}
+
+	report.AvgSize = float64(total) / float64(len(docs))
+
+	sort.Float64s(sizes)
+	for _, pct := range StatsPercentiles {
+		report.Percentiles[pct] = percentile(sizes, pct)
+	}
+
+	for _, f := range fields {
+		f.Share = f.AvgSize / float64(total)
+		f.AvgSize /= float64(f.Present)
+		report.Fields = append(report.Fields, *f)
+	}
+	sort.Slice(report.Fields, func(a, b int) bool {
+		if report.Fields[a].Share != report.Fields[b].Share {
+			return report.Fields[a].Share > report.Fields[b].Share
+		}
+		return report.Fields[a].Field < report.Fields[b].Field
+	})
+
+	return report, nil
+}
@@ -0,0 +1,44 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"strings"
+	"testing"
+)
+
+func TestSizeReport(t *testing.T) {
+	var docs [][]byte
+	for _, bio := range []string{strings.Repeat("x", 1000), strings.Repeat("x", 10)} {
+		data, err := bson.Marshal(bson.D{{Name: "name", Value: "George"}, {Name: "bio", Value: bio}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		docs = append(docs, data)
+	}
+
+	report, err := sizeReport(docs)
+	if err != nil {
+		t.Fatal("Couldn't analyze sizes:", err)
+	}
+
+	if report.Sampled != 2 || report.MaxSize != len(docs[0]) {
+		t.Fatalf("Unexpected report: %+v", report)
+	}
+
+	if len(report.Fields) != 2 || report.Fields[0].Field != "bio" || report.Fields[0].Present != 2 {
+		t.Fatalf("Expected bio to be the largest field: %+v", report.Fields)
+	}
+
+	// "bio" element: type byte, key, terminator, int32 length, 1000 bytes, terminator
+	if report.Fields[0].MaxSize != 1+3+1+4+1000+1 {
+		t.Fatal("Unexpected field size:", report.Fields[0].MaxSize)
+	}
+}
+
+func TestSizeReportEmpty(t *testing.T) {
+	report, err := sizeReport(nil)
+	if err != nil || report.Sampled != 0 || len(report.Fields) != 0 {
+		t.Fatal("Expected an empty report:", report, err)
+	}
+}
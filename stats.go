@@ -0,0 +1,138 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+
+	"errors"
+	"math"
+	"sort"
+)
+
+var (
+	// StatsPercentiles are the percentiles Stats estimates.
+	StatsPercentiles = []float64{50, 90, 95, 99}
+	// StatsSampleSize is the number of values Stats samples to estimate
+	// percentiles.
+	StatsSampleSize = 1000
+)
+
+// FieldStats summarizes the numeric values of a field.
+type FieldStats struct {
+	Count  int
+	Min    float64
+	Max    float64
+	Avg    float64
+	StdDev float64
+	// Percentiles maps each of StatsPercentiles to its approximate value.
+	Percentiles map[float64]float64
+}
+
+// Calculate min, max, average and population standard deviation of a numeric
+// field across the documents matching q. Percentiles are estimated from a
+// random sample of StatsSampleSize values. Field is the document key.
+func Stats(i interface{}, field string, q bson.M) (*FieldStats, error) {
+	return std.Stats(i, field, q)
+}
+
+// Summarize a field using the client. See Stats.
+func (c *Client) Stats(i interface{}, field string, q bson.M) (*FieldStats, error) {
+	match := bson.M{field: bson.M{"$exists": true}}
+	if len(q) > 0 {
+		match = bson.M{"$and": []bson.M{q, match}}
+	}
+
+	p := Pipeline{}.Match(match).Group(bson.M{
+		"_id":    nil,
+		"count":  bson.M{"$sum": 1},
+		"min":    bson.M{"$min": "$" + field},
+		"max":    bson.M{"$max": "$" + field},
+		"avg":    bson.M{"$avg": "$" + field},
+		"stddev": bson.M{"$stdDevPop": "$" + field},
+	})
+
+	var res struct {
+		Count  int
+		Min    interface{}
+		Max    interface{}
+		Avg    interface{}
+		StdDev interface{}
+	}
+	if err := c.Aggregate(i, p, &res); err != nil {
+		if errors.Is(err, mgo.ErrNotFound) {
+			return &FieldStats{Percentiles: map[float64]float64{}}, nil
+		}
+		return nil, err
+	}
+
+	stats := &FieldStats{
+		Count:       res.Count,
+		Min:         toFloat(res.Min),
+		Max:         toFloat(res.Max),
+		Avg:         toFloat(res.Avg),
+		StdDev:      toFloat(res.StdDev),
+		Percentiles: map[float64]float64{},
+	}
+
+	p = Pipeline{}.Match(match).
+		Stage("$sample", bson.M{"size": StatsSampleSize}).
+		Project(bson.M{"_id": 0, "v": "$" + field})
+
+	var sample []struct {
+		V interface{}
+	}
+	if err := c.Aggregate(i, p, &sample); err != nil {
+		return nil, err
+	}
+
+	values := make([]float64, 0, len(sample))
+	for _, s := range sample {
+		if f, ok := numeric(s.V); ok {
+			values = append(values, f)
+		}
+	}
+	sort.Float64s(values)
+
+	for _, pct := range StatsPercentiles {
+		stats.Percentiles[pct] = percentile(values, pct)
+	}
+
+	return stats, nil
+}
+
+// percentile returns the nearest-rank percentile of sorted values.
+func percentile(sorted []float64, pct float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := int(math.Ceil(pct / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
+
+func numeric(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+func toFloat(v interface{}) float64 {
+	f, _ := numeric(v)
+	return f
+}
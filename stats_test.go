@@ -0,0 +1,17 @@
+package mongo
+
+import "testing"
+
+func TestPercentile(t *testing.T) {
+	values := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+
+	for pct, want := range map[float64]float64{0: 1, 50: 5, 90: 9, 99: 10, 100: 10} {
+		if got := percentile(values, pct); got != want {
+			t.Fatalf("p%v: expected %v, got %v", pct, want, got)
+		}
+	}
+
+	if percentile(nil, 50) != 0 {
+		t.Fatal("Expected 0 for an empty sample")
+	}
+}
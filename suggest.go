@@ -0,0 +1,167 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+
+	"sort"
+	"strings"
+)
+
+// rangeOps are the operators SuggestIndex treats as ranges. Other operators,
+// such as $eq and $in, are treated as equality.
+var rangeOps = map[string]bool{
+	"$gt": true, "$gte": true, "$lt": true, "$lte": true,
+	"$ne": true, "$nin": true, "$regex": true, "$exists": true,
+	"$not": true, "$elemMatch": true, "$type": true, "$mod": true,
+}
+
+// Suggest a compound index key for a query and sort, in mgo's format, e.g.
+// []string{"status", "-createdat", "age"}. Fields follow the ESR rule:
+// fields matched by equality, then the sort fields, then fields matched by
+// a range. $and clauses are followed; $or, $nor and $expr can't be served
+// by a single index and are left out. Use ExistingIndex to check whether the
+// collection already has a suitable index.
+func SuggestIndex(i interface{}, q bson.M, sortFields ...string) []string {
+	equality, ranges := map[string]bool{}, map[string]bool{}
+	classify(scopeQuery(i, q), equality, ranges)
+
+	key := sortedKeys(equality)
+
+	sorted := map[string]bool{}
+	for _, f := range sortFields {
+		name := strings.TrimLeft(f, "+-")
+		if equality[name] || sorted[name] || name == "" {
+			continue
+		}
+		sorted[name] = true
+		key = append(key, strings.TrimPrefix(f, "+"))
+	}
+
+	for _, f := range sortedKeys(ranges) {
+		if !equality[f] && !sorted[f] {
+			key = append(key, f)
+		}
+	}
+
+	return key
+}
+
+// Returns an index of the collection for i that can serve key as well as an
+// index made from it would: key must be a prefix of the index's key. Sort
+// fields may have all their directions reversed. Returns nil if there's
+// none.
+func ExistingIndex(i interface{}, key []string) (*mgo.Index, error) {
+	return std.ExistingIndex(i, key)
+}
+
+// Find an index for a query using the client. See ExistingIndex.
+func (c *Client) ExistingIndex(i interface{}, key []string) (*mgo.Index, error) {
+	s, err := c.GetSession()
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	indexes, err := c.GetColl(s, collName(i)).Indexes()
+	if err != nil {
+		if isNamespaceMissing(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	for n := range indexes {
+		if indexCovers(indexes[n].Key, key) {
+			return &indexes[n], nil
+		}
+	}
+	return nil, nil
+}
+
+// indexCovers returns true if key is a prefix of index, allowing the
+// directions to be reversed as a whole.
+func indexCovers(index, key []string) bool {
+	if len(key) > len(index) {
+		return false
+	}
+
+	same, flipped := true, true
+	for n, k := range key {
+		if strings.TrimLeft(k, "+-") != strings.TrimLeft(index[n], "+-") {
+			return false
+		}
+
+		desc := strings.HasPrefix(k, "-")
+		if desc != strings.HasPrefix(index[n], "-") {
+			same = false
+		} else {
+			flipped = false
+		}
+	}
+
+	return same || flipped
+}
+
+// classify sorts the fields of q into those matched by equality and those
+// matched by a range.
+func classify(q bson.M, equality, ranges map[string]bool) {
+	for k, v := range q {
+		if k == "$and" {
+			if clauses, ok := v.([]bson.M); ok {
+				for _, c := range clauses {
+					classify(c, equality, ranges)
+				}
+			} else if clauses, ok := v.([]interface{}); ok {
+				for _, c := range clauses {
+					if m, ok := c.(bson.M); ok {
+						classify(m, equality, ranges)
+					}
+				}
+			}
+			continue
+		}
+
+		if strings.HasPrefix(k, "$") {
+			continue
+		}
+
+		if isRange(v) {
+			ranges[k] = true
+		} else {
+			equality[k] = true
+		}
+	}
+}
+
+func isRange(v interface{}) bool {
+	var keys []string
+	switch m := v.(type) {
+	case bson.M:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]interface{}:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case bson.RegEx:
+		return true
+	}
+
+	for _, k := range keys {
+		if rangeOps[k] {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,43 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"reflect"
+	"testing"
+)
+
+func TestSuggestIndex(t *testing.T) {
+	q := bson.M{
+		"status": "active",
+		"age":    bson.M{"$gte": 21},
+		"$and":   []bson.M{{"country": bson.M{"$in": []string{"GB", "US"}}}},
+		"$or":    []bson.M{{"a": 1}, {"b": 2}},
+	}
+
+	key := SuggestIndex(&MongoTest{}, q, "-createdat", "status")
+	expected := []string{"country", "status", "-createdat", "age"}
+
+	if !reflect.DeepEqual(key, expected) {
+		t.Fatalf("Expected %v, got %v", expected, key)
+	}
+}
+
+func TestIndexCovers(t *testing.T) {
+	cases := []struct {
+		index, key []string
+		covers     bool
+	}{
+		{[]string{"status", "-createdat", "age"}, []string{"status", "-createdat"}, true},
+		{[]string{"status", "createdat"}, []string{"status", "-createdat"}, false},
+		{[]string{"-status", "createdat"}, []string{"status", "-createdat"}, true},
+		{[]string{"status"}, []string{"status", "age"}, false},
+		{[]string{"age", "status"}, []string{"status"}, false},
+	}
+
+	for _, c := range cases {
+		if indexCovers(c.index, c.key) != c.covers {
+			t.Errorf("indexCovers(%v, %v) should be %v", c.index, c.key, c.covers)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxSuppressed caps the number of recent writes remembered for write
+// suppression.
+const maxSuppressed = 10000
+
+type recentWrite struct {
+	hash string
+	at   time.Time
+}
+
+var (
+	suppressMu     sync.Mutex
+	suppressWindow time.Duration
+	recentWrites   = map[string]recentWrite{}
+)
+
+// Skip an Update that would write exactly what the previous Update of the
+// same record wrote less than window ago, ignoring UpdatedAt, and return nil
+// as if it had been written. It cuts the load from clients that repeatedly
+// PUT unchanged resources. Only updates made by this process are
+// remembered, so a record changed some other way in between can have an
+// identical update skipped. Zero, the default, turns it off.
+func SetWriteSuppression(window time.Duration) {
+	suppressMu.Lock()
+	defer suppressMu.Unlock()
+
+	suppressWindow = window
+	recentWrites = map[string]recentWrite{}
+}
+
+// writeKey identifies a record for write suppression.
+func writeKey(db, coll string, id interface{}) string {
+	return fmt.Sprintf("%v.%v/%v", db, coll, id)
+}
+
+// writeHash returns the hash of doc, the document an update of i writes,
+// without its UpdatedAt. It's empty if write suppression is off.
+func writeHash(i interface{}, doc interface{}) (string, error) {
+	suppressMu.Lock()
+	on := suppressWindow > 0
+	suppressMu.Unlock()
+
+	if !on {
+		return "", nil
+	}
+
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return "", err
+	}
+
+	m := bson.M{}
+	if err := bson.Unmarshal(data, &m); err != nil {
+		return "", err
+	}
+	delete(m, fieldKey(i, "UpdatedAt"))
+
+	if data, err = bson.Marshal(m); err != nil {
+		return "", err
+	}
+	return hashDoc(data)
+}
+
+// duplicateWrite returns whether the last write of the record with key had
+// hash and was within the window.
+func duplicateWrite(key, hash string) bool {
+	suppressMu.Lock()
+	defer suppressMu.Unlock()
+
+	w, ok := recentWrites[key]
+	return ok && w.hash == hash && now().Sub(w.at) < suppressWindow
+}
+
+// rememberWrite records a write of the record with key for write
+// suppression. An empty hash forgets the record.
+func rememberWrite(key, hash string) {
+	suppressMu.Lock()
+	defer suppressMu.Unlock()
+
+	if hash == "" {
+		delete(recentWrites, key)
+		return
+	}
+
+	t := now()
+	if len(recentWrites) >= maxSuppressed {
+		for k, w := range recentWrites {
+			if t.Sub(w.at) >= suppressWindow {
+				delete(recentWrites, k)
+			}
+		}
+	}
+	if len(recentWrites) < maxSuppressed {
+		recentWrites[key] = recentWrite{hash: hash, at: t}
+	}
+}
+
+// forgetWrites forgets every record whose key starts with prefix, for
+// writes that can't tell which records they changed.
+func forgetWrites(prefix string) {
+	suppressMu.Lock()
+	defer suppressMu.Unlock()
+
+	for k := range recentWrites {
+		if strings.HasPrefix(k, prefix) {
+			delete(recentWrites, k)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package mongo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWriteSuppression(t *testing.T) {
+	at := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	SetClock(func() time.Time { return at })
+	defer SetClock(nil)
+
+	SetWriteSuppression(time.Minute)
+	defer SetWriteSuppression(0)
+
+	key := writeKey("db", "MongoTest", "1")
+	if duplicateWrite(key, "a") {
+		t.Fatal("Expected the first write not to be a duplicate")
+	}
+
+	rememberWrite(key, "a")
+	if !duplicateWrite(key, "a") {
+		t.Fatal("Expected an identical write to be a duplicate")
+	}
+	if duplicateWrite(key, "b") {
+		t.Fatal("Expected a different write not to be a duplicate")
+	}
+	if duplicateWrite(writeKey("other", "MongoTest", "1"), "a") {
+		t.Fatal("Expected a write to another database not to be a duplicate")
+	}
+
+	at = at.Add(time.Minute)
+	if duplicateWrite(key, "a") {
+		t.Fatal("Expected an identical write after the window not to be a duplicate")
+	}
+
+	rememberWrite(key, "a")
+	rememberWrite(key, "")
+	if duplicateWrite(key, "a") {
+		t.Fatal("Expected a forgotten record not to be a duplicate")
+	}
+}
+
+func TestWriteSuppressionOff(t *testing.T) {
+	if hash, err := writeHash(&MongoTest{}, &MongoTest{}); hash != "" || err != nil {
+		t.Fatal("Expected no hash with suppression off got:", hash, err)
+	}
+}
+
+func TestForgetWrites(t *testing.T) {
+	SetWriteSuppression(time.Minute)
+	defer SetWriteSuppression(0)
+
+	rememberWrite(writeKey("db", "items", 1), "a")
+	rememberWrite(writeKey("db", "itemsold", 1), "a")
+
+	forgetWrites(writeKey("db", "items", ""))
+
+	if duplicateWrite(writeKey("db", "items", 1), "a") {
+		t.Fatal("Expected the collection's writes to be forgotten")
+	}
+	if !duplicateWrite(writeKey("db", "itemsold", 1), "a") {
+		t.Fatal("Expected other collections' writes to be kept")
+	}
+}
@@ -0,0 +1,146 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// syncOverlap is subtracted from the time passed to SyncSince.
+var syncOverlap = int64(time.Minute)
+
+// Set how far before the time passed to SyncSince it looks for updated
+// documents, one minute by default. SyncSince compares UpdatedAt, set from
+// the clock of whichever process wrote the document, with the time it
+// returned, taken from the clock of the process syncing. The overlap must
+// cover the clock skew between them and any write still in flight when the
+// previous pass started; documents in it are copied again, which is
+// harmless.
+func SetSyncOverlap(d time.Duration) {
+	atomic.StoreInt64(&syncOverlap, int64(d))
+}
+
+// Copy every document in the collections for models from source to target,
+// replacing any existing copies by _id. It returns the time the copy started,
+// to pass to SyncSince for the next incremental pass.
+func Sync(source, target *Client, models ...interface{}) (time.Time, error) {
+	start := now()
+
+	for _, name := range uniqueCollections(models) {
+		if err := syncCollection(source, target, name, nil); err != nil {
+			return start, err
+		}
+	}
+
+	return start, nil
+}
+
+// Copy the documents in the collections for models whose UpdatedAt is after
+// since from source to target. Call it repeatedly with the returned time to
+// keep target in sync, e.g. during a blue/green migration. Models must have
+// an UpdatedAt field. Deletes aren't detected by UpdatedAt so they aren't
+// synced. Clocks are assumed to agree to within the overlap set by
+// SetSyncOverlap.
+func SyncSince(source, target *Client, since time.Time, models ...interface{}) (time.Time, error) {
+	start := now()
+
+	for _, m := range models {
+		if !hasStructField(m, "UpdatedAt") {
+			return since, fmt.Errorf("Can't sync %v incrementally without an UpdatedAt field", typeName(m))
+		}
+	}
+
+	done := map[string]bool{}
+	for _, m := range models {
+		name := collName(m)
+		if done[name] {
+			continue
+		}
+		done[name] = true
+
+		q := syncQuery(m, since)
+		if err := syncCollection(source, target, name, q); err != nil {
+			return since, err
+		}
+	}
+
+	return start, nil
+}
+
+// syncQuery matches the documents of m's collection updated after since,
+// less the overlap.
+func syncQuery(m interface{}, since time.Time) bson.M {
+	from := since.Add(-time.Duration(atomic.LoadInt64(&syncOverlap)))
+	return bson.M{fieldKey(m, "UpdatedAt"): bson.M{"$gt": from}}
+}
+
+func syncCollection(source, target *Client, name string, q bson.M) error {
+	op := source.startOp("sync", name, q)
+
+	if err := op.allowed(); err != nil {
+		return op.done(err)
+	}
+
+	// The copies are upserted, which may insert or update on the target.
+	for _, write := range []string{"insert", "update"} {
+		if err := target.startOp(write, name, nil).allowed(); err != nil {
+			return op.done(err)
+		}
+	}
+
+	src, err := source.GetSession()
+	if err != nil {
+		return op.done(err)
+	}
+	defer src.Close()
+
+	dst, err := target.GetSession()
+	if err != nil {
+		return op.done(err)
+	}
+	defer dst.Close()
+
+	iter := source.GetColl(src, name).Find(q).Sort("_id").Iter()
+	coll := target.GetColl(dst, name)
+	defer target.afterCollectionWrite(dst, name, nil)
+
+	pending := 0
+	bulk := coll.Bulk()
+
+	var raw bson.Raw
+	for iter.Next(&raw) {
+		var doc struct {
+			Id interface{} `bson:"_id"`
+		}
+		if err := raw.Unmarshal(&doc); err != nil {
+			iter.Close()
+			return op.done(err)
+		}
+
+		bulk.Upsert(bson.M{"_id": doc.Id}, bson.Raw{Kind: raw.Kind, Data: raw.Data})
+		if pending++; pending == backupBatch {
+			if err := runBulk(bulk); err != nil {
+				iter.Close()
+				return op.done(err)
+			}
+			bulk, pending = coll.Bulk(), 0
+		}
+	}
+
+	if err := iter.Close(); err != nil {
+		return op.done(err)
+	}
+
+	if pending > 0 {
+		return op.done(runBulk(bulk))
+	}
+	return op.done(nil)
+}
+
+func runBulk(b *mgo.Bulk) error {
+	_, err := b.Run()
+	return err
+}
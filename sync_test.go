@@ -0,0 +1,48 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSyncQueryOverlap(t *testing.T) {
+	defer SetSyncOverlap(time.Minute)
+
+	since := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	q := syncQuery(&MongoTest{}, since)
+	if got := q["updatedat"].(bson.M)["$gt"]; got != since.Add(-time.Minute) {
+		t.Fatal("Expected the default overlap of a minute got:", got)
+	}
+
+	SetSyncOverlap(0)
+	q = syncQuery(&MongoTest{}, since)
+	if got := q["updatedat"].(bson.M)["$gt"]; got != since {
+		t.Fatal("Expected no overlap got:", got)
+	}
+}
+
+func TestSyncSinceNoUpdatedAt(t *testing.T) {
+	since := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	got, err := SyncSince(std, std, since, &Category{})
+	if err == nil {
+		t.Fatal("Expected an error for a model without UpdatedAt")
+	}
+	if !got.Equal(since) {
+		t.Fatal("Expected since to be returned on error got:", got)
+	}
+}
+
+func TestSyncTargetAccessDenied(t *testing.T) {
+	SetAccessPolicy("MongoTest", AllowFind|AllowInsert)
+	defer ClearAccessPolicy("MongoTest")
+
+	source, target := &Client{database: "old"}, &Client{database: "new"}
+	if _, err := Sync(source, target, &MongoTest{}); !errors.Is(err, ErrAccessDenied) {
+		t.Fatal("Expected ErrAccessDenied got:", err)
+	}
+}
@@ -0,0 +1,236 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"time"
+)
+
+// TrashCollection holds records moved there by MoveToTrash.
+const TrashCollection = "trash"
+
+// TrashRetention is how long a trashed record is kept before the purger
+// deletes it for good.
+var TrashRetention = 30 * 24 * time.Hour
+
+// TrashEntry is a record in TrashCollection.
+type TrashEntry struct {
+	Id         bson.ObjectId `bson:"_id"`
+	Collection string        `bson:"collection"`
+	DocId      interface{}   `bson:"docid"`
+	Doc        bson.Raw      `bson:"doc"`
+	DeletedAt  time.Time     `bson:"deletedat"`
+	PurgeAfter time.Time     `bson:"purgeafter"`
+}
+
+// Delete a record by moving it to TrashCollection, from which it can be
+// restored with RestoreFromTrash until it's purged after TrashRetention. Uses
+// the Id to identify the record. Must pass in a pointer to a struct. Like
+// Delete, it leaves a Tombstone if deletes of the model are tracked.
+func MoveToTrash(i interface{}) error {
+	return std.MoveToTrash(i)
+}
+
+// Move a record to the trash using the client. See MoveToTrash.
+func (c *Client) MoveToTrash(i interface{}) error {
+	if !isPtr(i) {
+		return NoPtr
+	}
+
+	op := c.startOp("delete", collName(i), nil)
+
+	if err := op.allowed(); err != nil {
+		return op.done(err)
+	}
+
+	id, err := getObjIdFromStruct(i)
+	if err != nil {
+		return op.done(err)
+	}
+	op.query = bson.M{"_id": id}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return op.done(err)
+	}
+	defer s.Close()
+
+	coll := c.GetColl(s, op.coll)
+
+	var raw bson.Raw
+	if err := coll.FindId(id).One(&raw); err != nil {
+		return op.done(err)
+	}
+
+	ts := now()
+	entry := TrashEntry{
+		Id:         newObjectId(),
+		Collection: op.coll,
+		DocId:      id,
+		Doc:        raw,
+		DeletedAt:  ts,
+		PurgeAfter: ts.Add(TrashRetention),
+	}
+
+	trash := c.GetColl(s, TrashCollection)
+	if err := trash.Insert(entry); err != nil {
+		return op.done(err)
+	}
+
+	if err := coll.RemoveId(id); err != nil {
+		trash.RemoveId(entry.Id)
+		return op.done(err)
+	}
+
+	var before bson.M
+	if counted(i) {
+		if err := raw.Unmarshal(&before); err != nil {
+			return op.done(err)
+		}
+	}
+	return op.done(c.afterWrite(s, i, written{id: id, before: before}))
+}
+
+// Restore the most recently trashed record of i's type with the given id and
+// decode it into i, which must be a pointer to a struct. Fails if a record
+// with the id exists again. The restored record gets a new UpdatedAt and its
+// tombstones are removed, so ChangesSince reports it as changed rather than
+// deleted.
+func RestoreFromTrash(i interface{}, id string) error {
+	return std.RestoreFromTrash(i, id)
+}
+
+// Restore a record from the trash using the client. See RestoreFromTrash.
+func (c *Client) RestoreFromTrash(i interface{}, id string) error {
+	if !isPtr(i) {
+		return NoPtr
+	}
+
+	oid := bson.ObjectIdHex(id)
+	op := c.startOp("insert", collName(i), bson.M{"_id": oid})
+
+	if err := op.allowed(); err != nil {
+		return op.done(err)
+	}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return op.done(err)
+	}
+	defer s.Close()
+
+	trash := c.GetColl(s, TrashCollection)
+
+	var entry TrashEntry
+	err = trash.Find(bson.M{"collection": op.coll, "docid": oid}).Sort("-deletedat").One(&entry)
+	if err != nil {
+		return op.done(err)
+	}
+
+	doc := entry.Doc
+	if hasStructField(i, "UpdatedAt") {
+		if doc, err = restamp(doc, fieldKey(i, "UpdatedAt")); err != nil {
+			return op.done(err)
+		}
+	}
+
+	if err := c.GetColl(s, op.coll).Insert(doc); err != nil {
+		return op.done(err)
+	}
+
+	if err := trash.RemoveId(entry.Id); err != nil {
+		return op.done(err)
+	}
+
+	q := bson.M{"collection": op.coll, "docid": oid}
+	if _, err := c.GetColl(s, TombstoneCollection).RemoveAll(q); err != nil {
+		return op.done(err)
+	}
+
+	if err := c.afterWrite(s, i, written{id: oid, after: doc, created: true}); err != nil {
+		return op.done(err)
+	}
+
+	return op.done(unmarshalRecord(c, s, doc, i))
+}
+
+// restamp returns a copy of the document raw with key set to the current
+// time, keeping the order of its fields.
+func restamp(raw bson.Raw, key string) (bson.Raw, error) {
+	var doc bson.D
+	if err := raw.Unmarshal(&doc); err != nil {
+		return raw, err
+	}
+
+	ts, found := now(), false
+	for n := range doc {
+		if doc[n].Name == key {
+			doc[n].Value, found = ts, true
+		}
+	}
+	if !found {
+		doc = append(doc, bson.DocElem{Name: key, Value: ts})
+	}
+
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return raw, err
+	}
+	return bson.Raw{Kind: 3, Data: data}, nil
+}
+
+// Delete every trashed record past its purge time. Returns how many were
+// deleted.
+func PurgeTrash() (int, error) {
+	return std.PurgeTrash()
+}
+
+// Purge the trash using the client. See PurgeTrash.
+func (c *Client) PurgeTrash() (int, error) {
+	q := bson.M{"purgeafter": bson.M{"$lte": now()}}
+	op := c.startOp("delete", TrashCollection, q)
+
+	if err := op.allowed(); err != nil {
+		return 0, op.done(err)
+	}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return 0, op.done(err)
+	}
+	defer s.Close()
+
+	info, err := c.GetColl(s, TrashCollection).RemoveAll(q)
+	if err != nil {
+		return 0, op.done(err)
+	}
+	return info.Removed, op.done(nil)
+}
+
+// Run PurgeTrash every interval until stop is closed. Errors are passed to
+// onError, which may be nil, and don't stop the loop. Run it in its own
+// goroutine:
+//
+//	stop := make(chan struct{})
+//	go mongo.RunTrashPurger(time.Hour, stop, func(err error) { log.Println(err) })
+func RunTrashPurger(interval time.Duration, stop <-chan struct{}, onError func(error)) {
+	std.RunTrashPurger(interval, stop, onError)
+}
+
+// Purge the trash periodically using the client. See RunTrashPurger.
+func (c *Client) RunTrashPurger(interval time.Duration, stop <-chan struct{}, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := c.PurgeTrash(); err != nil && onError != nil {
+			onError(err)
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package mongo
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTrashNoPtr(t *testing.T) {
+	if err := MoveToTrash(MongoTest{}); err != NoPtr {
+		t.Fatal("Expected NoPtr, got:", err)
+	}
+	if err := RestoreFromTrash(MongoTest{}, "5e8f8f8f8f8f8f8f8f8f8f8f"); err != NoPtr {
+		t.Fatal("Expected NoPtr, got:", err)
+	}
+}
+
+func TestMoveToTrashHonorsPolicy(t *testing.T) {
+	SetAccessPolicy("MongoTest", AllowFind|AllowInsert)
+	defer ClearAccessPolicy("MongoTest")
+
+	if err := MoveToTrash(&MongoTest{}); !errors.Is(err, ErrAccessDenied) {
+		t.Fatal("Expected ErrAccessDenied, got:", err)
+	}
+}
@@ -0,0 +1,314 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// ErrCycle is returned by MoveSubtree when a node would be moved underneath
+// itself.
+var ErrCycle = errors.New("Can't move a node underneath itself")
+
+// Tree helpers work on models with a ParentId field (bson.ObjectId or Id)
+// and a Path string field. Path holds the ids of every ancestor, root first,
+// separated and surrounded by commas, e.g. ",<root>,<parent>,". It's
+// maintained by Insert and MoveSubtree so it should be treated as read only.
+
+// isTreeNode returns true if i has the fields needed for a materialized path.
+func isTreeNode(i interface{}) bool {
+	return hasStructField(i, "ParentId") && hasStructField(i, "Path")
+}
+
+// setPath computes the Path of a new node from its parent.
+func (c *Client) setPath(i interface{}) error {
+	if !isTreeNode(i) {
+		return nil
+	}
+
+	parent, ok := objectIdField(i, "ParentId")
+	if !ok {
+		return setStringField(i, "Path", ",")
+	}
+
+	path, err := c.nodePath(i, parent)
+	if err != nil {
+		return err
+	}
+
+	return setStringField(i, "Path", path+parent.Hex()+",")
+}
+
+// Find every ancestor of the node i, root first. Result must be a pointer to
+// a slice of the node's type.
+func Ancestors(i interface{}, result interface{}) error {
+	return std.Ancestors(i, result)
+}
+
+// Find the ancestors of a node using the client. See Ancestors.
+func (c *Client) Ancestors(i interface{}, result interface{}) error {
+	if !isPtr(i) || !isPtr(result) {
+		return NoPtr
+	}
+
+	path := reflect.ValueOf(i).Elem().FieldByName("Path").String()
+
+	var ids []bson.ObjectId
+	for _, hex := range strings.Split(strings.Trim(path, ","), ",") {
+		if bson.IsObjectIdHex(hex) {
+			ids = append(ids, bson.ObjectIdHex(hex))
+		}
+	}
+
+	// Roots have no ancestors, so there's nothing to find.
+	if len(ids) == 0 {
+		if sv := reflect.ValueOf(result).Elem(); sv.Kind() == reflect.Slice {
+			sv.Set(sv.Slice(0, 0))
+		}
+		return nil
+	}
+
+	if err := c.Find(result, bson.M{"_id": bson.M{"$in": ids}}); err != nil {
+		return err
+	}
+
+	// $in doesn't preserve order so put the ancestors back in path order.
+	sv := reflect.ValueOf(result).Elem()
+	byId := map[bson.ObjectId]reflect.Value{}
+	for n := 0; n < sv.Len(); n++ {
+		el := sv.Index(n)
+		if el.Kind() != reflect.Ptr {
+			el = el.Addr()
+		}
+
+		id, err := getObjIdFromStruct(el.Interface())
+		if err != nil {
+			return err
+		}
+		byId[id] = reflect.ValueOf(sv.Index(n).Interface())
+	}
+
+	sorted := reflect.MakeSlice(sv.Type(), 0, sv.Len())
+	for _, id := range ids {
+		if el, ok := byId[id]; ok {
+			sorted = reflect.Append(sorted, el)
+		}
+	}
+	sv.Set(sorted)
+
+	return nil
+}
+
+// Find every descendant of the node i. Result must be a pointer to a slice
+// of the node's type.
+func Descendants(i interface{}, result interface{}, sortFields ...string) error {
+	return std.Descendants(i, result, sortFields...)
+}
+
+// Find the descendants of a node using the client. See Descendants.
+func (c *Client) Descendants(i interface{}, result interface{}, sortFields ...string) error {
+	if !isPtr(i) || !isPtr(result) {
+		return NoPtr
+	}
+
+	prefix, err := subtreePrefix(i)
+	if err != nil {
+		return err
+	}
+
+	q := bson.M{fieldKey(i, "Path"): bson.RegEx{Pattern: "^" + regexp.QuoteMeta(prefix)}}
+	return c.Find(result, q, sortFields...)
+}
+
+// Move the node i and everything underneath it so it becomes a child of
+// newParent. Pass a nil newParent to make i a root.
+func MoveSubtree(i interface{}, newParent interface{}) error {
+	return std.MoveSubtree(i, newParent)
+}
+
+// Move a subtree using the client. See MoveSubtree.
+func (c *Client) MoveSubtree(i interface{}, newParent interface{}) error {
+	if !isPtr(i) {
+		return NoPtr
+	}
+
+	id, err := getObjIdFromStruct(i)
+	if err != nil {
+		return err
+	}
+
+	oldPrefix, err := subtreePrefix(i)
+	if err != nil {
+		return err
+	}
+
+	newPath := ","
+	var parentId bson.ObjectId
+	if newParent != nil {
+		if parentId, err = getObjIdFromStruct(newParent); err != nil {
+			return err
+		}
+
+		parentPath, err := c.nodePath(i, parentId)
+		if err != nil {
+			return err
+		}
+
+		if parentId == id || strings.Contains(parentPath, ","+id.Hex()+",") {
+			return ErrCycle
+		}
+		newPath = parentPath + parentId.Hex() + ","
+	}
+	newPrefix := newPath + id.Hex() + ","
+
+	pathKey := fieldKey(i, "Path")
+	parentKey := fieldKey(i, "ParentId")
+
+	op := c.startOp("update", collName(i), bson.M{pathKey: oldPrefix})
+
+	if err := op.allowed(); err != nil {
+		return op.done(err)
+	}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return op.done(err)
+	}
+	defer s.Close()
+
+	coll := c.GetColl(s, op.coll)
+
+	// Id fields are stored as ObjectIds, plain strings as hex.
+	var parentVal interface{}
+	if parentId != "" {
+		parentVal = parentId
+		if reflect.ValueOf(i).Elem().FieldByName("ParentId").Type() == reflect.TypeOf("") {
+			parentVal = parentId.Hex()
+		}
+	}
+
+	err = coll.UpdateId(id, bson.M{"$set": bson.M{pathKey: newPath, parentKey: parentVal}})
+	if err != nil {
+		return op.done(err)
+	}
+
+	iter := coll.Find(bson.M{pathKey: bson.RegEx{Pattern: "^" + regexp.QuoteMeta(oldPrefix)}}).
+		Select(bson.M{pathKey: 1}).Iter()
+
+	var node bson.M
+	for iter.Next(&node) {
+		path, _ := node[pathKey].(string)
+		path = newPrefix + strings.TrimPrefix(path, oldPrefix)
+
+		if err := coll.UpdateId(node["_id"], bson.M{"$set": bson.M{pathKey: path}}); err != nil {
+			iter.Close()
+			return op.done(err)
+		}
+	}
+	if err := iter.Close(); err != nil {
+		return op.done(err)
+	}
+	if err := op.done(c.afterCollectionWrite(s, op.coll, nil)); err != nil {
+		return err
+	}
+
+	if err := setStringField(i, "Path", newPath); err != nil {
+		return err
+	}
+	return setObjectIdField(i, "ParentId", parentId)
+}
+
+// subtreePrefix returns the path shared by every descendant of i.
+func subtreePrefix(i interface{}) (string, error) {
+	id, err := getObjIdFromStruct(i)
+	if err != nil {
+		return "", err
+	}
+
+	path := reflect.ValueOf(i).Elem().FieldByName("Path").String()
+	return path + id.Hex() + ",", nil
+}
+
+// nodePath loads the stored Path of the node with the given id from the
+// collection for i.
+func (c *Client) nodePath(i interface{}, id bson.ObjectId) (string, error) {
+	key := fieldKey(i, "Path")
+	op := c.startOp("find", collName(i), bson.M{"_id": id})
+
+	if err := op.allowed(); err != nil {
+		return "", op.done(err)
+	}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return "", op.done(err)
+	}
+	defer s.Close()
+
+	doc := bson.M{}
+	if err := c.GetColl(s, op.coll).FindId(id).Select(bson.M{key: 1}).One(&doc); err != nil {
+		return "", op.done(err)
+	}
+
+	path, _ := doc[key].(string)
+	if path == "" {
+		path = ","
+	}
+	return path, op.done(nil)
+}
+
+// objectIdField returns the value of a bson.ObjectId or Id field. The second
+// return value is false if the field is empty.
+func objectIdField(i interface{}, name string) (bson.ObjectId, bool) {
+	f := reflect.ValueOf(i).Elem().FieldByName(name)
+	if f.Kind() == reflect.Ptr {
+		if f.IsNil() {
+			return "", false
+		}
+		f = f.Elem()
+	}
+
+	if f.Kind() != reflect.String {
+		return "", false
+	}
+
+	if id, ok := f.Interface().(bson.ObjectId); ok {
+		return id, id.Valid()
+	}
+
+	if bson.IsObjectIdHex(f.String()) {
+		return bson.ObjectIdHex(f.String()), true
+	}
+	return "", false
+}
+
+// setObjectIdField sets a bson.ObjectId or Id field. Id fields get the hex
+// representation.
+func setObjectIdField(i interface{}, name string, id bson.ObjectId) error {
+	f := reflect.ValueOf(i).Elem().FieldByName(name)
+	if f.Kind() != reflect.String || !f.CanSet() {
+		return errors.New("Couldn't set field: " + name)
+	}
+
+	if _, ok := f.Interface().(bson.ObjectId); ok {
+		f.Set(reflect.ValueOf(id))
+	} else if id == "" {
+		f.SetString("")
+	} else {
+		f.SetString(id.Hex())
+	}
+	return nil
+}
+
+func setStringField(i interface{}, name, value string) error {
+	f := reflect.ValueOf(i).Elem().FieldByName(name)
+	if f.Kind() != reflect.String || !f.CanSet() {
+		return errors.New(name + " must be a string type.")
+	}
+
+	f.SetString(value)
+	return nil
+}
@@ -0,0 +1,63 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"testing"
+)
+
+type Category struct {
+	Id       bson.ObjectId `bson:"_id"`
+	Name     string
+	ParentId Id
+	Path     string
+}
+
+func TestRootPath(t *testing.T) {
+	c := &Category{Id: bson.NewObjectId(), Name: "root"}
+	if err := std.setPath(c); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Path != "," {
+		t.Fatal("Root nodes should have an empty path. Got:", c.Path)
+	}
+
+	prefix, err := subtreePrefix(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if prefix != ","+c.Id.Hex()+"," {
+		t.Fatal("Unexpected subtree prefix:", prefix)
+	}
+}
+
+func TestObjectIdField(t *testing.T) {
+	parent := bson.NewObjectId()
+	c := &Category{ParentId: Id(parent.Hex())}
+
+	id, ok := objectIdField(c, "ParentId")
+	if !ok || id != parent {
+		t.Fatal("Couldn't read the parent id:", id)
+	}
+
+	if err := setObjectIdField(c, "ParentId", ""); err != nil || c.ParentId != "" {
+		t.Fatal("Couldn't clear the parent id:", err)
+	}
+}
+
+func TestRootAncestors(t *testing.T) {
+	c := &Category{Id: bson.NewObjectId(), Path: ","}
+	ancestors := []Category{{Name: "stale"}}
+
+	// No client is set up, so this fails if it queries.
+	client := &Client{database: "app"}
+	if err := client.Ancestors(c, &ancestors); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ancestors) != 0 {
+		t.Fatal("Expected no ancestors got:", ancestors)
+	}
+}
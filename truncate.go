@@ -0,0 +1,101 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNotConfirmed is returned by Truncate when the confirmation isn't
+	// the name of the collection.
+	ErrNotConfirmed = errors.New("Truncate must be confirmed with the collection name")
+
+	// ErrSharedCollection is returned when dropping a collection that
+	// other types are registered in.
+	ErrSharedCollection = errors.New("Collection is shared with other types")
+)
+
+// TruncateOptions configures TruncateWith.
+type TruncateOptions struct {
+	// Drop drops the collection and recreates its indexes rather than
+	// removing each record, which is much faster for large collections.
+	// Collection options such as validators aren't recreated.
+	Drop bool
+}
+
+// Delete every record of i's type, e.g. to reset data between tests. As a
+// guardrail confirm must be the name of the collection:
+//
+//	err := mongo.Truncate(&Session{}, "Session")
+//
+// Counters kept by CountsCache and RefCount are marked dirty rather than
+// adjusted, and no tombstones are written.
+func Truncate(i interface{}, confirm string) error {
+	return std.Truncate(i, confirm)
+}
+
+// Delete every record of a type using the client. See Truncate.
+func (c *Client) Truncate(i interface{}, confirm string) error {
+	return c.TruncateWith(i, confirm, TruncateOptions{})
+}
+
+// Delete every record of i's type like Truncate, with options.
+func TruncateWith(i interface{}, confirm string, opts TruncateOptions) error {
+	return std.TruncateWith(i, confirm, opts)
+}
+
+// Delete every record of a type with options using the client. See
+// TruncateWith.
+func (c *Client) TruncateWith(i interface{}, confirm string, opts TruncateOptions) error {
+	q := scopeQuery(i, nil)
+	op := c.startOp("delete", collName(i), q)
+
+	if confirm != op.coll {
+		return op.done(fmt.Errorf("%w: %q", ErrNotConfirmed, op.coll))
+	}
+	if opts.Drop && q != nil {
+		return op.done(fmt.Errorf("%w: %v", ErrSharedCollection, op.coll))
+	}
+
+	if err := op.allowed(); err != nil {
+		return op.done(err)
+	}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return op.done(err)
+	}
+	defer s.Close()
+
+	coll := c.GetColl(s, op.coll)
+	defer c.afterCollectionWrite(s, op.coll, nil)
+
+	if !opts.Drop {
+		if q == nil {
+			q = bson.M{}
+		}
+		_, err := coll.RemoveAll(q)
+		return op.done(err)
+	}
+
+	indexes, err := coll.Indexes()
+	if err != nil && !isNamespaceMissing(err) {
+		return op.done(err)
+	}
+
+	if err := coll.DropCollection(); err != nil && !isNamespaceMissing(err) {
+		return op.done(err)
+	}
+
+	for _, idx := range indexes {
+		if idx.Name == "_id_" {
+			continue
+		}
+		if err := coll.EnsureIndex(idx); err != nil {
+			return op.done(err)
+		}
+	}
+	return op.done(nil)
+}
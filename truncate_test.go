@@ -0,0 +1,23 @@
+package mongo
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTruncateNotConfirmed(t *testing.T) {
+	for _, confirm := range []string{"", "validatedmodel", "other"} {
+		if err := Truncate(&validatedModel{}, confirm); !errors.Is(err, ErrNotConfirmed) {
+			t.Fatal("Expected ErrNotConfirmed got:", err)
+		}
+	}
+}
+
+func TestTruncateAccessPolicy(t *testing.T) {
+	SetAccessPolicy("validatedModel", AllowFind)
+	defer ClearAccessPolicy("validatedModel")
+
+	if err := Truncate(&validatedModel{}, "validatedModel"); !errors.Is(err, ErrAccessDenied) {
+		t.Fatal("Expected ErrAccessDenied got:", err)
+	}
+}
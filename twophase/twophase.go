@@ -0,0 +1,241 @@
+/*
+The twophase package applies updates to documents in several collections
+so that either all of them happen or none do, using the classic two-phase
+commit pattern for deployments without multi-document transactions:
+
+	t, err := twophase.Begin(
+		twophase.Change{Collection: "Account", Id: from, Apply: bson.M{"$inc": bson.M{"balance": -100}}, Revert: bson.M{"$inc": bson.M{"balance": 100}}},
+		twophase.Change{Collection: "Account", Id: to, Apply: bson.M{"$inc": bson.M{"balance": 100}}, Revert: bson.M{"$inc": bson.M{"balance": -100}}},
+	)
+	if err == nil {
+		err = twophase.Commit(t)
+	}
+
+A transaction document in Collection moves from initial through pending
+and applied to done. Each changed document lists the pending transactions
+it has been changed by under PendingField so a change is applied once
+even if it's retried. A transaction that fails while pending is rolled
+back with the Revert updates. Run Recover periodically to finish or roll
+back transactions abandoned by a crash.
+
+Other writers see the documents in between states, so this gives
+atomicity but not isolation.
+*/
+package twophase
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/sfreiberg/mongo"
+
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// Collection holds the transaction documents.
+	Collection = "transactions"
+	// PendingField is the key, in each changed document, of the list of
+	// pending transactions that changed it.
+	PendingField = "pendingtransactions"
+)
+
+// States of a transaction.
+const (
+	StateInitial   = "initial"
+	StatePending   = "pending"
+	StateApplied   = "applied"
+	StateDone      = "done"
+	StateCanceling = "canceling"
+	StateCanceled  = "canceled"
+)
+
+// ErrStateChanged is returned when a transaction isn't in the state a step
+// expects, because another process is working on it.
+var ErrStateChanged = errors.New("Transaction state changed")
+
+// Change is an update to one document. Apply and Revert are update
+// documents using operators, e.g. bson.M{"$inc": bson.M{"balance": -100}};
+// Revert undoes Apply.
+type Change struct {
+	Collection string      `bson:"collection"`
+	Id         interface{} `bson:"id"`
+	Apply      bson.M      `bson:"apply"`
+	Revert     bson.M      `bson:"revert"`
+}
+
+// Transaction is a transaction document.
+type Transaction struct {
+	Id           bson.ObjectId `bson:"_id"`
+	State        string        `bson:"state"`
+	Changes      []Change      `bson:"changes"`
+	LastModified time.Time     `bson:"lastmodified"`
+}
+
+// Begin a transaction by storing the changes it will make.
+func Begin(changes ...Change) (*Transaction, error) {
+	if len(changes) == 0 {
+		return nil, errors.New("A transaction needs at least one change")
+	}
+
+	s, err := mongo.GetSession()
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	t := &Transaction{Id: bson.NewObjectId(), State: StateInitial, Changes: changes, LastModified: time.Now()}
+	if err := mongo.GetColl(s, Collection).Insert(t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Commit a transaction returned by Begin. If applying a change fails the
+// changes already applied are reverted and the error is returned.
+func Commit(t *Transaction) error {
+	s, err := mongo.GetSession()
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	if err := transition(s, t, StateInitial, StatePending); err != nil {
+		return err
+	}
+	return commitPending(s, t)
+}
+
+// Finish or roll back transactions that have been pending, applied or
+// canceling for longer than timeout. Returns how many were recovered.
+func Recover(timeout time.Duration) (int, error) {
+	s, err := mongo.GetSession()
+	if err != nil {
+		return 0, err
+	}
+	defer s.Close()
+
+	var ts []Transaction
+	err = mongo.GetColl(s, Collection).Find(bson.M{
+		"state":        bson.M{"$in": []string{StatePending, StateApplied, StateCanceling}},
+		"lastmodified": bson.M{"$lt": time.Now().Add(-timeout)},
+	}).All(&ts)
+	if err != nil {
+		return 0, err
+	}
+
+	for n := range ts {
+		t := &ts[n]
+
+		switch t.State {
+		case StatePending:
+			err = commitPending(s, t)
+		case StateApplied:
+			err = finish(s, t)
+		case StateCanceling:
+			err = rollback(s, t)
+		}
+		if err != nil && !errors.Is(err, ErrStateChanged) {
+			return n, err
+		}
+	}
+	return len(ts), nil
+}
+
+func commitPending(s *mgo.Session, t *Transaction) error {
+	for _, c := range t.Changes {
+		err := mongo.GetColl(s, c.Collection).Update(
+			bson.M{"_id": c.Id, PendingField: bson.M{"$ne": t.Id}},
+			withPending(c.Apply, "$push", t.Id),
+		)
+		if err != nil && err != mgo.ErrNotFound {
+			return rollbackAfter(s, t, err)
+		}
+	}
+
+	if err := transition(s, t, StatePending, StateApplied); err != nil {
+		return err
+	}
+	return finish(s, t)
+}
+
+// finish removes the transaction from the changed documents and marks it
+// done.
+func finish(s *mgo.Session, t *Transaction) error {
+	for _, c := range t.Changes {
+		err := mongo.GetColl(s, c.Collection).Update(
+			bson.M{"_id": c.Id, PendingField: t.Id},
+			bson.M{"$pull": bson.M{PendingField: t.Id}},
+		)
+		if err != nil && err != mgo.ErrNotFound {
+			return err
+		}
+	}
+
+	return transition(s, t, StateApplied, StateDone)
+}
+
+func rollbackAfter(s *mgo.Session, t *Transaction, cause error) error {
+	if err := transition(s, t, StatePending, StateCanceling); err != nil {
+		return fmt.Errorf("%v (rolling back: %v)", cause, err)
+	}
+	if err := rollback(s, t); err != nil {
+		return fmt.Errorf("%v (rolling back: %v)", cause, err)
+	}
+	return cause
+}
+
+// rollback reverts the changes applied by a canceling transaction.
+func rollback(s *mgo.Session, t *Transaction) error {
+	for _, c := range t.Changes {
+		err := mongo.GetColl(s, c.Collection).Update(
+			bson.M{"_id": c.Id, PendingField: t.Id},
+			withPending(c.Revert, "$pull", t.Id),
+		)
+		if err != nil && err != mgo.ErrNotFound {
+			return err
+		}
+	}
+
+	return transition(s, t, StateCanceling, StateCanceled)
+}
+
+// transition moves t from one state to another, failing if it's no longer
+// in the first.
+func transition(s *mgo.Session, t *Transaction, from, to string) error {
+	ts := time.Now()
+	err := mongo.GetColl(s, Collection).Update(
+		bson.M{"_id": t.Id, "state": from},
+		bson.M{"$set": bson.M{"state": to, "lastmodified": ts}},
+	)
+	if err == mgo.ErrNotFound {
+		return fmt.Errorf("%w: expected %v", ErrStateChanged, from)
+	}
+	if err != nil {
+		return err
+	}
+
+	t.State, t.LastModified = to, ts
+	return nil
+}
+
+// withPending returns a copy of update that also adds or removes the
+// transaction id from PendingField with op, $push or $pull.
+func withPending(update bson.M, op string, id bson.ObjectId) bson.M {
+	out := bson.M{}
+	for k, v := range update {
+		out[k] = v
+	}
+
+	fields := bson.M{}
+	if existing, ok := out[op].(bson.M); ok {
+		for k, v := range existing {
+			fields[k] = v
+		}
+	}
+	fields[PendingField] = id
+	out[op] = fields
+
+	return out
+}
@@ -0,0 +1,36 @@
+package twophase
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"reflect"
+	"testing"
+)
+
+func TestWithPending(t *testing.T) {
+	id := bson.NewObjectId()
+	apply := bson.M{"$inc": bson.M{"balance": -100}, "$push": bson.M{"log": "debit"}}
+
+	update := withPending(apply, "$push", id)
+
+	expected := bson.M{"$inc": bson.M{"balance": -100}, "$push": bson.M{"log": "debit", PendingField: id}}
+	if !reflect.DeepEqual(update, expected) {
+		t.Fatal("Expected", expected, "got:", update)
+	}
+
+	if _, ok := apply["$push"].(bson.M)[PendingField]; ok {
+		t.Fatal("Expected the change's update to be left alone")
+	}
+
+	update = withPending(bson.M{"$inc": bson.M{"balance": 100}}, "$pull", id)
+	expected = bson.M{"$inc": bson.M{"balance": 100}, "$pull": bson.M{PendingField: id}}
+	if !reflect.DeepEqual(update, expected) {
+		t.Fatal("Expected", expected, "got:", update)
+	}
+}
+
+func TestBeginNoChanges(t *testing.T) {
+	if _, err := Begin(); err == nil {
+		t.Fatal("Expected an error for a transaction without changes")
+	}
+}
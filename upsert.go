@@ -0,0 +1,206 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+)
+
+// Find the record matching q or create it atomically if there's none, so
+// concurrent callers never create duplicates. i must be a pointer to a
+// struct and ends up holding the stored record either way. defaults, which
+// may be nil, is called before the upsert to fill in i; its values and the
+// generated Id and timestamps are only stored if the record is created.
+// Fields matched by equality in q are taken from q.
+//
+//	user := &User{}
+//	created, err := mongo.GetOrCreate(user, bson.M{"email": email}, func() {
+//		user.Email = email
+//		user.Plan = "free"
+//	})
+//
+// A unique index on the fields of q is still needed to make this safe when
+// two upserts race, see mgo.IsDup.
+func GetOrCreate(i interface{}, q bson.M, defaults func()) (created bool, err error) {
+	return std.GetOrCreate(i, q, defaults)
+}
+
+// Find or create a record using the client. See GetOrCreate.
+func (c *Client) GetOrCreate(i interface{}, q bson.M, defaults func()) (created bool, err error) {
+	if !isPtr(i) {
+		return false, NoPtr
+	}
+
+	q = scopeQuery(i, q)
+	op := c.startOp("update", collName(i), q)
+
+	if defaults != nil {
+		defaults()
+	}
+
+	return c.upsert(op, i, q, func(string) bool { return true })
+}
+
+// UpsertOptions configures UpsertWith.
+type UpsertOptions struct {
+	// InsertOnly names struct fields, e.g. "Plan", that are only stored when
+	// the record is created. Id, CreatedAt and readonly and immutable fields
+	// always are.
+	InsertOnly []string
+}
+
+// Insert the record matching q or update it if it exists. See UpsertWith.
+func Upsert(i interface{}, q bson.M) (created bool, err error) {
+	return std.Upsert(i, q)
+}
+
+// Upsert a record using the client. See Upsert.
+func (c *Client) Upsert(i interface{}, q bson.M) (created bool, err error) {
+	return c.UpsertWith(i, q, UpsertOptions{})
+}
+
+// Insert the record matching q or update it if it exists, atomically. i
+// must be a pointer to a struct and ends up holding the stored record. Id,
+// CreatedAt and the fields in opts.InsertOnly are set with $setOnInsert, so
+// an existing record keeps its values; every other field, including
+// UpdatedAt, is set on each write.
+//
+//	created, err := mongo.UpsertWith(profile, bson.M{"userid": id}, mongo.UpsertOptions{
+//		InsertOnly: []string{"Plan"},
+//	})
+func UpsertWith(i interface{}, q bson.M, opts UpsertOptions) (created bool, err error) {
+	return std.UpsertWith(i, q, opts)
+}
+
+// Upsert a record with options using the client. See UpsertWith.
+func (c *Client) UpsertWith(i interface{}, q bson.M, opts UpsertOptions) (created bool, err error) {
+	if !isPtr(i) {
+		return false, NoPtr
+	}
+
+	q = scopeQuery(i, q)
+	op := c.startOp("update", collName(i), q)
+
+	insertOnly := map[string]bool{"_id": true, fieldKey(i, "CreatedAt"): true}
+	for _, name := range opts.InsertOnly {
+		insertOnly[fieldKey(i, name)] = true
+	}
+
+	readonly, immutable := protectedKeys(i)
+	for _, k := range append(readonly, immutable...) {
+		insertOnly[k] = true
+	}
+
+	return c.upsert(op, i, q, func(key string) bool { return insertOnly[key] })
+}
+
+// upsert stores i in the record matching q, creating it if needed. Keys for
+// which insertOnly returns true are only written on creation; those matched
+// by q are left for the server to copy from q.
+func (c *Client) upsert(op *operation, i interface{}, q bson.M, insertOnly func(key string) bool) (bool, error) {
+	if err := op.allowed(); err != nil {
+		return false, op.done(err)
+	}
+
+	if err := normalize(i); err != nil {
+		return false, op.done(err)
+	}
+
+	if err := validate(i); err != nil {
+		return false, op.done(err)
+	}
+
+	if err := c.addNewFields(i); err != nil {
+		return false, op.done(err)
+	}
+
+	doc, err := recordDoc(i)
+	if err != nil {
+		return false, op.done(err)
+	}
+
+	// Lazy fields that weren't loaded keep their stored values.
+	_, unloaded := lazyKeys(i)
+	for _, k := range unloaded {
+		delete(doc, k)
+	}
+
+	s, err := c.GetSession()
+	if err != nil {
+		return false, op.done(err)
+	}
+	defer s.Close()
+
+	if q, err = op.check(c, s, q, true); err != nil {
+		return false, op.done(err)
+	}
+
+	coll := c.GetColl(s, op.coll)
+	change := mgo.Change{Update: upsertUpdate(doc, q, insertOnly), Upsert: true, ReturnNew: true}
+
+	// The record is only read first when counters need it.
+	var before bson.M
+	if counted(i) {
+		if err := coll.Find(q).One(&before); err != nil && err != mgo.ErrNotFound {
+			return false, op.done(err)
+		}
+	}
+
+	var raw bson.Raw
+	info, err := coll.Find(q).Apply(change, &raw)
+	if err != nil {
+		return false, op.done(err)
+	}
+
+	if err := unmarshalRecord(c, s, raw, i); err != nil {
+		return false, op.done(err)
+	}
+
+	id, err := getObjIdFromStruct(i)
+	if err != nil {
+		return false, op.done(err)
+	}
+
+	created := info.UpsertedId != nil
+	if created {
+		before = nil
+	}
+	return created, op.done(c.afterWrite(s, i, written{id: id, before: before, after: raw, created: created}))
+}
+
+// upsertUpdate splits doc into $set and $setOnInsert, leaving out empty
+// operators which the server rejects.
+func upsertUpdate(doc, q bson.M, insertOnly func(key string) bool) bson.M {
+	set, setOnInsert := bson.M{}, bson.M{}
+	for k, v := range doc {
+		if !insertOnly(k) {
+			set[k] = v
+		} else if _, ok := q[k]; !ok {
+			setOnInsert[k] = v
+		}
+	}
+
+	update := bson.M{}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	if len(setOnInsert) > 0 {
+		update["$setOnInsert"] = setOnInsert
+	}
+	return update
+}
+
+// recordDoc returns the stored representation of i as a map.
+func recordDoc(i interface{}) (bson.M, error) {
+	rec, err := marshalRecord(i)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := bson.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := bson.M{}
+	return doc, bson.Unmarshal(data, &doc)
+}
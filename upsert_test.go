@@ -0,0 +1,44 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"testing"
+)
+
+func TestGetOrCreateDefaultsBeforeValidation(t *testing.T) {
+	if _, err := GetOrCreate(MongoTest{}, nil, nil); err != NoPtr {
+		t.Fatal("Expected NoPtr, got:", err)
+	}
+
+	m := &validatedModel{}
+	called := false
+	_, err := GetOrCreate(m, nil, func() { called = true })
+
+	if !called {
+		t.Fatal("Expected defaults to be called")
+	}
+	if err == nil {
+		t.Fatal("Expected the record to fail validation")
+	}
+}
+
+func TestUpsertUpdate(t *testing.T) {
+	doc := bson.M{"_id": 1, "createdat": 2, "updatedat": 3, "email": "a@b.c", "plan": "free"}
+	insertOnly := map[string]bool{"_id": true, "createdat": true, "email": true}
+
+	u := upsertUpdate(doc, bson.M{"email": "a@b.c"}, func(k string) bool { return insertOnly[k] })
+
+	set, setOnInsert := u["$set"].(bson.M), u["$setOnInsert"].(bson.M)
+	if len(set) != 2 || set["updatedat"] != 3 || set["plan"] != "free" {
+		t.Fatal("Unexpected $set:", set)
+	}
+	if len(setOnInsert) != 2 || setOnInsert["_id"] != 1 || setOnInsert["createdat"] != 2 {
+		t.Fatal("Unexpected $setOnInsert, fields in the query should be left out:", setOnInsert)
+	}
+
+	u = upsertUpdate(bson.M{"_id": 1}, nil, func(string) bool { return true })
+	if _, ok := u["$set"]; ok {
+		t.Fatal("Expected an empty $set to be left out:", u)
+	}
+}
@@ -0,0 +1,40 @@
+package mongo
+
+// Validator is implemented by models that check themselves before they're
+// inserted or updated. A non-nil error stops the write and is returned
+// wrapped in a *ValidationError.
+type Validator interface {
+	Validate() error
+}
+
+// ValidationError is returned when a model's Validate method fails or one of
+// its geometry fields is invalid.
+type ValidationError struct {
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	return "Validation failed: " + e.Err.Error()
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// validate checks the geometry fields of i and then calls its Validate
+// method.
+func validate(i interface{}) error {
+	if err := validateGeo(i); err != nil {
+		return &ValidationError{Err: err}
+	}
+
+	v, ok := i.(Validator)
+	if !ok {
+		return nil
+	}
+
+	if err := v.Validate(); err != nil {
+		return &ValidationError{Err: err}
+	}
+	return nil
+}
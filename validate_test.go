@@ -0,0 +1,42 @@
+package mongo
+
+import (
+	"errors"
+	"testing"
+)
+
+type validatedModel struct {
+	Name string
+}
+
+func (m *validatedModel) Validate() error {
+	if m.Name == "" {
+		return errors.New("name is required")
+	}
+	return nil
+}
+
+func TestValidate(t *testing.T) {
+	err := validate(&validatedModel{})
+
+	var verr *ValidationError
+	if !errors.As(err, &verr) || verr.Err.Error() != "name is required" {
+		t.Fatal("Expected a ValidationError, got:", err)
+	}
+
+	if err := validate(&validatedModel{Name: "George"}); err != nil {
+		t.Fatal("Expected a valid model to pass:", err)
+	}
+
+	if err := Insert(&validatedModel{}); !errors.As(err, &verr) {
+		t.Fatal("Expected Insert to validate before connecting, got:", err)
+	}
+}
+
+func TestPageCount(t *testing.T) {
+	for _, c := range [][3]int{{0, 20, 0}, {1, 20, 1}, {20, 20, 1}, {21, 20, 2}} {
+		if n := pageCount(c[0], c[1]); n != c[2] {
+			t.Fatalf("pageCount(%v, %v) = %v, expected %v", c[0], c[1], n, c[2])
+		}
+	}
+}
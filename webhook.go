@@ -0,0 +1,144 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// SignatureHeader carries the HMAC-SHA256 of a webhook's body, hex encoded
+// and prefixed with "sha256=".
+const SignatureHeader = "X-Mongo-Signature"
+
+// WebhookDispatcher POSTs a JSON notification for each ChangeEvent to a set
+// of URLs. Its Handle method can be passed straight to NewOplogTailer.
+type WebhookDispatcher struct {
+	URLs []string
+	// Secret signs each body so receivers can verify where it came from.
+	Secret []byte
+	// MaxRetries is the number of times a failed delivery is retried.
+	// Network errors and 5xx responses are retried, other responses aren't.
+	MaxRetries int
+	// Backoff is the wait before the first retry. It doubles on each retry.
+	Backoff time.Duration
+	// OnError, if set, is called when a delivery fails for good and the
+	// event is skipped. Without it Handle returns the error, which stops a
+	// tailer until the receiver is fixed.
+	OnError func(url string, ev ChangeEvent, err error)
+	Client  *http.Client
+}
+
+// WebhookPayload is the JSON body of a notification.
+type WebhookPayload struct {
+	Op         ChangeOp    `json:"op"`
+	Collection string      `json:"collection"`
+	Id         interface{} `json:"id"`
+	Doc        bson.M      `json:"doc,omitempty"`
+	Timestamp  int64       `json:"timestamp"`
+}
+
+// Create a dispatcher that signs notifications with secret and sends them
+// to urls.
+func NewWebhookDispatcher(secret string, urls ...string) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		URLs:       urls,
+		Secret:     []byte(secret),
+		MaxRetries: 3,
+		Backoff:    time.Second,
+		Client:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Handle delivers ev to every URL.
+func (d *WebhookDispatcher) Handle(ev ChangeEvent) error {
+	payload := WebhookPayload{
+		Op:         ev.Op,
+		Collection: ev.Collection,
+		Id:         ev.Id,
+		Timestamp:  int64(ev.Timestamp),
+	}
+
+	if len(ev.Doc.Data) > 0 {
+		if err := ev.Decode(&payload.Doc); err != nil {
+			return err
+		}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	for _, url := range d.URLs {
+		if err := d.deliver(url, body); err != nil {
+			if d.OnError == nil {
+				return err
+			}
+			d.OnError(url, ev, err)
+		}
+	}
+
+	return nil
+}
+
+// Sign returns the signature header value for body.
+func (d *WebhookDispatcher) Sign(body []byte) string {
+	mac := hmac.New(sha256.New, d.Secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (d *WebhookDispatcher) deliver(url string, body []byte) error {
+	wait := d.Backoff
+	var err error
+
+	for attempt := 0; attempt <= d.MaxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(wait)
+			wait *= 2
+		}
+
+		var retry bool
+		if retry, err = d.post(url, body); err == nil || !retry {
+			return err
+		}
+	}
+
+	return err
+}
+
+// post sends a single request. The bool is true if a failure is worth
+// retrying.
+func (d *WebhookDispatcher) post(url string, body []byte) (bool, error) {
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, d.Sign(body))
+
+	client := d.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+
+	err = fmt.Errorf("Webhook %v responded with %v", url, resp.Status)
+	return resp.StatusCode >= 500, err
+}
@@ -0,0 +1,65 @@
+package mongo
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWebhookDispatcher(t *testing.T) {
+	calls := 0
+	var got WebhookPayload
+	var d *WebhookDispatcher
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Header.Get(SignatureHeader) != d.Sign(body) {
+			t.Error("Bad signature:", r.Header.Get(SignatureHeader))
+		}
+		json.Unmarshal(body, &got)
+	}))
+	defer srv.Close()
+
+	d = NewWebhookDispatcher("secret", srv.URL)
+	d.Backoff = 0
+
+	if err := d.Handle(ChangeEvent{Op: ChangeDelete, Collection: "MongoTest", Id: "abc"}); err != nil {
+		t.Fatal("Delivery failed:", err)
+	}
+
+	if calls != 2 {
+		t.Fatal("Expected a retry after the 503. Calls:", calls)
+	}
+
+	if got.Op != ChangeDelete || got.Collection != "MongoTest" || got.Id != "abc" {
+		t.Fatalf("Unexpected payload: %+v", got)
+	}
+}
+
+func TestWebhookNoRetryOn4xx(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	d := NewWebhookDispatcher("secret", srv.URL)
+	d.Backoff = 0
+
+	if err := d.Handle(ChangeEvent{Op: ChangeDelete}); err == nil {
+		t.Fatal("Expected an error for a 400")
+	}
+
+	if calls != 1 {
+		t.Fatal("4xx responses shouldn't be retried. Calls:", calls)
+	}
+}
@@ -0,0 +1,438 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrWriteConflict is passed to WriteQueue.OnDrop for queued writes dropped
+// because the record changed on the server after they were queued.
+var ErrWriteConflict = errors.New("Record changed on the server after the write was queued")
+
+// ConflictPolicy decides what WriteQueue.Replay does with queued writes to
+// records that changed on the server meanwhile.
+type ConflictPolicy int
+
+const (
+	// QueuedWins applies queued writes whatever the server holds: inserts
+	// and updates replace the stored record, creating it if needed.
+	QueuedWins ConflictPolicy = iota
+	// ServerWins drops queued writes to records inserted, updated or
+	// deleted on the server after the write was queued. Updates are only
+	// detected for records with an UpdatedAt field.
+	ServerWins
+)
+
+// QueuedWrite is a write held in a WriteQueue.
+type QueuedWrite struct {
+	Kind       string        `bson:"kind"`
+	Collection string        `bson:"collection"`
+	Id         bson.ObjectId `bson:"id"`
+	Doc        bson.Raw      `bson:"doc,omitempty"`
+	// Stamp is the key of the record's UpdatedAt field, if it has one.
+	Stamp    string    `bson:"stamp,omitempty"`
+	QueuedAt time.Time `bson:"queuedat"`
+}
+
+// WriteQueue sends writes to the database while it's reachable, and queues
+// them in a local file while it isn't, for deployments with flaky
+// connectivity. Queued writes are replayed in order by Replay or Run:
+//
+//	queue, err := mongo.OpenWriteQueue("/var/lib/sensor/writes.queue")
+//	queue.Conflicts = mongo.ServerWins
+//	go queue.Run(10*time.Second, stop, logErr)
+//
+//	err = queue.Insert(reading)
+//
+// Once a write is queued the following ones are queued as well until the
+// queue is replayed, so they're applied in the order they were made. Queued
+// inserts and updates are replayed as whole documents, so readonly and
+// immutable fields aren't protected and lazy fields must be loaded.
+type WriteQueue struct {
+	// Conflicts is the policy for records changed on the server while the
+	// writes were queued.
+	Conflicts ConflictPolicy
+	// OnDrop is called, if set, with each queued write Replay drops, either
+	// because of a conflict or because the server rejected it.
+	OnDrop func(w QueuedWrite, err error)
+
+	client *Client
+	path   string
+	// apply replays a single write, with replay outside of tests.
+	apply func(w QueuedWrite) error
+
+	mu      sync.Mutex
+	file    *os.File
+	pending int
+}
+
+// Open the queue kept in the file at path, creating it if needed. Writes
+// queued by an earlier process are kept.
+func OpenWriteQueue(path string) (*WriteQueue, error) {
+	return std.OpenWriteQueue(path)
+}
+
+// Open a write queue using the client. See OpenWriteQueue.
+func (c *Client) OpenWriteQueue(path string) (*WriteQueue, error) {
+	q := &WriteQueue{client: c, path: path}
+	q.apply = q.replay
+
+	writes, err := q.read()
+	if err != nil {
+		return nil, err
+	}
+	q.pending = len(writes)
+
+	if q.file, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// Close the queue's file. Queued writes stay in it.
+func (q *WriteQueue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.file.Close()
+}
+
+// Returns the number of queued writes.
+func (q *WriteQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.pending
+}
+
+// Insert a record like Insert, or queue it if the database is unreachable.
+func (q *WriteQueue) Insert(rec interface{}) error {
+	return q.write("insert", rec, q.client.insert)
+}
+
+// Update a record like Update, or queue it if the database is unreachable.
+func (q *WriteQueue) Update(rec interface{}) error {
+	return q.write("update", rec, q.client.Update)
+}
+
+// Delete a record like Delete, or queue it if the database is unreachable.
+func (q *WriteQueue) Delete(rec interface{}) error {
+	return q.write("delete", rec, q.client.Delete)
+}
+
+func (q *WriteQueue) write(kind string, rec interface{}, direct func(interface{}) error) error {
+	if !isPtr(rec) {
+		return NoPtr
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.pending == 0 {
+		// The write functions prepare the record before they connect, so it
+		// has its Id and timestamps if they fail to.
+		if err := direct(rec); err == nil || !isTimeout(err) {
+			return err
+		}
+	} else if err := q.client.prepareQueued(kind, rec); err != nil {
+		return err
+	}
+
+	w := QueuedWrite{Kind: kind, Collection: collName(rec), QueuedAt: now()}
+	if hasStructField(rec, "UpdatedAt") {
+		w.Stamp = fieldKey(rec, "UpdatedAt")
+	}
+
+	var err error
+	if w.Id, err = getObjIdFromStruct(rec); err != nil {
+		return err
+	}
+
+	if kind != "delete" {
+		doc, err := marshalRecord(rec)
+		if err != nil {
+			return err
+		}
+		data, err := bson.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		w.Doc = bson.Raw{Kind: 0x03, Data: data}
+	}
+
+	data, err := bson.Marshal(w)
+	if err != nil {
+		return err
+	}
+	if _, err := q.file.Write(data); err != nil {
+		return err
+	}
+	if err := q.file.Sync(); err != nil {
+		return err
+	}
+
+	q.pending++
+	return nil
+}
+
+// prepareQueued does to rec what the write functions do before they write.
+func (c *Client) prepareQueued(kind string, rec interface{}) error {
+	if kind == "delete" {
+		return nil
+	}
+
+	if err := normalize(rec); err != nil {
+		return err
+	}
+	if err := validate(rec); err != nil {
+		return err
+	}
+
+	if kind == "insert" {
+		return c.addNewFields(rec)
+	}
+	return addCurrentDateTime(rec, "UpdatedAt")
+}
+
+// Apply the queued writes in order. Stops when the database is unreachable,
+// keeping the writes that are left. Returns the number of writes taken off
+// the queue, including dropped ones.
+func (q *WriteQueue) Replay() (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.pending == 0 {
+		return 0, nil
+	}
+
+	writes, err := q.read()
+	if err != nil {
+		return 0, err
+	}
+
+	done := 0
+	for _, w := range writes {
+		if err = q.apply(w); err != nil && !rejected(err) {
+			break
+		}
+		if err != nil && q.OnDrop != nil {
+			q.OnDrop(w, err)
+		}
+		err = nil
+		done++
+	}
+
+	if done > 0 {
+		if rerr := q.rewrite(writes[done:]); rerr != nil {
+			return done, rerr
+		}
+	}
+	return done, err
+}
+
+// rejected returns whether err means the server, or the conflict policy,
+// rejected a write, which is then dropped. Other errors, such as failing to
+// connect, leave the write queued.
+func rejected(err error) bool {
+	var oe *OpError
+	return errors.As(err, &oe) && !isTimeout(err)
+}
+
+// replay applies a single queued write.
+func (q *WriteQueue) replay(w QueuedWrite) error {
+	s, err := q.client.GetSession()
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	op := q.client.startOp(w.Kind, w.Collection, bson.M{"_id": w.Id})
+
+	if err := op.allowed(); err != nil {
+		return op.done(err)
+	}
+
+	coll := q.client.GetColl(s, w.Collection)
+
+	var stored bson.M
+	if q.Conflicts == ServerWins {
+		if stored, err = storedStamp(coll, w); err != nil {
+			return op.done(err)
+		}
+	}
+
+	action, err := q.resolve(w, stored)
+	if err != nil {
+		return op.done(err)
+	}
+
+	switch action {
+	case "insert":
+		err = coll.Insert(w.Doc)
+	case "update":
+		err = coll.UpdateId(w.Id, w.Doc)
+	case "upsert":
+		_, err = coll.UpsertId(w.Id, w.Doc)
+	case "remove":
+		if err = coll.RemoveId(w.Id); err == mgo.ErrNotFound {
+			err = nil
+		}
+	}
+
+	if mgo.IsDup(err) || err == mgo.ErrNotFound {
+		return op.done(ErrWriteConflict)
+	}
+	if err != nil {
+		return op.done(err)
+	}
+
+	// Queued writes don't keep their model, so the write is run through
+	// the hooks by collection.
+	var deleted []interface{}
+	if w.Kind == "delete" && collectionDeletesTracked(w.Collection) {
+		deleted = []interface{}{w.Id}
+	}
+	return op.done(q.client.afterCollectionWrite(s, w.Collection, deleted))
+}
+
+// resolve returns how w is applied under the queue's conflict policy:
+// "insert", "update", "upsert" or "remove". stored is the _id and stamp of
+// the record w writes to, or nil if there's none, and is only used by
+// ServerWins. Returns ErrWriteConflict if w must be dropped.
+func (q *WriteQueue) resolve(w QueuedWrite, stored bson.M) (string, error) {
+	if w.Kind == "delete" {
+		if q.Conflicts == ServerWins && changedSince(w, stored) {
+			return "", ErrWriteConflict
+		}
+		return "remove", nil
+	}
+
+	if q.Conflicts == QueuedWins {
+		return "upsert", nil
+	}
+
+	switch {
+	case w.Kind == "insert" && stored != nil:
+		return "", ErrWriteConflict
+	case w.Kind == "insert":
+		return "insert", nil
+	case stored == nil || changedSince(w, stored):
+		return "", ErrWriteConflict
+	}
+	return "update", nil
+}
+
+// changedSince returns whether stored was updated after w was queued.
+func changedSince(w QueuedWrite, stored bson.M) bool {
+	ts, ok := stored[w.Stamp].(time.Time)
+	return ok && ts.After(w.QueuedAt)
+}
+
+// storedStamp returns the _id and stamp of the record w writes to, or nil
+// if there's none.
+func storedStamp(coll *mgo.Collection, w QueuedWrite) (bson.M, error) {
+	fields := bson.M{"_id": 1}
+	if w.Stamp != "" {
+		fields[w.Stamp] = 1
+	}
+
+	stored := bson.M{}
+	err := coll.FindId(w.Id).Select(fields).One(&stored)
+	if err == mgo.ErrNotFound {
+		return nil, nil
+	}
+	return stored, err
+}
+
+// Replay the queue every interval until stop is closed. Errors are passed
+// to onError, which may be nil.
+func (q *WriteQueue) Run(interval time.Duration, stop <-chan struct{}, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := q.Replay(); err != nil && onError != nil {
+			onError(err)
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// read returns the writes in the queue's file.
+func (q *WriteQueue) read() ([]QueuedWrite, error) {
+	f, err := os.Open(q.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var writes []QueuedWrite
+	for {
+		doc, err := readDocument(f)
+		if err == io.EOF {
+			return writes, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var w QueuedWrite
+		if err := bson.Unmarshal(doc, &w); err != nil {
+			return nil, err
+		}
+		writes = append(writes, w)
+	}
+}
+
+// rewrite replaces the queue's file with one holding writes, renaming it
+// into place so a crash leaves either the old or the new queue.
+func (q *WriteQueue) rewrite(writes []QueuedWrite) error {
+	tmp := q.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	for _, w := range writes {
+		data, err := bson.Marshal(w)
+		if err == nil {
+			_, err = f.Write(data)
+		}
+		if err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp, q.path); err != nil {
+		return err
+	}
+
+	q.file.Close()
+	if q.file, err = os.OpenFile(q.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600); err != nil {
+		return err
+	}
+	q.pending = len(writes)
+	return nil
+}
@@ -0,0 +1,235 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriteQueueEmpty(t *testing.T) {
+	q, err := OpenWriteQueue(filepath.Join(t.TempDir(), "writes.queue"))
+	if err != nil {
+		t.Fatal("Couldn't open the queue:", err)
+	}
+	defer q.Close()
+
+	if n := q.Len(); n != 0 {
+		t.Fatal("Expected an empty queue got:", n)
+	}
+
+	// Nothing is queued, so replaying doesn't need the database.
+	if n, err := q.Replay(); n != 0 || err != nil {
+		t.Fatal("Expected nothing to replay got:", n, err)
+	}
+
+	if err := q.Insert(validatedModel{Name: "George"}); err != NoPtr {
+		t.Fatal("Expected NoPtr got:", err)
+	}
+}
+
+type queuedItem struct {
+	Id        bson.ObjectId `bson:"_id"`
+	Name      string        `bson:"name"`
+	CreatedAt time.Time     `bson:"createdat"`
+	UpdatedAt time.Time     `bson:"updatedat"`
+}
+
+// openQueued opens a queue holding a write queued by an earlier process, so
+// the writes that follow are queued without trying the database.
+func openQueued(t *testing.T) (*WriteQueue, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "writes.queue")
+	q, err := OpenWriteQueue(path)
+	if err != nil {
+		t.Fatal("Couldn't open the queue:", err)
+	}
+
+	earlier := QueuedWrite{Kind: "delete", Collection: collName(&queuedItem{}), Id: bson.NewObjectId(), QueuedAt: now()}
+	if err := q.rewrite([]QueuedWrite{earlier}); err != nil {
+		t.Fatal("Couldn't write the queue:", err)
+	}
+	return q, path
+}
+
+func TestWriteQueuePersists(t *testing.T) {
+	q, path := openQueued(t)
+
+	item := &queuedItem{Name: "George"}
+	if err := q.Insert(item); err != nil {
+		t.Fatal("Couldn't queue the insert:", err)
+	}
+	if !item.Id.Valid() || item.CreatedAt.IsZero() {
+		t.Fatal("Expected the record to be prepared like Insert does got:", item)
+	}
+
+	item.Name = "Jane"
+	if err := q.Update(item); err != nil {
+		t.Fatal("Couldn't queue the update:", err)
+	}
+	if err := q.Delete(item); err != nil {
+		t.Fatal("Couldn't queue the delete:", err)
+	}
+	q.Close()
+
+	q, err := OpenWriteQueue(path)
+	if err != nil {
+		t.Fatal("Couldn't reopen the queue:", err)
+	}
+	defer q.Close()
+
+	if n := q.Len(); n != 4 {
+		t.Fatal("Expected 4 queued writes got:", n)
+	}
+
+	writes, err := q.read()
+	if err != nil {
+		t.Fatal("Couldn't read the queue:", err)
+	}
+	for n, kind := range []string{"delete", "insert", "update", "delete"} {
+		if writes[n].Kind != kind {
+			t.Fatalf("Expected write %v to be a %v got: %v", n, kind, writes[n].Kind)
+		}
+	}
+
+	stored := &queuedItem{}
+	if err := writes[2].Doc.Unmarshal(stored); err != nil || stored.Id != item.Id || stored.Name != "Jane" {
+		t.Fatal("Expected the updated record to be queued got:", stored, err)
+	}
+	if writes[2].Stamp != "updatedat" || writes[2].Collection != collName(item) {
+		t.Fatal("Expected the stamp and collection to be kept got:", writes[2])
+	}
+}
+
+func TestWriteQueueReplay(t *testing.T) {
+	q, path := openQueued(t)
+	defer q.Close()
+
+	for _, name := range []string{"a", "b", "c"} {
+		if err := q.Insert(&queuedItem{Name: name}); err != nil {
+			t.Fatal("Couldn't queue the insert:", err)
+		}
+	}
+
+	// The database goes away after the first two writes.
+	var applied []QueuedWrite
+	q.apply = func(w QueuedWrite) error {
+		if len(applied) == 2 {
+			return errors.New("read tcp: i/o timeout")
+		}
+		applied = append(applied, w)
+		return nil
+	}
+
+	n, err := q.Replay()
+	if n != 2 || err == nil {
+		t.Fatal("Expected two writes replayed and the timeout got:", n, err)
+	}
+	if q.Len() != 2 {
+		t.Fatal("Expected two writes left got:", q.Len())
+	}
+
+	// The rewritten file only holds the writes that are left.
+	reopened, err := OpenWriteQueue(path)
+	if err != nil {
+		t.Fatal("Couldn't reopen the queue:", err)
+	}
+	left, err := reopened.read()
+	reopened.Close()
+	if err != nil || len(left) != 2 {
+		t.Fatal("Expected two writes in the file got:", len(left), err)
+	}
+
+	var first queuedItem
+	if err := left[0].Doc.Unmarshal(&first); err != nil || first.Name != "b" {
+		t.Fatal("Expected the writes left in order got:", first, err)
+	}
+
+	// Failing to connect isn't a rejection, so nothing is dropped.
+	q.apply = func(w QueuedWrite) error {
+		return errors.New("server returned error on SASL authentication step")
+	}
+	q.OnDrop = func(w QueuedWrite, err error) {
+		t.Fatal("Expected nothing to be dropped got:", err)
+	}
+	if n, err := q.Replay(); n != 0 || err == nil || q.Len() != 2 {
+		t.Fatal("Expected the writes to stay queued got:", n, err, q.Len())
+	}
+
+	// Rejected writes are dropped and reported.
+	var dropped []QueuedWrite
+	q.apply = func(w QueuedWrite) error {
+		if len(dropped) == 0 {
+			return &OpError{Op: w.Kind, Collection: w.Collection, Err: ErrWriteConflict}
+		}
+		applied = append(applied, w)
+		return nil
+	}
+	q.OnDrop = func(w QueuedWrite, err error) {
+		if !errors.Is(err, ErrWriteConflict) {
+			t.Fatal("Expected ErrWriteConflict got:", err)
+		}
+		dropped = append(dropped, w)
+	}
+
+	if n, err := q.Replay(); n != 2 || err != nil || q.Len() != 0 {
+		t.Fatal("Expected both writes taken off the queue got:", n, err, q.Len())
+	}
+	if len(dropped) != 1 || len(applied) != 3 {
+		t.Fatal("Expected one write dropped and one applied got:", len(dropped), len(applied))
+	}
+
+	// An empty queue has nothing left to replay.
+	if n, err := q.Replay(); n != 0 || err != nil {
+		t.Fatal("Expected nothing to replay got:", n, err)
+	}
+}
+
+func TestWriteQueueConflicts(t *testing.T) {
+	queued := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	before, after := bson.M{"updatedat": queued.Add(-time.Minute)}, bson.M{"updatedat": queued.Add(time.Minute)}
+
+	write := func(kind string) QueuedWrite {
+		return QueuedWrite{Kind: kind, Id: bson.NewObjectId(), Stamp: "updatedat", QueuedAt: queued}
+	}
+
+	cases := []struct {
+		policy ConflictPolicy
+		w      QueuedWrite
+		stored bson.M
+		action string
+	}{
+		// The server's changes win.
+		{ServerWins, write("insert"), nil, "insert"},
+		{ServerWins, write("insert"), before, ""},
+		{ServerWins, write("update"), before, "update"},
+		{ServerWins, write("update"), after, ""},
+		{ServerWins, write("update"), nil, ""},
+		{ServerWins, write("delete"), before, "remove"},
+		{ServerWins, write("delete"), after, ""},
+		{ServerWins, write("delete"), nil, "remove"},
+		{ServerWins, QueuedWrite{Kind: "update", QueuedAt: queued}, after, "update"},
+
+		// The queued writes win.
+		{QueuedWins, write("insert"), nil, "upsert"},
+		{QueuedWins, write("insert"), after, "upsert"},
+		{QueuedWins, write("update"), after, "upsert"},
+		{QueuedWins, write("update"), nil, "upsert"},
+		{QueuedWins, write("delete"), after, "remove"},
+	}
+
+	for n, c := range cases {
+		q := &WriteQueue{Conflicts: c.policy}
+		action, err := q.resolve(c.w, c.stored)
+
+		if c.action == "" && !errors.Is(err, ErrWriteConflict) {
+			t.Fatalf("Case %v: expected ErrWriteConflict got: %v %v", n, action, err)
+		}
+		if c.action != "" && (action != c.action || err != nil) {
+			t.Fatalf("Case %v: expected %v got: %v %v", n, c.action, action, err)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"reflect"
+	"strings"
+	"sync/atomic"
+)
+
+// ZeroPolicy controls how fields holding their type's zero value are
+// stored by Insert, Update and the other writes.
+type ZeroPolicy int32
+
+const (
+	// ZeroTags follows each field's bson tag: fields tagged omitempty are
+	// left out when zero, others are stored. This is the default.
+	ZeroTags ZeroPolicy = iota
+	// ZeroStore stores zero values even for fields tagged omitempty.
+	ZeroStore
+	// ZeroOmit leaves out every zero valued field.
+	ZeroOmit
+	// ZeroNull stores every zero valued field as null.
+	ZeroNull
+)
+
+var zeroPolicy int32 = int32(ZeroTags)
+
+// Set how zero valued fields are written, overriding omitempty tags, so
+// models with mixed tags produce consistent documents. It applies to the
+// fields of the model and of structs it inlines, not to nested documents,
+// and never to _id. Models implementing Marshaler are written as they
+// marshal themselves.
+func SetZeroPolicy(p ZeroPolicy) {
+	atomic.StoreInt32(&zeroPolicy, int32(p))
+}
+
+// zeroField is a top level field of a record holding its zero value.
+type zeroField struct {
+	key   string
+	value interface{}
+}
+
+// applyZeroPolicy returns the stored representation of rec, a struct or a
+// pointer to one, with the zero policy applied, or rec itself under
+// ZeroTags.
+func applyZeroPolicy(rec interface{}) (interface{}, error) {
+	p := ZeroPolicy(atomic.LoadInt32(&zeroPolicy))
+	if p == ZeroTags || structType(rec) == nil || isSlice(reflect.TypeOf(rec)) {
+		return rec, nil
+	}
+
+	data, err := bson.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc bson.D
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	return zeroDoc(doc, zeroFields(reflect.ValueOf(rec)), p), nil
+}
+
+// zeroDoc applies p to doc given the record's zero valued fields.
+func zeroDoc(doc bson.D, zeros []zeroField, p ZeroPolicy) bson.D {
+	stored := map[string]int{}
+	for n, e := range doc {
+		stored[e.Name] = n
+	}
+
+	drop := map[string]bool{}
+	for _, z := range zeros {
+		n, ok := stored[z.key]
+
+		switch {
+		case p == ZeroOmit:
+			drop[z.key] = true
+		case p == ZeroNull && ok:
+			doc[n].Value = nil
+		case p == ZeroNull:
+			doc = append(doc, bson.DocElem{Name: z.key, Value: nil})
+		case p == ZeroStore && !ok:
+			doc = append(doc, bson.DocElem{Name: z.key, Value: z.value})
+		}
+	}
+
+	if len(drop) == 0 {
+		return doc
+	}
+
+	kept := doc[:0]
+	for _, e := range doc {
+		if !drop[e.Name] {
+			kept = append(kept, e)
+		}
+	}
+	return kept
+}
+
+// zeroFields returns the zero valued fields of the struct in v, following
+// inlined structs. _id and fields the bson package skips are left out.
+func zeroFields(v reflect.Value) []zeroField {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var zeros []zeroField
+	t := v.Type()
+	for n := 0; n < t.NumField(); n++ {
+		f := t.Field(n)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+
+		tag := f.Tag.Get("bson")
+		if tag == "-" {
+			continue
+		}
+
+		if strings.Contains(tag, ",inline") {
+			zeros = append(zeros, zeroFields(v.Field(n))...)
+			continue
+		}
+
+		key := bsonKey(f)
+		if key == "_id" || !v.Field(n).IsZero() {
+			continue
+		}
+		zeros = append(zeros, zeroField{key: key, value: v.Field(n).Interface()})
+	}
+	return zeros
+}
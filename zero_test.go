@@ -0,0 +1,61 @@
+package mongo
+
+import (
+	"github.com/globalsign/mgo/bson"
+
+	"reflect"
+	"testing"
+)
+
+type zeroInline struct {
+	Note string `bson:"note,omitempty"`
+}
+
+type zeroModel struct {
+	Id         bson.ObjectId `bson:"_id,omitempty"`
+	Name       string        `bson:"name"`
+	Age        int           `bson:"age,omitempty"`
+	Tags       []string      `bson:"tags"`
+	zeroInline `bson:",inline"`
+	Skipped    string `bson:"-"`
+}
+
+func TestZeroFields(t *testing.T) {
+	zeros := zeroFields(reflect.ValueOf(&zeroModel{Name: "George"}))
+
+	expected := []zeroField{{key: "age", value: 0}, {key: "tags", value: []string(nil)}, {key: "note", value: ""}}
+	if !reflect.DeepEqual(zeros, expected) {
+		t.Fatal("Expected", expected, "got:", zeros)
+	}
+}
+
+func TestZeroDoc(t *testing.T) {
+	zeros := []zeroField{{key: "age", value: 0}, {key: "tags", value: []string(nil)}}
+	doc := func() bson.D {
+		return bson.D{{Name: "name", Value: "George"}, {Name: "tags", Value: nil}}
+	}
+
+	cases := []struct {
+		policy   ZeroPolicy
+		expected bson.D
+	}{
+		{ZeroOmit, bson.D{{Name: "name", Value: "George"}}},
+		{ZeroNull, bson.D{{Name: "name", Value: "George"}, {Name: "tags", Value: nil}, {Name: "age", Value: nil}}},
+		{ZeroStore, bson.D{{Name: "name", Value: "George"}, {Name: "tags", Value: nil}, {Name: "age", Value: 0}}},
+	}
+
+	for _, c := range cases {
+		if got := zeroDoc(doc(), zeros, c.policy); !reflect.DeepEqual(got, c.expected) {
+			t.Errorf("Policy %v: expected %v got: %v", c.policy, c.expected, got)
+		}
+	}
+}
+
+func TestZeroTagsUnchanged(t *testing.T) {
+	rec := &zeroModel{Name: "George"}
+
+	got, err := applyZeroPolicy(rec)
+	if err != nil || got != rec {
+		t.Fatal("Expected the record itself under ZeroTags got:", got, err)
+	}
+}